@@ -0,0 +1,1020 @@
+package api_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	fileadapter "github.com/casbin/casbin/v3/persist/file-adapter"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/nasermirzaei89/scribble/api"
+	"github.com/nasermirzaei89/scribble/authentication"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/nasermirzaei89/scribble/authentication/oidctest"
+	"github.com/nasermirzaei89/scribble/authentication/webauthntest"
+	"github.com/nasermirzaei89/scribble/authorization"
+	"github.com/nasermirzaei89/scribble/authorization/casbin"
+	"github.com/nasermirzaei89/scribble/contents"
+	"github.com/nasermirzaei89/scribble/database/sqlite3"
+	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/nasermirzaei89/scribble/mail"
+	"github.com/nasermirzaei89/scribble/reactions"
+	"github.com/nasermirzaei89/scribble/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	ctx := context.Background()
+
+	db, err := sqlite3.NewDB(ctx, fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()))
+	require.NoError(t, err)
+
+	err = sqlite3.MigrateUp(ctx, db)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		err := db.Close()
+		require.NoError(t, err)
+	})
+
+	return db
+}
+
+func newTestHandler(
+	t *testing.T,
+	db *sql.DB,
+	passwordHasher authentication.PasswordHasher,
+) (http.Handler, *authentication.Service, *oidctest.Provider, *mail.MemorySender) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	provider, err := casbin.NewAuthorizationProvider(fileadapter.NewAdapter("../policy.csv"))
+	require.NoError(t, err)
+
+	authzSvc, err := authorization.NewService(provider)
+	require.NoError(t, err)
+
+	authzClient := authorization.NewClient(authzSvc)
+
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          "localhost",
+		RPDisplayName: "Scribble",
+		RPOrigins:     []string{"http://localhost:8080"},
+	})
+	require.NoError(t, err)
+
+	identityProvider, err := oidctest.NewProvider("scribble", "client-secret")
+	require.NoError(t, err)
+
+	t.Cleanup(identityProvider.Close)
+
+	oidcProvider, err := authentication.NewOIDCProvider(ctx, authentication.OIDCProviderConfig{
+		Name:         "mock",
+		DisplayName:  "Mock",
+		Issuer:       identityProvider.Issuer(),
+		ClientID:     identityProvider.ClientID,
+		ClientSecret: identityProvider.ClientSecret,
+		RedirectURL:  "http://localhost:8080/login/oidc/mock/callback",
+		Scopes:       []string{"profile", "email"},
+	})
+	require.NoError(t, err)
+
+	mailer := mail.NewMemorySender()
+
+	accountRecovery := authentication.DefaultAccountRecoveryPolicy()
+	accountRecovery.TokenKey = []byte("test-token-key")
+
+	authSvc := authentication.NewService(authentication.Dependencies{
+		UserRepo:           sqlite3.NewUserRepository(db),
+		SessionRepo:        sqlite3.NewSessionRepository(db),
+		APITokenRepo:       sqlite3.NewAPITokenRepository(db),
+		LoginAttemptRepo:   sqlite3.NewLoginAttemptRepository(db),
+		TOTPCredentialRepo: sqlite3.NewTOTPCredentialRepository(db),
+		RecoveryCodeRepo:   sqlite3.NewRecoveryCodeRepository(db),
+		LoginChallengeRepo: sqlite3.NewLoginChallengeRepository(db),
+		PasskeyRepo:        sqlite3.NewPasskeyRepository(db),
+		IdentityRepo:       sqlite3.NewIdentityRepository(db),
+		UserDataRepo:       sqlite3.NewUserDataRepository(db),
+		AuthzClient:        authzClient,
+		WebAuthn:           webAuthn,
+		OIDCProviders:      []*authentication.OIDCProvider{oidcProvider},
+		Mailer:             mailer,
+		PasswordHasher:     passwordHasher,
+		Credentials:        authentication.DefaultCredentialsPolicy(),
+		LoginThrottle:      authentication.DefaultLoginThrottlePolicy(),
+		AccountRecovery:    accountRecovery,
+		AccountDeletion:    authentication.DefaultAccountDeletionPolicy(),
+	})
+
+	handler := api.NewHandler(
+		authSvc,
+		contents.NewService(
+			sqlite3.NewPostRepository(db),
+			sqlite3.NewPostRevisionRepository(db),
+			authzClient,
+		),
+		discuss.NewService(
+			sqlite3.NewCommentRepository(db),
+			sqlite3.NewPostRepository(db),
+			sqlite3.NewUserRepository(db),
+			sqlite3.NewMentionRepository(db),
+			authzClient,
+			discuss.DefaultThreadPolicy(),
+		),
+		reactions.NewService(sqlite3.NewUserReactionRepository(db), authzClient),
+	)
+
+	return handler, authSvc, identityProvider, mailer
+}
+
+func do(t *testing.T, h http.Handler, method, path, token string, body any, out any) int {
+	t.Helper()
+
+	var reqBody bytes.Buffer
+
+	if body != nil {
+		err := json.NewEncoder(&reqBody).Encode(body)
+		require.NoError(t, err)
+	}
+
+	req := httptest.NewRequest(method, path, &reqBody)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if out != nil && rec.Body.Len() > 0 {
+		err := json.Unmarshal(rec.Body.Bytes(), out)
+		require.NoError(t, err, rec.Body.String())
+	}
+
+	return rec.Code
+}
+
+func login(t *testing.T, h http.Handler, username string) string {
+	t.Helper()
+
+	credentials := api.CredentialsRequest{Username: username, Password: "correct horse battery"}
+
+	status := do(t, h, http.MethodPost, "/api/v1/register", "", credentials, nil)
+	require.Equal(t, http.StatusCreated, status)
+
+	var res api.LoginResponse
+
+	status = do(t, h, http.MethodPost, "/api/v1/login", "", credentials, &res)
+	require.Equal(t, http.StatusOK, status)
+	require.NotEmpty(t, res.Token)
+
+	return res.Token
+}
+
+func TestAPI(t *testing.T) {
+	h, authSvc, identityProvider, mailer := newTestHandler(t, newTestDB(t), authentication.DefaultPasswordHasher())
+
+	alice := login(t, h, "alice")
+	bob := login(t, h, "bob")
+
+	var post api.Post
+
+	t.Run("register validates credentials", func(t *testing.T) {
+		var res api.ErrorResponse
+
+		credentials := api.CredentialsRequest{Username: "admin", Password: "short"}
+
+		status := do(t, h, http.MethodPost, "/api/v1/register", "", credentials, &res)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, api.CodeValidationFailed, res.Error.Code)
+		assert.NotEmpty(t, res.Error.Fields[authentication.FieldUsername])
+		assert.NotEmpty(t, res.Error.Fields[authentication.FieldPassword])
+	})
+
+	t.Run("usernames are unique regardless of case", func(t *testing.T) {
+		var res api.ErrorResponse
+
+		credentials := api.CredentialsRequest{Username: "ALICE", Password: "correct horse battery"}
+
+		status := do(t, h, http.MethodPost, "/api/v1/register", "", credentials, &res)
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, api.CodeUserAlreadyExists, res.Error.Code)
+	})
+
+	t.Run("repeated failed logins are throttled", func(t *testing.T) {
+		credentials := api.CredentialsRequest{Username: "carol", Password: "correct horse battery"}
+
+		status := do(t, h, http.MethodPost, "/api/v1/register", "", credentials, nil)
+		require.Equal(t, http.StatusCreated, status)
+
+		credentials.Password = "wrong horse battery"
+
+		freeAttempts := authentication.DefaultLoginThrottlePolicy().FreeAttempts
+
+		for range freeAttempts + 1 {
+			status := do(t, h, http.MethodPost, "/api/v1/login", "", credentials, nil)
+			require.Equal(t, http.StatusUnauthorized, status)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(
+			`{"username":"carol","password":"correct horse battery"}`,
+		))
+		rec := httptest.NewRecorder()
+
+		h.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+		assert.Contains(t, rec.Body.String(), api.CodeTooManyAttempts)
+	})
+
+	t.Run("two-factor login", func(t *testing.T) {
+		dave := login(t, h, "dave")
+
+		var me api.User
+
+		status := do(t, h, http.MethodGet, "/api/v1/me", dave, nil, &me)
+		require.Equal(t, http.StatusOK, status)
+
+		ctx := authcontext.WithSubject(context.Background(), me.ID)
+
+		enrollment, err := authSvc.BeginTOTPEnrollment(ctx)
+		require.NoError(t, err)
+
+		key, err := totp.ParseKey(enrollment.Secret)
+		require.NoError(t, err)
+
+		recoveryCodes, err := authSvc.ConfirmTOTPEnrollment(ctx, key.Code(time.Now()))
+		require.NoError(t, err)
+		require.NotEmpty(t, recoveryCodes)
+
+		credentials := api.CredentialsRequest{Username: "dave", Password: "correct horse battery"}
+
+		startLogin := func(t *testing.T) string {
+			t.Helper()
+
+			var res api.LoginResponse
+
+			status := do(t, h, http.MethodPost, "/api/v1/login", "", credentials, &res)
+			require.Equal(t, http.StatusOK, status)
+			require.True(t, res.SecondFactorRequired)
+			require.Empty(t, res.Token)
+			require.NotEmpty(t, res.ChallengeID)
+
+			return res.ChallengeID
+		}
+
+		challengeID := startLogin(t)
+
+		var errRes api.ErrorResponse
+
+		status = do(t, h, http.MethodPost, "/api/v1/login/second-factor", "",
+			api.SecondFactorRequest{ChallengeID: challengeID, Code: "wrong"}, &errRes)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, api.CodeInvalidCode, errRes.Error.Code)
+
+		var res api.LoginResponse
+
+		status = do(t, h, http.MethodPost, "/api/v1/login/second-factor", "",
+			api.SecondFactorRequest{ChallengeID: challengeID, Code: strings.ToUpper(recoveryCodes[0])}, &res)
+		require.Equal(t, http.StatusOK, status)
+		require.NotEmpty(t, res.Token)
+
+		status = do(t, h, http.MethodGet, "/api/v1/me", res.Token, nil, nil)
+		assert.Equal(t, http.StatusOK, status)
+
+		// The challenge is used up, and so is the recovery code.
+		status = do(t, h, http.MethodPost, "/api/v1/login/second-factor", "",
+			api.SecondFactorRequest{ChallengeID: challengeID, Code: recoveryCodes[1]}, &errRes)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, api.CodeInvalidChallenge, errRes.Error.Code)
+
+		status = do(t, h, http.MethodPost, "/api/v1/login/second-factor", "",
+			api.SecondFactorRequest{ChallengeID: startLogin(t), Code: recoveryCodes[0]}, &errRes)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, api.CodeInvalidCode, errRes.Error.Code)
+	})
+
+	t.Run("passkey login", func(t *testing.T) {
+		erin := login(t, h, "erin")
+
+		var me api.User
+
+		status := do(t, h, http.MethodGet, "/api/v1/me", erin, nil, &me)
+		require.Equal(t, http.StatusOK, status)
+
+		ctx := authcontext.WithSubject(context.Background(), me.ID)
+		authenticator := webauthntest.NewAuthenticator("http://localhost:8080")
+
+		ceremony, err := authSvc.BeginPasskeyRegistration(ctx)
+		require.NoError(t, err)
+
+		response, err := authenticator.Register(ceremony.Options)
+		require.NoError(t, err)
+
+		passkey, err := authSvc.FinishPasskeyRegistration(ctx, "  laptop ", ceremony.State, response)
+		require.NoError(t, err)
+		assert.Equal(t, "laptop", passkey.Name)
+		assert.Equal(t, me.ID, passkey.UserID)
+
+		// The same authenticator can't be registered twice.
+		ceremony, err = authSvc.BeginPasskeyRegistration(ctx)
+		require.NoError(t, err)
+
+		_, err = authenticator.Register(ceremony.Options)
+		require.Error(t, err)
+
+		signIn := func(t *testing.T, authenticator *webauthntest.Authenticator) (*authentication.Session, error) {
+			t.Helper()
+
+			ceremony, err := authSvc.BeginPasskeyLogin(context.Background())
+			require.NoError(t, err)
+
+			response, err := authenticator.Login(ceremony.Options)
+			require.NoError(t, err)
+
+			return authSvc.FinishPasskeyLogin(context.Background(), ceremony.State, response, authentication.ClientInfo{})
+		}
+
+		session, err := signIn(t, authenticator)
+		require.NoError(t, err)
+		assert.Equal(t, me.ID, session.UserID)
+
+		status = do(t, h, http.MethodGet, "/api/v1/me", session.ID, nil, nil)
+		assert.Equal(t, http.StatusOK, status)
+
+		passkeys, err := authSvc.ListPasskeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, passkeys, 1)
+		assert.NotNil(t, passkeys[0].LastUsedAt)
+		assert.Equal(t, uint32(1), passkeys[0].SignCount)
+
+		t.Run("without user verification", func(t *testing.T) {
+			authenticator.UserVerified = false
+			defer func() { authenticator.UserVerified = true }()
+
+			_, err := signIn(t, authenticator)
+			require.ErrorIs(t, err, authentication.ErrInvalidPasskey)
+		})
+
+		t.Run("from another origin", func(t *testing.T) {
+			phishing := &webauthntest.Authenticator{
+				Origin:       "https://scribble.example",
+				UserVerified: true,
+				Credentials:  authenticator.Credentials,
+			}
+
+			_, err := signIn(t, phishing)
+			require.ErrorIs(t, err, authentication.ErrInvalidPasskey)
+		})
+
+		t.Run("with a cloned key", func(t *testing.T) {
+			clone := *authenticator.Credentials[0]
+			clone.SignCount = 0
+
+			cloned := webauthntest.NewAuthenticator(authenticator.Origin)
+			cloned.Credentials = append(cloned.Credentials, &clone)
+
+			_, err := signIn(t, cloned)
+			require.ErrorIs(t, err, authentication.ErrInvalidPasskey)
+		})
+
+		t.Run("replaying a response", func(t *testing.T) {
+			ceremony, err := authSvc.BeginPasskeyLogin(context.Background())
+			require.NoError(t, err)
+
+			response, err := authenticator.Login(ceremony.Options)
+			require.NoError(t, err)
+
+			_, err = authSvc.FinishPasskeyLogin(context.Background(), ceremony.State, response, authentication.ClientInfo{})
+			require.NoError(t, err)
+
+			_, err = authSvc.FinishPasskeyLogin(context.Background(), ceremony.State, response, authentication.ClientInfo{})
+			require.ErrorIs(t, err, authentication.ErrInvalidPasskey)
+		})
+
+		t.Run("after it is removed", func(t *testing.T) {
+			err := authSvc.DeletePasskey(ctx, passkey.ID)
+			require.NoError(t, err)
+
+			_, err = signIn(t, authenticator)
+			require.ErrorIs(t, err, authentication.ErrInvalidPasskey)
+		})
+	})
+
+	t.Run("oidc login", func(t *testing.T) {
+		noRedirects := &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+
+		// authorize sends the user to the provider, which answers at once, and returns the callback parameters.
+		authorize := func(t *testing.T, authorization *authentication.OIDCAuthorization) (string, string) {
+			t.Helper()
+
+			res, err := noRedirects.Get(authorization.URL)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+			require.Equal(t, http.StatusFound, res.StatusCode)
+
+			callback, err := res.Location()
+			require.NoError(t, err)
+			assert.Equal(t, "/login/oidc/mock/callback", callback.Path)
+
+			return callback.Query().Get("state"), callback.Query().Get("code")
+		}
+
+		signIn := func(t *testing.T, claims oidctest.Claims) (*authentication.Session, error) {
+			t.Helper()
+
+			identityProvider.SetClaims(claims)
+
+			authorization, err := authSvc.BeginOIDCLogin(context.Background(), "mock")
+			require.NoError(t, err)
+
+			callbackState, code := authorize(t, authorization)
+
+			return authSvc.FinishOIDCLogin(
+				context.Background(), authorization.State, callbackState, code, authentication.ClientInfo{},
+			)
+		}
+
+		frankClaims := oidctest.Claims{
+			Subject:           "frank-at-mock",
+			Email:             "frank@example.com",
+			EmailVerified:     true,
+			PreferredUsername: "Frank Smith!",
+		}
+
+		session, err := signIn(t, frankClaims)
+		require.NoError(t, err)
+
+		var me api.User
+
+		status := do(t, h, http.MethodGet, "/api/v1/me", session.ID, nil, &me)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "Frank-Smith", me.Username)
+
+		// The account has no password to sign in with.
+		status = do(t, h, http.MethodPost, "/api/v1/login", "",
+			api.CredentialsRequest{Username: "Frank-Smith", Password: "correct horse battery"}, nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		frank := authcontext.WithSubject(context.Background(), me.ID)
+
+		t.Run("signs in to the same user again", func(t *testing.T) {
+			again, err := signIn(t, frankClaims)
+			require.NoError(t, err)
+			assert.Equal(t, me.ID, again.UserID)
+
+			identities, err := authSvc.ListIdentities(frank)
+			require.NoError(t, err)
+			require.Len(t, identities, 1)
+			assert.Equal(t, "frank@example.com", identities[0].Email)
+			assert.NotNil(t, identities[0].LastLoginAt)
+		})
+
+		t.Run("picks a free username", func(t *testing.T) {
+			other, err := signIn(t, oidctest.Claims{Subject: "another-alice", PreferredUsername: "alice"})
+			require.NoError(t, err)
+
+			var user api.User
+
+			status := do(t, h, http.MethodGet, "/api/v1/me", other.ID, nil, &user)
+			require.Equal(t, http.StatusOK, status)
+			assert.Equal(t, "alice-2", user.Username)
+		})
+
+		t.Run("rejects a forged state", func(t *testing.T) {
+			authorization, err := authSvc.BeginOIDCLogin(context.Background(), "mock")
+			require.NoError(t, err)
+
+			_, code := authorize(t, authorization)
+
+			_, err = authSvc.FinishOIDCLogin(
+				context.Background(), authorization.State, "forged", code, authentication.ClientInfo{},
+			)
+			require.ErrorIs(t, err, authentication.ErrInvalidOIDCResponse)
+		})
+
+		t.Run("rejects a code issued to another sign-in", func(t *testing.T) {
+			victim, err := authSvc.BeginOIDCLogin(context.Background(), "mock")
+			require.NoError(t, err)
+
+			attacker, err := authSvc.BeginOIDCLogin(context.Background(), "mock")
+			require.NoError(t, err)
+
+			_, victimCode := authorize(t, victim)
+			attackerState, _ := authorize(t, attacker)
+
+			// The state matches, but the code was bound to the victim's PKCE challenge.
+			_, err = authSvc.FinishOIDCLogin(
+				context.Background(), attacker.State, attackerState, victimCode, authentication.ClientInfo{},
+			)
+			require.ErrorIs(t, err, authentication.ErrInvalidOIDCResponse)
+		})
+
+		t.Run("rejects a reused code", func(t *testing.T) {
+			identityProvider.SetClaims(frankClaims)
+
+			authorization, err := authSvc.BeginOIDCLogin(context.Background(), "mock")
+			require.NoError(t, err)
+
+			callbackState, code := authorize(t, authorization)
+
+			_, err = authSvc.FinishOIDCLogin(
+				context.Background(), authorization.State, callbackState, code, authentication.ClientInfo{},
+			)
+			require.NoError(t, err)
+
+			_, err = authSvc.FinishOIDCLogin(
+				context.Background(), authorization.State, callbackState, code, authentication.ClientInfo{},
+			)
+			require.ErrorIs(t, err, authentication.ErrInvalidOIDCResponse)
+		})
+
+		t.Run("links and unlinks an existing user", func(t *testing.T) {
+			var user api.User
+
+			status := do(t, h, http.MethodGet, "/api/v1/me", alice, nil, &user)
+			require.Equal(t, http.StatusOK, status)
+
+			ctx := authcontext.WithSubject(context.Background(), user.ID)
+
+			link := func(t *testing.T, claims oidctest.Claims) (*authentication.Identity, error) {
+				t.Helper()
+
+				identityProvider.SetClaims(claims)
+
+				authorization, err := authSvc.BeginOIDCLink(ctx, "mock")
+				require.NoError(t, err)
+
+				callbackState, code := authorize(t, authorization)
+
+				return authSvc.FinishOIDCLink(ctx, authorization.State, callbackState, code)
+			}
+
+			identity, err := link(t, oidctest.Claims{Subject: "alice-at-mock"})
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, identity.UserID)
+
+			session, err := signIn(t, oidctest.Claims{Subject: "alice-at-mock"})
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, session.UserID)
+
+			// Another user's account can't be taken over.
+			_, err = link(t, frankClaims)
+
+			var alreadyLinkedErr *authentication.IdentityAlreadyLinkedError
+
+			require.ErrorAs(t, err, &alreadyLinkedErr)
+
+			// Alice still has her password.
+			err = authSvc.UnlinkIdentity(ctx, "mock")
+			require.NoError(t, err)
+
+			err = authSvc.UnlinkIdentity(ctx, "mock")
+
+			var notFoundErr *authentication.IdentityNotFoundError
+
+			require.ErrorAs(t, err, &notFoundErr)
+		})
+
+		_, err = authSvc.BeginOIDCLogin(context.Background(), "unknown")
+
+		var notFoundErr *authentication.OIDCProviderNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+
+		// Frank would be locked out without the identity.
+		err = authSvc.UnlinkIdentity(frank, "mock")
+		require.ErrorIs(t, err, authentication.ErrLastSignInMethod)
+	})
+
+	t.Run("password reset", func(t *testing.T) {
+		eve := login(t, h, "eve")
+
+		var me api.User
+
+		status := do(t, h, http.MethodGet, "/api/v1/me", eve, nil, &me)
+		require.Equal(t, http.StatusOK, status)
+
+		ctx := authcontext.WithSubject(context.Background(), me.ID)
+
+		// lastLinkToken returns the token from the link in the last email sent to the address.
+		lastLinkToken := func(t *testing.T, to string) string {
+			t.Helper()
+
+			msg, ok := mailer.Last()
+			require.True(t, ok)
+			require.Equal(t, to, msg.To)
+
+			match := regexp.MustCompile(`https?://\S+`).FindString(msg.Text)
+			require.NotEmpty(t, match, msg.Text)
+
+			link, err := url.Parse(match)
+			require.NoError(t, err)
+
+			return link.Query().Get("token")
+		}
+
+		err := authSvc.SetEmail(ctx, "Eve <eve@example.com>")
+
+		var validationErr *authentication.ValidationError
+
+		require.ErrorAs(t, err, &validationErr)
+		assert.NotEmpty(t, validationErr.Fields[authentication.FieldEmail])
+
+		err = authSvc.SetEmail(ctx, "Eve@Example.com")
+		require.NoError(t, err)
+
+		verifyToken := lastLinkToken(t, "eve@example.com")
+		sent := len(mailer.Messages())
+
+		// Unverified addresses can't be used to reset the password.
+		err = authSvc.RequestPasswordReset(context.Background(), "eve@example.com")
+		require.NoError(t, err)
+		assert.Len(t, mailer.Messages(), sent)
+
+		err = authSvc.VerifyEmail(context.Background(), verifyToken+"x")
+		require.ErrorIs(t, err, authentication.ErrInvalidToken)
+
+		err = authSvc.VerifyEmail(context.Background(), verifyToken)
+		require.NoError(t, err)
+
+		user, err := authSvc.GetCurrentUser(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "eve@example.com", user.Email)
+		assert.NotNil(t, user.EmailVerifiedAt)
+
+		t.Run("keeps verified addresses to one account", func(t *testing.T) {
+			var grace api.User
+
+			status := do(t, h, http.MethodGet, "/api/v1/me", login(t, h, "grace"), nil, &grace)
+			require.Equal(t, http.StatusOK, status)
+
+			graceCtx := authcontext.WithSubject(context.Background(), grace.ID)
+
+			err := authSvc.SetEmail(graceCtx, "eve@example.com")
+			require.NoError(t, err)
+
+			err = authSvc.VerifyEmail(context.Background(), lastLinkToken(t, "eve@example.com"))
+
+			var inUseErr *authentication.EmailAlreadyInUseError
+
+			require.ErrorAs(t, err, &inUseErr)
+
+			// A link stops working once the address changes.
+			staleToken := lastLinkToken(t, "eve@example.com")
+
+			err = authSvc.SetEmail(graceCtx, "grace@example.com")
+			require.NoError(t, err)
+
+			err = authSvc.VerifyEmail(context.Background(), staleToken)
+			require.ErrorIs(t, err, authentication.ErrInvalidToken)
+		})
+
+		sent = len(mailer.Messages())
+
+		err = authSvc.RequestPasswordReset(context.Background(), "nobody@example.com")
+		require.NoError(t, err)
+		assert.Len(t, mailer.Messages(), sent)
+
+		err = authSvc.RequestPasswordReset(context.Background(), "EVE@example.com")
+		require.NoError(t, err)
+
+		resetToken := lastLinkToken(t, "eve@example.com")
+
+		_, apiToken, err := authSvc.CreateAPIToken(ctx, authentication.CreateAPITokenRequest{
+			Name:   "script",
+			Scopes: []string{authentication.ScopeRead},
+		})
+		require.NoError(t, err)
+
+		status = do(t, h, http.MethodGet, "/api/v1/me", apiToken, nil, nil)
+		require.Equal(t, http.StatusOK, status)
+
+		// Tokens are only good for what they were sent for.
+		_, err = authSvc.CheckPasswordResetToken(context.Background(), verifyToken)
+		require.ErrorIs(t, err, authentication.ErrInvalidToken)
+
+		user, err = authSvc.CheckPasswordResetToken(context.Background(), resetToken)
+		require.NoError(t, err)
+		assert.Equal(t, "eve", user.Username)
+		assert.Empty(t, user.PasswordHash)
+
+		err = authSvc.ResetPassword(context.Background(), resetToken, "password")
+		require.ErrorAs(t, err, &validationErr)
+		assert.NotEmpty(t, validationErr.Fields[authentication.FieldPassword])
+
+		err = authSvc.ResetPassword(context.Background(), resetToken, "a brand new passphrase")
+		require.NoError(t, err)
+
+		// Every session and API token ends, and only the new password works.
+		status = do(t, h, http.MethodGet, "/api/v1/me", eve, nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		status = do(t, h, http.MethodGet, "/api/v1/me", apiToken, nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		status = do(t, h, http.MethodPost, "/api/v1/login", "",
+			api.CredentialsRequest{Username: "eve", Password: "correct horse battery"}, nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		status = do(t, h, http.MethodPost, "/api/v1/login", "",
+			api.CredentialsRequest{Username: "eve", Password: "a brand new passphrase"}, nil)
+		assert.Equal(t, http.StatusOK, status)
+
+		// The link only works once.
+		err = authSvc.ResetPassword(context.Background(), resetToken, "another new passphrase")
+		require.ErrorIs(t, err, authentication.ErrInvalidToken)
+	})
+
+	t.Run("export data and delete account", func(t *testing.T) {
+		mallory := login(t, h, "mallory")
+
+		var me api.User
+
+		status := do(t, h, http.MethodGet, "/api/v1/me", mallory, nil, &me)
+		require.Equal(t, http.StatusOK, status)
+
+		ctx := authcontext.WithSubject(context.Background(), me.ID)
+
+		var own api.Post
+
+		status = do(t, h, http.MethodPost, "/api/v1/posts", mallory, api.PostContentRequest{Content: "# Mine"}, &own)
+		require.Equal(t, http.StatusCreated, status)
+
+		status = do(t, h, http.MethodPost, "/api/v1/posts/"+own.ID+"/comments", mallory,
+			api.CreateCommentRequest{Content: "first"}, nil)
+		require.Equal(t, http.StatusCreated, status)
+
+		var buf bytes.Buffer
+
+		err := authSvc.ExportMyData(ctx, &buf)
+		require.NoError(t, err)
+
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+
+		files := make(map[string]string)
+
+		for _, f := range archive.File {
+			rc, err := f.Open()
+			require.NoError(t, err)
+
+			content, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+
+			files[f.Name] = string(content)
+		}
+
+		assert.Contains(t, files["account.json"], `"username": "mallory"`)
+		assert.Contains(t, files["posts.json"], own.ID)
+		assert.Equal(t, "# Mine", files["posts/"+own.ID+".md"])
+		assert.Contains(t, files["comments.json"], `"content": "first"`)
+		assert.JSONEq(t, "[]", files["reactions.json"])
+		assert.JSONEq(t, "null", files["profile.json"])
+		assert.Contains(t, files["sessions.json"], `"userAgent"`)
+		assert.NotContains(t, files["sessions.json"], `"id"`)
+
+		err = authSvc.DeleteAccount(ctx, "wrong password")
+		require.ErrorIs(t, err, authentication.ErrInvalidCredentials)
+
+		err = authSvc.DeleteAccount(ctx, "correct horse battery")
+		require.NoError(t, err)
+
+		status = do(t, h, http.MethodGet, "/api/v1/me", mallory, nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		status = do(t, h, http.MethodGet, "/api/v1/posts/"+own.ID, "", nil, nil)
+		assert.Equal(t, http.StatusNotFound, status)
+
+		// The username is free again.
+		login(t, h, "mallory")
+	})
+
+	t.Run("create post requires authentication", func(t *testing.T) {
+		var res api.ErrorResponse
+
+		status := do(t, h, http.MethodPost, "/api/v1/posts", "", api.PostContentRequest{Content: "hi"}, &res)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, api.CodeUnauthenticated, res.Error.Code)
+	})
+
+	t.Run("create and get post", func(t *testing.T) {
+		status := do(t, h, http.MethodPost, "/api/v1/posts", alice, api.PostContentRequest{Content: "hello"}, &post)
+		require.Equal(t, http.StatusCreated, status)
+		assert.Equal(t, "hello", post.Content)
+
+		var got api.Post
+
+		status = do(t, h, http.MethodGet, "/api/v1/posts/"+post.ID, "", nil, &got)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, post.ID, got.ID)
+		require.NotNil(t, got.Author)
+		assert.Equal(t, "alice", got.Author.Username)
+	})
+
+	t.Run("missing post", func(t *testing.T) {
+		var res api.ErrorResponse
+
+		status := do(t, h, http.MethodGet, "/api/v1/posts/missing", "", nil, &res)
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, api.CodePostNotFound, res.Error.Code)
+	})
+
+	t.Run("only the author can update", func(t *testing.T) {
+		var res api.ErrorResponse
+
+		path := "/api/v1/posts/" + post.ID
+
+		status := do(t, h, http.MethodPatch, path, bob, api.PostContentRequest{Content: "mine"}, &res)
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, api.CodeAccessDenied, res.Error.Code)
+
+		var updated api.Post
+
+		status = do(t, h, http.MethodPatch, path, alice, api.PostContentRequest{Content: "edited"}, &updated)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "edited", updated.Content)
+	})
+
+	t.Run("comments", func(t *testing.T) {
+		path := "/api/v1/posts/" + post.ID + "/comments"
+
+		status := do(t, h, http.MethodPost, path, bob, api.CreateCommentRequest{Content: "nice"}, nil)
+		require.Equal(t, http.StatusCreated, status)
+
+		var res api.ListCommentsResponse
+
+		status = do(t, h, http.MethodGet, path, "", nil, &res)
+		require.Equal(t, http.StatusOK, status)
+		require.Len(t, res.Comments, 1)
+		assert.Equal(t, "nice", res.Comments[0].Content)
+		assert.Equal(t, "bob", res.Comments[0].Author.Username)
+
+		var errRes api.ErrorResponse
+
+		reply := api.CreateCommentRequest{Content: "reply", ReplyTo: "missing"}
+		status = do(t, h, http.MethodPost, path, bob, reply, &errRes)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, api.CodeParentNotFound, errRes.Error.Code)
+
+		var list api.ListPostsResponse
+
+		status = do(t, h, http.MethodGet, "/api/v1/posts", "", nil, &list)
+		require.Equal(t, http.StatusOK, status)
+		require.Len(t, list.Posts, 1)
+		require.NotNil(t, list.Posts[0].CommentsCount)
+		assert.Equal(t, 1, *list.Posts[0].CommentsCount)
+	})
+
+	t.Run("reactions", func(t *testing.T) {
+		path := "/api/v1/reactions/post/" + post.ID
+
+		var res api.ErrorResponse
+
+		status := do(t, h, http.MethodPost, path, bob, api.ToggleReactionRequest{Emoji: "🦄"}, &res)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, api.CodeInvalidEmoji, res.Error.Code)
+
+		var reacted api.Reactions
+
+		status = do(t, h, http.MethodPost, path, bob, api.ToggleReactionRequest{Emoji: "👍"}, &reacted)
+		require.Equal(t, http.StatusOK, status)
+		assert.Contains(t, reacted.Options, api.ReactionOption{Emoji: "👍", Count: 1, Selected: true, Available: true})
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		var res api.ErrorResponse
+
+		status := do(t, h, http.MethodPost, "/api/v1/posts", alice, map[string]string{"text": "x"}, &res)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, api.CodeInvalidRequest, res.Error.Code)
+	})
+
+	t.Run("logout invalidates the token", func(t *testing.T) {
+		var me api.User
+
+		status := do(t, h, http.MethodGet, "/api/v1/me", bob, nil, &me)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "bob", me.Username)
+
+		status = do(t, h, http.MethodPost, "/api/v1/logout", bob, nil, nil)
+		require.Equal(t, http.StatusNoContent, status)
+
+		status = do(t, h, http.MethodGet, "/api/v1/me", bob, nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+}
+
+func TestPasswordHashUpgrade(t *testing.T) {
+	db := newTestDB(t)
+	users := sqlite3.NewUserRepository(db)
+
+	h, _, _, _ := newTestHandler(t, db, authentication.BcryptHasher{Cost: bcrypt.MinCost})
+
+	login(t, h, "alice")
+
+	user, err := users.FindByUsername(context.Background(), "alice")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(user.PasswordHash, "$2a$"), user.PasswordHash)
+
+	credentials := api.CredentialsRequest{Username: "alice", Password: "correct horse battery"}
+
+	// A failed login leaves the hash alone.
+	h, _, _, _ = newTestHandler(t, db, authentication.BcryptHasher{Cost: bcrypt.MinCost + 1})
+
+	status := do(t, h, http.MethodPost, "/api/v1/login", "",
+		api.CredentialsRequest{Username: "alice", Password: "wrong horse battery"}, nil)
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	found, err := users.FindByUsername(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, user.PasswordHash, found.PasswordHash)
+
+	// A higher cost takes effect on the next successful login.
+	status = do(t, h, http.MethodPost, "/api/v1/login", "", credentials, nil)
+	require.Equal(t, http.StatusOK, status)
+
+	found, err = users.FindByUsername(context.Background(), "alice")
+	require.NoError(t, err)
+
+	cost, err := bcrypt.Cost([]byte(found.PasswordHash))
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost+1, cost)
+
+	// So does another algorithm, and the old password keeps working throughout.
+	argon2id := authentication.Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	h, _, _, _ = newTestHandler(t, db, argon2id)
+
+	status = do(t, h, http.MethodPost, "/api/v1/login", "", credentials, nil)
+	require.Equal(t, http.StatusOK, status)
+
+	found, err = users.FindByUsername(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(found.PasswordHash, "$argon2id$"), found.PasswordHash)
+
+	status = do(t, h, http.MethodPost, "/api/v1/login", "", credentials, nil)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestSecondFactorFailuresAreThrottled(t *testing.T) {
+	h, authSvc, _, _ := newTestHandler(t, newTestDB(t), authentication.BcryptHasher{Cost: bcrypt.MinCost})
+
+	token := login(t, h, "dave")
+
+	var me api.User
+
+	status := do(t, h, http.MethodGet, "/api/v1/me", token, nil, &me)
+	require.Equal(t, http.StatusOK, status)
+
+	ctx := authcontext.WithSubject(context.Background(), me.ID)
+
+	enrollment, err := authSvc.BeginTOTPEnrollment(ctx)
+	require.NoError(t, err)
+
+	key, err := totp.ParseKey(enrollment.Secret)
+	require.NoError(t, err)
+
+	_, err = authSvc.ConfirmTOTPEnrollment(ctx, key.Code(time.Now()))
+	require.NoError(t, err)
+
+	credentials := api.CredentialsRequest{Username: "dave", Password: "correct horse battery"}
+
+	// The right password doesn't forgive wrong codes, so a fresh challenge for each guess doesn't get around the
+	// throttle.
+	for range authentication.DefaultLoginThrottlePolicy().FreeAttempts + 1 {
+		var res api.LoginResponse
+
+		status := do(t, h, http.MethodPost, "/api/v1/login", "", credentials, &res)
+		require.Equal(t, http.StatusOK, status)
+		require.NotEmpty(t, res.ChallengeID)
+
+		status = do(t, h, http.MethodPost, "/api/v1/login/second-factor", "",
+			api.SecondFactorRequest{ChallengeID: res.ChallengeID, Code: "wrong"}, nil)
+		require.Equal(t, http.StatusUnauthorized, status)
+	}
+
+	var errRes api.ErrorResponse
+
+	status = do(t, h, http.MethodPost, "/api/v1/login", "", credentials, &errRes)
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Equal(t, api.CodeTooManyAttempts, errRes.Error.Code)
+}
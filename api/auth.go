@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/nasermirzaei89/scribble/authentication"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+)
+
+func (h *Handler) HandleRegister() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CredentialsRequest
+		if !decodeBody(w, r, &req) {
+			return
+		}
+
+		if req.Username == "" || req.Password == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "username and password are required")
+
+			return
+		}
+
+		err := h.authSvc.Register(r.Context(), req.Username, req.Password)
+		if err != nil {
+			handleError(w, r, err, "failed to register user")
+
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, struct{}{})
+	})
+}
+
+func (h *Handler) HandleLogin() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CredentialsRequest
+		if !decodeBody(w, r, &req) {
+			return
+		}
+
+		session, err := h.authSvc.Login(r.Context(), req.Username, req.Password, clientInfo(r))
+		if err != nil {
+			if secondFactorErr, ok := errors.AsType[*authentication.SecondFactorRequiredError](err); ok {
+				writeJSON(w, http.StatusOK, LoginResponse{
+					ExpiresAt:            secondFactorErr.ExpiresAt,
+					SecondFactorRequired: true,
+					ChallengeID:          secondFactorErr.ChallengeID,
+				})
+
+				return
+			}
+
+			handleError(w, r, err, "failed to login")
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, LoginResponse{Token: session.ID, ExpiresAt: session.ExpiresAt})
+	})
+}
+
+func (h *Handler) HandleLoginSecondFactor() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req SecondFactorRequest
+		if !decodeBody(w, r, &req) {
+			return
+		}
+
+		session, err := h.authSvc.VerifySecondFactor(r.Context(), req.ChallengeID, req.Code, clientInfo(r))
+		if err != nil {
+			handleError(w, r, err, "failed to verify second factor")
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, LoginResponse{Token: session.ID, ExpiresAt: session.ExpiresAt})
+	})
+}
+
+func (h *Handler) HandleLogout() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionID, ok := authcontext.SessionIDFromContext(r.Context())
+		if !ok {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "personal access tokens are revoked, not logged out")
+
+			return
+		}
+
+		err := h.authSvc.Logout(r.Context(), sessionID)
+		if err != nil {
+			handleError(w, r, err, "failed to logout")
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (h *Handler) HandleGetMe() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := h.authSvc.GetCurrentUser(r.Context())
+		if err != nil {
+			handleError(w, r, err, "failed to get current user")
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newUser(user))
+	})
+}
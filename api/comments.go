@@ -0,0 +1,117 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/nasermirzaei89/scribble/discuss"
+)
+
+func (h *Handler) HandleListComments() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		post, err := h.contentsSvc.GetPost(r.Context(), r.PathValue("postId"))
+		if err != nil {
+			handleError(w, r, err, "failed to get post")
+
+			return
+		}
+
+		limit := discuss.DefaultListCommentsLimit
+
+		if value := r.URL.Query().Get("limit"); value != "" {
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit < 1 || limit > discuss.MaxListCommentsLimit {
+				writeError(
+					w,
+					http.StatusBadRequest,
+					CodeInvalidRequest,
+					fmt.Sprintf("limit must be between 1 and %d", discuss.MaxListCommentsLimit),
+				)
+
+				return
+			}
+		}
+
+		// Each page has comments on the post, with every reply below them.
+		result, err := h.discussSvc.ListComments(r.Context(), discuss.ListCommentsParams{
+			PostID:   post.ID,
+			ParentID: "",
+			Sort:     discuss.CommentSort(r.URL.Query().Get("sort")),
+			Cursor:   r.URL.Query().Get("cursor"),
+			Limit:    limit,
+			Depth:    0,
+		})
+		if err != nil {
+			handleError(w, r, err, "failed to list comments")
+
+			return
+		}
+
+		comments := result.Comments
+
+		authorIDs := make([]string, 0, len(comments))
+		for _, comment := range comments {
+			if !comment.IsDeleted() {
+				authorIDs = append(authorIDs, comment.AuthorID)
+			}
+		}
+
+		authors, err := h.getUsers(r, authorIDs)
+		if err != nil {
+			handleError(w, r, err, "failed to get comment authors")
+
+			return
+		}
+
+		items := make([]*Comment, 0, len(comments))
+
+		for _, comment := range comments {
+			item := newComment(comment)
+			if !item.Deleted {
+				item.Author = newUser(authors[comment.AuthorID])
+			}
+
+			items = append(items, item)
+		}
+
+		writeJSON(w, http.StatusOK, ListCommentsResponse{Comments: items, NextCursor: result.NextCursor})
+	})
+}
+
+func (h *Handler) HandleCreateComment() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CreateCommentRequest
+		if !decodeBody(w, r, &req) {
+			return
+		}
+
+		if req.Content == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "content is required")
+
+			return
+		}
+
+		post, err := h.contentsSvc.GetPost(r.Context(), r.PathValue("postId"))
+		if err != nil {
+			handleError(w, r, err, "failed to get post")
+
+			return
+		}
+
+		comment, err := h.discussSvc.CreateComment(r.Context(), discuss.CreateCommentRequest{
+			PostID:   post.ID,
+			AuthorID: authcontext.GetSubject(r.Context()),
+			Content:  req.Content,
+			ReplyTo:  req.ReplyTo,
+		})
+		if err != nil {
+			handleError(w, r, err, "failed to create comment")
+
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, newComment(comment))
+	})
+}
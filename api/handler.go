@@ -0,0 +1,195 @@
+// Package api serves a JSON REST API under /api/v1 on top of the same services the web handler uses.
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/nasermirzaei89/scribble/authentication"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/nasermirzaei89/scribble/contents"
+	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/nasermirzaei89/scribble/reactions"
+)
+
+// Prefix is the path all API routes are mounted under.
+const Prefix = "/api/v1"
+
+type Handler struct {
+	mux          *http.ServeMux
+	handler      http.Handler
+	authSvc      *authentication.Service
+	contentsSvc  contents.Service
+	discussSvc   discuss.Service
+	reactionsSvc reactions.Service
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+func NewHandler(
+	authSvc *authentication.Service,
+	contentsSvc contents.Service,
+	discussSvc discuss.Service,
+	reactionsSvc reactions.Service,
+) *Handler {
+	h := &Handler{
+		mux:          &http.ServeMux{},
+		handler:      nil,
+		authSvc:      authSvc,
+		contentsSvc:  contentsSvc,
+		discussSvc:   discussSvc,
+		reactionsSvc: reactionsSvc,
+	}
+
+	h.registerRoutes()
+
+	h.handler = h.authMiddleware(h.mux)
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+func (h *Handler) registerRoutes() {
+	h.mux.Handle("POST "+Prefix+"/register", h.HandleRegister())
+	h.mux.Handle("POST "+Prefix+"/login", h.HandleLogin())
+	h.mux.Handle("POST "+Prefix+"/login/second-factor", h.HandleLoginSecondFactor())
+	h.mux.Handle("POST "+Prefix+"/logout", h.AuthenticatedOnly(h.HandleLogout()))
+	h.mux.Handle("GET "+Prefix+"/me", h.AuthenticatedOnly(h.HandleGetMe()))
+
+	h.mux.Handle("GET "+Prefix+"/posts", h.HandleListPosts())
+	h.mux.Handle("POST "+Prefix+"/posts", h.AuthenticatedOnly(h.HandleCreatePost()))
+	h.mux.Handle("GET "+Prefix+"/posts/{postId}", h.HandleGetPost())
+	h.mux.Handle("PATCH "+Prefix+"/posts/{postId}", h.AuthenticatedOnly(h.HandleUpdatePost()))
+	h.mux.Handle("DELETE "+Prefix+"/posts/{postId}", h.AuthenticatedOnly(h.HandleDeletePost()))
+
+	h.mux.Handle("GET "+Prefix+"/posts/{postId}/comments", h.HandleListComments())
+	h.mux.Handle("POST "+Prefix+"/posts/{postId}/comments", h.AuthenticatedOnly(h.HandleCreateComment()))
+
+	h.mux.Handle("GET "+Prefix+"/reactions/{targetType}/{targetId}", h.AuthenticatedOnly(h.HandleGetReactions()))
+	h.mux.Handle("POST "+Prefix+"/reactions/{targetType}/{targetId}", h.AuthenticatedOnly(h.HandleToggleReaction()))
+
+	h.mux.Handle(Prefix+"/", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "no such endpoint")
+	}))
+}
+
+// authMiddleware authenticates requests carrying an "Authorization: Bearer <token>" header. The token is either one
+// returned by the login endpoint or a personal access token. Requests without the header stay anonymous.
+func (h *Handler) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			writeError(w, http.StatusUnauthorized, CodeUnauthenticated, "malformed authorization header")
+
+			return
+		}
+
+		if strings.HasPrefix(token, authentication.APITokenPrefix) {
+			h.authenticateAPIToken(w, r, next, token)
+
+			return
+		}
+
+		session, err := h.authSvc.GetSession(r.Context(), token)
+		if err != nil {
+			_, notFound := errors.AsType[*authentication.SessionNotFoundError](err)
+			_, expired := errors.AsType[*authentication.SessionExpiredError](err)
+
+			if notFound || expired {
+				writeError(w, http.StatusUnauthorized, CodeUnauthenticated, "invalid or expired token")
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to get session", "error", err)
+			writeError(w, http.StatusInternalServerError, CodeInternal, "internal server error")
+
+			return
+		}
+
+		err = h.authSvc.TouchSession(r.Context(), session, clientInfo(r))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to touch session", "sessionId", session.ID, "error", err)
+		}
+
+		ctx := authcontext.WithSessionID(r.Context(), session.ID)
+		ctx = authcontext.WithSubject(ctx, session.UserID)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *Handler) authenticateAPIToken(w http.ResponseWriter, r *http.Request, next http.Handler, secret string) {
+	token, err := h.authSvc.AuthenticateAPIToken(r.Context(), secret)
+	if err != nil {
+		_, expired := errors.AsType[*authentication.APITokenExpiredError](err)
+
+		if expired || errors.Is(err, authentication.ErrInvalidAPIToken) {
+			writeError(w, http.StatusUnauthorized, CodeUnauthenticated, "invalid or expired token")
+
+			return
+		}
+
+		slog.ErrorContext(r.Context(), "failed to authenticate api token", "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "internal server error")
+
+		return
+	}
+
+	if !isSafeMethod(r.Method) && !token.HasScope(authentication.ScopeWrite) {
+		writeError(w, http.StatusForbidden, CodeInsufficientScope, "token does not have the write scope")
+
+		return
+	}
+
+	ctx := authcontext.WithSubject(r.Context(), token.UserID)
+	ctx = authcontext.WithAPITokenID(ctx, token.ID)
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func isAuthenticatedRequest(r *http.Request) bool {
+	return authcontext.GetSubject(r.Context()) != authcontext.Anonymous
+}
+
+func (h *Handler) AuthenticatedOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAuthenticatedRequest(r) {
+			writeError(w, http.StatusUnauthorized, CodeUnauthenticated, "authentication required")
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientInfo describes the client behind r. The address is the direct peer; forwarding headers are not trusted.
+func clientInfo(r *http.Request) authentication.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return authentication.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IPAddress: ip,
+	}
+}
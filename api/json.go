@@ -0,0 +1,227 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/nasermirzaei89/scribble/authentication"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/nasermirzaei89/scribble/authorization"
+	"github.com/nasermirzaei89/scribble/contents"
+	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/nasermirzaei89/scribble/reactions"
+)
+
+// Error codes are stable identifiers clients can switch on; messages are for humans and may change.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeTooManyAttempts    = "too_many_attempts"
+	CodeInvalidCode        = "invalid_code"
+	CodeInvalidChallenge   = "invalid_challenge"
+	CodeUnauthenticated    = "unauthenticated"
+	CodeAccessDenied       = "access_denied"
+	CodeInsufficientScope  = "insufficient_scope"
+	CodeNotFound           = "not_found"
+	CodePostNotFound       = "post_not_found"
+	CodeParentNotFound     = "parent_comment_not_found"
+	CodeCrossPostReply     = "cross_post_reply"
+	CodeThreadTooDeep      = "thread_too_deep"
+	CodeCommentsLocked     = "comments_locked"
+	CodeAccountTooNew      = "account_too_new"
+	CodeUserAlreadyExists  = "user_already_exists"
+	CodeValidationFailed   = "validation_failed"
+	CodeInvalidCursor      = "invalid_cursor"
+	CodeInvalidSort        = "invalid_sort"
+	CodeInvalidTargetType  = "invalid_target_type"
+	CodeInvalidEmoji       = "invalid_emoji"
+	CodeInternal           = "internal_error"
+)
+
+const maxRequestBodySize = 1 << 20
+
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Fields lists per-field messages when Code is validation_failed.
+	Fields map[string][]string `json:"fields,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		slog.Error("failed to encode response body", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
+
+// handleError maps a service error to its JSON error response. Anything unrecognized is logged and reported as an
+// internal error without leaking details.
+func handleError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	if _, ok := errors.AsType[*authorization.AccessDeniedError](err); ok {
+		if authcontext.GetSubject(r.Context()) == authcontext.Anonymous {
+			writeError(w, http.StatusUnauthorized, CodeUnauthenticated, "authentication required")
+
+			return
+		}
+
+		writeError(w, http.StatusForbidden, CodeAccessDenied, "you are not allowed to perform this action")
+
+		return
+	}
+
+	if postNotFoundErr, ok := errors.AsType[contents.PostNotFoundError](err); ok {
+		writeError(w, http.StatusNotFound, CodePostNotFound, postNotFoundErr.Error())
+
+		return
+	}
+
+	if postNotFoundErr, ok := errors.AsType[discuss.PostNotFoundError](err); ok {
+		writeError(w, http.StatusNotFound, CodePostNotFound, postNotFoundErr.Error())
+
+		return
+	}
+
+	if parentNotFoundErr, ok := errors.AsType[discuss.ParentCommentNotFoundError](err); ok {
+		writeError(w, http.StatusUnprocessableEntity, CodeParentNotFound, parentNotFoundErr.Error())
+
+		return
+	}
+
+	if crossPostReplyErr, ok := errors.AsType[discuss.CrossPostReplyError](err); ok {
+		writeError(w, http.StatusUnprocessableEntity, CodeCrossPostReply, crossPostReplyErr.Error())
+
+		return
+	}
+
+	if threadTooDeepErr, ok := errors.AsType[discuss.ThreadTooDeepError](err); ok {
+		writeError(w, http.StatusUnprocessableEntity, CodeThreadTooDeep, threadTooDeepErr.Error())
+
+		return
+	}
+
+	if commentsLockedErr, ok := errors.AsType[discuss.CommentsLockedError](err); ok {
+		writeError(w, http.StatusForbidden, CodeCommentsLocked, commentsLockedErr.Error())
+
+		return
+	}
+
+	if accountTooNewErr, ok := errors.AsType[discuss.AccountTooNewError](err); ok {
+		writeError(w, http.StatusForbidden, CodeAccountTooNew, accountTooNewErr.Error())
+
+		return
+	}
+
+	if invalidCursorErr, ok := errors.AsType[contents.InvalidPostCursorError](err); ok {
+		writeError(w, http.StatusBadRequest, CodeInvalidCursor, invalidCursorErr.Error())
+
+		return
+	}
+
+	if invalidCursorErr, ok := errors.AsType[discuss.InvalidCommentCursorError](err); ok {
+		writeError(w, http.StatusBadRequest, CodeInvalidCursor, invalidCursorErr.Error())
+
+		return
+	}
+
+	if invalidSortErr, ok := errors.AsType[discuss.InvalidCommentSortError](err); ok {
+		writeError(w, http.StatusBadRequest, CodeInvalidSort, invalidSortErr.Error())
+
+		return
+	}
+
+	if invalidTargetTypeErr, ok := errors.AsType[reactions.InvalidTargetTypeError](err); ok {
+		writeError(w, http.StatusBadRequest, CodeInvalidTargetType, invalidTargetTypeErr.Error())
+
+		return
+	}
+
+	if invalidEmojiErr, ok := errors.AsType[reactions.InvalidEmojiError](err); ok {
+		writeError(w, http.StatusUnprocessableEntity, CodeInvalidEmoji, invalidEmojiErr.Error())
+
+		return
+	}
+
+	if validationErr, ok := errors.AsType[*authentication.ValidationError](err); ok {
+		writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: ErrorBody{
+			Code:    CodeValidationFailed,
+			Message: "the request contains invalid fields",
+			Fields:  validationErr.Fields,
+		}})
+
+		return
+	}
+
+	if userAlreadyExistsErr, ok := errors.AsType[*authentication.UserAlreadyExistsError](err); ok {
+		writeError(w, http.StatusConflict, CodeUserAlreadyExists, userAlreadyExistsErr.Error())
+
+		return
+	}
+
+	if tooManyAttemptsErr, ok := errors.AsType[*authentication.TooManyAttemptsError](err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(tooManyAttemptsErr.RetryAfterSeconds()))
+		writeError(w, http.StatusTooManyRequests, CodeTooManyAttempts, "too many failed login attempts, try again later")
+
+		return
+	}
+
+	if errors.Is(err, authentication.ErrInvalidSecondFactorCode) {
+		writeError(w, http.StatusUnauthorized, CodeInvalidCode, "invalid second factor code")
+
+		return
+	}
+
+	if errors.Is(err, authentication.ErrInvalidLoginChallenge) {
+		writeError(w, http.StatusUnauthorized, CodeInvalidChallenge, "login challenge is invalid or expired")
+
+		return
+	}
+
+	if errors.Is(err, authentication.ErrInvalidCredentials) {
+		writeError(w, http.StatusUnauthorized, CodeInvalidCredentials, "invalid username or password")
+
+		return
+	}
+
+	if errors.Is(err, contents.ErrEmptyPostContent) {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "content is required")
+
+		return
+	}
+
+	slog.ErrorContext(r.Context(), msg, "error", err)
+	writeError(w, http.StatusInternalServerError, CodeInternal, "internal server error")
+}
+
+// decodeBody reads a JSON request body into dst, rejecting unknown fields and trailing data.
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(dst)
+	if err == nil && decoder.More() {
+		err = errors.New("unexpected data after JSON body")
+	}
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("invalid request body: %v", err))
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/nasermirzaei89/scribble/authentication"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/nasermirzaei89/scribble/contents"
+)
+
+func (h *Handler) HandleListPosts() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := contents.DefaultListPostsLimit
+
+		if value := r.URL.Query().Get("limit"); value != "" {
+			var err error
+
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit < 1 || limit > contents.MaxListPostsLimit {
+				writeError(
+					w,
+					http.StatusBadRequest,
+					CodeInvalidRequest,
+					fmt.Sprintf("limit must be between 1 and %d", contents.MaxListPostsLimit),
+				)
+
+				return
+			}
+		}
+
+		result, err := h.contentsSvc.ListPosts(r.Context(), contents.ListPostsParams{
+			Cursor: r.URL.Query().Get("cursor"),
+			Limit:  limit,
+		})
+		if err != nil {
+			handleError(w, r, err, "failed to list posts")
+
+			return
+		}
+
+		postIDs := make([]string, 0, len(result.Posts))
+		authorIDs := make([]string, 0, len(result.Posts))
+
+		for _, post := range result.Posts {
+			postIDs = append(postIDs, post.ID)
+			authorIDs = append(authorIDs, post.AuthorID)
+		}
+
+		authors, err := h.getUsers(r, authorIDs)
+		if err != nil {
+			handleError(w, r, err, "failed to get post authors")
+
+			return
+		}
+
+		commentsCounts, err := h.discussSvc.CountCommentsByPosts(r.Context(), postIDs)
+		if err != nil {
+			handleError(w, r, err, "failed to count comments")
+
+			return
+		}
+
+		posts := make([]*Post, 0, len(result.Posts))
+
+		for _, post := range result.Posts {
+			commentsCount := commentsCounts[post.ID]
+
+			item := newPost(post)
+			item.Author = newUser(authors[post.AuthorID])
+			item.CommentsCount = &commentsCount
+
+			posts = append(posts, item)
+		}
+
+		writeJSON(w, http.StatusOK, ListPostsResponse{Posts: posts, NextCursor: result.NextCursor})
+	})
+}
+
+func (h *Handler) HandleGetPost() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		post, err := h.contentsSvc.GetPost(r.Context(), r.PathValue("postId"))
+		if err != nil {
+			handleError(w, r, err, "failed to get post")
+
+			return
+		}
+
+		author, err := h.authSvc.GetUser(r.Context(), post.AuthorID)
+		if err != nil {
+			handleError(w, r, err, "failed to get post author")
+
+			return
+		}
+
+		commentsCount, err := h.discussSvc.CountComments(r.Context(), post.ID)
+		if err != nil {
+			handleError(w, r, err, "failed to count comments")
+
+			return
+		}
+
+		item := newPost(post)
+		item.Author = newUser(author)
+		item.CommentsCount = &commentsCount
+
+		writeJSON(w, http.StatusOK, item)
+	})
+}
+
+func (h *Handler) HandleCreatePost() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req PostContentRequest
+		if !decodeBody(w, r, &req) {
+			return
+		}
+
+		post, err := h.contentsSvc.CreatePost(r.Context(), contents.CreatePostRequest{
+			AuthorID: authcontext.GetSubject(r.Context()),
+			Content:  req.Content,
+		})
+		if err != nil {
+			handleError(w, r, err, "failed to create post")
+
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, newPost(post))
+	})
+}
+
+func (h *Handler) HandleUpdatePost() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req PostContentRequest
+		if !decodeBody(w, r, &req) {
+			return
+		}
+
+		post, err := h.contentsSvc.UpdatePost(r.Context(), contents.UpdatePostRequest{
+			PostID:   r.PathValue("postId"),
+			EditorID: authcontext.GetSubject(r.Context()),
+			Content:  req.Content,
+		})
+		if err != nil {
+			handleError(w, r, err, "failed to update post")
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newPost(post))
+	})
+}
+
+func (h *Handler) HandleDeletePost() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h.contentsSvc.DeletePost(r.Context(), r.PathValue("postId"))
+		if err != nil {
+			handleError(w, r, err, "failed to delete post")
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// getUsers loads the distinct users among ids in one call.
+func (h *Handler) getUsers(r *http.Request, ids []string) (map[string]*authentication.User, error) {
+	unique := make([]string, 0, len(ids))
+	seen := make(map[string]struct{}, len(ids))
+
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+
+	users, err := h.authSvc.GetUsers(r.Context(), unique)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	return users, nil
+}
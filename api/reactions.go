@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/nasermirzaei89/scribble/reactions"
+)
+
+func (h *Handler) HandleGetReactions() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetType := reactions.TargetType(r.PathValue("targetType"))
+		targetID := r.PathValue("targetId")
+
+		targetReactions, err := h.reactionsSvc.GetMyReactions(r.Context(), targetType, targetID)
+		if err != nil {
+			handleError(w, r, err, "failed to get reactions")
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newReactions(targetReactions))
+	})
+}
+
+// HandleToggleReaction sets the current user's reaction on a target, or removes it when the same emoji is sent
+// again, and returns the target's updated reactions.
+func (h *Handler) HandleToggleReaction() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ToggleReactionRequest
+		if !decodeBody(w, r, &req) {
+			return
+		}
+
+		targetType := reactions.TargetType(r.PathValue("targetType"))
+		targetID := r.PathValue("targetId")
+
+		err := h.reactionsSvc.ToggleMyReaction(r.Context(), targetType, targetID, req.Emoji)
+		if err != nil {
+			handleError(w, r, err, "failed to toggle reaction")
+
+			return
+		}
+
+		targetReactions, err := h.reactionsSvc.GetMyReactions(r.Context(), targetType, targetID)
+		if err != nil {
+			handleError(w, r, err, "failed to get reactions")
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newReactions(targetReactions))
+	})
+}
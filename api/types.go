@@ -0,0 +1,153 @@
+package api
+
+import (
+	"time"
+
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/contents"
+	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/nasermirzaei89/scribble/reactions"
+)
+
+type User struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	RegisteredAt time.Time `json:"registeredAt"`
+}
+
+func newUser(user *authentication.User) *User {
+	if user == nil {
+		return nil
+	}
+
+	return &User{
+		ID:           user.ID,
+		Username:     user.Username,
+		RegisteredAt: user.RegisteredAt,
+	}
+}
+
+type Post struct {
+	ID             string    `json:"id"`
+	AuthorID       string    `json:"authorId"`
+	Author         *User     `json:"author,omitempty"`
+	Content        string    `json:"content"`
+	CommentsCount  *int      `json:"commentsCount,omitempty"`
+	CommentsLocked bool      `json:"commentsLocked"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+func newPost(post *contents.Post) *Post {
+	return &Post{
+		ID:             post.ID,
+		AuthorID:       post.AuthorID,
+		Author:         nil,
+		Content:        post.Content,
+		CommentsCount:  nil,
+		CommentsLocked: post.CommentsLocked,
+		CreatedAt:      post.CreatedAt,
+	}
+}
+
+type ListPostsResponse struct {
+	Posts      []*Post `json:"posts"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
+
+// Comment is a comment or, with Deleted set, the tombstone of a deleted comment that still has replies. Tombstones
+// have neither author nor content.
+type Comment struct {
+	ID        string     `json:"id"`
+	PostID    string     `json:"postId"`
+	AuthorID  string     `json:"authorId,omitempty"`
+	Author    *User      `json:"author,omitempty"`
+	ReplyTo   *string    `json:"replyTo"`
+	Content   string     `json:"content"`
+	Deleted   bool       `json:"deleted,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+func newComment(comment *discuss.Comment) *Comment {
+	item := &Comment{
+		ID:        comment.ID,
+		PostID:    comment.PostID,
+		AuthorID:  comment.AuthorID,
+		Author:    nil,
+		ReplyTo:   comment.ReplyTo,
+		Content:   comment.Content,
+		Deleted:   comment.IsDeleted(),
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}
+
+	if item.Deleted {
+		item.AuthorID = ""
+	}
+
+	return item
+}
+
+type ListCommentsResponse struct {
+	Comments   []*Comment `json:"comments"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+type ReactionOption struct {
+	Emoji     string `json:"emoji"`
+	Count     int    `json:"count"`
+	Selected  bool   `json:"selected"`
+	Available bool   `json:"available"`
+}
+
+type Reactions struct {
+	TargetType reactions.TargetType `json:"targetType"`
+	TargetID   string               `json:"targetId"`
+	Options    []ReactionOption     `json:"options"`
+}
+
+func newReactions(targetReactions *reactions.TargetReactions) *Reactions {
+	options := make([]ReactionOption, 0, len(targetReactions.Options))
+
+	for _, option := range targetReactions.Options {
+		options = append(options, ReactionOption(option))
+	}
+
+	return &Reactions{
+		TargetType: targetReactions.TargetType,
+		TargetID:   targetReactions.TargetID,
+		Options:    options,
+	}
+}
+
+type CredentialsRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse carries the session token, or, for accounts with two-factor authentication, the challenge to finish
+// the login with at /login/second-factor. ExpiresAt applies to whichever of the two is set.
+type LoginResponse struct {
+	Token                string    `json:"token,omitempty"`
+	ExpiresAt            time.Time `json:"expiresAt"`
+	SecondFactorRequired bool      `json:"secondFactorRequired,omitempty"`
+	ChallengeID          string    `json:"challengeId,omitempty"`
+}
+
+type SecondFactorRequest struct {
+	ChallengeID string `json:"challengeId"`
+	Code        string `json:"code"`
+}
+
+type PostContentRequest struct {
+	Content string `json:"content"`
+}
+
+type CreateCommentRequest struct {
+	Content string `json:"content"`
+	ReplyTo string `json:"replyTo"`
+}
+
+type ToggleReactionRequest struct {
+	Emoji string `json:"emoji"`
+}
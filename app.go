@@ -4,29 +4,48 @@ import (
 	"context"
 	"database/sql"
 	_ "embed"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
+	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/gorilla/sessions"
 	"github.com/nasermirzaei89/env"
+	"github.com/nasermirzaei89/scribble/api"
 	"github.com/nasermirzaei89/scribble/authentication"
 	"github.com/nasermirzaei89/scribble/authorization"
 	"github.com/nasermirzaei89/scribble/authorization/casbin"
+	"github.com/nasermirzaei89/scribble/blob"
 	"github.com/nasermirzaei89/scribble/contents"
 	"github.com/nasermirzaei89/scribble/database/sqlite3"
 	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/nasermirzaei89/scribble/jobs"
+	"github.com/nasermirzaei89/scribble/mail"
+	"github.com/nasermirzaei89/scribble/profiles"
 	"github.com/nasermirzaei89/scribble/random"
 	"github.com/nasermirzaei89/scribble/reactions"
+	"github.com/nasermirzaei89/scribble/sanitizer"
+	"github.com/nasermirzaei89/scribble/search"
 	"github.com/nasermirzaei89/scribble/server"
 	"github.com/nasermirzaei89/scribble/web"
 )
 
 type App struct {
 	server  *server.Server
-	handler *web.Handler
+	handler http.Handler
+	jobs    *jobs.Runner
 	db      *sql.DB
+	// mailLog is the file the log mail driver writes to, if any.
+	mailLog io.Closer
 }
 
 //go:embed policy.csv
@@ -45,9 +64,21 @@ func NewApp(ctx context.Context) (*App, error) {
 
 	userRepo := sqlite3.NewUserRepository(db)
 	sessionRepo := sqlite3.NewSessionRepository(db)
+	apiTokenRepo := sqlite3.NewAPITokenRepository(db)
+	loginAttemptRepo := sqlite3.NewLoginAttemptRepository(db)
+	totpCredentialRepo := sqlite3.NewTOTPCredentialRepository(db)
+	recoveryCodeRepo := sqlite3.NewRecoveryCodeRepository(db)
+	loginChallengeRepo := sqlite3.NewLoginChallengeRepository(db)
+	passkeyRepo := sqlite3.NewPasskeyRepository(db)
+	identityRepo := sqlite3.NewIdentityRepository(db)
+	userDataRepo := sqlite3.NewUserDataRepository(db)
+	profileRepo := sqlite3.NewProfileRepository(db)
 	postRepo := sqlite3.NewPostRepository(db)
+	postRevisionRepo := sqlite3.NewPostRevisionRepository(db)
 	commentRepo := sqlite3.NewCommentRepository(db)
+	mentionRepo := sqlite3.NewMentionRepository(db)
 	userReactionRepo := sqlite3.NewUserReactionRepository(db)
+	searchRepo := sqlite3.NewSearchRepository(db)
 
 	authzProvider, err := newAuthorizationProvider(ctx, db)
 	if err != nil {
@@ -60,11 +91,84 @@ func NewApp(ctx context.Context) (*App, error) {
 	}
 
 	authzClient := authorization.NewClient(authzSvc)
-	authSvc := authentication.NewService(userRepo, sessionRepo, authzClient)
 
-	contentsSvc := contents.NewService(postRepo, authzClient)
-	discussSvc := discuss.NewService(commentRepo, authzClient)
+	credentialsPolicy, err := newCredentialsPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credentials policy: %w", err)
+	}
+
+	loginThrottlePolicy, err := newLoginThrottlePolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create login throttle policy: %w", err)
+	}
+
+	webAuthn, err := newWebAuthn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webauthn relying party: %w", err)
+	}
+
+	oidcProviders, err := newOIDCProviders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create oidc providers: %w", err)
+	}
+
+	mailer, mailLog, err := newMailer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mailer: %w", err)
+	}
+
+	passwordHasher, err := newPasswordHasher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create password hasher: %w", err)
+	}
+
+	accountRecoveryPolicy, err := newAccountRecoveryPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create account recovery policy: %w", err)
+	}
+
+	accountDeletionPolicy, err := newAccountDeletionPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create account deletion policy: %w", err)
+	}
+
+	threadPolicy, err := newThreadPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create thread policy: %w", err)
+	}
+
+	blobStore, err := newBlobStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob store: %w", err)
+	}
+
+	authSvc := authentication.NewService(authentication.Dependencies{
+		UserRepo:           userRepo,
+		SessionRepo:        sessionRepo,
+		APITokenRepo:       apiTokenRepo,
+		LoginAttemptRepo:   loginAttemptRepo,
+		TOTPCredentialRepo: totpCredentialRepo,
+		RecoveryCodeRepo:   recoveryCodeRepo,
+		LoginChallengeRepo: loginChallengeRepo,
+		PasskeyRepo:        passkeyRepo,
+		IdentityRepo:       identityRepo,
+		UserDataRepo:       userDataRepo,
+		AuthzClient:        authzClient,
+		WebAuthn:           webAuthn,
+		OIDCProviders:      oidcProviders,
+		Mailer:             mailer,
+		PasswordHasher:     passwordHasher,
+		Credentials:        credentialsPolicy,
+		LoginThrottle:      loginThrottlePolicy,
+		AccountRecovery:    accountRecoveryPolicy,
+		AccountDeletion:    accountDeletionPolicy,
+	})
+
+	contentsSvc := contents.NewService(postRepo, postRevisionRepo, authzClient)
+	discussSvc := discuss.NewService(commentRepo, postRepo, userRepo, mentionRepo, authzClient, threadPolicy)
 	reactionsSvc := reactions.NewService(userReactionRepo, authzClient)
+	searchSvc := search.NewService(searchRepo, authzClient)
+	profilesSvc := profiles.NewService(profileRepo, blobStore, authzClient)
 
 	sessionName := env.GetString("SESSION_NAME", "scribble-"+random.String(4))
 	sessionKey := env.GetString("SESSION_KEY", random.String(32))
@@ -73,24 +177,50 @@ func NewApp(ctx context.Context) (*App, error) {
 	csrfAuthKeys := []byte(env.GetString("CSRF_AUTH_KEY", random.String(32)))
 	csrfTrustedOrigins := env.GetStringSlice("CSRF_TRUSTED_ORIGINS", []string{})
 
-	httpHandler, err := web.NewHandler(
+	webHandler, err := web.NewHandler(
 		authSvc,
 		contentsSvc,
 		discussSvc,
 		reactionsSvc,
+		searchSvc,
+		profilesSvc,
+		authzClient,
 		cookieStore,
 		sessionName,
 		csrfAuthKeys,
 		csrfTrustedOrigins,
+		sanitizer.Policy{
+			AllowImages: env.GetBool("MARKDOWN_ALLOW_IMAGES", true),
+		},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP handler: %w", err)
 	}
 
+	apiHandler := api.NewHandler(authSvc, contentsSvc, discussSvc, reactionsSvc)
+
+	// The API authenticates with bearer tokens instead of cookies, so it is kept outside the web handler and its
+	// session and CSRF middlewares.
+	httpHandler := http.NewServeMux()
+	httpHandler.Handle(api.Prefix+"/", apiHandler)
+	httpHandler.Handle("/", webHandler)
+
+	sessionSweepInterval, err := getDurationFromEnv("SESSION_SWEEP_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session sweep interval: %w", err)
+	}
+
+	jobRunner := jobs.NewRunner()
+	jobRunner.Add(newSessionSweepJob(authSvc, sessionSweepInterval))
+	jobRunner.Add(newLoginAttemptSweepJob(authSvc, sessionSweepInterval))
+	jobRunner.Add(newLoginChallengeSweepJob(authSvc, sessionSweepInterval))
+
 	app := &App{
 		server:  newServer(),
 		handler: httpHandler,
+		jobs:    jobRunner,
 		db:      db,
+		mailLog: mailLog,
 	}
 
 	return app, nil
@@ -108,8 +238,27 @@ func (app *App) Run(ctx context.Context) error {
 				slog.ErrorContext(ctx, "failed to close database", "error", err)
 			}
 		}
+
+		if app.mailLog != nil {
+			err := app.mailLog.Close()
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to close mail log", "error", err)
+			}
+		}
 	}()
 
+	// Background jobs share the server's lifetime; they are stopped and awaited before the database is closed.
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+
+	wg.Go(func() {
+		app.jobs.Run(jobsCtx)
+	})
+
+	defer wg.Wait()
+	defer stopJobs()
+
 	err := app.server.Run(ctx, app.handler)
 	if err != nil {
 		return fmt.Errorf("failed to run server: %w", err)
@@ -118,6 +267,294 @@ func (app *App) Run(ctx context.Context) error {
 	return nil
 }
 
+func newSessionSweepJob(authSvc *authentication.Service, interval time.Duration) jobs.Job {
+	return jobs.Job{
+		Name:     "sweep-expired-sessions",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			deleted, err := authSvc.DeleteExpiredSessions(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to delete expired sessions: %w", err)
+			}
+
+			slog.InfoContext(ctx, "expired sessions deleted", "count", deleted)
+
+			return nil
+		},
+	}
+}
+
+func newLoginAttemptSweepJob(authSvc *authentication.Service, interval time.Duration) jobs.Job {
+	return jobs.Job{
+		Name:     "sweep-stale-login-attempts",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			deleted, err := authSvc.DeleteStaleLoginAttempts(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to delete stale login attempts: %w", err)
+			}
+
+			slog.InfoContext(ctx, "stale login attempts deleted", "count", deleted)
+
+			return nil
+		},
+	}
+}
+
+func newLoginChallengeSweepJob(authSvc *authentication.Service, interval time.Duration) jobs.Job {
+	return jobs.Job{
+		Name:     "sweep-expired-login-challenges",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			deleted, err := authSvc.DeleteExpiredLoginChallenges(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to delete expired login challenges: %w", err)
+			}
+
+			slog.InfoContext(ctx, "expired login challenges deleted", "count", deleted)
+
+			return nil
+		},
+	}
+}
+
+func getDurationFromEnv(key string, def time.Duration) (time.Duration, error) {
+	value := env.GetString(key, "")
+	if value == "" {
+		return def, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s as duration: %w", key, err)
+	}
+
+	return duration, nil
+}
+
+func newLoginThrottlePolicy() (authentication.LoginThrottlePolicy, error) {
+	policy := authentication.DefaultLoginThrottlePolicy()
+
+	policy.FreeAttempts = env.GetInt("LOGIN_FREE_ATTEMPTS", policy.FreeAttempts)
+	policy.IPFreeAttempts = env.GetInt("LOGIN_IP_FREE_ATTEMPTS", policy.IPFreeAttempts)
+	policy.LockoutThreshold = env.GetInt("LOGIN_LOCKOUT_THRESHOLD", policy.LockoutThreshold)
+	policy.IPLockoutThreshold = env.GetInt("LOGIN_IP_LOCKOUT_THRESHOLD", policy.IPLockoutThreshold)
+
+	durations := []struct {
+		key   string
+		value *time.Duration
+	}{
+		{key: "LOGIN_BASE_DELAY", value: &policy.BaseDelay},
+		{key: "LOGIN_MAX_DELAY", value: &policy.MaxDelay},
+		{key: "LOGIN_LOCKOUT_DURATION", value: &policy.LockoutDuration},
+		{key: "LOGIN_RESET_AFTER", value: &policy.ResetAfter},
+	}
+
+	for _, duration := range durations {
+		value, err := getDurationFromEnv(duration.key, *duration.value)
+		if err != nil {
+			return authentication.LoginThrottlePolicy{}, err
+		}
+
+		*duration.value = value
+	}
+
+	return policy, nil
+}
+
+func newCredentialsPolicy() (authentication.CredentialsPolicy, error) {
+	policy := authentication.DefaultCredentialsPolicy()
+
+	policy.UsernameMinLength = env.GetInt("USERNAME_MIN_LENGTH", policy.UsernameMinLength)
+	policy.UsernameMaxLength = env.GetInt("USERNAME_MAX_LENGTH", policy.UsernameMaxLength)
+	policy.ReservedUsernames = env.GetStringSlice("RESERVED_USERNAMES", policy.ReservedUsernames)
+	policy.PasswordMinLength = env.GetInt("PASSWORD_MIN_LENGTH", policy.PasswordMinLength)
+	policy.RejectCommonPasswords = env.GetBool("PASSWORD_REJECT_COMMON", policy.RejectCommonPasswords)
+	policy.MinPasswordStrength = env.GetInt("PASSWORD_MIN_STRENGTH", policy.MinPasswordStrength)
+
+	usernamePattern := env.GetString("USERNAME_PATTERN", "")
+	if usernamePattern != "" {
+		pattern, err := regexp.Compile(usernamePattern)
+		if err != nil {
+			return authentication.CredentialsPolicy{}, fmt.Errorf("failed to compile username pattern: %w", err)
+		}
+
+		policy.UsernamePattern = pattern
+	}
+
+	return policy, nil
+}
+
+// newWebAuthn configures this site as a WebAuthn relying party. Passkeys are bound to the RP ID, which must be the
+// site's domain or a parent of it, and are only accepted from the listed origins.
+func newWebAuthn() (*webauthn.WebAuthn, error) {
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          env.GetString("WEBAUTHN_RP_ID", "localhost"),
+		RPDisplayName: env.GetString("WEBAUTHN_RP_NAME", "Scribble"),
+		RPOrigins:     env.GetStringSlice("WEBAUTHN_RP_ORIGINS", []string{"http://localhost:8080"}),
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: 5 * time.Minute, TimeoutUVD: 5 * time.Minute},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: 5 * time.Minute, TimeoutUVD: 5 * time.Minute},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webauthn: %w", err)
+	}
+
+	return webAuthn, nil
+}
+
+// newOIDCProviders sets up the identity providers listed in OIDC_PROVIDERS. Each name is configured by variables
+// prefixed with OIDC_ and the upper-cased name, such as OIDC_GOOGLE_ISSUER for "google".
+func newOIDCProviders(ctx context.Context) ([]*authentication.OIDCProvider, error) {
+	names := env.GetStringSlice("OIDC_PROVIDERS", []string{})
+	baseURL := strings.TrimSuffix(env.GetString("OIDC_REDIRECT_BASE_URL", "http://localhost:8080"), "/")
+
+	providers := make([]*authentication.OIDCProvider, 0, len(names))
+
+	for _, name := range names {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		provider, err := authentication.NewOIDCProvider(ctx, authentication.OIDCProviderConfig{
+			Name:         name,
+			DisplayName:  env.GetString(prefix+"DISPLAY_NAME", name),
+			Issuer:       env.GetString(prefix+"ISSUER", ""),
+			ClientID:     env.GetString(prefix+"CLIENT_ID", ""),
+			ClientSecret: env.GetString(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  baseURL + "/login/oidc/" + name + "/callback",
+			Scopes:       env.GetStringSlice(prefix+"SCOPES", []string{"profile", "email"}),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create oidc provider %q: %w", name, err)
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
+// newPasswordHasher returns the hasher selected by PASSWORD_HASH_ALGORITHM. Existing hashes made by another
+// algorithm or weaker parameters are replaced as their users log in.
+func newPasswordHasher() (authentication.PasswordHasher, error) { //nolint:ireturn
+	switch algorithm := env.GetString("PASSWORD_HASH_ALGORITHM", "bcrypt"); algorithm {
+	case "bcrypt":
+		hasher, err := authentication.NewBcryptHasher(env.GetInt("BCRYPT_COST", authentication.DefaultPasswordHasher().Cost))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bcrypt cost: %w", err)
+		}
+
+		return hasher, nil
+	case "argon2id":
+		hasher := authentication.DefaultArgon2idHasher()
+
+		memory := env.GetInt("ARGON2_MEMORY", int(hasher.Memory))
+		iterations := env.GetInt("ARGON2_ITERATIONS", int(hasher.Iterations))
+		parallelism := env.GetInt("ARGON2_PARALLELISM", int(hasher.Parallelism))
+
+		if memory < 8*parallelism || iterations < 1 || parallelism < 1 || parallelism > math.MaxUint8 {
+			return nil, errors.New("invalid argon2 parameters")
+		}
+
+		hasher.Memory = uint32(memory)         //nolint:gosec // checked to be positive above
+		hasher.Iterations = uint32(iterations) //nolint:gosec // checked to be positive above
+		hasher.Parallelism = uint8(parallelism)
+
+		return hasher, nil
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", algorithm)
+	}
+}
+
+// newMailer returns the sender selected by MAIL_DRIVER. The "log" driver writes messages to MAIL_LOG_FILE, or to
+// the standard output when it's empty, and returns the file so it can be closed.
+func newMailer() (mail.Sender, io.Closer, error) {
+	from := env.GetString("MAIL_FROM", "Scribble <no-reply@localhost>")
+
+	switch driver := env.GetString("MAIL_DRIVER", "log"); driver {
+	case "smtp":
+		return mail.NewSMTPSender(
+			env.GetString("SMTP_ADDR", "localhost:587"),
+			env.GetString("SMTP_USERNAME", ""),
+			env.GetString("SMTP_PASSWORD", ""),
+			from,
+		), nil, nil
+	case "log":
+		path := env.GetString("MAIL_LOG_FILE", "")
+		if path == "" {
+			return mail.NewLogSender(os.Stdout, from), nil, nil
+		}
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open mail log file: %w", err)
+		}
+
+		return mail.NewLogSender(file, from), file, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown mail driver %q", driver)
+	}
+}
+
+func newAccountRecoveryPolicy() (authentication.AccountRecoveryPolicy, error) {
+	policy := authentication.DefaultAccountRecoveryPolicy()
+
+	baseURL := strings.TrimSuffix(env.GetString("MAIL_LINK_BASE_URL", "http://localhost:8080"), "/")
+
+	policy.TokenKey = []byte(env.GetString("ACCOUNT_TOKEN_KEY", random.String(32)))
+	policy.PasswordResetURL = baseURL + "/reset-password"
+	policy.EmailVerificationURL = baseURL + "/verify-email"
+
+	var err error
+
+	policy.PasswordResetTTL, err = getDurationFromEnv("PASSWORD_RESET_TTL", policy.PasswordResetTTL)
+	if err != nil {
+		return authentication.AccountRecoveryPolicy{}, err
+	}
+
+	policy.EmailVerificationTTL, err = getDurationFromEnv("EMAIL_VERIFICATION_TTL", policy.EmailVerificationTTL)
+	if err != nil {
+		return authentication.AccountRecoveryPolicy{}, err
+	}
+
+	return policy, nil
+}
+
+func newAccountDeletionPolicy() (authentication.AccountDeletionPolicy, error) {
+	policy := authentication.DefaultAccountDeletionPolicy()
+
+	policy.Content = authentication.ContentRetention(env.GetString("ACCOUNT_DELETION_CONTENT", string(policy.Content)))
+	if !policy.Content.IsValid() {
+		return authentication.AccountDeletionPolicy{}, fmt.Errorf("unknown account deletion content %q", policy.Content)
+	}
+
+	return policy, nil
+}
+
+func newThreadPolicy() (discuss.ThreadPolicy, error) {
+	policy := discuss.DefaultThreadPolicy()
+
+	policy.MaxDepth = env.GetInt("COMMENT_MAX_DEPTH", policy.MaxDepth)
+	if policy.MaxDepth < 0 {
+		return discuss.ThreadPolicy{}, fmt.Errorf("comment max depth must not be negative, got %d", policy.MaxDepth)
+	}
+
+	return policy, nil
+}
+
+// newBlobStore returns the store selected by BLOB_DRIVER. The "file" driver keeps blobs under BLOB_DIR, and the
+// "memory" driver loses them on restart, like the in-memory database the app defaults to.
+func newBlobStore() (blob.Store, error) { //nolint:ireturn
+	switch driver := env.GetString("BLOB_DRIVER", "memory"); driver {
+	case "file":
+		return blob.NewFileStore(env.GetString("BLOB_DIR", "./blobs")), nil
+	case "memory":
+		return blob.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown blob driver %q", driver)
+	}
+}
+
 func newServer() *server.Server {
 	server := &server.Server{
 		Port: env.GetString("PORT", server.DefaultPort),
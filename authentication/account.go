@@ -0,0 +1,325 @@
+package authentication
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DeletedUserID is the account the posts and comments of deleted users are kept under when the deletion policy
+// keeps content. The row is created by a migration, has no password and is shown as DeletedUsername.
+const (
+	DeletedUserID   = "00000000-0000-0000-0000-000000000000"
+	DeletedUsername = "[deleted]"
+)
+
+// ContentRetention decides what becomes of the posts, comments and reactions of a deleted account.
+type ContentRetention string
+
+const (
+	// ContentRetentionDelete removes them along with the account, as the foreign keys of the schema cascade.
+	// Comments on the removed posts and replies to the removed comments go with them, whoever wrote them. Revisions
+	// the user made of other users' posts are kept under DeletedUserID, so those posts keep their history.
+	ContentRetentionDelete ContentRetention = "delete"
+	// ContentRetentionTombstone moves posts and comments to the DeletedUserID account, so discussions stay whole.
+	// Reactions are moved too and only count toward the totals.
+	ContentRetentionTombstone ContentRetention = "tombstone"
+)
+
+func (retention ContentRetention) IsValid() bool {
+	switch retention {
+	case ContentRetentionDelete, ContentRetentionTombstone:
+		return true
+	default:
+		return false
+	}
+}
+
+type AccountDeletionPolicy struct {
+	Content ContentRetention
+}
+
+func DefaultAccountDeletionPolicy() AccountDeletionPolicy {
+	return AccountDeletionPolicy{
+		Content: ContentRetentionDelete,
+	}
+}
+
+// UserPost, UserComment, UserProfile and UserReaction are what a user wrote, shows and reacted with, as listed for a
+// data export. They are defined here, not taken from the packages that own them, since most of those depend on this
+// one.
+type UserPost struct {
+	ID        string    `json:"id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// UserComment leaves out the comments the user deleted, as nothing of them is left but a place in the thread.
+type UserComment struct {
+	ID        string     `json:"id"`
+	PostID    string     `json:"postId"`
+	ReplyTo   *string    `json:"replyTo"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// UserProfile is the profile the user shows to others. The avatar image is left out of exports.
+type UserProfile struct {
+	DisplayName     string     `json:"displayName"`
+	Bio             string     `json:"bio"`
+	AvatarUpdatedAt *time.Time `json:"avatarUpdatedAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+type UserReaction struct {
+	TargetType string    `json:"targetType"`
+	TargetID   string    `json:"targetId"`
+	Emoji      string    `json:"emoji"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// UserDataRepository reaches across the tables other packages own, for what has to cover all of a user's data.
+type UserDataRepository interface {
+	ListPosts(ctx context.Context, userID string) (posts []*UserPost, err error)
+	ListComments(ctx context.Context, userID string) (comments []*UserComment, err error)
+	ListReactions(ctx context.Context, userID string) (reactions []*UserReaction, err error)
+	// FindProfile returns nil if the user hasn't set up a profile.
+	FindProfile(ctx context.Context, userID string) (profile *UserProfile, err error)
+	// DeleteUser removes the user and everything that belongs to it in one transaction, keeping or removing
+	// content as retention says.
+	DeleteUser(ctx context.Context, userID string, retention ContentRetention) (err error)
+}
+
+var ErrDeletedUser = errors.New("the deleted user placeholder can't be changed")
+
+// AccountDeletionContent tells what becomes of a user's content when the account is deleted.
+func (svc *Service) AccountDeletionContent() ContentRetention {
+	return svc.accountDeletion.Content
+}
+
+// HasPassword reports whether the current user has a password, rather than only passkeys or identity providers to
+// sign in with.
+func (svc *Service) HasPassword(ctx context.Context) (bool, error) {
+	user, err := svc.GetCurrentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// GetCurrentUser clears the hash, so the stored user is checked for a password.
+	stored, err := svc.userRepo.Find(ctx, user.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return stored.PasswordHash != "", nil
+}
+
+// DeleteAccount deletes the current user's account for good. The password must be given again if the account has
+// one; accounts that only sign in with passkeys or identity providers rely on the session alone. What happens to
+// the user's content depends on the AccountDeletionPolicy.
+func (svc *Service) DeleteAccount(ctx context.Context, password string) error {
+	err := requireSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := svc.GetCurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if user.ID == DeletedUserID {
+		return ErrDeletedUser
+	}
+
+	// GetCurrentUser clears the hash, so the stored user is checked for a password.
+	stored, err := svc.userRepo.Find(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if stored.PasswordHash != "" {
+		if password == "" || len(password) > passwordMaxBytes {
+			return ErrInvalidCredentials
+		}
+
+		ok, err := svc.passwordHasher.Verify(stored.PasswordHash, password)
+		if err != nil {
+			return fmt.Errorf("failed to verify password hash: %w", err)
+		}
+
+		if !ok {
+			return ErrInvalidCredentials
+		}
+	}
+
+	err = svc.userDataRepo.DeleteUser(ctx, user.ID, svc.accountDeletion.Content)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	// Failed logins are kept by username, which is free to register again now.
+	err = svc.loginAttemptRepo.Delete(ctx, LoginAttemptScopeUsername, CanonicalUsername(user.Username))
+	if err != nil {
+		return fmt.Errorf("failed to delete failed logins: %w", err)
+	}
+
+	return nil
+}
+
+type exportedAccount struct {
+	ID              string     `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	RegisteredAt    time.Time  `json:"registeredAt"`
+}
+
+type exportedSession struct {
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+}
+
+// ExportMyData writes a ZIP archive of the current user's account, profile, posts, comments, reactions and sessions
+// to w. Each is a JSON file, and every post and comment is also a Markdown file of its own. Session IDs are left
+// out, since they are the secrets the session cookies carry.
+func (svc *Service) ExportMyData(ctx context.Context, w io.Writer) error {
+	err := requireSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := svc.GetCurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	posts, err := svc.userDataRepo.ListPosts(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list posts: %w", err)
+	}
+
+	comments, err := svc.userDataRepo.ListComments(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list comments: %w", err)
+	}
+
+	reactions, err := svc.userDataRepo.ListReactions(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list reactions: %w", err)
+	}
+
+	profile, err := svc.userDataRepo.FindProfile(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to find profile: %w", err)
+	}
+
+	sessions, err := svc.sessionRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	exportedSessions := make([]exportedSession, 0, len(sessions))
+
+	for _, session := range sessions {
+		exportedSessions = append(exportedSessions, exportedSession{
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			ExpiresAt:  session.ExpiresAt,
+			LastSeenAt: session.LastSeenAt,
+		})
+	}
+
+	archive := dataExport{zw: zip.NewWriter(w), modified: time.Now()}
+
+	archive.writeJSON("account.json", exportedAccount{
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		RegisteredAt:    user.RegisteredAt,
+	})
+	archive.writeJSON("profile.json", profile)
+	archive.writeJSON("posts.json", posts)
+	archive.writeJSON("comments.json", comments)
+	archive.writeJSON("reactions.json", reactions)
+	archive.writeJSON("sessions.json", exportedSessions)
+
+	for _, post := range posts {
+		archive.writeMarkdown("posts/"+post.ID+".md", post.CreatedAt, post.Content)
+	}
+
+	for _, comment := range comments {
+		archive.writeMarkdown("comments/"+comment.ID+".md", comment.CreatedAt, comment.Content)
+	}
+
+	if archive.err != nil {
+		return archive.err
+	}
+
+	err = archive.zw.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close archive: %w", err)
+	}
+
+	return nil
+}
+
+// dataExport adds files to a ZIP archive and keeps the first error, so the files can be written one after another.
+type dataExport struct {
+	zw       *zip.Writer
+	modified time.Time
+	err      error
+}
+
+func (export *dataExport) create(name string, modified time.Time) io.Writer {
+	if export.err != nil {
+		return nil
+	}
+
+	f, err := export.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		export.err = fmt.Errorf("failed to create %s: %w", name, err)
+
+		return nil
+	}
+
+	return f
+}
+
+func (export *dataExport) writeJSON(name string, v any) {
+	f := export.create(name, export.modified)
+	if f == nil {
+		return
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+
+	err := enc.Encode(v)
+	if err != nil {
+		export.err = fmt.Errorf("failed to write %s: %w", name, err)
+	}
+}
+
+// writeMarkdown writes content as is, since posts and comments are written in Markdown.
+func (export *dataExport) writeMarkdown(name string, createdAt time.Time, content string) {
+	f := export.create(name, createdAt)
+	if f == nil {
+		return
+	}
+
+	_, err := io.WriteString(f, content)
+	if err != nil {
+		export.err = fmt.Errorf("failed to write %s: %w", name, err)
+	}
+}
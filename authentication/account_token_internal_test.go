@@ -0,0 +1,83 @@
+package authentication
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountToken(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("test-key")
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	token, err := signAccountToken(key, tokenPurposePasswordReset, "user-1", "binding", time.Hour, now)
+	require.NoError(t, err)
+
+	userID, err := parseAccountToken(token, tokenPurposePasswordReset, now.Add(59*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+	require.NoError(t, verifyAccountToken(key, token, "binding"))
+
+	tests := []struct {
+		name  string
+		check func() error
+	}{
+		{
+			name: "expired",
+			check: func() error {
+				_, err := parseAccountToken(token, tokenPurposePasswordReset, now.Add(time.Hour))
+
+				return err
+			},
+		},
+		{
+			name: "other purpose",
+			check: func() error {
+				_, err := parseAccountToken(token, tokenPurposeEmailVerification, now)
+
+				return err
+			},
+		},
+		{
+			name: "malformed",
+			check: func() error {
+				_, err := parseAccountToken("not-a-token", tokenPurposePasswordReset, now)
+
+				return err
+			},
+		},
+		{
+			name:  "changed binding",
+			check: func() error { return verifyAccountToken(key, token, "other binding") },
+		},
+		{
+			name:  "other key",
+			check: func() error { return verifyAccountToken([]byte("other-key"), token, "binding") },
+		},
+		{
+			name: "tampered payload",
+			check: func() error {
+				forged, err := signAccountToken(key, tokenPurposePasswordReset, "user-2", "binding", time.Hour, now)
+				require.NoError(t, err)
+
+				_, forgedSignature, _ := strings.Cut(forged, ".")
+				payload, _, _ := strings.Cut(token, ".")
+
+				return verifyAccountToken(key, payload+"."+forgedSignature, "binding")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.ErrorIs(t, tt.check(), ErrInvalidToken)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/nasermirzaei89/scribble/random"
+)
+
+var ErrInvalidAPIToken = errors.New("invalid api token")
+
+// ErrAPITokenNotAllowed is returned when a request authenticated by a personal access token tries to manage the
+// account: its tokens, email, second factors, sign-in methods, sessions or the account itself.
+var ErrAPITokenNotAllowed = errors.New("personal access tokens can't manage the account")
+
+// requireSession rejects requests authenticated by a personal access token, for what only a signed-in user may do.
+func requireSession(ctx context.Context) error {
+	if _, ok := authcontext.APITokenIDFromContext(ctx); ok {
+		return ErrAPITokenNotAllowed
+	}
+
+	return nil
+}
+
+// apiTokenLastUsedResolution bounds how often a token's last-used time is written, so a busy client does not turn
+// every request into a database write.
+const apiTokenLastUsedResolution = time.Minute
+
+type CreateAPITokenRequest struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+// CreateAPIToken creates a personal access token for the current user. The returned secret is not stored anywhere
+// and cannot be retrieved again.
+func (svc *Service) CreateAPIToken(ctx context.Context, req CreateAPITokenRequest) (*APIToken, string, error) {
+	err := requireSession(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sub := authcontext.GetSubject(ctx)
+	if sub == authcontext.Anonymous {
+		return nil, "", ErrCurrentUserNotFound
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, "", &APITokenNameRequiredError{}
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, "", &InvalidScopeError{Scope: ""}
+	}
+
+	for _, scope := range req.Scopes {
+		if !slices.Contains(scopes, scope) {
+			return nil, "", &InvalidScopeError{Scope: scope}
+		}
+	}
+
+	secret := APITokenPrefix + random.String(32)
+
+	token := &APIToken{
+		ID:         uuid.NewString(),
+		UserID:     sub,
+		Name:       name,
+		TokenHash:  HashAPIToken(secret),
+		Scopes:     slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+		CreatedAt:  time.Now(),
+		ExpiresAt:  req.ExpiresAt,
+		LastUsedAt: nil,
+	}
+
+	err = svc.apiTokenRepo.Insert(ctx, token)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to insert api token: %w", err)
+	}
+
+	return token, secret, nil
+}
+
+// ListAPITokens returns the current user's personal access tokens, newest first.
+func (svc *Service) ListAPITokens(ctx context.Context) ([]*APIToken, error) {
+	err := requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := authcontext.GetSubject(ctx)
+	if sub == authcontext.Anonymous {
+		return nil, ErrCurrentUserNotFound
+	}
+
+	tokens, err := svc.apiTokenRepo.ListByUser(ctx, sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// RevokeAPIToken deletes one of the current user's personal access tokens.
+func (svc *Service) RevokeAPIToken(ctx context.Context, tokenID string) error {
+	err := requireSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	sub := authcontext.GetSubject(ctx)
+	if sub == authcontext.Anonymous {
+		return ErrCurrentUserNotFound
+	}
+
+	err = svc.apiTokenRepo.Delete(ctx, sub, tokenID)
+	if err != nil {
+		return fmt.Errorf("failed to delete api token: %w", err)
+	}
+
+	return nil
+}
+
+// AuthenticateAPIToken resolves a token secret to its token and records that it was used.
+func (svc *Service) AuthenticateAPIToken(ctx context.Context, secret string) (*APIToken, error) {
+	if !strings.HasPrefix(secret, APITokenPrefix) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	token, err := svc.apiTokenRepo.FindByHash(ctx, HashAPIToken(secret))
+	if err != nil {
+		if _, ok := errors.AsType[*APITokenByHashNotFoundError](err); ok {
+			return nil, ErrInvalidAPIToken
+		}
+
+		return nil, fmt.Errorf("failed to find api token: %w", err)
+	}
+
+	now := time.Now()
+
+	if token.IsExpired(now) {
+		return nil, &APITokenExpiredError{ID: token.ID}
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenLastUsedResolution {
+		err = svc.apiTokenRepo.UpdateLastUsed(ctx, token.ID, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update api token last used time: %w", err)
+		}
+
+		token.LastUsedAt = &now
+	}
+
+	return token, nil
+}
@@ -0,0 +1,24 @@
+package authentication
+
+import (
+	"context"
+	"testing"
+
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokenCantManageAccount(t *testing.T) {
+	t.Parallel()
+
+	// The checks come before any repository is used, so the service needs none.
+	svc := &Service{}
+	ctx := authcontext.WithAPITokenID(authcontext.WithSubject(context.Background(), "user-1"), "token-1")
+
+	_, _, err := svc.CreateAPIToken(ctx, CreateAPITokenRequest{Name: "new", Scopes: []string{ScopeRead}, ExpiresAt: nil})
+	require.ErrorIs(t, err, ErrAPITokenNotAllowed)
+
+	require.ErrorIs(t, svc.SetEmail(ctx, "user@example.com"), ErrAPITokenNotAllowed)
+	require.ErrorIs(t, svc.DisableTOTP(ctx, "123456"), ErrAPITokenNotAllowed)
+	require.ErrorIs(t, svc.DeleteAccount(ctx, ""), ErrAPITokenNotAllowed)
+}
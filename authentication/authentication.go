@@ -4,40 +4,100 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/google/uuid"
 	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
 	"github.com/nasermirzaei89/scribble/authorization"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/nasermirzaei89/scribble/mail"
+)
+
+const ServiceName = "github.com/nasermirzaei89/scribble/authentication"
+
+const (
+	ActionListSessions  = "listSessions"
+	ActionRevokeSession = "revokeSession"
 )
 
 type Service struct {
-	userRepo    UserRepository
-	sessionRepo SessionRepository
-	authzClient *authorization.Client
+	userRepo           UserRepository
+	sessionRepo        SessionRepository
+	apiTokenRepo       APITokenRepository
+	loginAttemptRepo   LoginAttemptRepository
+	totpCredentialRepo TOTPCredentialRepository
+	recoveryCodeRepo   RecoveryCodeRepository
+	loginChallengeRepo LoginChallengeRepository
+	passkeyRepo        PasskeyRepository
+	identityRepo       IdentityRepository
+	userDataRepo       UserDataRepository
+	authzClient        *authorization.Client
+	webAuthn           *webauthn.WebAuthn
+	oidcProviders      []*OIDCProvider
+	mailer             mail.Sender
+	passwordHasher     PasswordHasher
+	credentials        CredentialsPolicy
+	loginThrottle      LoginThrottlePolicy
+	accountRecovery    AccountRecoveryPolicy
+	accountDeletion    AccountDeletionPolicy
+}
+
+// Dependencies is what a Service is built from. The repositories and the authorization client are required; the rest
+// configure the sign-in methods and policies.
+type Dependencies struct {
+	UserRepo           UserRepository
+	SessionRepo        SessionRepository
+	APITokenRepo       APITokenRepository
+	LoginAttemptRepo   LoginAttemptRepository
+	TOTPCredentialRepo TOTPCredentialRepository
+	RecoveryCodeRepo   RecoveryCodeRepository
+	LoginChallengeRepo LoginChallengeRepository
+	PasskeyRepo        PasskeyRepository
+	IdentityRepo       IdentityRepository
+	UserDataRepo       UserDataRepository
+	AuthzClient        *authorization.Client
+	WebAuthn           *webauthn.WebAuthn
+	OIDCProviders      []*OIDCProvider
+	Mailer             mail.Sender
+	PasswordHasher     PasswordHasher
+	Credentials        CredentialsPolicy
+	LoginThrottle      LoginThrottlePolicy
+	AccountRecovery    AccountRecoveryPolicy
+	AccountDeletion    AccountDeletionPolicy
 }
 
-func NewService(userRepo UserRepository, sessionRepo SessionRepository, authzClient *authorization.Client) *Service {
+func NewService(deps Dependencies) *Service {
 	return &Service{
-		userRepo:    userRepo,
-		sessionRepo: sessionRepo,
-		authzClient: authzClient,
+		userRepo:           deps.UserRepo,
+		sessionRepo:        deps.SessionRepo,
+		apiTokenRepo:       deps.APITokenRepo,
+		loginAttemptRepo:   deps.LoginAttemptRepo,
+		totpCredentialRepo: deps.TOTPCredentialRepo,
+		recoveryCodeRepo:   deps.RecoveryCodeRepo,
+		loginChallengeRepo: deps.LoginChallengeRepo,
+		passkeyRepo:        deps.PasskeyRepo,
+		identityRepo:       deps.IdentityRepo,
+		userDataRepo:       deps.UserDataRepo,
+		authzClient:        deps.AuthzClient,
+		webAuthn:           deps.WebAuthn,
+		oidcProviders:      deps.OIDCProviders,
+		mailer:             deps.Mailer,
+		passwordHasher:     deps.PasswordHasher,
+		credentials:        deps.Credentials,
+		loginThrottle:      deps.LoginThrottle,
+		accountRecovery:    deps.AccountRecovery,
+		accountDeletion:    deps.AccountDeletion,
 	}
 }
 
-func HashPassword(password string) (string, error) {
-	bcryptHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (svc *Service) Register(ctx context.Context, username, password string) error {
+	err := svc.credentials.Validate(username, password)
 	if err != nil {
-		return "", fmt.Errorf("failed to hash password: %w", err)
+		return fmt.Errorf("invalid credentials: %w", err)
 	}
 
-	return string(bcryptHash), nil
-}
-
-func (svc *Service) Register(ctx context.Context, username, password string) error {
-	// TODO: validate username and password
-	_, err := svc.userRepo.FindByUsername(ctx, username)
+	_, err = svc.userRepo.FindByUsername(ctx, username)
 	if err != nil {
 		if _, ok := errors.AsType[*UserByUsernameNotFoundError](err); !ok {
 			return fmt.Errorf("failed to check if username already exists: %w", err)
@@ -46,7 +106,7 @@ func (svc *Service) Register(ctx context.Context, username, password string) err
 		return &UserAlreadyExistsError{Username: username}
 	}
 
-	passwordHash, err := HashPassword(password)
+	passwordHash, err := svc.passwordHasher.Hash(password)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -75,36 +135,106 @@ var ErrInvalidCredentials = errors.New("invalid credentials")
 
 const defaultSessionDuration = 30 * 24 * time.Hour
 
-func (svc *Service) Login(ctx context.Context, username, password string) (*Session, error) {
-	// TODO: validate username and password
+func (svc *Service) Login(ctx context.Context, username, password string, client ClientInfo) (*Session, error) {
+	// Registration rules may change over time, so existing accounts are only held to what bcrypt can check.
+	if username == "" || password == "" || len(password) > passwordMaxBytes {
+		return nil, ErrInvalidCredentials
+	}
+
+	timeNow := time.Now()
+	attemptKeys := loginAttemptKeys(username, client)
+
+	// Blocked attempts are turned away before the password is checked, so they can't be used to keep guessing.
+	err := svc.checkLoginThrottle(ctx, attemptKeys, timeNow)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := svc.userRepo.FindByUsername(ctx, username)
 	if err != nil {
 		if _, ok := errors.AsType[*UserByUsernameNotFoundError](err); ok {
-			return nil, ErrInvalidCredentials
+			return nil, svc.failLogin(ctx, attemptKeys, timeNow)
 		}
 
 		return nil, fmt.Errorf("failed to find user by username: %w", err)
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	// Accounts created through an identity provider have no password.
+	if user.PasswordHash == "" {
+		return nil, svc.failLogin(ctx, attemptKeys, timeNow)
+	}
+
+	ok, err := svc.passwordHasher.Verify(user.PasswordHash, password)
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return nil, ErrInvalidCredentials
-		}
+		return nil, fmt.Errorf("failed to verify password hash: %w", err)
+	}
 
-		return nil, fmt.Errorf("failed to compare password hash: %w", err)
+	if !ok {
+		return nil, svc.failLogin(ctx, attemptKeys, timeNow)
 	}
 
-	timeNow := time.Now()
+	svc.rehashPassword(ctx, user, password)
+
+	secondFactor, err := svc.hasSecondFactor(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// With two-factor authentication the session is only issued by VerifySecondFactor, which also keeps the failed
+	// logins until the second factor is right.
+	if secondFactor {
+		return nil, svc.startLoginChallenge(ctx, user.ID, timeNow)
+	}
+
+	err = svc.resetLoginFailures(ctx, attemptKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.createSession(ctx, user.ID, client, timeNow)
+}
+
+// rehashPassword replaces a hash made under an older hashing policy, now that the plain password is at hand. A
+// failure only leaves the old hash in place, so it doesn't fail the login.
+func (svc *Service) rehashPassword(ctx context.Context, user *User, password string) {
+	if !svc.passwordHasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	passwordHash, err := svc.passwordHasher.Hash(password)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to rehash password", "userId", user.ID, "error", err)
+
+		return
+	}
+
+	err = svc.userRepo.UpdatePasswordHash(ctx, user.ID, passwordHash)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to update rehashed password", "userId", user.ID, "error", err)
 
+		return
+	}
+
+	user.PasswordHash = passwordHash
+}
+
+func (svc *Service) createSession(
+	ctx context.Context,
+	userID string,
+	client ClientInfo,
+	now time.Time,
+) (*Session, error) {
 	session := &Session{
-		ID:        uuid.NewString(),
-		UserID:    user.ID,
-		CreatedAt: timeNow,
-		ExpiresAt: timeNow.Add(defaultSessionDuration),
+		ID:         uuid.NewString(),
+		UserID:     userID,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(defaultSessionDuration),
+		LastSeenAt: now,
 	}
 
-	err = svc.sessionRepo.Insert(ctx, session)
+	err := svc.sessionRepo.Insert(ctx, session)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
@@ -112,6 +242,16 @@ func (svc *Service) Login(ctx context.Context, username, password string) (*Sess
 	return session, nil
 }
 
+// failLogin records a failed login and returns the error Login reports for it.
+func (svc *Service) failLogin(ctx context.Context, keys []loginAttemptKey, now time.Time) error {
+	err := svc.recordLoginFailure(ctx, keys, now)
+	if err != nil {
+		return err
+	}
+
+	return ErrInvalidCredentials
+}
+
 func (svc *Service) Logout(ctx context.Context, sessionID string) error {
 	err := svc.sessionRepo.Delete(ctx, sessionID)
 	if err != nil {
@@ -128,7 +268,7 @@ func (svc *Service) GetSession(ctx context.Context, sessionID string) (*Session,
 	}
 
 	if session.ExpiresAt.Before(time.Now()) {
-		// TODO: delete expired session
+		// Expired rows are removed by the background sweeper, see DeleteExpiredSessions.
 		return nil, &SessionExpiredError{ID: sessionID}
 	}
 
@@ -146,6 +286,60 @@ func (svc *Service) GetUser(ctx context.Context, userID string) (*User, error) {
 	return user, nil
 }
 
+// GetUserByUsername finds a user by username, compared the way usernames are at login.
+func (svc *Service) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	user, err := svc.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user by username: %w", err)
+	}
+
+	user.PasswordHash = "" // clear password hash before returning user
+
+	return user, nil
+}
+
+// GetUsersByUsernames returns the users among the given usernames keyed by their canonical username, loading them all
+// in one query. Unknown usernames are left out.
+func (svc *Service) GetUsersByUsernames(ctx context.Context, usernames []string) (map[string]*User, error) {
+	users, err := svc.userRepo.FindManyByUsernames(ctx, usernames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users by usernames: %w", err)
+	}
+
+	result := make(map[string]*User, len(users))
+
+	for _, user := range users {
+		user.PasswordHash = "" // clear password hash before returning user
+		result[CanonicalUsername(user.Username)] = user
+	}
+
+	return result, nil
+}
+
+// GetUsers returns the users with the given IDs keyed by ID, loading them all in one query. Any missing user is
+// reported as a UserNotFoundError.
+func (svc *Service) GetUsers(ctx context.Context, userIDs []string) (map[string]*User, error) {
+	users, err := svc.userRepo.FindMany(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users by ids: %w", err)
+	}
+
+	result := make(map[string]*User, len(users))
+
+	for _, user := range users {
+		user.PasswordHash = "" // clear password hash before returning user
+		result[user.ID] = user
+	}
+
+	for _, userID := range userIDs {
+		if _, ok := result[userID]; !ok {
+			return nil, &UserNotFoundError{ID: userID}
+		}
+	}
+
+	return result, nil
+}
+
 func (svc *Service) GetCurrentUser(ctx context.Context) (*User, error) {
 	sub := authcontext.GetSubject(ctx)
 	if sub == authcontext.Anonymous {
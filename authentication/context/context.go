@@ -45,3 +45,20 @@ func WithSubject(ctx context.Context, userID string) context.Context {
 func WithServiceSubject(ctx context.Context, serviceName string) context.Context {
 	return WithSubject(ctx, "system:service:"+serviceName)
 }
+
+type contextKeyAPITokenID struct{}
+
+// WithAPITokenID marks the request as authenticated by a personal access token rather than a session. Managing the
+// account is left to sessions, so a leaked token can't be turned into a takeover.
+func WithAPITokenID(ctx context.Context, tokenID string) context.Context {
+	return context.WithValue(ctx, contextKeyAPITokenID{}, tokenID)
+}
+
+func APITokenIDFromContext(ctx context.Context) (string, bool) {
+	tokenID, ok := ctx.Value(contextKeyAPITokenID{}).(string)
+	if !ok {
+		return "", false
+	}
+
+	return tokenID, true
+}
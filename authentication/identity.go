@@ -0,0 +1,43 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Identity links a user to an account at an external OpenID Connect provider. Subject is the provider's stable ID for
+// that account; the email is only kept for display.
+type Identity struct {
+	Provider    string
+	Subject     string
+	UserID      string
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt *time.Time
+}
+
+type IdentityRepository interface {
+	Insert(ctx context.Context, identity *Identity) (err error)
+	Find(ctx context.Context, provider, subject string) (identity *Identity, err error)
+	ListByUser(ctx context.Context, userID string) (identities []*Identity, err error)
+	UpdateLastLogin(ctx context.Context, provider, subject string, loginAt time.Time) (err error)
+	Delete(ctx context.Context, userID, provider string) (err error)
+}
+
+type IdentityNotFoundError struct {
+	Provider string
+	Subject  string
+}
+
+func (err IdentityNotFoundError) Error() string {
+	return fmt.Sprintf("identity %q at provider %q not found", err.Subject, err.Provider)
+}
+
+type IdentityAlreadyLinkedError struct {
+	Provider string
+}
+
+func (err IdentityAlreadyLinkedError) Error() string {
+	return fmt.Sprintf("an account at provider %q is already linked", err.Provider)
+}
@@ -0,0 +1,117 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Failed logins are counted separately per username and per client IP address.
+const (
+	LoginAttemptScopeUsername = "username"
+	LoginAttemptScopeIP       = "ip"
+)
+
+// LoginAttempt tracks recent failed logins for one username or one client IP address.
+type LoginAttempt struct {
+	Scope        string
+	Subject      string
+	Failures     int
+	LastFailedAt time.Time
+	BlockedUntil *time.Time
+}
+
+func (attempt *LoginAttempt) IsBlocked(now time.Time) bool {
+	return attempt.BlockedUntil != nil && now.Before(*attempt.BlockedUntil)
+}
+
+type LoginAttemptRepository interface {
+	Find(ctx context.Context, scope, subject string) (attempt *LoginAttempt, err error)
+	// RecordFailure adds a failed attempt and returns the new count. Counts whose last failure is before resetBefore
+	// start over at one.
+	RecordFailure(ctx context.Context, scope, subject string, failedAt, resetBefore time.Time) (failures int, err error)
+	Block(ctx context.Context, scope, subject string, until time.Time) (err error)
+	// Delete forgets a scope and subject. Forgetting one that isn't tracked is not an error.
+	Delete(ctx context.Context, scope, subject string) (err error)
+	// DeleteStale removes entries whose last failure and block both ended before the given time.
+	DeleteStale(ctx context.Context, before time.Time) (deleted int, err error)
+}
+
+type LoginAttemptNotFoundError struct {
+	Scope   string
+	Subject string
+}
+
+func (err LoginAttemptNotFoundError) Error() string {
+	return fmt.Sprintf("login attempt for %s %q not found", err.Scope, err.Subject)
+}
+
+// TooManyAttemptsError is returned by Login while a username or client is blocked after repeated failures.
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (err TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry after %s", err.RetryAfter)
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds, as used by the Retry-After header.
+func (err TooManyAttemptsError) RetryAfterSeconds() int {
+	return int((err.RetryAfter + time.Second - 1) / time.Second)
+}
+
+// LoginThrottlePolicy controls how Login slows down repeated failures. After the free attempts, each failure blocks
+// further attempts for a delay that doubles every time up to MaxDelay. Reaching the lockout threshold blocks for
+// LockoutDuration instead. Client IPs get their own, usually higher, limits because addresses may be shared.
+type LoginThrottlePolicy struct {
+	FreeAttempts       int
+	IPFreeAttempts     int
+	LockoutThreshold   int
+	IPLockoutThreshold int
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+	LockoutDuration    time.Duration
+	// ResetAfter forgets failures once this long has passed without a new one.
+	ResetAfter time.Duration
+}
+
+func DefaultLoginThrottlePolicy() LoginThrottlePolicy {
+	return LoginThrottlePolicy{
+		FreeAttempts:       3,
+		IPFreeAttempts:     10,
+		LockoutThreshold:   10,
+		IPLockoutThreshold: 50,
+		BaseDelay:          time.Second,
+		MaxDelay:           5 * time.Minute,
+		LockoutDuration:    15 * time.Minute,
+		ResetAfter:         24 * time.Hour,
+	}
+}
+
+// BlockDuration returns how long to block a scope after its given number of consecutive failures, or zero when
+// another attempt may follow right away. A zero lockout threshold disables the lockout.
+func (policy LoginThrottlePolicy) BlockDuration(scope string, failures int) time.Duration {
+	freeAttempts, lockoutThreshold := policy.FreeAttempts, policy.LockoutThreshold
+	if scope == LoginAttemptScopeIP {
+		freeAttempts, lockoutThreshold = policy.IPFreeAttempts, policy.IPLockoutThreshold
+	}
+
+	if lockoutThreshold > 0 && failures >= lockoutThreshold {
+		return policy.LockoutDuration
+	}
+
+	if failures <= freeAttempts {
+		return 0
+	}
+
+	delay := policy.BaseDelay
+
+	for range failures - freeAttempts - 1 {
+		delay *= 2
+		if delay >= policy.MaxDelay {
+			return policy.MaxDelay
+		}
+	}
+
+	return min(delay, policy.MaxDelay)
+}
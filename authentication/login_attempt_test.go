@@ -0,0 +1,54 @@
+package authentication_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginThrottlePolicyBlockDuration(t *testing.T) {
+	t.Parallel()
+
+	policy := authentication.LoginThrottlePolicy{
+		FreeAttempts:       3,
+		IPFreeAttempts:     5,
+		LockoutThreshold:   10,
+		IPLockoutThreshold: 20,
+		BaseDelay:          time.Second,
+		MaxDelay:           30 * time.Second,
+		LockoutDuration:    15 * time.Minute,
+	}
+
+	tests := []struct {
+		name     string
+		scope    string
+		failures int
+		expected time.Duration
+	}{
+		{name: "free attempt", scope: authentication.LoginAttemptScopeUsername, failures: 3, expected: 0},
+		{name: "first delay", scope: authentication.LoginAttemptScopeUsername, failures: 4, expected: time.Second},
+		{name: "doubles", scope: authentication.LoginAttemptScopeUsername, failures: 6, expected: 4 * time.Second},
+		{name: "capped", scope: authentication.LoginAttemptScopeUsername, failures: 9, expected: 30 * time.Second},
+		{name: "locked out", scope: authentication.LoginAttemptScopeUsername, failures: 10, expected: 15 * time.Minute},
+		{name: "ip free attempt", scope: authentication.LoginAttemptScopeIP, failures: 5, expected: 0},
+		{name: "ip first delay", scope: authentication.LoginAttemptScopeIP, failures: 6, expected: time.Second},
+		{name: "ip locked out", scope: authentication.LoginAttemptScopeIP, failures: 20, expected: 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, policy.BlockDuration(tt.scope, tt.failures))
+		})
+	}
+}
+
+func TestTooManyAttemptsErrorRetryAfterSeconds(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 1, authentication.TooManyAttemptsError{RetryAfter: 200 * time.Millisecond}.RetryAfterSeconds())
+	assert.Equal(t, 2, authentication.TooManyAttemptsError{RetryAfter: 2 * time.Second}.RetryAfterSeconds())
+}
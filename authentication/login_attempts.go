@@ -0,0 +1,107 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type loginAttemptKey struct {
+	scope   string
+	subject string
+}
+
+// loginAttemptKeys returns what a login attempt counts against: the canonical username and, when known, the client
+// IP address.
+func loginAttemptKeys(username string, client ClientInfo) []loginAttemptKey {
+	keys := []loginAttemptKey{{scope: LoginAttemptScopeUsername, subject: CanonicalUsername(username)}}
+
+	if client.IPAddress != "" {
+		keys = append(keys, loginAttemptKey{scope: LoginAttemptScopeIP, subject: client.IPAddress})
+	}
+
+	return keys
+}
+
+// checkLoginThrottle returns a TooManyAttemptsError while any of the keys is blocked, with the longest remaining
+// block as the retry delay.
+func (svc *Service) checkLoginThrottle(ctx context.Context, keys []loginAttemptKey, now time.Time) error {
+	var retryAfter time.Duration
+
+	for _, key := range keys {
+		attempt, err := svc.loginAttemptRepo.Find(ctx, key.scope, key.subject)
+		if err != nil {
+			if _, ok := errors.AsType[*LoginAttemptNotFoundError](err); ok {
+				continue
+			}
+
+			return fmt.Errorf("failed to find login attempt: %w", err)
+		}
+
+		if attempt.IsBlocked(now) {
+			retryAfter = max(retryAfter, attempt.BlockedUntil.Sub(now))
+		}
+	}
+
+	if retryAfter > 0 {
+		return &TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+func (svc *Service) recordLoginFailure(ctx context.Context, keys []loginAttemptKey, now time.Time) error {
+	for _, key := range keys {
+		failures, err := svc.loginAttemptRepo.RecordFailure(
+			ctx,
+			key.scope,
+			key.subject,
+			now,
+			now.Add(-svc.loginThrottle.ResetAfter),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record failed login: %w", err)
+		}
+
+		blockFor := svc.loginThrottle.BlockDuration(key.scope, failures)
+		if blockFor == 0 {
+			continue
+		}
+
+		err = svc.loginAttemptRepo.Block(ctx, key.scope, key.subject, now.Add(blockFor))
+		if err != nil {
+			return fmt.Errorf("failed to block login attempts: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// resetLoginFailures forgets the failed logins of the username after a successful login. The client's count stands,
+// so an attacker can't reset it by logging into an account of their own.
+func (svc *Service) resetLoginFailures(ctx context.Context, keys []loginAttemptKey) error {
+	for _, key := range keys {
+		if key.scope != LoginAttemptScopeUsername {
+			continue
+		}
+
+		err := svc.loginAttemptRepo.Delete(ctx, key.scope, key.subject)
+		if err != nil {
+			return fmt.Errorf("failed to reset failed logins: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteStaleLoginAttempts forgets failed logins that no longer affect anyone. It is meant for background
+// maintenance and performs no authorization check.
+func (svc *Service) DeleteStaleLoginAttempts(ctx context.Context) (int, error) {
+	deleted, err := svc.loginAttemptRepo.DeleteStale(ctx, time.Now().Add(-svc.loginThrottle.ResetAfter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale login attempts: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,498 @@
+package authentication
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/google/uuid"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrInvalidOIDCResponse = errors.New("invalid response from identity provider")
+	ErrLastSignInMethod    = errors.New("this is the only way left to sign in")
+)
+
+type OIDCProviderNotFoundError struct {
+	Name string
+}
+
+func (err OIDCProviderNotFoundError) Error() string {
+	return fmt.Sprintf("identity provider %q not found", err.Name)
+}
+
+// oidcAuthorizationTimeout is how long the user has to sign in at the provider and come back.
+const oidcAuthorizationTimeout = 10 * time.Minute
+
+// OIDCProviderConfig describes an OpenID Connect provider users can sign in with. Name identifies the provider in
+// URLs and stored identities, so it must not change once users have linked accounts.
+type OIDCProviderConfig struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes are requested on top of "openid".
+	Scopes []string
+}
+
+type OIDCProvider struct {
+	Name        string
+	DisplayName string
+	oauth2      oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+}
+
+// NewOIDCProvider fetches the provider's discovery document from the issuer, so it needs the provider to be reachable.
+func NewOIDCProvider(ctx context.Context, cfg OIDCProviderConfig) (*OIDCProvider, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover provider %q: %w", cfg.Name, err)
+	}
+
+	displayName := cfg.DisplayName
+	if displayName == "" {
+		displayName = cfg.Name
+	}
+
+	return &OIDCProvider{
+		Name:        cfg.Name,
+		DisplayName: displayName,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       append([]string{oidc.ScopeOpenID}, cfg.Scopes...),
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+// OIDCAuthorization is the first half of a sign-in at an identity provider. The user is sent to URL, and State has to
+// be handed back together with the provider's callback. State holds the PKCE verifier, so it must be kept where the
+// client can't read or change it, such as an encrypted and signed cookie.
+type OIDCAuthorization struct {
+	URL   string
+	State []byte
+}
+
+// oidcState is what OIDCAuthorization.State carries between the two halves of the flow.
+type oidcState struct {
+	Provider string    `json:"provider"`
+	State    string    `json:"state"`
+	Nonce    string    `json:"nonce"`
+	Verifier string    `json:"verifier"`
+	Expires  time.Time `json:"expires"`
+}
+
+// oidcClaims are the ID token claims used to create and describe accounts.
+type oidcClaims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+}
+
+// OIDCProviders returns the configured identity providers in the order they were given.
+func (svc *Service) OIDCProviders() []*OIDCProvider {
+	return svc.oidcProviders
+}
+
+func (svc *Service) oidcProvider(name string) (*OIDCProvider, error) {
+	for _, provider := range svc.oidcProviders {
+		if provider.Name == name {
+			return provider, nil
+		}
+	}
+
+	return nil, &OIDCProviderNotFoundError{Name: name}
+}
+
+// BeginOIDCLogin starts signing in at the named provider using the authorization code flow with PKCE.
+func (svc *Service) BeginOIDCLogin(_ context.Context, providerName string) (*OIDCAuthorization, error) {
+	provider, err := svc.oidcProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state := oidcState{
+		Provider: provider.Name,
+		State:    rand.Text(),
+		Nonce:    rand.Text(),
+		Verifier: oauth2.GenerateVerifier(),
+		Expires:  time.Now().Add(oidcAuthorizationTimeout),
+	}
+
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal oidc state: %w", err)
+	}
+
+	authURL := provider.oauth2.AuthCodeURL(
+		state.State,
+		oidc.Nonce(state.Nonce),
+		oauth2.S256ChallengeOption(state.Verifier),
+	)
+
+	return &OIDCAuthorization{URL: authURL, State: stateJSON}, nil
+}
+
+// BeginOIDCLink starts linking an account at the named provider to the current user. The flow is the same as for
+// signing in, and is finished with FinishOIDCLink.
+func (svc *Service) BeginOIDCLink(ctx context.Context, providerName string) (*OIDCAuthorization, error) {
+	err := requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := authcontext.GetSubject(ctx)
+	if sub == authcontext.Anonymous {
+		return nil, ErrCurrentUserNotFound
+	}
+
+	return svc.BeginOIDCLogin(ctx, providerName)
+}
+
+// FinishOIDCLogin redeems the code from the provider's callback and signs in the user linked to the provider's
+// account, creating one on first sign-in. The provider stands in for the password only: users with two-factor
+// authentication still get a *SecondFactorRequiredError.
+func (svc *Service) FinishOIDCLogin(
+	ctx context.Context,
+	state []byte,
+	callbackState, code string,
+	client ClientInfo,
+) (*Session, error) {
+	provider, claims, err := svc.exchangeOIDCCode(ctx, state, callbackState, code)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	identity, err := svc.identityRepo.Find(ctx, provider.Name, claims.Subject)
+	if err != nil {
+		if _, ok := errors.AsType[*IdentityNotFoundError](err); !ok {
+			return nil, fmt.Errorf("failed to find identity: %w", err)
+		}
+
+		identity, err = svc.registerOIDCUser(ctx, provider, claims, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = svc.identityRepo.UpdateLastLogin(ctx, identity.Provider, identity.Subject, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update identity last login: %w", err)
+	}
+
+	secondFactor, err := svc.hasSecondFactor(ctx, identity.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if secondFactor {
+		return nil, svc.startLoginChallenge(ctx, identity.UserID, now)
+	}
+
+	return svc.createSession(ctx, identity.UserID, client, now)
+}
+
+// FinishOIDCLink redeems the code from the provider's callback and links the provider's account to the current user.
+func (svc *Service) FinishOIDCLink(ctx context.Context, state []byte, callbackState, code string) (*Identity, error) {
+	err := requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := authcontext.GetSubject(ctx)
+	if sub == authcontext.Anonymous {
+		return nil, ErrCurrentUserNotFound
+	}
+
+	provider, claims, err := svc.exchangeOIDCCode(ctx, state, callbackState, code)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := svc.identityRepo.Find(ctx, provider.Name, claims.Subject)
+	if err == nil {
+		if identity.UserID != sub {
+			return nil, &IdentityAlreadyLinkedError{Provider: provider.Name}
+		}
+
+		return identity, nil
+	}
+
+	if _, ok := errors.AsType[*IdentityNotFoundError](err); !ok {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+
+	identities, err := svc.identityRepo.ListByUser(ctx, sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+
+	for _, linked := range identities {
+		if linked.Provider == provider.Name {
+			return nil, &IdentityAlreadyLinkedError{Provider: provider.Name}
+		}
+	}
+
+	identity = newIdentity(provider, claims, sub, time.Now())
+
+	err = svc.identityRepo.Insert(ctx, identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+// ListIdentities returns the provider accounts linked to the current user.
+func (svc *Service) ListIdentities(ctx context.Context) ([]*Identity, error) {
+	sub := authcontext.GetSubject(ctx)
+	if sub == authcontext.Anonymous {
+		return nil, ErrCurrentUserNotFound
+	}
+
+	identities, err := svc.identityRepo.ListByUser(ctx, sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+// UnlinkIdentity removes the current user's account at the named provider. It fails with ErrLastSignInMethod if the
+// user would have no password, passkey or other provider left to sign in with.
+func (svc *Service) UnlinkIdentity(ctx context.Context, providerName string) error {
+	err := requireSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := svc.GetCurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	identities, err := svc.identityRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list identities: %w", err)
+	}
+
+	passkeys, err := svc.passkeyRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list passkeys: %w", err)
+	}
+
+	// GetCurrentUser clears the hash, so the stored user is checked for a password.
+	stored, err := svc.userRepo.Find(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if stored.PasswordHash == "" && len(passkeys) == 0 && len(identities) <= 1 {
+		return ErrLastSignInMethod
+	}
+
+	err = svc.identityRepo.Delete(ctx, user.ID, providerName)
+	if err != nil {
+		return fmt.Errorf("failed to delete identity: %w", err)
+	}
+
+	return nil
+}
+
+// exchangeOIDCCode checks the callback against the stored state, redeems the code with the PKCE verifier and
+// verifies the ID token the provider returns.
+func (svc *Service) exchangeOIDCCode(
+	ctx context.Context,
+	stateJSON []byte,
+	callbackState, code string,
+) (*OIDCProvider, *oidcClaims, error) {
+	var state oidcState
+
+	err := json.Unmarshal(stateJSON, &state)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: malformed state: %w", ErrInvalidOIDCResponse, err)
+	}
+
+	if !time.Now().Before(state.Expires) {
+		return nil, nil, fmt.Errorf("%w: sign-in expired", ErrInvalidOIDCResponse)
+	}
+
+	if state.State == "" || subtle.ConstantTimeCompare([]byte(state.State), []byte(callbackState)) != 1 {
+		return nil, nil, fmt.Errorf("%w: state mismatch", ErrInvalidOIDCResponse)
+	}
+
+	provider, err := svc.oidcProvider(state.Provider)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token, err := provider.oauth2.Exchange(ctx, code, oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidOIDCResponse, err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: no id token", ErrInvalidOIDCResponse)
+	}
+
+	idToken, err := provider.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidOIDCResponse, err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(state.Nonce)) != 1 {
+		return nil, nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidOIDCResponse)
+	}
+
+	var claims oidcClaims
+
+	err = idToken.Claims(&claims)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidOIDCResponse, err)
+	}
+
+	if claims.Subject == "" {
+		return nil, nil, fmt.Errorf("%w: no subject", ErrInvalidOIDCResponse)
+	}
+
+	return provider, &claims, nil
+}
+
+// registerOIDCUser creates a user for a first sign-in at a provider. The account has no password; the provider is
+// how it signs in until the user adds another way.
+func (svc *Service) registerOIDCUser(
+	ctx context.Context,
+	provider *OIDCProvider,
+	claims *oidcClaims,
+	now time.Time,
+) (*Identity, error) {
+	username, err := svc.availableUsername(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &User{
+		ID:           uuid.NewString(),
+		Username:     username,
+		PasswordHash: "",
+		RegisteredAt: now,
+	}
+
+	err = svc.userRepo.Insert(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register user: %w", err)
+	}
+
+	err = svc.authzClient.AddToGroup(ctx, user.ID, authcontext.Authenticated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add user to authenticated group: %w", err)
+	}
+
+	identity := newIdentity(provider, claims, user.ID, now)
+
+	err = svc.identityRepo.Insert(ctx, identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+func newIdentity(provider *OIDCProvider, claims *oidcClaims, userID string, now time.Time) *Identity {
+	email := ""
+	if claims.EmailVerified {
+		email = claims.Email
+	}
+
+	return &Identity{
+		Provider:    provider.Name,
+		Subject:     claims.Subject,
+		UserID:      userID,
+		Email:       email,
+		CreatedAt:   now,
+		LastLoginAt: nil,
+	}
+}
+
+var usernameDisallowedChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// maxUsernameAttempts bounds how many numbered variants of a taken username are tried.
+const maxUsernameAttempts = 10
+
+// availableUsername picks a free username for a new account, based on what the provider knows about the user. It
+// falls back to numbered variants, and to a random name if nothing fits the registration rules.
+func (svc *Service) availableUsername(ctx context.Context, claims *oidcClaims) (string, error) {
+	emailName, _, _ := strings.Cut(claims.Email, "@")
+
+	base := ""
+
+	for _, candidate := range []string{claims.PreferredUsername, emailName, claims.Name} {
+		candidate = usernameDisallowedChars.ReplaceAllString(candidate, "-")
+
+		// Leave room for a number, in case the name is taken.
+		if maxLength := svc.credentials.UsernameMaxLength - 3; maxLength > 0 && len(candidate) > maxLength {
+			candidate = candidate[:maxLength]
+		}
+
+		candidate = strings.Trim(candidate, "_.-")
+		if svc.credentials.usernameAllowed(candidate) {
+			base = candidate
+
+			break
+		}
+	}
+
+	if base == "" {
+		base = "user"
+	}
+
+	for attempt := range maxUsernameAttempts {
+		username := base
+		if attempt > 0 {
+			username = fmt.Sprintf("%s-%d", base, attempt+1)
+		}
+
+		if !svc.credentials.usernameAllowed(username) {
+			continue
+		}
+
+		_, err := svc.userRepo.FindByUsername(ctx, username)
+		if err != nil {
+			if _, ok := errors.AsType[*UserByUsernameNotFoundError](err); ok {
+				return username, nil
+			}
+
+			return "", fmt.Errorf("failed to check if username already exists: %w", err)
+		}
+	}
+
+	return "user-" + strings.ToLower(rand.Text()[:8]), nil
+}
+
+// usernameAllowed reports whether a username follows the policy.
+func (policy CredentialsPolicy) usernameAllowed(username string) bool {
+	var validationErr ValidationError
+
+	policy.validateUsername(&validationErr, username)
+
+	return len(validationErr.Fields) == 0
+}
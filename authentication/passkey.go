@@ -0,0 +1,40 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Passkey is a WebAuthn credential a user can sign in with instead of a password. ID is the credential ID in
+// unpadded base64url, the same form browsers use.
+type Passkey struct {
+	ID              string
+	UserID          string
+	Name            string
+	PublicKey       []byte
+	AttestationType string
+	Transports      []string
+	AAGUID          []byte
+	SignCount       uint32
+	BackupEligible  bool
+	BackupState     bool
+	CreatedAt       time.Time
+	LastUsedAt      *time.Time
+}
+
+type PasskeyRepository interface {
+	Insert(ctx context.Context, passkey *Passkey) (err error)
+	Find(ctx context.Context, id string) (passkey *Passkey, err error)
+	ListByUser(ctx context.Context, userID string) (passkeys []*Passkey, err error)
+	UpdateUsage(ctx context.Context, id string, signCount uint32, backupState bool, usedAt time.Time) (err error)
+	Delete(ctx context.Context, userID, id string) (err error)
+}
+
+type PasskeyNotFoundError struct {
+	ID string
+}
+
+func (err PasskeyNotFoundError) Error() string {
+	return fmt.Sprintf("passkey with id %q not found", err.ID)
+}
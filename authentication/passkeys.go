@@ -0,0 +1,334 @@
+package authentication
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+)
+
+var ErrInvalidPasskey = errors.New("invalid passkey")
+
+const defaultPasskeyName = "Passkey"
+
+// PasskeyCeremony is the first half of a WebAuthn ceremony. Options is the JSON for the browser's
+// navigator.credentials.create() or get() call, and State has to be handed back together with the browser's answer.
+// State carries the challenge, so it must be kept where the client can't change it, such as a signed cookie.
+type PasskeyCeremony struct {
+	Options []byte
+	State   []byte
+}
+
+// BeginPasskeyRegistration starts adding a passkey to the current user's account. Passkeys the user already has are
+// excluded, so an authenticator isn't registered twice.
+func (svc *Service) BeginPasskeyRegistration(ctx context.Context) (*PasskeyCeremony, error) {
+	err := requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := svc.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := svc.passkeyRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+
+	webAuthnUser := newWebAuthnUser(user, passkeys)
+
+	creation, session, err := svc.webAuthn.BeginRegistration(
+		webAuthnUser,
+		webauthn.WithExclusions(webauthn.Credentials(webAuthnUser.WebAuthnCredentials()).CredentialDescriptors()),
+		// Passkeys replace the password, so the authenticator has to be discoverable and verify the user itself.
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			UserVerification:   protocol.VerificationRequired,
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin passkey registration: %w", err)
+	}
+
+	return newPasskeyCeremony(creation, session)
+}
+
+// FinishPasskeyRegistration verifies the browser's answer to BeginPasskeyRegistration and stores the new passkey.
+func (svc *Service) FinishPasskeyRegistration(
+	ctx context.Context,
+	name string,
+	state, response []byte,
+) (*Passkey, error) {
+	err := requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := svc.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := parsePasskeyState(state)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedResponse, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+	}
+
+	passkeys, err := svc.passkeyRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+
+	credential, err := svc.webAuthn.CreateCredential(newWebAuthnUser(user, passkeys), *session, parsedResponse)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = defaultPasskeyName
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	passkey := &Passkey{
+		ID:              base64.RawURLEncoding.EncodeToString(credential.ID),
+		UserID:          user.ID,
+		Name:            name,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		CreatedAt:       time.Now(),
+		LastUsedAt:      nil,
+	}
+
+	err = svc.passkeyRepo.Insert(ctx, passkey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert passkey: %w", err)
+	}
+
+	return passkey, nil
+}
+
+// BeginPasskeyLogin starts a passwordless sign-in. No username is needed: the browser offers the passkeys it has for
+// this site and the chosen one identifies the user.
+func (svc *Service) BeginPasskeyLogin(_ context.Context) (*PasskeyCeremony, error) {
+	assertion, session, err := svc.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin passkey login: %w", err)
+	}
+
+	return newPasskeyCeremony(assertion, session)
+}
+
+// FinishPasskeyLogin verifies the browser's answer to BeginPasskeyLogin and issues a session. The passkey already
+// proves possession and user verification, so no second factor is asked for.
+func (svc *Service) FinishPasskeyLogin(
+	ctx context.Context,
+	state, response []byte,
+	client ClientInfo,
+) (*Session, error) {
+	session, err := parsePasskeyState(state)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if !session.Expires.IsZero() && !now.Before(session.Expires) {
+		return nil, fmt.Errorf("%w: ceremony expired", ErrInvalidPasskey)
+	}
+
+	parsedResponse, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+	}
+
+	var passkey *Passkey
+
+	findUser := func(rawID, userHandle []byte) (webauthn.User, error) {
+		found, err := svc.passkeyRepo.Find(ctx, base64.RawURLEncoding.EncodeToString(rawID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find passkey: %w", err)
+		}
+
+		if found.UserID != string(userHandle) {
+			return nil, &PasskeyNotFoundError{ID: found.ID}
+		}
+
+		user, err := svc.userRepo.Find(ctx, found.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find user: %w", err)
+		}
+
+		passkey = found
+
+		return newWebAuthnUser(user, []*Passkey{found}), nil
+	}
+
+	_, credential, err := svc.webAuthn.ValidatePasskeyLogin(findUser, *session, parsedResponse)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+	}
+
+	// A counter that didn't move forward means the key may have been copied off the authenticator.
+	if credential.Authenticator.CloneWarning {
+		return nil, fmt.Errorf("%w: signature counter did not increase", ErrInvalidPasskey)
+	}
+
+	err = svc.passkeyRepo.UpdateUsage(
+		ctx,
+		passkey.ID,
+		credential.Authenticator.SignCount,
+		credential.Flags.BackupState,
+		now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update passkey usage: %w", err)
+	}
+
+	return svc.createSession(ctx, passkey.UserID, client, now)
+}
+
+// ListPasskeys returns the current user's passkeys, oldest first.
+func (svc *Service) ListPasskeys(ctx context.Context) ([]*Passkey, error) {
+	sub := authcontext.GetSubject(ctx)
+	if sub == authcontext.Anonymous {
+		return nil, ErrCurrentUserNotFound
+	}
+
+	passkeys, err := svc.passkeyRepo.ListByUser(ctx, sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+
+	return passkeys, nil
+}
+
+// DeletePasskey removes one of the current user's passkeys.
+func (svc *Service) DeletePasskey(ctx context.Context, passkeyID string) error {
+	err := requireSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	sub := authcontext.GetSubject(ctx)
+	if sub == authcontext.Anonymous {
+		return ErrCurrentUserNotFound
+	}
+
+	err = svc.passkeyRepo.Delete(ctx, sub, passkeyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete passkey: %w", err)
+	}
+
+	return nil
+}
+
+func newPasskeyCeremony(options any, session *webauthn.SessionData) (*PasskeyCeremony, error) {
+	optionsJSON, err := json.Marshal(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal passkey options: %w", err)
+	}
+
+	state, err := json.Marshal(session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal passkey state: %w", err)
+	}
+
+	return &PasskeyCeremony{Options: optionsJSON, State: state}, nil
+}
+
+func parsePasskeyState(state []byte) (*webauthn.SessionData, error) {
+	var session webauthn.SessionData
+
+	err := json.Unmarshal(state, &session)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed ceremony state: %w", ErrInvalidPasskey, err)
+	}
+
+	return &session, nil
+}
+
+// webAuthnUser adapts a user and their passkeys to what the webauthn package expects.
+type webAuthnUser struct {
+	user     *User
+	passkeys []*Passkey
+}
+
+var _ webauthn.User = (*webAuthnUser)(nil)
+
+func newWebAuthnUser(user *User, passkeys []*Passkey) *webAuthnUser {
+	return &webAuthnUser{user: user, passkeys: passkeys}
+}
+
+// WebAuthnID is the user handle stored on the authenticator. User IDs are random UUIDs, so they reveal nothing about
+// the account.
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return []byte(u.user.ID)
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Username
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.passkeys))
+
+	for _, passkey := range u.passkeys {
+		id, err := base64.RawURLEncoding.DecodeString(passkey.ID)
+		if err != nil {
+			continue
+		}
+
+		transports := make([]protocol.AuthenticatorTransport, 0, len(passkey.Transports))
+		for _, transport := range passkey.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              id,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.AAGUID,
+				SignCount: passkey.SignCount,
+			},
+		})
+	}
+
+	return credentials
+}
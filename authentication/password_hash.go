@@ -0,0 +1,210 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownPasswordHash is returned for stored hashes in a format none of the supported algorithms produce.
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// PasswordHasher hashes new passwords with one algorithm and its current parameters. Verify detects the algorithm
+// from the prefix of the stored hash, so hashes made under an earlier policy keep working until they are replaced.
+type PasswordHasher interface {
+	Hash(password string) (hash string, err error)
+	// Verify reports whether the password matches the hash, whichever supported algorithm made it.
+	Verify(hash, password string) (ok bool, err error)
+	// NeedsRehash reports whether the hash was made by another algorithm or with weaker parameters than the hasher
+	// uses now.
+	NeedsRehash(hash string) bool
+}
+
+// verifyPasswordHash checks a password against a hash made by any supported algorithm.
+func verifyPasswordHash(hash, password string) (bool, error) {
+	switch {
+	case isBcryptHash(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, nil
+			}
+
+			return false, fmt.Errorf("failed to compare bcrypt hash: %w", err)
+		}
+
+		return true, nil
+	case strings.HasPrefix(hash, argon2idPrefix):
+		params, salt, key, err := parseArgon2idHash(hash)
+		if err != nil {
+			return false, err
+		}
+
+		keyLength := uint32(len(key)) //nolint:gosec // Decoded from a short string.
+		derived := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, keyLength)
+
+		return subtle.ConstantTimeCompare(derived, key) == 1, nil
+	default:
+		return false, ErrUnknownPasswordHash
+	}
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// DefaultPasswordHasher is bcrypt at its default cost, which is what every password was hashed with before the
+// algorithm became configurable.
+func DefaultPasswordHasher() BcryptHasher {
+	return BcryptHasher{Cost: bcrypt.DefaultCost}
+}
+
+// BcryptHasher hashes passwords with bcrypt at the given cost.
+type BcryptHasher struct {
+	Cost int
+}
+
+var _ PasswordHasher = BcryptHasher{}
+
+func NewBcryptHasher(cost int) (BcryptHasher, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return BcryptHasher{}, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return BcryptHasher{Cost: cost}, nil
+}
+
+func (hasher BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), hasher.Cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate bcrypt hash: %w", err)
+	}
+
+	return string(hash), nil
+}
+
+func (hasher BcryptHasher) Verify(hash, password string) (bool, error) {
+	return verifyPasswordHash(hash, password)
+}
+
+func (hasher BcryptHasher) NeedsRehash(hash string) bool {
+	if !isBcryptHash(hash) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+
+	return err != nil || cost < hasher.Cost
+}
+
+const argon2idPrefix = "$argon2id$"
+
+// Argon2idHasher hashes passwords with Argon2id. Hashes are stored in the PHC string format, as in
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>, which records the parameters next to the salt and key.
+type Argon2idHasher struct {
+	// Memory is in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+var _ PasswordHasher = Argon2idHasher{}
+
+// DefaultArgon2idHasher uses the second recommended option of RFC 9106, for systems with less memory to spare than
+// the 2 GiB of the first.
+func DefaultArgon2idHasher() Argon2idHasher {
+	return Argon2idHasher{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 4,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+func (hasher Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, hasher.SaltLength)
+	_, _ = rand.Read(salt)
+
+	key := argon2.IDKey([]byte(password), salt, hasher.Iterations, hasher.Memory, hasher.Parallelism, hasher.KeyLength)
+
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		hasher.Memory,
+		hasher.Iterations,
+		hasher.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (hasher Argon2idHasher) Verify(hash, password string) (bool, error) {
+	return verifyPasswordHash(hash, password)
+}
+
+func (hasher Argon2idHasher) NeedsRehash(hash string) bool {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		return true
+	}
+
+	params, salt, key, err := parseArgon2idHash(hash)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory < hasher.Memory ||
+		params.Iterations < hasher.Iterations ||
+		params.Parallelism < hasher.Parallelism ||
+		uint32(len(salt)) < hasher.SaltLength || //nolint:gosec // Decoded from a short string.
+		uint32(len(key)) < hasher.KeyLength //nolint:gosec // Decoded from a short string.
+}
+
+type argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+func parseArgon2idHash(hash string) (argon2idParams, []byte, []byte, error) {
+	var params argon2idParams
+
+	// The hash starts with "$", so the first part is empty.
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, fmt.Errorf("%w: expected 6 parts in argon2id hash", ErrUnknownPasswordHash)
+	}
+
+	var version int
+
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: unsupported argon2id version", ErrUnknownPasswordHash)
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil || params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, fmt.Errorf("%w: invalid argon2id parameters", ErrUnknownPasswordHash)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("%w: invalid argon2id salt", ErrUnknownPasswordHash)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("%w: invalid argon2id key", ErrUnknownPasswordHash)
+	}
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,99 @@
+package authentication_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHashers(t *testing.T) {
+	t.Parallel()
+
+	// Parameters are kept low so the tests stay fast.
+	argon2id := authentication.Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	bcryptHasher := authentication.BcryptHasher{Cost: bcrypt.MinCost}
+
+	tests := []struct {
+		name   string
+		hasher authentication.PasswordHasher
+		prefix string
+	}{
+		{name: "bcrypt", hasher: bcryptHasher, prefix: "$2a$04$"},
+		{name: "argon2id", hasher: argon2id, prefix: "$argon2id$v=19$m=64,t=1,p=1$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			hash, err := tt.hasher.Hash("correct horse battery")
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(hash, tt.prefix), hash)
+			assert.False(t, tt.hasher.NeedsRehash(hash))
+
+			ok, err := tt.hasher.Verify(hash, "correct horse battery")
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = tt.hasher.Verify(hash, "wrong horse battery")
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			// Salts differ, so the same password never hashes the same way twice.
+			again, err := tt.hasher.Hash("correct horse battery")
+			require.NoError(t, err)
+			assert.NotEqual(t, hash, again)
+
+			// Hashes of the other algorithm are verified too, and marked for rehashing.
+			for _, other := range tests {
+				otherHash, err := other.hasher.Hash("correct horse battery")
+				require.NoError(t, err)
+
+				ok, err := tt.hasher.Verify(otherHash, "correct horse battery")
+				require.NoError(t, err)
+				assert.True(t, ok, other.name)
+				assert.Equal(t, other.name != tt.name, tt.hasher.NeedsRehash(otherHash), other.name)
+			}
+
+			_, err = tt.hasher.Verify("plain-text-password", "plain-text-password")
+			require.ErrorIs(t, err, authentication.ErrUnknownPasswordHash)
+
+			_, err = tt.hasher.Verify("$argon2id$v=19$m=64,t=1$c2FsdA$a2V5", "correct horse battery")
+			require.ErrorIs(t, err, authentication.ErrUnknownPasswordHash)
+		})
+	}
+}
+
+func TestPasswordHasherNeedsRehash(t *testing.T) {
+	t.Parallel()
+
+	weakBcrypt, err := authentication.BcryptHasher{Cost: bcrypt.MinCost}.Hash("correct horse battery")
+	require.NoError(t, err)
+
+	assert.True(t, authentication.BcryptHasher{Cost: bcrypt.MinCost + 1}.NeedsRehash(weakBcrypt))
+	assert.False(t, authentication.BcryptHasher{Cost: bcrypt.MinCost - 1}.NeedsRehash(weakBcrypt))
+
+	weak := authentication.Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+	weakArgon2id, err := weak.Hash("correct horse battery")
+	require.NoError(t, err)
+
+	stronger := []authentication.Argon2idHasher{
+		{Memory: 128, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		{Memory: 64, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		{Memory: 64, Iterations: 1, Parallelism: 2, SaltLength: 16, KeyLength: 32},
+		{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 32, KeyLength: 32},
+		{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 64},
+	}
+
+	for _, hasher := range stronger {
+		assert.True(t, hasher.NeedsRehash(weakArgon2id), hasher)
+	}
+
+	_, err = authentication.NewBcryptHasher(bcrypt.MaxCost + 1)
+	require.Error(t, err)
+}
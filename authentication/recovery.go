@@ -0,0 +1,363 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nasermirzaei89/scribble/mail"
+)
+
+// emailMaxLength is the longest address SMTP can deliver to.
+const emailMaxLength = 254
+
+var ErrEmailNotSet = errors.New("email not set")
+
+// EmailAlreadyInUseError is returned when verifying an address that another account has already verified. It is
+// only reported to whoever holds the verification token, so it doesn't reveal which addresses are registered.
+type EmailAlreadyInUseError struct {
+	Email string
+}
+
+func (err EmailAlreadyInUseError) Error() string {
+	return fmt.Sprintf("email %q is already in use", err.Email)
+}
+
+// AccountRecoveryPolicy controls the links emailed for password resets and email verification. The links carry a
+// token signed with TokenKey, so changing the key invalidates every link sent before.
+type AccountRecoveryPolicy struct {
+	TokenKey []byte
+	SiteName string
+	// PasswordResetURL and EmailVerificationURL are the pages the links open, with the token added as a query
+	// parameter.
+	PasswordResetURL     string
+	EmailVerificationURL string
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+}
+
+func DefaultAccountRecoveryPolicy() AccountRecoveryPolicy {
+	return AccountRecoveryPolicy{
+		TokenKey:             nil,
+		SiteName:             "Scribble",
+		PasswordResetURL:     "http://localhost:8080/reset-password",
+		EmailVerificationURL: "http://localhost:8080/verify-email",
+		PasswordResetTTL:     time.Hour,
+		EmailVerificationTTL: 48 * time.Hour,
+	}
+}
+
+func (policy AccountRecoveryPolicy) link(base, token string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse link url: %w", err)
+	}
+
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func validateEmail(email string) error {
+	var validationErr ValidationError
+
+	addr, err := netmail.ParseAddress(email)
+
+	switch {
+	case email == "":
+		validationErr.add(FieldEmail, "Email is required.")
+	case len(email) > emailMaxLength:
+		validationErr.add(FieldEmail, fmt.Sprintf("Email must be at most %d characters.", emailMaxLength))
+	case err != nil || addr.Address != email:
+		// Display names and comments parse as addresses too, but only a bare address is accepted.
+		validationErr.add(FieldEmail, "Email is not a valid address.")
+	}
+
+	if len(validationErr.Fields) > 0 {
+		return &validationErr
+	}
+
+	return nil
+}
+
+// SetEmail replaces the current user's email and sends a verification link to it. The address is unverified, and
+// can't be used to reset the password, until the link is followed.
+func (svc *Service) SetEmail(ctx context.Context, email string) error {
+	err := requireSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := svc.GetCurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	err = validateEmail(email)
+	if err != nil {
+		return err
+	}
+
+	if email == user.Email && user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	err = svc.userRepo.SetEmail(ctx, user.ID, email)
+	if err != nil {
+		return fmt.Errorf("failed to set email: %w", err)
+	}
+
+	user.Email = email
+
+	return svc.sendEmailVerification(ctx, user)
+}
+
+// ResendEmailVerification sends a new verification link to the current user's unverified email.
+func (svc *Service) ResendEmailVerification(ctx context.Context) error {
+	err := requireSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := svc.GetCurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if user.Email == "" {
+		return ErrEmailNotSet
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	return svc.sendEmailVerification(ctx, user)
+}
+
+func (svc *Service) sendEmailVerification(ctx context.Context, user *User) error {
+	token, err := signAccountToken(
+		svc.accountRecovery.TokenKey,
+		tokenPurposeEmailVerification,
+		user.ID,
+		user.Email,
+		svc.accountRecovery.EmailVerificationTTL,
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to sign email verification token: %w", err)
+	}
+
+	link, err := svc.accountRecovery.link(svc.accountRecovery.EmailVerificationURL, token)
+	if err != nil {
+		return err
+	}
+
+	err = svc.mailer.Send(ctx, &mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email for " + svc.accountRecovery.SiteName,
+		Text: fmt.Sprintf(
+			"Hi %s,\n\nFollow this link to verify your email address:\n\n%s\n\n"+
+				"The link expires in %s. If you didn't add this address to your account, you can ignore this "+
+				"email.\n",
+			user.Username,
+			link,
+			svc.accountRecovery.EmailVerificationTTL,
+		),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send email verification: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the email a verification token was sent to as verified. The token doesn't need the user to be
+// logged in, since the link may be opened in another browser, and stops working once the email changes.
+func (svc *Service) VerifyEmail(ctx context.Context, token string) error {
+	userID, err := parseAccountToken(token, tokenPurposeEmailVerification, time.Now())
+	if err != nil {
+		return err
+	}
+
+	user, err := svc.userRepo.Find(ctx, userID)
+	if err != nil {
+		if _, ok := errors.AsType[*UserNotFoundError](err); ok {
+			return ErrInvalidToken
+		}
+
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user.Email == "" {
+		return ErrInvalidToken
+	}
+
+	err = verifyAccountToken(svc.accountRecovery.TokenKey, token, user.Email)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	owner, err := svc.userRepo.FindByEmail(ctx, user.Email)
+
+	switch {
+	case err == nil && owner.ID != user.ID:
+		return &EmailAlreadyInUseError{Email: user.Email}
+	case err != nil:
+		if _, ok := errors.AsType[*UserByEmailNotFoundError](err); !ok {
+			return fmt.Errorf("failed to find user by email: %w", err)
+		}
+	}
+
+	err = svc.userRepo.MarkEmailVerified(ctx, user.ID, user.Email, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+
+	return nil
+}
+
+// RequestPasswordReset emails a password reset link to the account with the given verified email. Unknown
+// addresses are ignored without an error, so the result doesn't reveal which addresses are registered.
+func (svc *Service) RequestPasswordReset(ctx context.Context, email string) error {
+	email = strings.TrimSpace(email)
+	if validateEmail(strings.ToLower(email)) != nil {
+		return nil
+	}
+
+	user, err := svc.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if _, ok := errors.AsType[*UserByEmailNotFoundError](err); ok {
+			slog.DebugContext(ctx, "password reset requested for unknown email")
+
+			return nil
+		}
+
+		return fmt.Errorf("failed to find user by email: %w", err)
+	}
+
+	token, err := signAccountToken(
+		svc.accountRecovery.TokenKey,
+		tokenPurposePasswordReset,
+		user.ID,
+		user.PasswordHash,
+		svc.accountRecovery.PasswordResetTTL,
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to sign password reset token: %w", err)
+	}
+
+	link, err := svc.accountRecovery.link(svc.accountRecovery.PasswordResetURL, token)
+	if err != nil {
+		return err
+	}
+
+	err = svc.mailer.Send(ctx, &mail.Message{
+		To:      user.Email,
+		Subject: "Reset your " + svc.accountRecovery.SiteName + " password",
+		Text: fmt.Sprintf(
+			"Hi %s,\n\nFollow this link to choose a new password:\n\n%s\n\n"+
+				"The link expires in %s and works once. If you didn't ask for a reset, you can ignore this email; "+
+				"your password stays the same.\n",
+			user.Username,
+			link,
+			svc.accountRecovery.PasswordResetTTL,
+		),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send password reset: %w", err)
+	}
+
+	return nil
+}
+
+// CheckPasswordResetToken returns the user a password reset token is for, or ErrInvalidToken if it can't be used.
+func (svc *Service) CheckPasswordResetToken(ctx context.Context, token string) (*User, error) {
+	userID, err := parseAccountToken(token, tokenPurposePasswordReset, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := svc.userRepo.Find(ctx, userID)
+	if err != nil {
+		if _, ok := errors.AsType[*UserNotFoundError](err); ok {
+			return nil, ErrInvalidToken
+		}
+
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	err = verifyAccountToken(svc.accountRecovery.TokenKey, token, user.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+
+	user.PasswordHash = ""
+
+	return user, nil
+}
+
+// ResetPassword sets a new password with a password reset token. The token stops working once the password
+// changes, and every session, API token and pending second-factor login of the user is ended, so whoever knew the old
+// password is logged out.
+func (svc *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	user, err := svc.CheckPasswordResetToken(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	var validationErr ValidationError
+
+	svc.credentials.validatePassword(&validationErr, user.Username, newPassword)
+
+	if len(validationErr.Fields) > 0 {
+		return &validationErr
+	}
+
+	passwordHash, err := svc.passwordHasher.Hash(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	err = svc.userRepo.UpdatePasswordHash(ctx, user.ID, passwordHash)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	_, err = svc.sessionRepo.DeleteByUserExcept(ctx, user.ID, "")
+	if err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	err = svc.apiTokenRepo.DeleteByUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete api tokens: %w", err)
+	}
+
+	err = svc.loginChallengeRepo.DeleteByUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete login challenges: %w", err)
+	}
+
+	// Whoever failed to log in before is now allowed to try the new password right away.
+	err = svc.loginAttemptRepo.Delete(ctx, LoginAttemptScopeUsername, CanonicalUsername(user.Username))
+	if err != nil {
+		return fmt.Errorf("failed to reset failed logins: %w", err)
+	}
+
+	return nil
+}
@@ -2,21 +2,35 @@ package authentication
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 )
 
 type Session struct {
-	ID        string
-	UserID    string
-	CreatedAt time.Time
-	ExpiresAt time.Time
+	ID         string
+	UserID     string
+	UserAgent  string
+	IPAddress  string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastSeenAt time.Time
+}
+
+// ClientInfo describes the client a session is used from.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
 }
 
 type SessionRepository interface {
 	Insert(ctx context.Context, session *Session) (err error)
 	Find(ctx context.Context, id string) (session *Session, err error)
+	ListByUser(ctx context.Context, userID string) (sessions []*Session, err error)
+	Touch(ctx context.Context, id string, lastSeenAt time.Time, ipAddress string) (err error)
 	Delete(ctx context.Context, id string) (err error)
+	DeleteByUserExcept(ctx context.Context, userID, keepSessionID string) (deleted int, err error)
+	DeleteExpired(ctx context.Context, before time.Time) (deleted int, err error)
 }
 
 type SessionNotFoundError struct {
@@ -34,3 +48,5 @@ type SessionExpiredError struct {
 func (err SessionExpiredError) Error() string {
 	return fmt.Sprintf("session with id %q has expired", err.ID)
 }
+
+var ErrCurrentSessionNotFound = errors.New("current session not found")
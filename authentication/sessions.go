@@ -0,0 +1,115 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+)
+
+// sessionLastSeenResolution bounds how often a session's last-seen time is written.
+const sessionLastSeenResolution = time.Minute
+
+// TouchSession records that the session was just used from the given client. Writes are skipped while the stored
+// time is recent enough and the address is unchanged.
+func (svc *Service) TouchSession(ctx context.Context, session *Session, client ClientInfo) error {
+	now := time.Now()
+
+	if now.Sub(session.LastSeenAt) < sessionLastSeenResolution && session.IPAddress == client.IPAddress {
+		return nil
+	}
+
+	err := svc.sessionRepo.Touch(ctx, session.ID, now, client.IPAddress)
+	if err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+
+	session.LastSeenAt = now
+	session.IPAddress = client.IPAddress
+
+	return nil
+}
+
+// ListSessions returns the user's sessions, most recently seen first. Users may list their own sessions; listing
+// anyone else's is up to the authorization policy.
+func (svc *Service) ListSessions(ctx context.Context, userID string) ([]*Session, error) {
+	if authcontext.GetSubject(ctx) != userID {
+		err := svc.authzClient.CheckAccess(ctx, ServiceName, userID, ActionListSessions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check authorization: %w", err)
+		}
+	}
+
+	sessions, err := svc.sessionRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession ends a session. Users may revoke their own sessions; revoking anyone else's is up to the
+// authorization policy.
+func (svc *Service) RevokeSession(ctx context.Context, sessionID string) error {
+	err := requireSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	session, err := svc.sessionRepo.Find(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to find session: %w", err)
+	}
+
+	if authcontext.GetSubject(ctx) != session.UserID {
+		err = svc.authzClient.CheckAccess(ctx, ServiceName, session.UserID, ActionRevokeSession)
+		if err != nil {
+			return fmt.Errorf("failed to check authorization: %w", err)
+		}
+	}
+
+	err = svc.sessionRepo.Delete(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeAllOtherSessions ends every session of the current user except the one the request came with, and returns
+// how many were ended.
+func (svc *Service) RevokeAllOtherSessions(ctx context.Context) (int, error) {
+	err := requireSession(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sub := authcontext.GetSubject(ctx)
+	if sub == authcontext.Anonymous {
+		return 0, ErrCurrentUserNotFound
+	}
+
+	currentSessionID, ok := authcontext.SessionIDFromContext(ctx)
+	if !ok {
+		return 0, ErrCurrentSessionNotFound
+	}
+
+	deleted, err := svc.sessionRepo.DeleteByUserExcept(ctx, sub, currentSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete other sessions: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// DeleteExpiredSessions removes every session that expired before now. It is meant for background maintenance and
+// performs no authorization check.
+func (svc *Service) DeleteExpiredSessions(ctx context.Context) (int, error) {
+	deleted, err := svc.sessionRepo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+
+	return deleted, nil
+}
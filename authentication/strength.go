@@ -0,0 +1,219 @@
+package authentication
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+//go:embed common-passwords.txt
+var commonPasswordsContent string
+
+// commonPasswordRanks maps each common password to its position in the list, most common first.
+var commonPasswordRanks = func() map[string]int {
+	ranks := make(map[string]int)
+
+	for i, line := range strings.Split(commonPasswordsContent, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if _, ok := ranks[line]; !ok {
+			ranks[line] = i + 1
+		}
+	}
+
+	return ranks
+}()
+
+// IsCommonPassword reports whether the password is on the embedded list of common passwords, ignoring case.
+func IsCommonPassword(password string) bool {
+	_, ok := commonPasswordRanks[strings.ToLower(password)]
+
+	return ok
+}
+
+const (
+	// strengthMaxRunes bounds the work done by PasswordStrength. Anything longer already scores the maximum.
+	strengthMaxRunes = 64
+	// minPatternLength is the shortest run treated as a pattern rather than as independent characters.
+	minPatternLength = 3
+)
+
+var (
+	keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+	leetReplacer = strings.NewReplacer("@", "a", "4", "a", "3", "e", "1", "i", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t")
+)
+
+// PasswordStrength estimates how hard a password is to guess, in the spirit of zxcvbn. It splits the password into
+// the cheapest sequence of common words, the user's own inputs, repeats, sequences, keyboard runs and single
+// characters, multiplies their guess counts and maps the total onto a score from 0 (trivial) to 4 (strong).
+// userInputs, such as the username, are treated as the first words an attacker would try.
+func PasswordStrength(password string, userInputs ...string) int {
+	runes := []rune(password)
+	if len(runes) > strengthMaxRunes {
+		return 4
+	}
+
+	words := make(map[string]int, len(userInputs))
+
+	for _, input := range userInputs {
+		input = strings.ToLower(input)
+		if len([]rune(input)) >= minPatternLength {
+			words[input] = 1
+		}
+	}
+
+	// best[i] is the lowest log10 guess count for the first i runes.
+	best := make([]float64, len(runes)+1)
+
+	for end := 1; end <= len(runes); end++ {
+		best[end] = best[end-1] + math.Log10(float64(bruteforceCardinality(runes[end-1])))
+
+		for start := 0; start <= end-minPatternLength; start++ {
+			guesses, ok := patternGuesses(runes[start:end], words)
+			if ok {
+				best[end] = math.Min(best[end], best[start]+math.Log10(guesses))
+			}
+		}
+	}
+
+	return scoreFromLog10Guesses(best[len(runes)])
+}
+
+func scoreFromLog10Guesses(log10Guesses float64) int {
+	switch {
+	case log10Guesses < 3:
+		return 0
+	case log10Guesses < 6:
+		return 1
+	case log10Guesses < 8:
+		return 2
+	case log10Guesses < 10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func bruteforceCardinality(r rune) int {
+	switch {
+	case unicode.IsDigit(r):
+		return 10
+	case unicode.IsLower(r), unicode.IsUpper(r):
+		return 26
+	case r < unicode.MaxASCII:
+		return 33
+	default:
+		return 100
+	}
+}
+
+// patternGuesses returns the guess count for a chunk matching a known pattern, or false when it matches none.
+func patternGuesses(chunk []rune, words map[string]int) (float64, bool) {
+	guesses := math.Inf(1)
+
+	if rank, ok := wordRank(string(chunk), words); ok {
+		guesses = math.Min(guesses, float64(rank)*caseVariations(chunk))
+	}
+
+	if isRepeat(chunk) {
+		guesses = math.Min(guesses, float64(bruteforceCardinality(chunk[0])*len(chunk)))
+	}
+
+	if isSequence(chunk) {
+		guesses = math.Min(guesses, float64(bruteforceCardinality(chunk[0])*len(chunk)*2))
+	}
+
+	if isKeyboardRun(chunk) {
+		guesses = math.Min(guesses, float64(len(keyboardRows)*10*len(chunk)*2))
+	}
+
+	return guesses, !math.IsInf(guesses, 1)
+}
+
+func wordRank(chunk string, words map[string]int) (int, bool) {
+	lower := strings.ToLower(chunk)
+
+	for _, candidate := range []string{lower, leetReplacer.Replace(lower)} {
+		if rank, ok := words[candidate]; ok {
+			return rank, true
+		}
+
+		if rank, ok := commonPasswordRanks[candidate]; ok {
+			return rank, true
+		}
+	}
+
+	return 0, false
+}
+
+// caseVariations charges for capitalization beyond the all-lowercase and capitalized-first-letter forms.
+func caseVariations(chunk []rune) float64 {
+	upper := 0
+
+	for _, r := range chunk {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+
+	switch {
+	case upper == 0:
+		return 1
+	case upper == 1 && unicode.IsUpper(chunk[0]), upper == len(chunk):
+		return 2
+	default:
+		return math.Pow(2, float64(upper))
+	}
+}
+
+func isRepeat(chunk []rune) bool {
+	for _, r := range chunk[1:] {
+		if r != chunk[0] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isSequence matches runs like "abcd", "4321" or "ACEG" that step by a constant small delta.
+func isSequence(chunk []rune) bool {
+	delta := chunk[1] - chunk[0]
+	if delta == 0 || delta > 2 || delta < -2 {
+		return false
+	}
+
+	for i := 2; i < len(chunk); i++ {
+		if chunk[i]-chunk[i-1] != delta {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isKeyboardRun(chunk []rune) bool {
+	lower := strings.ToLower(string(chunk))
+
+	for _, row := range keyboardRows {
+		if strings.Contains(row, lower) || strings.Contains(reverse(row), lower) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+
+	return string(runes)
+}
@@ -0,0 +1,142 @@
+package authentication
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TOTPIssuer names the site in authenticator apps.
+const TOTPIssuer = "Scribble"
+
+// TOTPCredential is a user's TOTP secret. It only protects logins once confirmed, which proves the user's
+// authenticator app produces matching codes.
+type TOTPCredential struct {
+	UserID    string
+	Secret    string
+	CreatedAt time.Time
+	// ConfirmedAt is nil while enrollment is pending.
+	ConfirmedAt *time.Time
+	// LastUsedCounter is the time step of the last accepted code, so the same code can't be used twice.
+	LastUsedCounter int64
+}
+
+func (credential *TOTPCredential) IsConfirmed() bool {
+	return credential.ConfirmedAt != nil
+}
+
+type TOTPCredentialRepository interface {
+	// Upsert stores a credential, replacing any existing one for the user.
+	Upsert(ctx context.Context, credential *TOTPCredential) (err error)
+	Find(ctx context.Context, userID string) (credential *TOTPCredential, err error)
+	Confirm(ctx context.Context, userID string, confirmedAt time.Time, counter int64) (err error)
+	// UseCounter records an accepted time step. It fails with TOTPCodeReusedError unless counter is newer than the
+	// last one used, which also settles concurrent logins with the same code.
+	UseCounter(ctx context.Context, userID string, counter int64) (err error)
+	Delete(ctx context.Context, userID string) (err error)
+}
+
+type RecoveryCodeRepository interface {
+	// Replace drops the user's recovery codes and stores the given hashes instead.
+	Replace(ctx context.Context, userID string, codeHashes []string) (err error)
+	// Use marks an unused code as used, failing with RecoveryCodeNotFoundError if there is none.
+	Use(ctx context.Context, userID, codeHash string, usedAt time.Time) (err error)
+	CountUnused(ctx context.Context, userID string) (count int, err error)
+	DeleteByUser(ctx context.Context, userID string) (err error)
+}
+
+// LoginChallenge is a login that passed the password check and still needs a second factor.
+type LoginChallenge struct {
+	ID        string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	Failures  int
+}
+
+type LoginChallengeRepository interface {
+	Insert(ctx context.Context, challenge *LoginChallenge) (err error)
+	Find(ctx context.Context, id string) (challenge *LoginChallenge, err error)
+	// RecordFailure increments the failure count and returns the new value.
+	RecordFailure(ctx context.Context, id string) (failures int, err error)
+	Delete(ctx context.Context, id string) (err error)
+	DeleteByUser(ctx context.Context, userID string) (err error)
+	DeleteExpired(ctx context.Context, before time.Time) (deleted int, err error)
+}
+
+// HashRecoveryCode returns the stored form of a recovery code. Codes are compared ignoring case, spaces and dashes.
+func HashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+
+		return r
+	}, strings.ToLower(code))
+
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:])
+}
+
+type TOTPCredentialNotFoundError struct {
+	UserID string
+}
+
+func (err TOTPCredentialNotFoundError) Error() string {
+	return fmt.Sprintf("totp credential for user %q not found", err.UserID)
+}
+
+type TOTPCodeReusedError struct {
+	UserID string
+}
+
+func (err TOTPCodeReusedError) Error() string {
+	return fmt.Sprintf("totp code for user %q was already used", err.UserID)
+}
+
+type TOTPAlreadyEnabledError struct {
+	UserID string
+}
+
+func (err TOTPAlreadyEnabledError) Error() string {
+	return fmt.Sprintf("two-factor authentication is already enabled for user %q", err.UserID)
+}
+
+type TOTPNotEnabledError struct {
+	UserID string
+}
+
+func (err TOTPNotEnabledError) Error() string {
+	return fmt.Sprintf("two-factor authentication is not enabled for user %q", err.UserID)
+}
+
+type RecoveryCodeNotFoundError struct {
+	UserID string
+}
+
+func (err RecoveryCodeNotFoundError) Error() string {
+	return fmt.Sprintf("unused recovery code for user %q not found", err.UserID)
+}
+
+type LoginChallengeNotFoundError struct {
+	ID string
+}
+
+func (err LoginChallengeNotFoundError) Error() string {
+	return fmt.Sprintf("login challenge with id %q not found", err.ID)
+}
+
+// SecondFactorRequiredError is returned by Login when the password was right but the account has two-factor
+// authentication enabled. The login is finished by passing ChallengeID and a code to VerifySecondFactor.
+type SecondFactorRequiredError struct {
+	ChallengeID string
+	ExpiresAt   time.Time
+}
+
+func (err SecondFactorRequiredError) Error() string {
+	return "second authentication factor required"
+}
@@ -0,0 +1,426 @@
+package authentication
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/nasermirzaei89/scribble/random"
+	"github.com/nasermirzaei89/scribble/totp"
+)
+
+var (
+	ErrInvalidSecondFactorCode = errors.New("invalid second factor code")
+	ErrInvalidLoginChallenge   = errors.New("invalid or expired login challenge")
+)
+
+const (
+	loginChallengeDuration    = 5 * time.Minute
+	maxLoginChallengeFailures = 5
+	// totpSkew accepts codes from one time step before or after the current one, to allow for clock drift.
+	totpSkew          = 1
+	recoveryCodeCount = 10
+)
+
+// TOTPEnrollment is what a user needs to add the pending secret to an authenticator app.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+type TwoFactorStatus struct {
+	Enabled           bool
+	PendingEnrollment bool
+	RecoveryCodesLeft int
+}
+
+// GetTwoFactorStatus reports whether the current user has two-factor authentication enabled.
+func (svc *Service) GetTwoFactorStatus(ctx context.Context) (*TwoFactorStatus, error) {
+	sub := authcontext.GetSubject(ctx)
+	if sub == authcontext.Anonymous {
+		return nil, ErrCurrentUserNotFound
+	}
+
+	credential, err := svc.totpCredentialRepo.Find(ctx, sub)
+	if err != nil {
+		if _, ok := errors.AsType[*TOTPCredentialNotFoundError](err); ok {
+			return &TwoFactorStatus{}, nil
+		}
+
+		return nil, fmt.Errorf("failed to find totp credential: %w", err)
+	}
+
+	if !credential.IsConfirmed() {
+		return &TwoFactorStatus{PendingEnrollment: true}, nil
+	}
+
+	recoveryCodesLeft, err := svc.recoveryCodeRepo.CountUnused(ctx, sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return &TwoFactorStatus{Enabled: true, RecoveryCodesLeft: recoveryCodesLeft}, nil
+}
+
+// BeginTOTPEnrollment creates a new pending TOTP secret for the current user, replacing any earlier pending one.
+// Logins are not affected until the enrollment is confirmed.
+func (svc *Service) BeginTOTPEnrollment(ctx context.Context) (*TOTPEnrollment, error) {
+	err := requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := svc.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := svc.totpCredentialRepo.Find(ctx, user.ID)
+	if err != nil {
+		if _, ok := errors.AsType[*TOTPCredentialNotFoundError](err); !ok {
+			return nil, fmt.Errorf("failed to find totp credential: %w", err)
+		}
+	} else if credential.IsConfirmed() {
+		return nil, &TOTPAlreadyEnabledError{UserID: user.ID}
+	}
+
+	key := totp.NewKey()
+
+	err = svc.totpCredentialRepo.Upsert(ctx, &TOTPCredential{
+		UserID:    user.ID,
+		Secret:    key.EncodedSecret(),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store totp credential: %w", err)
+	}
+
+	return &TOTPEnrollment{Secret: key.EncodedSecret(), URI: key.URI(TOTPIssuer, user.Username)}, nil
+}
+
+// GetTOTPEnrollment returns the current user's pending enrollment, for showing it again.
+func (svc *Service) GetTOTPEnrollment(ctx context.Context) (*TOTPEnrollment, error) {
+	err := requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := svc.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, key, err := svc.findTOTPKey(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if credential.IsConfirmed() {
+		return nil, &TOTPAlreadyEnabledError{UserID: user.ID}
+	}
+
+	return &TOTPEnrollment{Secret: credential.Secret, URI: key.URI(TOTPIssuer, user.Username)}, nil
+}
+
+// ConfirmTOTPEnrollment enables two-factor authentication once the user proves their app produces valid codes.
+// It returns fresh recovery codes, which are shown once and only stored hashed.
+func (svc *Service) ConfirmTOTPEnrollment(ctx context.Context, code string) ([]string, error) {
+	err := requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := authcontext.GetSubject(ctx)
+	if sub == authcontext.Anonymous {
+		return nil, ErrCurrentUserNotFound
+	}
+
+	credential, key, err := svc.findTOTPKey(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	if credential.IsConfirmed() {
+		return nil, &TOTPAlreadyEnabledError{UserID: sub}
+	}
+
+	now := time.Now()
+
+	counter, ok := key.Verify(normalizeTOTPCode(code), now, totpSkew)
+	if !ok {
+		return nil, ErrInvalidSecondFactorCode
+	}
+
+	err = svc.totpCredentialRepo.Confirm(ctx, sub, now, int64(counter)) //nolint:gosec // Time steps fit in int64.
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm totp credential: %w", err)
+	}
+
+	return svc.replaceRecoveryCodes(ctx, sub)
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes after checking a second factor code.
+func (svc *Service) RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error) {
+	err := requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := authcontext.GetSubject(ctx)
+	if sub == authcontext.Anonymous {
+		return nil, ErrCurrentUserNotFound
+	}
+
+	err = svc.checkSecondFactorCode(ctx, sub, code, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.replaceRecoveryCodes(ctx, sub)
+}
+
+// DisableTOTP turns two-factor authentication off for the current user after checking a second factor code.
+func (svc *Service) DisableTOTP(ctx context.Context, code string) error {
+	err := requireSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	sub := authcontext.GetSubject(ctx)
+	if sub == authcontext.Anonymous {
+		return ErrCurrentUserNotFound
+	}
+
+	err = svc.checkSecondFactorCode(ctx, sub, code, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = svc.totpCredentialRepo.Delete(ctx, sub)
+	if err != nil {
+		return fmt.Errorf("failed to delete totp credential: %w", err)
+	}
+
+	err = svc.recoveryCodeRepo.DeleteByUser(ctx, sub)
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+// hasSecondFactor reports whether logins for the user need a second factor.
+func (svc *Service) hasSecondFactor(ctx context.Context, userID string) (bool, error) {
+	credential, err := svc.totpCredentialRepo.Find(ctx, userID)
+	if err != nil {
+		if _, ok := errors.AsType[*TOTPCredentialNotFoundError](err); ok {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to find totp credential: %w", err)
+	}
+
+	return credential.IsConfirmed(), nil
+}
+
+// startLoginChallenge records a login waiting for its second factor.
+func (svc *Service) startLoginChallenge(ctx context.Context, userID string, now time.Time) error {
+	challenge := &LoginChallenge{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(loginChallengeDuration),
+	}
+
+	err := svc.loginChallengeRepo.Insert(ctx, challenge)
+	if err != nil {
+		return fmt.Errorf("failed to create login challenge: %w", err)
+	}
+
+	return &SecondFactorRequiredError{ChallengeID: challenge.ID, ExpiresAt: challenge.ExpiresAt}
+}
+
+// VerifySecondFactor finishes a login started by Login with a TOTP or recovery code and issues the session. A
+// challenge is dropped after it expires or after too many wrong codes, and the login has to start over. Wrong codes
+// also count as failed logins of the username and client, so starting over doesn't give more guesses.
+func (svc *Service) VerifySecondFactor(
+	ctx context.Context,
+	challengeID, code string,
+	client ClientInfo,
+) (*Session, error) {
+	challenge, err := svc.loginChallengeRepo.Find(ctx, challengeID)
+	if err != nil {
+		if _, ok := errors.AsType[*LoginChallengeNotFoundError](err); ok {
+			return nil, ErrInvalidLoginChallenge
+		}
+
+		return nil, fmt.Errorf("failed to find login challenge: %w", err)
+	}
+
+	now := time.Now()
+
+	if !now.Before(challenge.ExpiresAt) {
+		return nil, svc.dropLoginChallenge(ctx, challenge.ID, ErrInvalidLoginChallenge)
+	}
+
+	user, err := svc.userRepo.Find(ctx, challenge.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	attemptKeys := loginAttemptKeys(user.Username, client)
+
+	err = svc.checkLoginThrottle(ctx, attemptKeys, now)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.checkSecondFactorCode(ctx, challenge.UserID, code, now)
+	if err != nil {
+		if !errors.Is(err, ErrInvalidSecondFactorCode) {
+			return nil, err
+		}
+
+		err = svc.recordLoginFailure(ctx, attemptKeys, now)
+		if err != nil {
+			return nil, err
+		}
+
+		failures, err := svc.loginChallengeRepo.RecordFailure(ctx, challenge.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record failed second factor: %w", err)
+		}
+
+		if failures >= maxLoginChallengeFailures {
+			return nil, svc.dropLoginChallenge(ctx, challenge.ID, ErrInvalidLoginChallenge)
+		}
+
+		return nil, ErrInvalidSecondFactorCode
+	}
+
+	err = svc.loginChallengeRepo.Delete(ctx, challenge.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete login challenge: %w", err)
+	}
+
+	err = svc.resetLoginFailures(ctx, attemptKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.createSession(ctx, challenge.UserID, client, now)
+}
+
+// DeleteExpiredLoginChallenges removes abandoned second factor prompts. It is meant for background maintenance and
+// performs no authorization check.
+func (svc *Service) DeleteExpiredLoginChallenges(ctx context.Context) (int, error) {
+	deleted, err := svc.loginChallengeRepo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired login challenges: %w", err)
+	}
+
+	return deleted, nil
+}
+
+func (svc *Service) dropLoginChallenge(ctx context.Context, challengeID string, reason error) error {
+	err := svc.loginChallengeRepo.Delete(ctx, challengeID)
+	if err != nil {
+		return fmt.Errorf("failed to delete login challenge: %w", err)
+	}
+
+	return reason
+}
+
+func (svc *Service) findTOTPKey(ctx context.Context, userID string) (*TOTPCredential, totp.Key, error) {
+	credential, err := svc.totpCredentialRepo.Find(ctx, userID)
+	if err != nil {
+		return nil, totp.Key{}, fmt.Errorf("failed to find totp credential: %w", err)
+	}
+
+	key, err := totp.ParseKey(credential.Secret)
+	if err != nil {
+		return nil, totp.Key{}, fmt.Errorf("failed to parse totp secret: %w", err)
+	}
+
+	return credential, key, nil
+}
+
+// checkSecondFactorCode accepts either a current TOTP code or an unused recovery code, and returns
+// ErrInvalidSecondFactorCode otherwise. Accepted codes are used up.
+func (svc *Service) checkSecondFactorCode(ctx context.Context, userID, code string, now time.Time) error {
+	credential, key, err := svc.findTOTPKey(ctx, userID)
+	if err != nil {
+		if _, ok := errors.AsType[*TOTPCredentialNotFoundError](err); ok {
+			return &TOTPNotEnabledError{UserID: userID}
+		}
+
+		return err
+	}
+
+	if !credential.IsConfirmed() {
+		return &TOTPNotEnabledError{UserID: userID}
+	}
+
+	code = normalizeTOTPCode(code)
+
+	if counter, ok := key.Verify(code, now, totpSkew); ok {
+		err := svc.totpCredentialRepo.UseCounter(ctx, userID, int64(counter)) //nolint:gosec // Time steps fit in int64.
+		if err != nil {
+			if _, ok := errors.AsType[*TOTPCodeReusedError](err); ok {
+				return ErrInvalidSecondFactorCode
+			}
+
+			return fmt.Errorf("failed to record used totp code: %w", err)
+		}
+
+		return nil
+	}
+
+	err = svc.recoveryCodeRepo.Use(ctx, userID, HashRecoveryCode(code), now)
+	if err != nil {
+		if _, ok := errors.AsType[*RecoveryCodeNotFoundError](err); ok {
+			return ErrInvalidSecondFactorCode
+		}
+
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return nil
+}
+
+func (svc *Service) replaceRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		code := newRecoveryCode()
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+
+	err := svc.recoveryCodeRepo.Replace(ctx, userID, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// newRecoveryCode returns a random code like "k3f9x-2mqpa", 50 bits in all.
+func newRecoveryCode() string {
+	code := recoveryCodeEncoding.EncodeToString(random.Bytes(7))[:10]
+
+	return code[:5] + "-" + code[5:]
+}
+
+// normalizeTOTPCode drops the spaces some apps show in the middle of a code.
+func normalizeTOTPCode(code string) string {
+	return strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+}
@@ -12,12 +12,26 @@ type User struct {
 	Username     string
 	PasswordHash string
 	RegisteredAt time.Time
+	// Email is empty until the user adds one, and only counts as theirs once EmailVerifiedAt is set.
+	Email           string
+	EmailVerifiedAt *time.Time
 }
 
 type UserRepository interface {
 	Insert(ctx context.Context, user *User) (err error)
 	Find(ctx context.Context, userID string) (user *User, err error)
+	FindMany(ctx context.Context, userIDs []string) (users []*User, err error)
 	FindByUsername(ctx context.Context, username string) (user *User, err error)
+	// FindManyByUsernames returns the users among the given usernames, compared the way FindByUsername compares them.
+	// Unknown usernames are left out.
+	FindManyByUsernames(ctx context.Context, usernames []string) (users []*User, err error)
+	// FindByEmail only matches verified addresses.
+	FindByEmail(ctx context.Context, email string) (user *User, err error)
+	// SetEmail replaces the user's address and marks it unverified.
+	SetEmail(ctx context.Context, userID, email string) (err error)
+	// MarkEmailVerified verifies the user's address, unless it was changed from email in the meantime.
+	MarkEmailVerified(ctx context.Context, userID, email string, verifiedAt time.Time) (err error)
+	UpdatePasswordHash(ctx context.Context, userID, passwordHash string) (err error)
 }
 
 type UserNotFoundError struct {
@@ -36,6 +50,14 @@ func (err UserByUsernameNotFoundError) Error() string {
 	return fmt.Sprintf("user with username %q not found", err.Username)
 }
 
+type UserByEmailNotFoundError struct {
+	Email string
+}
+
+func (err UserByEmailNotFoundError) Error() string {
+	return fmt.Sprintf("user with email %q not found", err.Email)
+}
+
 type UserAlreadyExistsError struct {
 	Username string
 }
@@ -0,0 +1,13 @@
+package authentication
+
+import (
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// CanonicalUsername returns the form usernames are compared in: NFKC-normalized and case-folded, so "Alice",
+// "ALICE" and the full-width "Ａｌｉｃｅ" all name the same account. The display form is kept as the user typed it.
+func CanonicalUsername(username string) string {
+	// A Caser is stateful and can't be shared between goroutines.
+	return norm.NFKC.String(cases.Fold().String(norm.NFKC.String(username)))
+}
@@ -0,0 +1,34 @@
+package authentication_test
+
+import (
+	"testing"
+
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalUsername(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		username string
+		expected string
+	}{
+		{username: "alice", expected: "alice"},
+		{username: "Alice", expected: "alice"},
+		{username: "ALICE", expected: "alice"},
+		{username: "Ａｌｉｃｅ", expected: "alice"},
+		{username: "straße", expected: "strasse"},
+		{username: "ﬁona", expected: "fiona"},
+		{username: "Ⅻ", expected: "xii"},
+		{username: "E\u0301mile", expected: "\u00e9mile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, authentication.CanonicalUsername(tt.username))
+		})
+	}
+}
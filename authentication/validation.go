@@ -0,0 +1,176 @@
+package authentication
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	FieldUsername = "username"
+	FieldPassword = "password"
+	FieldEmail    = "email"
+)
+
+// ValidationError reports every rule the input broke, keyed by field name.
+type ValidationError struct {
+	Fields map[string][]string
+}
+
+func (err ValidationError) Error() string {
+	parts := make([]string, 0, len(err.Fields))
+
+	for _, field := range slices.Sorted(maps.Keys(err.Fields)) {
+		parts = append(parts, field+": "+strings.Join(err.Fields[field], ", "))
+	}
+
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+func (err *ValidationError) add(field, message string) {
+	if err.Fields == nil {
+		err.Fields = make(map[string][]string)
+	}
+
+	err.Fields[field] = append(err.Fields[field], message)
+}
+
+// passwordMaxBytes is the most bcrypt will hash; longer passwords are rejected instead of silently truncated.
+const passwordMaxBytes = 72
+
+// CredentialsPolicy holds the rules usernames and passwords must follow at registration.
+type CredentialsPolicy struct {
+	UsernameMinLength int
+	UsernameMaxLength int
+	// UsernamePattern restricts the characters a username may contain.
+	UsernamePattern *regexp.Regexp
+	// ReservedUsernames can't be registered, compared case-insensitively. A trailing "*" reserves a whole prefix.
+	ReservedUsernames []string
+	PasswordMinLength int
+	// RejectCommonPasswords refuses passwords found in the embedded list of common passwords.
+	RejectCommonPasswords bool
+	// MinPasswordStrength is the lowest accepted PasswordStrength score, from 0 (disabled) to 4.
+	MinPasswordStrength int
+}
+
+var DefaultUsernamePattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9_.-]*[A-Za-z0-9])?$`)
+
+func DefaultReservedUsernames() []string {
+	return []string{
+		"admin",
+		"administrator",
+		"root",
+		"system",
+		"system:*",
+		"anonymous",
+		"api",
+		"settings",
+		"login",
+		"logout",
+		"register",
+	}
+}
+
+func DefaultCredentialsPolicy() CredentialsPolicy {
+	return CredentialsPolicy{
+		UsernameMinLength:     3,
+		UsernameMaxLength:     32,
+		UsernamePattern:       DefaultUsernamePattern,
+		ReservedUsernames:     DefaultReservedUsernames(),
+		PasswordMinLength:     8,
+		RejectCommonPasswords: true,
+		MinPasswordStrength:   0,
+	}
+}
+
+// Validate checks a username and password pair against the policy and returns a *ValidationError listing every
+// failure, or nil.
+func (policy CredentialsPolicy) Validate(username, password string) error {
+	var validationErr ValidationError
+
+	policy.validateUsername(&validationErr, username)
+	policy.validatePassword(&validationErr, username, password)
+
+	if len(validationErr.Fields) > 0 {
+		return &validationErr
+	}
+
+	return nil
+}
+
+func (policy CredentialsPolicy) validateUsername(validationErr *ValidationError, username string) {
+	length := utf8.RuneCountInString(username)
+
+	switch {
+	case length == 0:
+		validationErr.add(FieldUsername, "Username is required.")
+
+		return
+	case length < policy.UsernameMinLength:
+		validationErr.add(FieldUsername, fmt.Sprintf("Username must be at least %d characters.", policy.UsernameMinLength))
+	case policy.UsernameMaxLength > 0 && length > policy.UsernameMaxLength:
+		validationErr.add(FieldUsername, fmt.Sprintf("Username must be at most %d characters.", policy.UsernameMaxLength))
+	}
+
+	if policy.UsernamePattern != nil && !policy.UsernamePattern.MatchString(username) {
+		validationErr.add(FieldUsername, "Username contains characters that are not allowed.")
+	}
+
+	if policy.isReserved(username) {
+		validationErr.add(FieldUsername, "This username is reserved.")
+	}
+}
+
+// isReserved compares usernames the way logins do, so a reserved name can't be taken in another case or in look-alike
+// forms such as full-width letters.
+func (policy CredentialsPolicy) isReserved(username string) bool {
+	username = CanonicalUsername(username)
+
+	for _, reserved := range policy.ReservedUsernames {
+		if prefix, ok := strings.CutSuffix(reserved, "*"); ok {
+			if strings.HasPrefix(username, CanonicalUsername(prefix)) {
+				return true
+			}
+
+			continue
+		}
+
+		if username == CanonicalUsername(reserved) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (policy CredentialsPolicy) validatePassword(validationErr *ValidationError, username, password string) {
+	switch {
+	case password == "":
+		validationErr.add(FieldPassword, "Password is required.")
+
+		return
+	case utf8.RuneCountInString(password) < policy.PasswordMinLength:
+		validationErr.add(FieldPassword, fmt.Sprintf("Password must be at least %d characters.", policy.PasswordMinLength))
+	case len(password) > passwordMaxBytes:
+		validationErr.add(FieldPassword, fmt.Sprintf("Password must be at most %d bytes.", passwordMaxBytes))
+	}
+
+	if policy.RejectCommonPasswords && IsCommonPassword(password) {
+		validationErr.add(FieldPassword, "This password is too common.")
+
+		return
+	}
+
+	if username != "" && strings.EqualFold(password, username) {
+		validationErr.add(FieldPassword, "Password must not be the same as the username.")
+
+		return
+	}
+
+	if policy.MinPasswordStrength > 0 && PasswordStrength(password, username) < policy.MinPasswordStrength {
+		validationErr.add(FieldPassword, "Password is too easy to guess. Try a longer passphrase or mix in more words.")
+	}
+}
@@ -0,0 +1,155 @@
+package authentication_test
+
+import (
+	"testing"
+
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialsPolicyValidate(t *testing.T) {
+	t.Parallel()
+
+	policy := authentication.DefaultCredentialsPolicy()
+
+	tests := []struct {
+		name           string
+		username       string
+		password       string
+		usernameErrors int
+		passwordErrors int
+	}{
+		{
+			name:     "valid",
+			username: "alice",
+			password: "correct horse battery",
+		},
+		{
+			name:           "empty",
+			username:       "",
+			password:       "",
+			usernameErrors: 1,
+			passwordErrors: 1,
+		},
+		{
+			name:           "too short",
+			username:       "al",
+			password:       "x7!",
+			usernameErrors: 1,
+			passwordErrors: 1,
+		},
+		{
+			name:           "too long username",
+			username:       "a123456789012345678901234567890123",
+			password:       "correct horse battery",
+			usernameErrors: 1,
+		},
+		{
+			name:           "bad charset",
+			username:       "alice bob",
+			password:       "correct horse battery",
+			usernameErrors: 1,
+		},
+		{
+			name:           "reserved",
+			username:       "Admin",
+			password:       "correct horse battery",
+			usernameErrors: 1,
+		},
+		{
+			name:           "reserved in full-width letters",
+			username:       "ａｄｍｉｎ",
+			password:       "correct horse battery",
+			usernameErrors: 2,
+		},
+		{
+			name:           "reserved prefix",
+			username:       "system:anonymous",
+			password:       "correct horse battery",
+			usernameErrors: 2,
+		},
+		{
+			name:           "common password",
+			username:       "alice",
+			password:       "Password123",
+			passwordErrors: 1,
+		},
+		{
+			name:           "password equals username",
+			username:       "alice-wonder",
+			password:       "Alice-Wonder",
+			passwordErrors: 1,
+		},
+		{
+			name:           "password longer than bcrypt allows",
+			username:       "alice",
+			password:       "correct horse battery staple correct horse battery staple correct horse battery",
+			passwordErrors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := policy.Validate(tt.username, tt.password)
+
+			if tt.usernameErrors == 0 && tt.passwordErrors == 0 {
+				require.NoError(t, err)
+
+				return
+			}
+
+			validationErr := &authentication.ValidationError{}
+			require.ErrorAs(t, err, &validationErr)
+			assert.Len(t, validationErr.Fields[authentication.FieldUsername], tt.usernameErrors)
+			assert.Len(t, validationErr.Fields[authentication.FieldPassword], tt.passwordErrors)
+		})
+	}
+}
+
+func TestCredentialsPolicyMinPasswordStrength(t *testing.T) {
+	t.Parallel()
+
+	policy := authentication.DefaultCredentialsPolicy()
+	policy.MinPasswordStrength = 3
+
+	err := policy.Validate("alice", "aaaaaaaaaaaa")
+
+	validationErr := &authentication.ValidationError{}
+	require.ErrorAs(t, err, &validationErr)
+	assert.Len(t, validationErr.Fields[authentication.FieldPassword], 1)
+
+	err = policy.Validate("alice", "violet tugboat marmalade")
+	require.NoError(t, err)
+}
+
+func TestPasswordStrength(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		password string
+		maxScore int
+		minScore int
+	}{
+		{password: "password", maxScore: 0},
+		{password: "P@ssw0rd", maxScore: 1},
+		{password: "aaaaaaaaaaaa", maxScore: 1},
+		{password: "abcdefghijkl", maxScore: 1},
+		{password: "qwertyuiop", maxScore: 1},
+		{password: "alicealice", maxScore: 1},
+		{password: "Tr0ub4dour&3", minScore: 3, maxScore: 4},
+		{password: "violet tugboat marmalade", minScore: 4, maxScore: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			t.Parallel()
+
+			score := authentication.PasswordStrength(tt.password, "alice")
+			assert.GreaterOrEqual(t, score, tt.minScore)
+			assert.LessOrEqual(t, score, tt.maxScore)
+		})
+	}
+}
@@ -0,0 +1,277 @@
+// Package webauthntest provides a software WebAuthn authenticator, so passkey ceremonies can be tested without
+// hardware or a browser.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+var ErrNoCredential = errors.New("no matching credential")
+
+// Authenticator flags, from the WebAuthn authenticator data layout.
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// Credential is a key pair the authenticator created. Every credential is discoverable.
+type Credential struct {
+	ID         []byte
+	RPID       string
+	UserHandle []byte
+	PrivateKey *ecdsa.PrivateKey
+	SignCount  uint32
+}
+
+// Authenticator answers the options of navigator.credentials.create() and get() the way a browser with a platform
+// authenticator would, using ES256 keys and "none" attestation. It is not safe for concurrent use.
+type Authenticator struct {
+	// Origin is reported as the page the ceremony ran on.
+	Origin string
+	// UserVerified controls whether the authenticator claims to have verified the user, with a PIN or biometrics.
+	UserVerified bool
+	// Credentials holds every credential created so far, and can be edited to set up a test.
+	Credentials []*Credential
+}
+
+func NewAuthenticator(origin string) *Authenticator {
+	return &Authenticator{Origin: origin, UserVerified: true, Credentials: nil}
+}
+
+// Register creates a credential for the given navigator.credentials.create() options, and returns the response
+// JSON a browser would send back.
+func (a *Authenticator) Register(options []byte) ([]byte, error) {
+	var creation protocol.CredentialCreation
+
+	err := json.Unmarshal(options, &creation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse creation options: %w", err)
+	}
+
+	opts := creation.Response
+
+	for _, excluded := range opts.CredentialExcludeList {
+		if a.find(opts.RelyingParty.ID, excluded.CredentialID) != nil {
+			return nil, errors.New("authenticator already holds an excluded credential")
+		}
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	credentialID := make([]byte, 32)
+
+	_, err = rand.Read(credentialID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate credential id: %w", err)
+	}
+
+	// The user handle arrives as base64url text, since the options type can't know its JSON shape.
+	encodedUserHandle, ok := opts.User.ID.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected user id type %T", opts.User.ID)
+	}
+
+	userHandle, err := base64.RawURLEncoding.DecodeString(encodedUserHandle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode user id: %w", err)
+	}
+
+	credential := &Credential{
+		ID:         credentialID,
+		RPID:       opts.RelyingParty.ID,
+		UserHandle: userHandle,
+		PrivateKey: privateKey,
+		SignCount:  0,
+	}
+
+	// An uncompressed point is 0x04 followed by the two 32-byte coordinates.
+	point, err := privateKey.PublicKey.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key point: %w", err)
+	}
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: point[1:33],
+		YCoord: point[33:],
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	// Attested credential data: a zero AAGUID, the credential ID with its length, and the COSE public key.
+	attested := make([]byte, 16, 16+2+len(credentialID)+len(publicKey))
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(credentialID))) //nolint:gosec // 32 bytes.
+	attested = append(attested, credentialID...)
+	attested = append(attested, publicKey...)
+
+	authData := a.authenticatorData(credential, flagAttested, attested)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode attestation object: %w", err)
+	}
+
+	clientData, err := a.clientData(protocol.CreateCeremony, opts.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Credentials = append(a.Credentials, credential)
+
+	return json.Marshal(map[string]any{
+		"id":                      encode(credentialID),
+		"rawId":                   encode(credentialID),
+		"type":                    "public-key",
+		"authenticatorAttachment": "platform",
+		"clientExtensionResults":  map[string]any{},
+		"response": map[string]any{
+			"clientDataJSON":    encode(clientData),
+			"attestationObject": encode(attestationObject),
+			"transports":        []string{"internal"},
+		},
+	})
+}
+
+// Login signs the challenge of the given navigator.credentials.get() options with a matching credential, and
+// returns the response JSON a browser would send back. Without an allow list, the first credential for the relying
+// party is used, as if the user picked it.
+func (a *Authenticator) Login(options []byte) ([]byte, error) {
+	var assertion protocol.CredentialAssertion
+
+	err := json.Unmarshal(options, &assertion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse request options: %w", err)
+	}
+
+	opts := assertion.Response
+
+	credential := a.pick(opts.RelyingPartyID, opts.AllowedCredentials)
+	if credential == nil {
+		return nil, ErrNoCredential
+	}
+
+	credential.SignCount++
+
+	authData := a.authenticatorData(credential, 0, nil)
+
+	clientData, err := a.clientData(protocol.AssertCeremony, opts.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, credential.PrivateKey, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign assertion: %w", err)
+	}
+
+	return json.Marshal(map[string]any{
+		"id":                      encode(credential.ID),
+		"rawId":                   encode(credential.ID),
+		"type":                    "public-key",
+		"authenticatorAttachment": "platform",
+		"clientExtensionResults":  map[string]any{},
+		"response": map[string]any{
+			"clientDataJSON":    encode(clientData),
+			"authenticatorData": encode(authData),
+			"signature":         encode(signature),
+			"userHandle":        encode(credential.UserHandle),
+		},
+	})
+}
+
+func (a *Authenticator) find(rpID string, id []byte) *Credential {
+	for _, credential := range a.Credentials {
+		if credential.RPID == rpID && string(credential.ID) == string(id) {
+			return credential
+		}
+	}
+
+	return nil
+}
+
+func (a *Authenticator) pick(rpID string, allowed []protocol.CredentialDescriptor) *Credential {
+	if len(allowed) == 0 {
+		for _, credential := range a.Credentials {
+			if credential.RPID == rpID {
+				return credential
+			}
+		}
+
+		return nil
+	}
+
+	for _, descriptor := range allowed {
+		if credential := a.find(rpID, descriptor.CredentialID); credential != nil {
+			return credential
+		}
+	}
+
+	return nil
+}
+
+// authenticatorData lays out the RP ID hash, the flags, the signature counter and any attested credential data.
+func (a *Authenticator) authenticatorData(credential *Credential, flags byte, attested []byte) []byte {
+	flags |= flagUserPresent
+	if a.UserVerified {
+		flags |= flagUserVerified
+	}
+
+	rpIDHash := sha256.Sum256([]byte(credential.RPID))
+
+	data := make([]byte, 0, len(rpIDHash)+1+4+len(attested))
+	data = append(data, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, credential.SignCount)
+	data = append(data, attested...)
+
+	return data
+}
+
+func (a *Authenticator) clientData(
+	ceremony protocol.CeremonyType,
+	challenge protocol.URLEncodedBase64,
+) ([]byte, error) {
+	clientData, err := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   encode(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode client data: %w", err)
+	}
+
+	return clientData, nil
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,54 @@
+// Package blob keeps binary objects, such as uploaded images, in a pluggable Store.
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Store keeps blobs under slash-separated keys like "avatars/<id>/small.png". Putting a key that exists replaces its
+// blob, and readers never see a blob half written.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) (err error)
+	// Get returns a NotFoundError if there is no blob under the key.
+	Get(ctx context.Context, key string) (rc io.ReadCloser, err error)
+	// Delete does nothing if there is no blob under the key.
+	Delete(ctx context.Context, key string) (err error)
+}
+
+type NotFoundError struct {
+	Key string
+}
+
+func (err NotFoundError) Error() string {
+	return fmt.Sprintf("blob with key %q not found", err.Key)
+}
+
+type InvalidKeyError struct {
+	Key string
+}
+
+func (err InvalidKeyError) Error() string {
+	return fmt.Sprintf("invalid blob key %q", err.Key)
+}
+
+var keySegmentPattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// validateKey accepts keys whose segments are made of letters, digits, dots, dashes and underscores, so they are
+// safe to use as file paths and in URLs.
+func validateKey(key string) error {
+	if key == "" {
+		return &InvalidKeyError{Key: key}
+	}
+
+	for segment := range strings.SplitSeq(key, "/") {
+		if !keySegmentPattern.MatchString(segment) {
+			return &InvalidKeyError{Key: key}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package blob_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nasermirzaei89/scribble/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) blob.Store{
+		"file":   func(t *testing.T) blob.Store { t.Helper(); return blob.NewFileStore(t.TempDir()) },
+		"memory": func(t *testing.T) blob.Store { t.Helper(); return blob.NewMemoryStore() },
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore(t)
+
+			_, err := store.Get(ctx, "avatars/u1/small.png")
+
+			var notFoundErr *blob.NotFoundError
+
+			require.ErrorAs(t, err, &notFoundErr)
+			assert.Equal(t, "avatars/u1/small.png", notFoundErr.Key)
+
+			err = store.Put(ctx, "avatars/u1/small.png", strings.NewReader("first"))
+			require.NoError(t, err)
+
+			err = store.Put(ctx, "avatars/u1/small.png", strings.NewReader("second"))
+			require.NoError(t, err)
+
+			assert.Equal(t, "second", readBlob(ctx, t, store, "avatars/u1/small.png"))
+
+			err = store.Delete(ctx, "avatars/u1/small.png")
+			require.NoError(t, err)
+
+			_, err = store.Get(ctx, "avatars/u1/small.png")
+			require.ErrorAs(t, err, &notFoundErr)
+
+			// Deleting a missing blob is not an error.
+			err = store.Delete(ctx, "avatars/u1/small.png")
+			require.NoError(t, err)
+
+			for _, key := range []string{"", "/abs", "a//b", "../escape", "a/../b", "a/./b", ".hidden", "a b", "a\\b"} {
+				err = store.Put(ctx, key, strings.NewReader("x"))
+
+				var invalidKeyErr *blob.InvalidKeyError
+
+				require.ErrorAs(t, err, &invalidKeyErr, "key %q", key)
+			}
+		})
+	}
+}
+
+func TestFileStoreWritesUnderDir(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := blob.NewFileStore(dir)
+
+	err := store.Put(ctx, "avatars/u1/large.png", strings.NewReader("image"))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "avatars", "u1", "large.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "image", string(data))
+
+	// Only the blob is left behind, not the temporary file it was written to.
+	entries, err := os.ReadDir(filepath.Join(dir, "avatars", "u1"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func readBlob(ctx context.Context, t *testing.T, store blob.Store, key string) string {
+	t.Helper()
+
+	rc, err := store.Get(ctx, key)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, rc.Close())
+	}()
+
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+
+	return string(data)
+}
@@ -0,0 +1,145 @@
+package blob
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+)
+
+// FileStore keeps each blob in a file under Dir, at the path its key names. Files are opened through an os.Root, so
+// no key can reach outside Dir.
+type FileStore struct {
+	Dir string
+}
+
+var _ Store = (*FileStore)(nil)
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{Dir: dir}
+}
+
+func (store *FileStore) openRoot() (*os.Root, error) {
+	err := os.MkdirAll(store.Dir, 0o750)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	root, err := os.OpenRoot(store.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob directory: %w", err)
+	}
+
+	return root, nil
+}
+
+// Put writes the blob to a temporary file next to its final path and renames it into place once complete.
+func (store *FileStore) Put(_ context.Context, key string, r io.Reader) error {
+	err := validateKey(key)
+	if err != nil {
+		return err
+	}
+
+	root, err := store.openRoot()
+	if err != nil {
+		return err
+	}
+
+	defer closeRoot(root)
+
+	err = root.MkdirAll(path.Dir(key), 0o750)
+	if err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	// Keys never start with a dot, so a temporary file can't be mistaken for a blob.
+	tmp := path.Join(path.Dir(key), "."+path.Base(key)+"."+rand.Text())
+
+	f, err := root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to create blob file: %w", err)
+	}
+
+	_, err = io.Copy(f, r)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = root.Rename(tmp, key)
+	}
+
+	if err != nil {
+		removeErr := root.Remove(tmp)
+		if removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			slog.Error("failed to remove temporary blob file", "path", tmp, "error", removeErr)
+		}
+
+		return fmt.Errorf("failed to write blob file: %w", err)
+	}
+
+	return nil
+}
+
+func (store *FileStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	err := validateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := store.openRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	defer closeRoot(root)
+
+	f, err := root.Open(key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &NotFoundError{Key: key}
+		}
+
+		return nil, fmt.Errorf("failed to open blob file: %w", err)
+	}
+
+	return f, nil
+}
+
+func (store *FileStore) Delete(_ context.Context, key string) error {
+	err := validateKey(key)
+	if err != nil {
+		return err
+	}
+
+	root, err := store.openRoot()
+	if err != nil {
+		return err
+	}
+
+	defer closeRoot(root)
+
+	err = root.Remove(key)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove blob file: %w", err)
+	}
+
+	return nil
+}
+
+func closeRoot(root *os.Root) {
+	err := root.Close()
+	if err != nil {
+		slog.Error("failed to close blob directory", "error", err)
+	}
+}
@@ -0,0 +1,71 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// MemoryStore keeps blobs in memory. They are gone once the process exits, so it suits tests and trying things out.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{mu: sync.RWMutex{}, blobs: make(map[string][]byte)}
+}
+
+func (store *MemoryStore) Put(_ context.Context, key string, r io.Reader) error {
+	err := validateKey(key)
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read blob: %w", err)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.blobs[key] = data
+
+	return nil
+}
+
+func (store *MemoryStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	err := validateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	data, ok := store.blobs[key]
+	if !ok {
+		return nil, &NotFoundError{Key: key}
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (store *MemoryStore) Delete(_ context.Context, key string) error {
+	err := validateKey(key)
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.blobs, key)
+
+	return nil
+}
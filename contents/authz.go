@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
 	"github.com/nasermirzaei89/scribble/authorization"
 )
 
@@ -11,6 +12,12 @@ const (
 	ActionCreatePost = "createPost"
 	ActionListPosts  = "listPosts"
 	ActionGetPost    = "getPost"
+	ActionUpdatePost = "updatePost"
+	ActionDeletePost = "deletePost"
+
+	ActionUpdateCommentSettings = "updateCommentSettings"
+
+	ActionListPostRevisions = "listPostRevisions"
 )
 
 type AuthorizationMiddleware struct {
@@ -41,18 +48,18 @@ func (mw *AuthorizationMiddleware) CreatePost(ctx context.Context, req CreatePos
 	return post, nil
 }
 
-func (mw *AuthorizationMiddleware) ListPosts(ctx context.Context) ([]*Post, error) {
+func (mw *AuthorizationMiddleware) ListPosts(ctx context.Context, params ListPostsParams) (*ListPostsResult, error) {
 	err := mw.authzClient.CheckAccess(ctx, ServiceName, "", ActionListPosts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check authorization: %w", err)
 	}
 
-	posts, err := mw.next.ListPosts(ctx)
+	result, err := mw.next.ListPosts(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call next method: %w", err)
 	}
 
-	return posts, nil
+	return result, nil
 }
 
 func (mw *AuthorizationMiddleware) GetPost(ctx context.Context, postID string) (*Post, error) {
@@ -68,3 +75,84 @@ func (mw *AuthorizationMiddleware) GetPost(ctx context.Context, postID string) (
 
 	return post, nil
 }
+
+// checkPostOwnerAccess allows the author of the post unconditionally and falls back to the policy for everyone else,
+// so moderators and root can still manage posts they don't own.
+func (mw *AuthorizationMiddleware) checkPostOwnerAccess(ctx context.Context, postID, action string) error {
+	post, err := mw.next.GetPost(ctx, postID)
+	if err != nil {
+		return fmt.Errorf("failed to get post: %w", err)
+	}
+
+	if post.AuthorID == authcontext.GetSubject(ctx) {
+		return nil
+	}
+
+	err = mw.authzClient.CheckAccess(ctx, ServiceName, postID, action)
+	if err != nil {
+		return fmt.Errorf("failed to check access: %w", err)
+	}
+
+	return nil
+}
+
+func (mw *AuthorizationMiddleware) UpdatePost(ctx context.Context, req UpdatePostRequest) (*Post, error) {
+	err := mw.checkPostOwnerAccess(ctx, req.PostID, ActionUpdatePost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	post, err := mw.next.UpdatePost(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return post, nil
+}
+
+func (mw *AuthorizationMiddleware) DeletePost(ctx context.Context, postID string) error {
+	err := mw.checkPostOwnerAccess(ctx, postID, ActionDeletePost)
+	if err != nil {
+		return fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	err = mw.next.DeletePost(ctx, postID)
+	if err != nil {
+		return fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return nil
+}
+
+func (mw *AuthorizationMiddleware) UpdateCommentSettings(
+	ctx context.Context,
+	req UpdateCommentSettingsRequest,
+) (*Post, error) {
+	err := mw.checkPostOwnerAccess(ctx, req.PostID, ActionUpdateCommentSettings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	post, err := mw.next.UpdateCommentSettings(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return post, nil
+}
+
+// ListPostRevisions is held to the same check as editing, since the history of a post is for its author and those who
+// moderate it.
+func (mw *AuthorizationMiddleware) ListPostRevisions(ctx context.Context, postID string) ([]*PostRevision, error) {
+	err := mw.checkPostOwnerAccess(ctx, postID, ActionListPostRevisions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	revisions, err := mw.next.ListPostRevisions(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return revisions, nil
+}
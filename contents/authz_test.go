@@ -15,18 +15,42 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-type stubService struct{}
+type stubService struct {
+	authorID string
+}
 
 func (s *stubService) CreatePost(ctx context.Context, req contents.CreatePostRequest) (*contents.Post, error) {
 	return &contents.Post{ID: "post1", AuthorID: req.AuthorID, Content: req.Content}, nil
 }
 
-func (s *stubService) ListPosts(ctx context.Context) ([]*contents.Post, error) {
-	return []*contents.Post{}, nil
+func (s *stubService) ListPosts(
+	ctx context.Context,
+	params contents.ListPostsParams,
+) (*contents.ListPostsResult, error) {
+	return &contents.ListPostsResult{Posts: []*contents.Post{}}, nil
 }
 
 func (s *stubService) GetPost(ctx context.Context, postID string) (*contents.Post, error) {
-	return &contents.Post{ID: postID, AuthorID: "author1", Content: "test"}, nil
+	return &contents.Post{ID: postID, AuthorID: s.authorID, Content: "test"}, nil
+}
+
+func (s *stubService) UpdatePost(ctx context.Context, req contents.UpdatePostRequest) (*contents.Post, error) {
+	return &contents.Post{ID: req.PostID, AuthorID: s.authorID, Content: req.Content}, nil
+}
+
+func (s *stubService) DeletePost(ctx context.Context, postID string) error {
+	return nil
+}
+
+func (s *stubService) UpdateCommentSettings(
+	ctx context.Context,
+	req contents.UpdateCommentSettingsRequest,
+) (*contents.Post, error) {
+	return &contents.Post{ID: req.PostID, AuthorID: s.authorID, CommentsLocked: req.Locked}, nil
+}
+
+func (s *stubService) ListPostRevisions(ctx context.Context, postID string) ([]*contents.PostRevision, error) {
+	return []*contents.PostRevision{}, nil
 }
 
 func TestAuthorizationMiddleware(t *testing.T) {
@@ -36,6 +60,8 @@ func TestAuthorizationMiddleware(t *testing.T) {
 	tmpFile := filepath.Join(tmpDir, "policy.csv")
 	content := []byte(`g, system:anonymous, system:unauthenticated
 
+p, system:group:root, *, *, *
+
 p, system:authenticated, github.com/nasermirzaei89/scribble/contents, -, createPost
 p, system:authenticated, github.com/nasermirzaei89/scribble/contents, -, listPosts
 p, system:unauthenticated, github.com/nasermirzaei89/scribble/contents, -, listPosts
@@ -54,17 +80,26 @@ p, system:unauthenticated, github.com/nasermirzaei89/scribble/contents, *, getPo
 	authzSvc, err := authorization.NewService(provider)
 	require.NoError(t, err)
 
+	authorID := uuid.NewString()
+
 	client := authorization.NewClient(authzSvc)
-	svc := contents.NewAuthorizationMiddleware(client, &stubService{})
+	svc := contents.NewAuthorizationMiddleware(client, &stubService{authorID: authorID})
 
 	userID := uuid.NewString()
 	err = client.AddToGroup(ctx, userID, authcontext.Authenticated)
 	require.NoError(t, err)
 
-	authorID := uuid.NewString()
+	err = client.AddToGroup(ctx, authorID, authcontext.Authenticated)
+	require.NoError(t, err)
+
+	rootID := uuid.NewString()
+	err = client.AddToGroup(ctx, rootID, "system:group:root")
+	require.NoError(t, err)
 
 	anonymousCtx := ctx
 	authenticatedCtx := authcontext.WithSubject(ctx, userID)
+	authorCtx := authcontext.WithSubject(ctx, authorID)
+	rootCtx := authcontext.WithSubject(ctx, rootID)
 
 	t.Run("anonymous", func(t *testing.T) {
 		_, err := svc.CreatePost(anonymousCtx, contents.CreatePostRequest{AuthorID: authorID, Content: "post"})
@@ -73,21 +108,77 @@ p, system:unauthenticated, github.com/nasermirzaei89/scribble/contents, *, getPo
 		accessDeniedErr := &authorization.AccessDeniedError{}
 		require.ErrorAs(t, err, &accessDeniedErr)
 
-		_, err = svc.ListPosts(anonymousCtx)
+		_, err = svc.ListPosts(anonymousCtx, contents.ListPostsParams{})
 		require.NoError(t, err)
 
 		_, err = svc.GetPost(anonymousCtx, "post1")
 		require.NoError(t, err)
+
+		_, err = svc.UpdatePost(anonymousCtx, contents.UpdatePostRequest{PostID: "post1", Content: "edited"})
+		require.ErrorAs(t, err, &accessDeniedErr)
+
+		err = svc.DeletePost(anonymousCtx, "post1")
+		require.ErrorAs(t, err, &accessDeniedErr)
+
+		_, err = svc.UpdateCommentSettings(anonymousCtx, contents.UpdateCommentSettingsRequest{PostID: "post1"})
+		require.ErrorAs(t, err, &accessDeniedErr)
+
+		_, err = svc.ListPostRevisions(anonymousCtx, "post1")
+		require.ErrorAs(t, err, &accessDeniedErr)
 	})
 
 	t.Run("authenticated", func(t *testing.T) {
 		_, err := svc.CreatePost(authenticatedCtx, contents.CreatePostRequest{AuthorID: authorID, Content: "post"})
 		require.NoError(t, err)
 
-		_, err = svc.ListPosts(authenticatedCtx)
+		_, err = svc.ListPosts(authenticatedCtx, contents.ListPostsParams{})
 		require.NoError(t, err)
 
 		_, err = svc.GetPost(authenticatedCtx, "post1")
 		require.NoError(t, err)
 	})
+
+	t.Run("authenticated non-author", func(t *testing.T) {
+		accessDeniedErr := &authorization.AccessDeniedError{}
+
+		_, err := svc.UpdatePost(authenticatedCtx, contents.UpdatePostRequest{PostID: "post1", Content: "edited"})
+		require.ErrorAs(t, err, &accessDeniedErr)
+
+		err = svc.DeletePost(authenticatedCtx, "post1")
+		require.ErrorAs(t, err, &accessDeniedErr)
+
+		_, err = svc.UpdateCommentSettings(authenticatedCtx, contents.UpdateCommentSettingsRequest{PostID: "post1"})
+		require.ErrorAs(t, err, &accessDeniedErr)
+
+		_, err = svc.ListPostRevisions(authenticatedCtx, "post1")
+		require.ErrorAs(t, err, &accessDeniedErr)
+	})
+
+	t.Run("author", func(t *testing.T) {
+		_, err := svc.UpdatePost(authorCtx, contents.UpdatePostRequest{PostID: "post1", Content: "edited"})
+		require.NoError(t, err)
+
+		err = svc.DeletePost(authorCtx, "post1")
+		require.NoError(t, err)
+
+		_, err = svc.UpdateCommentSettings(authorCtx, contents.UpdateCommentSettingsRequest{PostID: "post1", Locked: true})
+		require.NoError(t, err)
+
+		_, err = svc.ListPostRevisions(authorCtx, "post1")
+		require.NoError(t, err)
+	})
+
+	t.Run("root", func(t *testing.T) {
+		_, err := svc.UpdatePost(rootCtx, contents.UpdatePostRequest{PostID: "post1", Content: "edited"})
+		require.NoError(t, err)
+
+		err = svc.DeletePost(rootCtx, "post1")
+		require.NoError(t, err)
+
+		_, err = svc.UpdateCommentSettings(rootCtx, contents.UpdateCommentSettingsRequest{PostID: "post1", Locked: true})
+		require.NoError(t, err)
+
+		_, err = svc.ListPostRevisions(rootCtx, "post1")
+		require.NoError(t, err)
+	})
 }
@@ -7,29 +7,40 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/nasermirzaei89/scribble/authorization"
+	"github.com/nasermirzaei89/scribble/mentions"
 )
 
 const ServiceName = "github.com/nasermirzaei89/scribble/contents"
 
 type Service interface {
 	CreatePost(ctx context.Context, req CreatePostRequest) (*Post, error)
-	ListPosts(ctx context.Context) ([]*Post, error)
+	ListPosts(ctx context.Context, params ListPostsParams) (*ListPostsResult, error)
 	GetPost(ctx context.Context, postID string) (*Post, error)
+	UpdatePost(ctx context.Context, req UpdatePostRequest) (*Post, error)
+	DeletePost(ctx context.Context, postID string) error
+	UpdateCommentSettings(ctx context.Context, req UpdateCommentSettingsRequest) (*Post, error)
+	ListPostRevisions(ctx context.Context, postID string) ([]*PostRevision, error)
 }
 
 type BaseService struct {
-	postRepo PostRepository
+	postRepo         PostRepository
+	postRevisionRepo PostRevisionRepository
 }
 
 var _ Service = (*BaseService)(nil)
 
-func NewService(postRepo PostRepository, authzClient *authorization.Client) Service { //nolint:ireturn
-	return NewAuthorizationMiddleware(authzClient, NewBaseService(postRepo))
+func NewService( //nolint:ireturn
+	postRepo PostRepository,
+	postRevisionRepo PostRevisionRepository,
+	authzClient *authorization.Client,
+) Service {
+	return NewAuthorizationMiddleware(authzClient, NewBaseService(postRepo, postRevisionRepo))
 }
 
-func NewBaseService(postRepo PostRepository) *BaseService {
+func NewBaseService(postRepo PostRepository, postRevisionRepo PostRevisionRepository) *BaseService {
 	return &BaseService{
-		postRepo: postRepo,
+		postRepo:         postRepo,
+		postRevisionRepo: postRevisionRepo,
 	}
 }
 
@@ -39,14 +50,18 @@ type CreatePostRequest struct {
 }
 
 func (svc *BaseService) CreatePost(ctx context.Context, req CreatePostRequest) (*Post, error) {
+	if req.Content == "" {
+		return nil, ErrEmptyPostContent
+	}
+
 	post := &Post{
 		ID:        uuid.NewString(),
 		AuthorID:  req.AuthorID,
 		Content:   req.Content,
-		CreatedAt: time.Now(),
+		CreatedAt: time.Now().UTC(),
 	}
 
-	err := svc.postRepo.Insert(ctx, post)
+	err := svc.postRepo.Create(ctx, post, newRevision(post, req.AuthorID, post.CreatedAt), mentions.Extract(post.Content))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create post: %w", err)
 	}
@@ -54,13 +69,45 @@ func (svc *BaseService) CreatePost(ctx context.Context, req CreatePostRequest) (
 	return post, nil
 }
 
-func (svc *BaseService) ListPosts(ctx context.Context) ([]*Post, error) {
-	posts, err := svc.postRepo.List(ctx)
+type ListPostsResult struct {
+	Posts []*Post
+	// NextCursor points right after the last post of this page. It is empty on the last page.
+	NextCursor string
+}
+
+func (svc *BaseService) ListPosts(ctx context.Context, params ListPostsParams) (*ListPostsResult, error) {
+	if params.Cursor != "" {
+		_, err := ParsePostCursor(params.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cursor: %w", err)
+		}
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = DefaultListPostsLimit
+	}
+
+	limit = min(limit, MaxListPostsLimit)
+
+	// Fetch one extra post to find out whether there is a next page without a separate count query.
+	posts, err := svc.postRepo.List(ctx, &ListPostsParams{
+		AuthorID: params.AuthorID,
+		Cursor:   params.Cursor,
+		Limit:    limit + 1,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list posts: %w", err)
 	}
 
-	return posts, nil
+	result := &ListPostsResult{Posts: posts, NextCursor: ""}
+
+	if len(posts) > limit {
+		result.Posts = posts[:limit]
+		result.NextCursor = NewPostCursor(result.Posts[limit-1]).String()
+	}
+
+	return result, nil
 }
 
 func (svc *BaseService) GetPost(ctx context.Context, postID string) (*Post, error) {
@@ -71,3 +118,92 @@ func (svc *BaseService) GetPost(ctx context.Context, postID string) (*Post, erro
 
 	return post, nil
 }
+
+type UpdatePostRequest struct {
+	PostID   string
+	EditorID string
+	Content  string
+}
+
+func (svc *BaseService) UpdatePost(ctx context.Context, req UpdatePostRequest) (*Post, error) {
+	if req.Content == "" {
+		return nil, ErrEmptyPostContent
+	}
+
+	post, err := svc.postRepo.Find(ctx, req.PostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find post: %w", err)
+	}
+
+	if post.Content == req.Content {
+		return post, nil
+	}
+
+	post.Content = req.Content
+
+	err = svc.postRepo.Edit(ctx, post, newRevision(post, req.EditorID, time.Now().UTC()), mentions.Extract(post.Content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+
+	return post, nil
+}
+
+func (svc *BaseService) DeletePost(ctx context.Context, postID string) error {
+	err := svc.postRepo.Delete(ctx, postID)
+	if err != nil {
+		return fmt.Errorf("failed to delete post: %w", err)
+	}
+
+	return nil
+}
+
+type UpdateCommentSettingsRequest struct {
+	PostID string
+	Locked bool
+	// MinAccountAgeDays only lets users registered at least that many days ago comment. Zero lets everyone.
+	MinAccountAgeDays int
+}
+
+// UpdateCommentSettings locks or unlocks the comments of a post and sets how old accounts must be to comment on it.
+// Comments already there are left as they are.
+func (svc *BaseService) UpdateCommentSettings(ctx context.Context, req UpdateCommentSettingsRequest) (*Post, error) {
+	if req.MinAccountAgeDays < 0 {
+		return nil, InvalidMinAccountAgeError{Days: req.MinAccountAgeDays}
+	}
+
+	post, err := svc.postRepo.Find(ctx, req.PostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find post: %w", err)
+	}
+
+	post.CommentsLocked = req.Locked
+	post.CommentsMinAccountAgeDays = req.MinAccountAgeDays
+
+	err = svc.postRepo.Update(ctx, post)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+
+	return post, nil
+}
+
+func (svc *BaseService) ListPostRevisions(ctx context.Context, postID string) ([]*PostRevision, error) {
+	revisions, err := svc.postRevisionRepo.List(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list post revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// newRevision records the current content of a post, as the given editor left it at the given time.
+func newRevision(post *Post, editorID string, at time.Time) *PostRevision {
+	return &PostRevision{
+		ID:        uuid.NewString(),
+		PostID:    post.ID,
+		EditorID:  editorID,
+		Content:   post.Content,
+		CreatedAt: at,
+	}
+}
@@ -2,7 +2,10 @@ package contents
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -11,14 +14,25 @@ type Post struct {
 	AuthorID  string
 	Content   string
 	CreatedAt time.Time
+	// CommentsLocked and CommentsMinAccountAgeDays decide who may comment, as the discuss package reads them.
+	CommentsLocked            bool
+	CommentsMinAccountAgeDays int
 }
 
 type PostRepository interface {
-	Insert(ctx context.Context, post *Post) (err error)
+	// Create stores a new post and Edit the new content of one, each along with the revision that records the content
+	// and the users mentioned in it, in place of those mentioned before, all in one transaction.
+	Create(ctx context.Context, post *Post, revision *PostRevision, mentionedUsernames []string) (err error)
+	Edit(ctx context.Context, post *Post, revision *PostRevision, mentionedUsernames []string) (err error)
 	Find(ctx context.Context, postID string) (post *Post, err error)
-	List(ctx context.Context) (posts []*Post, err error)
+	List(ctx context.Context, params *ListPostsParams) (posts []*Post, err error)
+	Update(ctx context.Context, post *Post) (err error)
+	Delete(ctx context.Context, postID string) (err error)
 }
 
+// ErrEmptyPostContent is returned for a post created or edited without content.
+var ErrEmptyPostContent = errors.New("post content is empty")
+
 type PostNotFoundError struct {
 	ID string
 }
@@ -26,3 +40,69 @@ type PostNotFoundError struct {
 func (err PostNotFoundError) Error() string {
 	return fmt.Sprintf("post with id %q not found", err.ID)
 }
+
+const (
+	DefaultListPostsLimit = 20
+	MaxListPostsLimit     = 100
+)
+
+// ListPostsParams selects a page of posts ordered from newest to oldest. An empty Cursor starts from the newest post.
+// A non-empty AuthorID only lists the posts of that user.
+type ListPostsParams struct {
+	AuthorID string
+	Cursor   string
+	Limit    int
+}
+
+// PostCursor is the keyset position of a post in the feed. Posts are ordered by (CreatedAt, ID) so posts sharing a
+// timestamp still page deterministically.
+type PostCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+func NewPostCursor(post *Post) PostCursor {
+	return PostCursor{CreatedAt: post.CreatedAt, ID: post.ID}
+}
+
+// String encodes the cursor into an opaque, URL-safe token.
+func (cursor PostCursor) String() string {
+	raw := cursor.CreatedAt.Format(time.RFC3339Nano) + "|" + cursor.ID
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParsePostCursor(s string) (*PostCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, InvalidPostCursorError{Cursor: s}
+	}
+
+	createdAtStr, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, InvalidPostCursorError{Cursor: s}
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr)
+	if err != nil {
+		return nil, InvalidPostCursorError{Cursor: s}
+	}
+
+	return &PostCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+type InvalidPostCursorError struct {
+	Cursor string
+}
+
+func (err InvalidPostCursorError) Error() string {
+	return fmt.Sprintf("invalid post cursor %q", err.Cursor)
+}
+
+type InvalidMinAccountAgeError struct {
+	Days int
+}
+
+func (err InvalidMinAccountAgeError) Error() string {
+	return fmt.Sprintf("minimum account age of %d days is invalid", err.Days)
+}
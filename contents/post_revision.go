@@ -0,0 +1,19 @@
+package contents
+
+import (
+	"context"
+	"time"
+)
+
+// PostRevision is a snapshot of a post's content, taken each time the content changes.
+type PostRevision struct {
+	ID        string
+	PostID    string
+	EditorID  string
+	Content   string
+	CreatedAt time.Time
+}
+
+type PostRevisionRepository interface {
+	List(ctx context.Context, postID string) (revisions []*PostRevision, err error)
+}
@@ -0,0 +1,218 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nasermirzaei89/scribble/authentication"
+)
+
+const tableAPITokens = "api_tokens"
+
+type APITokenRepository struct {
+	db *sql.DB
+}
+
+var _ authentication.APITokenRepository = (*APITokenRepository)(nil)
+
+func NewAPITokenRepository(db *sql.DB) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+const (
+	apiTokenFieldID         = "id"
+	apiTokenFieldUserID     = "user_id"
+	apiTokenFieldName       = "name"
+	apiTokenFieldTokenHash  = "token_hash"
+	apiTokenFieldScopes     = "scopes"
+	apiTokenFieldCreatedAt  = "created_at"
+	apiTokenFieldExpiresAt  = "expires_at"
+	apiTokenFieldLastUsedAt = "last_used_at"
+)
+
+func apiTokenColumns() []string {
+	return []string{
+		apiTokenFieldID,
+		apiTokenFieldUserID,
+		apiTokenFieldName,
+		apiTokenFieldTokenHash,
+		apiTokenFieldScopes,
+		apiTokenFieldCreatedAt,
+		apiTokenFieldExpiresAt,
+		apiTokenFieldLastUsedAt,
+	}
+}
+
+func scanAPIToken(row sq.RowScanner) (*authentication.APIToken, error) {
+	var (
+		token  authentication.APIToken
+		scopes string
+	)
+
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.TokenHash,
+		&scopes,
+		&token.CreatedAt,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	token.Scopes = strings.Fields(scopes)
+
+	return &token, nil
+}
+
+func (repo *APITokenRepository) Insert(ctx context.Context, token *authentication.APIToken) error {
+	q := sq.Insert(tableAPITokens).
+		Columns(apiTokenColumns()...).
+		Values(
+			token.ID,
+			token.UserID,
+			token.Name,
+			token.TokenHash,
+			strings.Join(token.Scopes, " "),
+			token.CreatedAt,
+			token.ExpiresAt,
+			token.LastUsedAt,
+		)
+
+	q = q.RunWith(repo.db)
+
+	_, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec insert: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *APITokenRepository) FindByHash(ctx context.Context, tokenHash string) (*authentication.APIToken, error) {
+	q := sq.Select(apiTokenColumns()...).
+		From(tableAPITokens).
+		Where(sq.Eq{apiTokenFieldTokenHash: tokenHash})
+
+	q = q.RunWith(repo.db)
+
+	token, err := scanAPIToken(q.QueryRowContext(ctx))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &authentication.APITokenByHashNotFoundError{TokenHash: tokenHash}
+		}
+
+		return nil, fmt.Errorf("failed to scan api token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (repo *APITokenRepository) ListByUser(ctx context.Context, userID string) ([]*authentication.APIToken, error) {
+	q := sq.Select(apiTokenColumns()...).
+		From(tableAPITokens).
+		Where(sq.Eq{apiTokenFieldUserID: userID}).
+		OrderBy(apiTokenFieldCreatedAt + " DESC")
+
+	q = q.RunWith(repo.db)
+
+	rows, err := q.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close rows", "error", err)
+		}
+	}()
+
+	tokens := make([]*authentication.APIToken, 0)
+
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api token failed: %w", err)
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (repo *APITokenRepository) Delete(ctx context.Context, userID, tokenID string) error {
+	q := sq.Delete(tableAPITokens).
+		Where(sq.Eq{apiTokenFieldID: tokenID, apiTokenFieldUserID: userID})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &authentication.APITokenNotFoundError{ID: tokenID}
+	}
+
+	return nil
+}
+
+func (repo *APITokenRepository) DeleteByUser(ctx context.Context, userID string) error {
+	q := sq.Delete(tableAPITokens).
+		Where(sq.Eq{apiTokenFieldUserID: userID})
+
+	q = q.RunWith(repo.db)
+
+	_, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *APITokenRepository) UpdateLastUsed(ctx context.Context, tokenID string, lastUsedAt time.Time) error {
+	q := sq.Update(tableAPITokens).
+		Set(apiTokenFieldLastUsedAt, lastUsedAt).
+		Where(sq.Eq{apiTokenFieldID: tokenID})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &authentication.APITokenNotFoundError{ID: tokenID}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package sqlite3_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/database/sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokenRepository(t *testing.T) {
+	ctx, db := newTestDB(t)
+
+	userRepo := sqlite3.NewUserRepository(db)
+	repo := sqlite3.NewAPITokenRepository(db)
+
+	user := &authentication.User{
+		ID:           uuid.NewString(),
+		Username:     "token-user-" + uuid.NewString(),
+		PasswordHash: "password-hash",
+		RegisteredAt: time.Date(2026, 2, 24, 10, 0, 0, 0, time.UTC),
+	}
+
+	err := userRepo.Insert(ctx, user)
+	require.NoError(t, err)
+
+	expiresAt := time.Date(2026, 3, 24, 10, 0, 0, 0, time.UTC)
+
+	token1 := &authentication.APIToken{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		Name:      "laptop script",
+		TokenHash: authentication.HashAPIToken("secret-1"),
+		Scopes:    []string{authentication.ScopeRead, authentication.ScopeWrite},
+		CreatedAt: time.Date(2026, 2, 24, 11, 0, 0, 0, time.UTC),
+		ExpiresAt: &expiresAt,
+	}
+
+	token2 := &authentication.APIToken{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		Name:      "reader",
+		TokenHash: authentication.HashAPIToken("secret-2"),
+		Scopes:    []string{authentication.ScopeRead},
+		CreatedAt: time.Date(2026, 2, 24, 12, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("FindByHash not found", func(t *testing.T) {
+		_, err := repo.FindByHash(ctx, "missing")
+
+		var notFoundErr *authentication.APITokenByHashNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+	})
+
+	t.Run("Insert and find", func(t *testing.T) {
+		err := repo.Insert(ctx, token1)
+		require.NoError(t, err)
+
+		err = repo.Insert(ctx, token2)
+		require.NoError(t, err)
+
+		found, err := repo.FindByHash(ctx, token1.TokenHash)
+		require.NoError(t, err)
+		assert.Equal(t, token1.ID, found.ID)
+		assert.Equal(t, token1.Name, found.Name)
+		assert.Equal(t, token1.Scopes, found.Scopes)
+		require.NotNil(t, found.ExpiresAt)
+		assert.True(t, found.ExpiresAt.Equal(expiresAt))
+		assert.Nil(t, found.LastUsedAt)
+
+		found, err = repo.FindByHash(ctx, token2.TokenHash)
+		require.NoError(t, err)
+		assert.Nil(t, found.ExpiresAt)
+	})
+
+	t.Run("ListByUser newest first", func(t *testing.T) {
+		tokens, err := repo.ListByUser(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, tokens, 2)
+		assert.Equal(t, token2.ID, tokens[0].ID)
+		assert.Equal(t, token1.ID, tokens[1].ID)
+	})
+
+	t.Run("UpdateLastUsed", func(t *testing.T) {
+		lastUsedAt := time.Date(2026, 2, 25, 9, 0, 0, 0, time.UTC)
+
+		err := repo.UpdateLastUsed(ctx, token1.ID, lastUsedAt)
+		require.NoError(t, err)
+
+		found, err := repo.FindByHash(ctx, token1.TokenHash)
+		require.NoError(t, err)
+		require.NotNil(t, found.LastUsedAt)
+		assert.True(t, found.LastUsedAt.Equal(lastUsedAt))
+	})
+
+	t.Run("Delete is scoped to the owner", func(t *testing.T) {
+		var notFoundErr *authentication.APITokenNotFoundError
+
+		err := repo.Delete(ctx, uuid.NewString(), token1.ID)
+		require.ErrorAs(t, err, &notFoundErr)
+
+		err = repo.Delete(ctx, user.ID, token1.ID)
+		require.NoError(t, err)
+
+		_, err = repo.FindByHash(ctx, token1.TokenHash)
+
+		var byHashNotFoundErr *authentication.APITokenByHashNotFoundError
+
+		require.ErrorAs(t, err, &byHashNotFoundErr)
+	})
+
+	t.Run("DeleteByUser", func(t *testing.T) {
+		err := repo.DeleteByUser(ctx, user.ID)
+		require.NoError(t, err)
+
+		tokens, err := repo.ListByUser(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, tokens)
+	})
+}
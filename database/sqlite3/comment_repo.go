@@ -3,11 +3,14 @@ package sqlite3
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/nasermirzaei89/scribble/reactions"
 )
 
 const tableComments = "comments"
@@ -29,6 +32,8 @@ const (
 	commentFieldReplyTo   = "reply_to"
 	commentFieldContent   = "content"
 	commentFieldCreatedAt = "created_at"
+	commentFieldUpdatedAt = "updated_at"
+	commentFieldDeletedAt = "deleted_at"
 )
 
 func commentColumns() []string {
@@ -39,6 +44,8 @@ func commentColumns() []string {
 		commentFieldReplyTo,
 		commentFieldContent,
 		commentFieldCreatedAt,
+		commentFieldUpdatedAt,
+		commentFieldDeletedAt,
 	}
 }
 
@@ -52,6 +59,8 @@ func scanComment(row sq.RowScanner) (*discuss.Comment, error) {
 		&comment.ReplyTo,
 		&comment.Content,
 		&comment.CreatedAt,
+		&comment.UpdatedAt,
+		&comment.DeletedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan row: %w", err)
@@ -70,6 +79,8 @@ func (repo *CommentRepository) Insert(ctx context.Context, comment *discuss.Comm
 			comment.ReplyTo,
 			comment.Content,
 			comment.CreatedAt,
+			comment.UpdatedAt,
+			comment.DeletedAt,
 		)
 
 	q = q.RunWith(repo.db)
@@ -82,18 +93,142 @@ func (repo *CommentRepository) Insert(ctx context.Context, comment *discuss.Comm
 	return nil
 }
 
+func (repo *CommentRepository) Find(ctx context.Context, commentID string) (*discuss.Comment, error) {
+	q := sq.Select(commentColumns()...).
+		From(tableComments).
+		Where(sq.Eq{commentFieldID: commentID})
+
+	q = q.RunWith(repo.db)
+
+	row := q.QueryRowContext(ctx)
+
+	comment, err := scanComment(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, discuss.CommentNotFoundError{ID: commentID}
+		}
+
+		return nil, fmt.Errorf("failed to scan comment: %w", err)
+	}
+
+	return comment, nil
+}
+
 func (repo *CommentRepository) List(
 	ctx context.Context,
 	params *discuss.ListCommentsParams,
 ) ([]*discuss.Comment, error) {
 	query := sq.Select(commentColumns()...).
-		From(tableComments).
-		OrderBy(commentFieldCreatedAt + " ASC")
+		From(tableComments)
 
 	if params.PostID != "" {
 		query = query.Where(sq.Eq{commentFieldPostID: params.PostID})
 	}
 
+	if params.ParentID != "" {
+		query = query.Where(sq.Eq{commentFieldReplyTo: params.ParentID})
+	} else {
+		query = query.Where(sq.Eq{commentFieldReplyTo: nil})
+	}
+
+	var cursor *discuss.CommentCursor
+
+	if params.Cursor != "" {
+		var err error
+
+		cursor, err = discuss.ParseCommentCursor(params.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cursor: %w", err)
+		}
+	}
+
+	switch params.Sort {
+	case discuss.CommentSortNewest:
+		query = query.OrderBy(commentFieldCreatedAt+" DESC", commentFieldID+" DESC")
+
+		if cursor != nil {
+			query = query.Where(sq.Or{
+				sq.Lt{commentFieldCreatedAt: cursor.CreatedAt},
+				sq.And{sq.Eq{commentFieldCreatedAt: cursor.CreatedAt}, sq.Lt{commentFieldID: cursor.ID}},
+			})
+		}
+	case discuss.CommentSortTop:
+		query = query.OrderBy(commentReactionsCount()+" DESC", commentFieldCreatedAt+" ASC", commentFieldID+" ASC")
+
+		if cursor != nil && params.Limit > 0 {
+			query = query.Offset(uint64(cursor.Offset)) //nolint:gosec // cursors can't hold negative offsets
+		}
+	default:
+		query = query.OrderBy(commentFieldCreatedAt+" ASC", commentFieldID+" ASC")
+
+		if cursor != nil {
+			query = query.Where(sq.Or{
+				sq.Gt{commentFieldCreatedAt: cursor.CreatedAt},
+				sq.And{sq.Eq{commentFieldCreatedAt: cursor.CreatedAt}, sq.Gt{commentFieldID: cursor.ID}},
+			})
+		}
+	}
+
+	switch {
+	case params.Limit > 0:
+		query = query.Limit(uint64(params.Limit)) //nolint:gosec // checked to be positive above
+	case cursor != nil && cursor.Offset > 0:
+		// SQLite takes no OFFSET without a LIMIT, and a negative one means none.
+		query = query.Suffix("LIMIT -1 OFFSET ?", cursor.Offset)
+	}
+
+	return repo.query(ctx, query)
+}
+
+// commentReactionsCount is an SQL expression for the number of reactions to the comment of the current row.
+func commentReactionsCount() string {
+	return fmt.Sprintf(
+		"(SELECT COUNT(*) FROM %s WHERE %s.%s = '%s' AND %s.%s = %s.%s)",
+		tableReactions,
+		tableReactions, userReactionFieldTargetType, reactions.TargetTypeComment,
+		tableReactions, userReactionFieldTargetID, tableComments, commentFieldID,
+	)
+}
+
+func (repo *CommentRepository) ListReplies(ctx context.Context, parentIDs []string) ([]*discuss.Comment, error) {
+	if len(parentIDs) == 0 {
+		return make([]*discuss.Comment, 0), nil
+	}
+
+	query := sq.Select(commentColumns()...).
+		From(tableComments).
+		Where(sq.Eq{commentFieldReplyTo: parentIDs}).
+		OrderBy(commentFieldCreatedAt+" ASC", commentFieldID+" ASC")
+
+	return repo.query(ctx, query)
+}
+
+// ListAncestors follows reply_to up the thread with a recursive query, so a deep thread still takes a single one.
+func (repo *CommentRepository) ListAncestors(ctx context.Context, commentID string) ([]*discuss.Comment, error) {
+	columns := commentColumns()
+	for i, column := range columns {
+		columns[i] = tableComments + "." + column
+	}
+
+	query := sq.Select(columns...).
+		Prefix(
+			"WITH RECURSIVE ancestors (id, depth) AS ("+
+				"SELECT "+commentFieldReplyTo+", 1 FROM "+tableComments+
+				" WHERE "+commentFieldID+" = ? AND "+commentFieldReplyTo+" IS NOT NULL"+
+				" UNION ALL "+
+				"SELECT c."+commentFieldReplyTo+", a.depth + 1 FROM "+tableComments+" c"+
+				" JOIN ancestors a ON c."+commentFieldID+" = a.id"+
+				" WHERE c."+commentFieldReplyTo+" IS NOT NULL)",
+			commentID,
+		).
+		From(tableComments).
+		Join("ancestors ON ancestors.id = " + tableComments + "." + commentFieldID).
+		OrderBy("ancestors.depth DESC")
+
+	return repo.query(ctx, query)
+}
+
+func (repo *CommentRepository) query(ctx context.Context, query sq.SelectBuilder) ([]*discuss.Comment, error) {
 	query = query.RunWith(repo.db)
 
 	rows, err := query.QueryContext(ctx)
@@ -132,7 +267,8 @@ func (repo *CommentRepository) Count(
 	params *discuss.CountCommentsParams,
 ) (int, error) {
 	query := sq.Select("COUNT(*)").
-		From(tableComments)
+		From(tableComments).
+		Where(sq.Eq{commentFieldDeletedAt: nil})
 
 	if params.PostID != "" {
 		query = query.Where(sq.Eq{commentFieldPostID: params.PostID})
@@ -149,3 +285,192 @@ func (repo *CommentRepository) Count(
 
 	return count, nil
 }
+
+func (repo *CommentRepository) CountByPosts(ctx context.Context, postIDs []string) (map[string]int, error) {
+	return repo.countGrouped(ctx, commentFieldPostID, postIDs, sq.Eq{commentFieldDeletedAt: nil})
+}
+
+func (repo *CommentRepository) CountReplies(ctx context.Context, commentIDs []string) (map[string]int, error) {
+	return repo.countGrouped(ctx, commentFieldReplyTo, commentIDs, sq.Eq{})
+}
+
+// countGrouped counts the comments matching where for each of the given values of field.
+func (repo *CommentRepository) countGrouped(
+	ctx context.Context,
+	field string,
+	values []string,
+	where sq.Eq,
+) (map[string]int, error) {
+	counts := make(map[string]int, len(values))
+
+	if len(values) == 0 {
+		return counts, nil
+	}
+
+	where[field] = values
+
+	query := sq.Select(field, "COUNT(*)").
+		From(tableComments).
+		Where(where).
+		GroupBy(field)
+
+	query = query.RunWith(repo.db)
+
+	rows, err := query.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close rows", "error", err)
+		}
+	}()
+
+	for rows.Next() {
+		var value string
+
+		var count int
+
+		err := rows.Scan(&value, &count)
+		if err != nil {
+			return nil, fmt.Errorf("scan count failed: %w", err)
+		}
+
+		counts[value] = count
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (repo *CommentRepository) Update(ctx context.Context, comment *discuss.Comment) error {
+	q := sq.Update(tableComments).
+		Set(commentFieldContent, comment.Content).
+		Set(commentFieldUpdatedAt, comment.UpdatedAt).
+		Set(commentFieldDeletedAt, comment.DeletedAt).
+		Where(sq.Eq{commentFieldID: comment.ID})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return discuss.CommentNotFoundError{ID: comment.ID}
+	}
+
+	return nil
+}
+
+func (repo *CommentRepository) Tombstone(ctx context.Context, commentID string, deletedAt time.Time) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.ErrorContext(ctx, "failed to rollback transaction", "error", err)
+		}
+	}()
+
+	result, err := sq.Update(tableComments).
+		Set(commentFieldContent, "").
+		Set(commentFieldDeletedAt, deletedAt).
+		Where(sq.Eq{commentFieldID: commentID}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	err = deleteCommentLeftovers(ctx, tx, commentID, result)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *CommentRepository) Delete(ctx context.Context, commentID string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.ErrorContext(ctx, "failed to rollback transaction", "error", err)
+		}
+	}()
+
+	result, err := sq.Delete(tableComments).
+		Where(sq.Eq{commentFieldID: commentID}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	err = deleteCommentLeftovers(ctx, tx, commentID, result)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// deleteCommentLeftovers removes the reactions to a comment and the mentions in it, once result shows the comment
+// itself was found. Foreign keys aren't enforced on the connection to do it.
+func deleteCommentLeftovers(ctx context.Context, tx *sql.Tx, commentID string, result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return discuss.CommentNotFoundError{ID: commentID}
+	}
+
+	deletes := []sq.DeleteBuilder{
+		sq.Delete(tableReactions).Where(sq.Eq{
+			userReactionFieldTargetType: string(reactions.TargetTypeComment),
+			userReactionFieldTargetID:   commentID,
+		}),
+		sq.Delete(tableMentions).Where(sq.Eq{mentionFieldCommentID: commentID}),
+	}
+
+	for _, q := range deletes {
+		err = execDelete(ctx, tx, q)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/nasermirzaei89/scribble/contents"
 	"github.com/nasermirzaei89/scribble/database/sqlite3"
 	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/nasermirzaei89/scribble/reactions"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -98,16 +99,33 @@ func TestCommentRepository(t *testing.T) {
 
 		comments, err := commentRepo.List(ctx, &discuss.ListCommentsParams{})
 		require.NoError(t, err)
-		assert.Len(t, comments, 3)
+		assert.Len(t, comments, 2)
 		assert.Equal(t, comment1.ID, comments[0].ID)
-		assert.Equal(t, comment2.ID, comments[1].ID)
-		assert.Equal(t, comment3.ID, comments[2].ID)
+		assert.Equal(t, comment3.ID, comments[1].ID)
 
 		post1Comments, err := commentRepo.List(ctx, &discuss.ListCommentsParams{PostID: post1.ID})
 		require.NoError(t, err)
-		assert.Len(t, post1Comments, 2)
+		require.Len(t, post1Comments, 1)
 		assert.Equal(t, comment1.ID, post1Comments[0].ID)
-		assert.Equal(t, comment2.ID, post1Comments[1].ID)
+
+		replies, err := commentRepo.List(ctx, &discuss.ListCommentsParams{PostID: post1.ID, ParentID: comment1.ID})
+		require.NoError(t, err)
+		require.Len(t, replies, 1)
+		assert.Equal(t, comment2.ID, replies[0].ID)
+
+		replies, err = commentRepo.ListReplies(ctx, []string{comment1.ID, comment3.ID})
+		require.NoError(t, err)
+		require.Len(t, replies, 1)
+		assert.Equal(t, comment2.ID, replies[0].ID)
+
+		ancestors, err := commentRepo.ListAncestors(ctx, comment2.ID)
+		require.NoError(t, err)
+		require.Len(t, ancestors, 1)
+		assert.Equal(t, comment1.ID, ancestors[0].ID)
+
+		ancestors, err = commentRepo.ListAncestors(ctx, comment1.ID)
+		require.NoError(t, err)
+		assert.Empty(t, ancestors)
 
 		countAll, err := commentRepo.Count(ctx, &discuss.CountCommentsParams{})
 		require.NoError(t, err)
@@ -117,4 +135,206 @@ func TestCommentRepository(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, 2, countPost1)
 	})
+
+	t.Run("CountByPosts", func(t *testing.T) {
+		emptyPostID := uuid.NewString()
+
+		counts, err := commentRepo.CountByPosts(ctx, []string{post1.ID, post2.ID, emptyPostID})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{post1.ID: 2, post2.ID: 1}, counts)
+
+		counts, err = commentRepo.CountByPosts(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, counts)
+	})
+
+	t.Run("Find update and delete", func(t *testing.T) {
+		parent := &discuss.Comment{
+			ID:        uuid.NewString(),
+			PostID:    post2.ID,
+			AuthorID:  user.ID,
+			Content:   "parent",
+			CreatedAt: time.Date(2026, 2, 24, 16, 0, 0, 0, time.UTC),
+		}
+
+		replyTo := parent.ID
+		reply := &discuss.Comment{
+			ID:        uuid.NewString(),
+			PostID:    post2.ID,
+			AuthorID:  user.ID,
+			ReplyTo:   &replyTo,
+			Content:   "reply",
+			CreatedAt: time.Date(2026, 2, 24, 17, 0, 0, 0, time.UTC),
+		}
+
+		require.NoError(t, commentRepo.Insert(ctx, parent))
+		require.NoError(t, commentRepo.Insert(ctx, reply))
+
+		replyCounts, err := commentRepo.CountReplies(ctx, []string{parent.ID, reply.ID})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{parent.ID: 1}, replyCounts)
+
+		deletedAt := time.Date(2026, 2, 24, 18, 0, 0, 0, time.UTC)
+		parent.Content = ""
+		parent.DeletedAt = &deletedAt
+
+		err = commentRepo.Update(ctx, parent)
+		require.NoError(t, err)
+
+		found, err := commentRepo.Find(ctx, parent.ID)
+		require.NoError(t, err)
+		assert.True(t, found.IsDeleted())
+		assert.Empty(t, found.Content)
+		assert.Nil(t, found.UpdatedAt)
+
+		// Tombstones are listed, so the thread holds together, but not counted.
+		post2Comments, err := commentRepo.List(ctx, &discuss.ListCommentsParams{PostID: post2.ID})
+		require.NoError(t, err)
+		require.Len(t, post2Comments, 2)
+		assert.Equal(t, parent.ID, post2Comments[1].ID)
+
+		counts, err := commentRepo.CountByPosts(ctx, []string{post2.ID})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{post2.ID: 2}, counts)
+
+		countPost2, err := commentRepo.Count(ctx, &discuss.CountCommentsParams{PostID: post2.ID})
+		require.NoError(t, err)
+		assert.Equal(t, 2, countPost2)
+
+		err = commentRepo.Delete(ctx, reply.ID)
+		require.NoError(t, err)
+
+		_, err = commentRepo.Find(ctx, reply.ID)
+		require.ErrorAs(t, err, new(discuss.CommentNotFoundError))
+
+		err = commentRepo.Delete(ctx, reply.ID)
+		require.ErrorAs(t, err, new(discuss.CommentNotFoundError))
+
+		err = commentRepo.Update(ctx, reply)
+		require.ErrorAs(t, err, new(discuss.CommentNotFoundError))
+	})
+
+	t.Run("Tombstone and Delete remove reactions and mentions", func(t *testing.T) {
+		at := time.Date(2026, 2, 24, 19, 0, 0, 0, time.UTC)
+		reactionRepo := sqlite3.NewUserReactionRepository(db)
+		mentionRepo := sqlite3.NewMentionRepository(db)
+
+		newComment := func() *discuss.Comment {
+			comment := &discuss.Comment{
+				ID:        uuid.NewString(),
+				PostID:    post1.ID,
+				AuthorID:  user.ID,
+				Content:   "hi @" + user.Username,
+				CreatedAt: at,
+			}
+			require.NoError(t, commentRepo.Insert(ctx, comment))
+
+			require.NoError(t, reactionRepo.Upsert(ctx, &reactions.UserReaction{
+				TargetType: reactions.TargetTypeComment,
+				TargetID:   comment.ID,
+				UserID:     user.ID,
+				Emoji:      "👍",
+				CreatedAt:  at,
+			}))
+			require.NoError(t, mentionRepo.Replace(ctx, post1.ID, &comment.ID, []string{user.Username}, at))
+
+			return comment
+		}
+
+		assertLeftoversRemoved := func(t *testing.T, commentID string) {
+			t.Helper()
+
+			counts, err := reactionRepo.CountByTarget(ctx, reactions.TargetTypeComment, commentID)
+			require.NoError(t, err)
+			assert.Empty(t, counts)
+
+			var mentionCount int
+
+			err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM mentions WHERE comment_id = ?", commentID).
+				Scan(&mentionCount)
+			require.NoError(t, err)
+			assert.Zero(t, mentionCount)
+		}
+
+		tombstoned := newComment()
+		require.NoError(t, commentRepo.Tombstone(ctx, tombstoned.ID, at.Add(time.Hour)))
+
+		found, err := commentRepo.Find(ctx, tombstoned.ID)
+		require.NoError(t, err)
+		assert.True(t, found.IsDeleted())
+		assert.Empty(t, found.Content)
+		assertLeftoversRemoved(t, tombstoned.ID)
+
+		deleted := newComment()
+		require.NoError(t, commentRepo.Delete(ctx, deleted.ID))
+		assertLeftoversRemoved(t, deleted.ID)
+
+		err = commentRepo.Tombstone(ctx, deleted.ID, at)
+		require.ErrorAs(t, err, new(discuss.CommentNotFoundError))
+	})
+
+	t.Run("List sorted and paged", func(t *testing.T) {
+		post := &contents.Post{
+			ID:        uuid.NewString(),
+			AuthorID:  user.ID,
+			Content:   "post for sorted comments",
+			CreatedAt: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		}
+		require.NoError(t, postRepo.Insert(ctx, post))
+
+		comments := make([]*discuss.Comment, 3)
+		for i := range comments {
+			comments[i] = &discuss.Comment{
+				ID:        uuid.NewString(),
+				PostID:    post.ID,
+				AuthorID:  user.ID,
+				Content:   "sorted comment",
+				CreatedAt: time.Date(2026, 3, 1, 11+i, 0, 0, 0, time.UTC),
+			}
+			require.NoError(t, commentRepo.Insert(ctx, comments[i]))
+		}
+
+		reactionRepo := sqlite3.NewUserReactionRepository(db)
+		for _, userID := range []string{uuid.NewString(), uuid.NewString()} {
+			require.NoError(t, reactionRepo.Upsert(ctx, &reactions.UserReaction{
+				TargetType: reactions.TargetTypeComment,
+				TargetID:   comments[1].ID,
+				UserID:     userID,
+				Emoji:      "👍",
+				CreatedAt:  time.Date(2026, 3, 1, 15, 0, 0, 0, time.UTC),
+			}))
+		}
+
+		list := func(sort discuss.CommentSort, cursor string, limit int) []string {
+			t.Helper()
+
+			page, err := commentRepo.List(ctx, &discuss.ListCommentsParams{
+				PostID: post.ID,
+				Sort:   sort,
+				Cursor: cursor,
+				Limit:  limit,
+			})
+			require.NoError(t, err)
+
+			ids := make([]string, 0, len(page))
+			for _, comment := range page {
+				ids = append(ids, comment.ID)
+			}
+
+			return ids
+		}
+
+		assert.Equal(t, []string{comments[0].ID, comments[1].ID, comments[2].ID}, list(discuss.CommentSortOldest, "", 0))
+		assert.Equal(t, []string{comments[2].ID, comments[1].ID, comments[0].ID}, list(discuss.CommentSortNewest, "", 0))
+		assert.Equal(t, []string{comments[1].ID, comments[0].ID, comments[2].ID}, list(discuss.CommentSortTop, "", 0))
+
+		cursor := discuss.NewCommentCursor(comments[0]).String()
+		assert.Equal(t, []string{comments[1].ID}, list(discuss.CommentSortOldest, cursor, 1))
+
+		cursor = discuss.NewCommentCursor(comments[2]).String()
+		assert.Equal(t, []string{comments[1].ID, comments[0].ID}, list(discuss.CommentSortNewest, cursor, 0))
+
+		cursor = discuss.CommentCursor{Offset: 2}.String()
+		assert.Equal(t, []string{comments[2].ID}, list(discuss.CommentSortTop, cursor, 0))
+	})
 }
@@ -7,10 +7,15 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 
+	sq "github.com/Masterminds/squirrel"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/nasermirzaei89/scribble/authentication"
 	_ "modernc.org/sqlite"
 )
 
@@ -68,6 +73,125 @@ func MigrateUp(ctx context.Context, db *sql.DB) error {
 
 	slog.InfoContext(ctx, "migration applied successfully", "version", version, "dirty", dirty)
 
+	err = canonicalizeUsernames(ctx, db)
+	if err != nil {
+		return fmt.Errorf("failed to canonicalize usernames: %w", err)
+	}
+
+	return nil
+}
+
+// UsernameCollisionError lists existing accounts whose usernames become equal once canonicalized, keyed by the
+// canonical form. They have to be renamed before the canonical column can be filled in.
+type UsernameCollisionError struct {
+	Collisions map[string][]string
+}
+
+func (err UsernameCollisionError) Error() string {
+	groups := make([]string, 0, len(err.Collisions))
+
+	for _, canonical := range slices.Sorted(maps.Keys(err.Collisions)) {
+		groups = append(groups, fmt.Sprintf("%q: %q", canonical, err.Collisions[canonical]))
+	}
+
+	return "usernames collide once canonicalized: " + strings.Join(groups, ", ")
+}
+
+// canonicalizeUsernames fills in users.username_canonical for rows that don't have it yet. SQLite can't normalize
+// Unicode itself, so this runs in Go after the migrations. If any two accounts end up with the same canonical
+// username, nothing is written and the collisions are reported instead.
+func canonicalizeUsernames(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.ErrorContext(ctx, "failed to rollback transaction", "error", err)
+		}
+	}()
+
+	q := sq.Select(userFieldID, userFieldUsername, userFieldCanonical).
+		From(tableUsers).
+		RunWith(tx)
+
+	rows, err := q.QueryContext(ctx)
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	usernames := make(map[string][]string)
+	pending := make(map[string]string)
+
+	for rows.Next() {
+		var (
+			id, username string
+			canonical    sql.NullString
+		)
+
+		err := rows.Scan(&id, &username, &canonical)
+		if err != nil {
+			_ = rows.Close()
+
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if !canonical.Valid {
+			canonical.String = authentication.CanonicalUsername(username)
+			pending[id] = canonical.String
+		}
+
+		usernames[canonical.String] = append(usernames[canonical.String], username)
+	}
+
+	err = rows.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close rows: %w", err)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	collisions := make(map[string][]string)
+
+	for canonical, group := range usernames {
+		if len(group) > 1 {
+			slog.ErrorContext(ctx, "usernames collide once canonicalized", "canonical", canonical, "usernames", group)
+
+			collisions[canonical] = group
+		}
+	}
+
+	if len(collisions) > 0 {
+		return &UsernameCollisionError{Collisions: collisions}
+	}
+
+	for id, canonical := range pending {
+		_, err := sq.Update(tableUsers).
+			Set(userFieldCanonical, canonical).
+			Where(sq.Eq{userFieldID: id}).
+			RunWith(tx).
+			ExecContext(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to exec update: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.InfoContext(ctx, "usernames canonicalized", "count", len(pending))
+
 	return nil
 }
 
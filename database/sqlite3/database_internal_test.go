@@ -0,0 +1,62 @@
+package sqlite3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalizeUsernames(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := NewDB(ctx, "file:TestCanonicalizeUsernames?mode=memory&cache=shared")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		err := MigrateDown(db)
+		require.NoError(t, err)
+
+		err = db.Close()
+		require.NoError(t, err)
+	})
+
+	m, err := getMigrateInstance(db)
+	require.NoError(t, err)
+
+	// Go back to the schema before canonical usernames existed and register colliding accounts.
+	const versionBeforeCanonicalUsernames = 9
+
+	err = m.Migrate(versionBeforeCanonicalUsernames)
+	require.NoError(t, err)
+
+	for id, username := range map[string]string{"1": "Alice", "2": "alice", "3": "ａｌｉｃｅ", "4": "bob"} {
+		_, err := db.ExecContext(ctx, "INSERT INTO users (id, username, password_hash) VALUES (?, ?, '')", id, username)
+		require.NoError(t, err)
+	}
+
+	err = MigrateUp(ctx, db)
+
+	collisionErr := &UsernameCollisionError{}
+	require.ErrorAs(t, err, &collisionErr)
+	assert.Len(t, collisionErr.Collisions, 1)
+	assert.ElementsMatch(t, []string{"Alice", "alice", "ａｌｉｃｅ"}, collisionErr.Collisions["alice"])
+
+	var pending int
+
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE username_canonical IS NULL").Scan(&pending)
+	require.NoError(t, err)
+	assert.Equal(t, 4, pending, "nothing is written while collisions remain")
+
+	_, err = db.ExecContext(ctx, "DELETE FROM users WHERE id IN ('2', '3')")
+	require.NoError(t, err)
+
+	err = MigrateUp(ctx, db)
+	require.NoError(t, err)
+
+	user, err := NewUserRepository(db).FindByUsername(ctx, "ALICE")
+	require.NoError(t, err)
+	assert.Equal(t, "1", user.ID)
+	assert.Equal(t, "Alice", user.Username)
+}
@@ -0,0 +1,194 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nasermirzaei89/scribble/authentication"
+)
+
+const tableUserIdentities = "user_identities"
+
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+var _ authentication.IdentityRepository = (*IdentityRepository)(nil)
+
+func NewIdentityRepository(db *sql.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+const (
+	identityFieldProvider    = "provider"
+	identityFieldSubject     = "subject"
+	identityFieldUserID      = "user_id"
+	identityFieldEmail       = "email"
+	identityFieldCreatedAt   = "created_at"
+	identityFieldLastLoginAt = "last_login_at"
+)
+
+func identityColumns() []string {
+	return []string{
+		identityFieldProvider,
+		identityFieldSubject,
+		identityFieldUserID,
+		identityFieldEmail,
+		identityFieldCreatedAt,
+		identityFieldLastLoginAt,
+	}
+}
+
+func scanIdentity(row sq.RowScanner) (*authentication.Identity, error) {
+	var identity authentication.Identity
+
+	err := row.Scan(
+		&identity.Provider,
+		&identity.Subject,
+		&identity.UserID,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	return &identity, nil
+}
+
+func (repo *IdentityRepository) Insert(ctx context.Context, identity *authentication.Identity) error {
+	q := sq.Insert(tableUserIdentities).
+		Columns(identityColumns()...).
+		Values(
+			identity.Provider,
+			identity.Subject,
+			identity.UserID,
+			identity.Email,
+			identity.CreatedAt,
+			identity.LastLoginAt,
+		)
+
+	q = q.RunWith(repo.db)
+
+	_, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec insert: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *IdentityRepository) Find(ctx context.Context, provider, subject string) (*authentication.Identity, error) {
+	q := sq.Select(identityColumns()...).
+		From(tableUserIdentities).
+		Where(sq.Eq{identityFieldProvider: provider, identityFieldSubject: subject})
+
+	q = q.RunWith(repo.db)
+
+	identity, err := scanIdentity(q.QueryRowContext(ctx))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &authentication.IdentityNotFoundError{Provider: provider, Subject: subject}
+		}
+
+		return nil, fmt.Errorf("failed to scan identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+func (repo *IdentityRepository) ListByUser(ctx context.Context, userID string) ([]*authentication.Identity, error) {
+	q := sq.Select(identityColumns()...).
+		From(tableUserIdentities).
+		Where(sq.Eq{identityFieldUserID: userID}).
+		OrderBy(identityFieldCreatedAt)
+
+	q = q.RunWith(repo.db)
+
+	rows, err := q.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close rows", "error", err)
+		}
+	}()
+
+	identities := make([]*authentication.Identity, 0)
+
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan identity failed: %w", err)
+		}
+
+		identities = append(identities, identity)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return identities, nil
+}
+
+func (repo *IdentityRepository) UpdateLastLogin(
+	ctx context.Context,
+	provider, subject string,
+	loginAt time.Time,
+) error {
+	q := sq.Update(tableUserIdentities).
+		Set(identityFieldLastLoginAt, loginAt).
+		Where(sq.Eq{identityFieldProvider: provider, identityFieldSubject: subject})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &authentication.IdentityNotFoundError{Provider: provider, Subject: subject}
+	}
+
+	return nil
+}
+
+func (repo *IdentityRepository) Delete(ctx context.Context, userID, provider string) error {
+	q := sq.Delete(tableUserIdentities).
+		Where(sq.Eq{identityFieldUserID: userID, identityFieldProvider: provider})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &authentication.IdentityNotFoundError{Provider: provider, Subject: ""}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package sqlite3_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/database/sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityRepository(t *testing.T) {
+	ctx, db := newTestDB(t)
+
+	repo := sqlite3.NewIdentityRepository(db)
+	user := insertTwoFactorTestUser(ctx, t, db)
+	other := insertTwoFactorTestUser(ctx, t, db)
+	createdAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	identity := &authentication.Identity{
+		Provider:    "google",
+		Subject:     "1234567890",
+		UserID:      user.ID,
+		Email:       "user@example.com",
+		CreatedAt:   createdAt,
+		LastLoginAt: nil,
+	}
+
+	t.Run("Find not found", func(t *testing.T) {
+		_, err := repo.Find(ctx, identity.Provider, identity.Subject)
+
+		var notFoundErr *authentication.IdentityNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.Equal(t, identity.Provider, notFoundErr.Provider)
+		assert.Equal(t, identity.Subject, notFoundErr.Subject)
+	})
+
+	t.Run("Insert and Find", func(t *testing.T) {
+		err := repo.Insert(ctx, identity)
+		require.NoError(t, err)
+
+		found, err := repo.Find(ctx, identity.Provider, identity.Subject)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.UserID)
+		assert.Equal(t, "user@example.com", found.Email)
+		assert.Nil(t, found.LastLoginAt)
+
+		// The same subject at another provider is another account.
+		_, err = repo.Find(ctx, "gitlab", identity.Subject)
+
+		var notFoundErr *authentication.IdentityNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+	})
+
+	t.Run("Insert rejects a second account at the same provider", func(t *testing.T) {
+		second := *identity
+		second.Subject = "another"
+
+		err := repo.Insert(ctx, &second)
+		require.Error(t, err)
+
+		second.UserID = other.ID
+
+		err = repo.Insert(ctx, &second)
+		require.NoError(t, err)
+	})
+
+	t.Run("ListByUser", func(t *testing.T) {
+		gitlab := *identity
+		gitlab.Provider = "gitlab"
+		gitlab.CreatedAt = createdAt.Add(time.Hour)
+
+		err := repo.Insert(ctx, &gitlab)
+		require.NoError(t, err)
+
+		identities, err := repo.ListByUser(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, identities, 2)
+		assert.Equal(t, "google", identities[0].Provider)
+		assert.Equal(t, "gitlab", identities[1].Provider)
+	})
+
+	t.Run("UpdateLastLogin", func(t *testing.T) {
+		loginAt := createdAt.Add(2 * time.Hour)
+
+		err := repo.UpdateLastLogin(ctx, identity.Provider, identity.Subject, loginAt)
+		require.NoError(t, err)
+
+		found, err := repo.Find(ctx, identity.Provider, identity.Subject)
+		require.NoError(t, err)
+		require.NotNil(t, found.LastLoginAt)
+		assert.True(t, found.LastLoginAt.Equal(loginAt))
+
+		err = repo.UpdateLastLogin(ctx, identity.Provider, "missing", loginAt)
+
+		var notFoundErr *authentication.IdentityNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		err := repo.Delete(ctx, user.ID, "google")
+		require.NoError(t, err)
+
+		err = repo.Delete(ctx, user.ID, "google")
+
+		var notFoundErr *authentication.IdentityNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+
+		// The other user's account at the same provider is untouched.
+		identities, err := repo.ListByUser(ctx, other.ID)
+		require.NoError(t, err)
+		assert.Len(t, identities, 1)
+	})
+}
@@ -0,0 +1,179 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nasermirzaei89/scribble/authentication"
+)
+
+const tableLoginAttempts = "login_attempts"
+
+type LoginAttemptRepository struct {
+	db *sql.DB
+}
+
+var _ authentication.LoginAttemptRepository = (*LoginAttemptRepository)(nil)
+
+func NewLoginAttemptRepository(db *sql.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+const (
+	loginAttemptFieldScope        = "scope"
+	loginAttemptFieldSubject      = "subject"
+	loginAttemptFieldFailures     = "failures"
+	loginAttemptFieldLastFailedAt = "last_failed_at"
+	loginAttemptFieldBlockedUntil = "blocked_until"
+)
+
+func loginAttemptColumns() []string {
+	return []string{
+		loginAttemptFieldScope,
+		loginAttemptFieldSubject,
+		loginAttemptFieldFailures,
+		loginAttemptFieldLastFailedAt,
+		loginAttemptFieldBlockedUntil,
+	}
+}
+
+func scanLoginAttempt(row sq.RowScanner) (*authentication.LoginAttempt, error) {
+	var attempt authentication.LoginAttempt
+
+	err := row.Scan(
+		&attempt.Scope,
+		&attempt.Subject,
+		&attempt.Failures,
+		&attempt.LastFailedAt,
+		&attempt.BlockedUntil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	return &attempt, nil
+}
+
+func (repo *LoginAttemptRepository) Find(
+	ctx context.Context,
+	scope, subject string,
+) (*authentication.LoginAttempt, error) {
+	q := sq.Select(loginAttemptColumns()...).
+		From(tableLoginAttempts).
+		Where(sq.Eq{loginAttemptFieldScope: scope, loginAttemptFieldSubject: subject})
+
+	q = q.RunWith(repo.db)
+
+	row := q.QueryRowContext(ctx)
+
+	attempt, err := scanLoginAttempt(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &authentication.LoginAttemptNotFoundError{Scope: scope, Subject: subject}
+		}
+
+		return nil, fmt.Errorf("failed to scan login attempt: %w", err)
+	}
+
+	return attempt, nil
+}
+
+// RecordFailure upserts and increments the count in one statement, so concurrent failures are all counted.
+func (repo *LoginAttemptRepository) RecordFailure(
+	ctx context.Context,
+	scope, subject string,
+	failedAt, resetBefore time.Time,
+) (int, error) {
+	q := sq.Insert(tableLoginAttempts).
+		Columns(
+			loginAttemptFieldScope,
+			loginAttemptFieldSubject,
+			loginAttemptFieldFailures,
+			loginAttemptFieldLastFailedAt,
+		).
+		Values(scope, subject, 1, failedAt).
+		Suffix(
+			"ON CONFLICT ("+loginAttemptFieldScope+", "+loginAttemptFieldSubject+") DO UPDATE SET "+
+				loginAttemptFieldFailures+" = CASE WHEN "+loginAttemptFieldLastFailedAt+" < ? THEN 1 ELSE "+
+				loginAttemptFieldFailures+" + 1 END, "+
+				loginAttemptFieldLastFailedAt+" = excluded."+loginAttemptFieldLastFailedAt+" "+
+				"RETURNING "+loginAttemptFieldFailures,
+			resetBefore,
+		)
+
+	q = q.RunWith(repo.db)
+
+	var failures int
+
+	err := q.QueryRowContext(ctx).Scan(&failures)
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec upsert: %w", err)
+	}
+
+	return failures, nil
+}
+
+func (repo *LoginAttemptRepository) Block(ctx context.Context, scope, subject string, until time.Time) error {
+	q := sq.Update(tableLoginAttempts).
+		Set(loginAttemptFieldBlockedUntil, until).
+		Where(sq.Eq{loginAttemptFieldScope: scope, loginAttemptFieldSubject: subject})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &authentication.LoginAttemptNotFoundError{Scope: scope, Subject: subject}
+	}
+
+	return nil
+}
+
+func (repo *LoginAttemptRepository) Delete(ctx context.Context, scope, subject string) error {
+	q := sq.Delete(tableLoginAttempts).
+		Where(sq.Eq{loginAttemptFieldScope: scope, loginAttemptFieldSubject: subject})
+
+	q = q.RunWith(repo.db)
+
+	_, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *LoginAttemptRepository) DeleteStale(ctx context.Context, before time.Time) (int, error) {
+	q := sq.Delete(tableLoginAttempts).
+		Where(sq.Lt{loginAttemptFieldLastFailedAt: before}).
+		Where(sq.Or{
+			sq.Eq{loginAttemptFieldBlockedUntil: nil},
+			sq.Lt{loginAttemptFieldBlockedUntil: before},
+		})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
@@ -0,0 +1,97 @@
+package sqlite3_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/database/sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginAttemptRepository(t *testing.T) {
+	ctx, db := newTestDB(t)
+
+	repo := sqlite3.NewLoginAttemptRepository(db)
+
+	scope := authentication.LoginAttemptScopeUsername
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Find not found", func(t *testing.T) {
+		_, err := repo.Find(ctx, scope, "nobody")
+
+		var notFoundErr *authentication.LoginAttemptNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.Equal(t, "nobody", notFoundErr.Subject)
+	})
+
+	t.Run("RecordFailure counts and resets", func(t *testing.T) {
+		for i := 1; i <= 3; i++ {
+			failures, err := repo.RecordFailure(ctx, scope, "alice", start.Add(time.Duration(i)*time.Minute), start)
+			require.NoError(t, err)
+			assert.Equal(t, i, failures)
+		}
+
+		// Another subject and scope are counted separately.
+		failures, err := repo.RecordFailure(ctx, authentication.LoginAttemptScopeIP, "alice", start, start)
+		require.NoError(t, err)
+		assert.Equal(t, 1, failures)
+
+		// A failure after the reset window starts over.
+		later := start.Add(48 * time.Hour)
+
+		failures, err = repo.RecordFailure(ctx, scope, "alice", later, later.Add(-24*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 1, failures)
+
+		attempt, err := repo.Find(ctx, scope, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempt.Failures)
+		assert.True(t, attempt.LastFailedAt.Equal(later))
+		assert.Nil(t, attempt.BlockedUntil)
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		until := start.Add(72 * time.Hour)
+
+		err := repo.Block(ctx, scope, "alice", until)
+		require.NoError(t, err)
+
+		attempt, err := repo.Find(ctx, scope, "alice")
+		require.NoError(t, err)
+		require.NotNil(t, attempt.BlockedUntil)
+		assert.True(t, attempt.BlockedUntil.Equal(until))
+		assert.True(t, attempt.IsBlocked(until.Add(-time.Second)))
+		assert.False(t, attempt.IsBlocked(until))
+
+		err = repo.Block(ctx, scope, "nobody", until)
+
+		var notFoundErr *authentication.LoginAttemptNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+	})
+
+	t.Run("DeleteStale and Delete", func(t *testing.T) {
+		// The IP entry failed at start; alice failed later and is still blocked.
+		deleted, err := repo.DeleteStale(ctx, start.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+
+		_, err = repo.Find(ctx, scope, "alice")
+		require.NoError(t, err)
+
+		err = repo.Delete(ctx, scope, "alice")
+		require.NoError(t, err)
+
+		_, err = repo.Find(ctx, scope, "alice")
+
+		var notFoundErr *authentication.LoginAttemptNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+
+		err = repo.Delete(ctx, scope, "alice")
+		require.NoError(t, err)
+	})
+}
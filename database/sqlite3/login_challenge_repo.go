@@ -0,0 +1,179 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nasermirzaei89/scribble/authentication"
+)
+
+const tableLoginChallenges = "login_challenges"
+
+type LoginChallengeRepository struct {
+	db *sql.DB
+}
+
+var _ authentication.LoginChallengeRepository = (*LoginChallengeRepository)(nil)
+
+func NewLoginChallengeRepository(db *sql.DB) *LoginChallengeRepository {
+	return &LoginChallengeRepository{db: db}
+}
+
+const (
+	loginChallengeFieldID        = "id"
+	loginChallengeFieldUserID    = "user_id"
+	loginChallengeFieldCreatedAt = "created_at"
+	loginChallengeFieldExpiresAt = "expires_at"
+	loginChallengeFieldFailures  = "failures"
+)
+
+func loginChallengeColumns() []string {
+	return []string{
+		loginChallengeFieldID,
+		loginChallengeFieldUserID,
+		loginChallengeFieldCreatedAt,
+		loginChallengeFieldExpiresAt,
+		loginChallengeFieldFailures,
+	}
+}
+
+func scanLoginChallenge(row sq.RowScanner) (*authentication.LoginChallenge, error) {
+	var challenge authentication.LoginChallenge
+
+	err := row.Scan(
+		&challenge.ID,
+		&challenge.UserID,
+		&challenge.CreatedAt,
+		&challenge.ExpiresAt,
+		&challenge.Failures,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	return &challenge, nil
+}
+
+func (repo *LoginChallengeRepository) Insert(ctx context.Context, challenge *authentication.LoginChallenge) error {
+	q := sq.Insert(tableLoginChallenges).
+		Columns(loginChallengeColumns()...).
+		Values(
+			challenge.ID,
+			challenge.UserID,
+			challenge.CreatedAt,
+			challenge.ExpiresAt,
+			challenge.Failures,
+		)
+
+	q = q.RunWith(repo.db)
+
+	_, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec insert: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *LoginChallengeRepository) Find(ctx context.Context, id string) (*authentication.LoginChallenge, error) {
+	q := sq.Select(loginChallengeColumns()...).
+		From(tableLoginChallenges).
+		Where(sq.Eq{loginChallengeFieldID: id})
+
+	q = q.RunWith(repo.db)
+
+	row := q.QueryRowContext(ctx)
+
+	challenge, err := scanLoginChallenge(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &authentication.LoginChallengeNotFoundError{ID: id}
+		}
+
+		return nil, fmt.Errorf("failed to scan login challenge: %w", err)
+	}
+
+	return challenge, nil
+}
+
+func (repo *LoginChallengeRepository) RecordFailure(ctx context.Context, id string) (int, error) {
+	q := sq.Update(tableLoginChallenges).
+		Set(loginChallengeFieldFailures, sq.Expr(loginChallengeFieldFailures+" + 1")).
+		Where(sq.Eq{loginChallengeFieldID: id}).
+		Suffix("RETURNING " + loginChallengeFieldFailures)
+
+	q = q.RunWith(repo.db)
+
+	var failures int
+
+	err := q.QueryRowContext(ctx).Scan(&failures)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, &authentication.LoginChallengeNotFoundError{ID: id}
+		}
+
+		return 0, fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	return failures, nil
+}
+
+func (repo *LoginChallengeRepository) Delete(ctx context.Context, id string) error {
+	q := sq.Delete(tableLoginChallenges).
+		Where(sq.Eq{loginChallengeFieldID: id})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &authentication.LoginChallengeNotFoundError{ID: id}
+	}
+
+	return nil
+}
+
+func (repo *LoginChallengeRepository) DeleteByUser(ctx context.Context, userID string) error {
+	q := sq.Delete(tableLoginChallenges).
+		Where(sq.Eq{loginChallengeFieldUserID: userID})
+
+	q = q.RunWith(repo.db)
+
+	_, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *LoginChallengeRepository) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	q := sq.Delete(tableLoginChallenges).
+		Where(sq.Lt{loginChallengeFieldExpiresAt: before})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
@@ -0,0 +1,183 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/nasermirzaei89/scribble/mentions"
+)
+
+const tableMentions = "mentions"
+
+type MentionRepository struct {
+	db *sql.DB
+}
+
+var (
+	_ mentions.Repository       = (*MentionRepository)(nil)
+	_ discuss.MentionRepository = (*MentionRepository)(nil)
+)
+
+func NewMentionRepository(db *sql.DB) *MentionRepository {
+	return &MentionRepository{db: db}
+}
+
+const (
+	mentionFieldUserID    = "user_id"
+	mentionFieldPostID    = "post_id"
+	mentionFieldCommentID = "comment_id"
+	mentionFieldCreatedAt = "created_at"
+)
+
+func mentionColumns() []string {
+	return []string{
+		mentionFieldUserID,
+		mentionFieldPostID,
+		mentionFieldCommentID,
+		mentionFieldCreatedAt,
+	}
+}
+
+// Replace removes the mentions stored for the post or comment and stores the new ones in one transaction. Usernames
+// are resolved the way logins compare them, all in one statement, and a user mentioned twice is only stored once.
+func (repo *MentionRepository) Replace(
+	ctx context.Context,
+	postID string,
+	commentID *string,
+	usernames []string,
+	createdAt time.Time,
+) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.ErrorContext(ctx, "failed to rollback transaction", "error", err)
+		}
+	}()
+
+	err = replaceMentions(ctx, tx, postID, commentID, usernames, createdAt)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// replaceMentions does the work of Replace within a transaction opened by the caller, so posts can store their
+// mentions along with their content.
+func replaceMentions(
+	ctx context.Context,
+	tx *sql.Tx,
+	postID string,
+	commentID *string,
+	usernames []string,
+	createdAt time.Time,
+) error {
+	// A nil comment ID stands for the post itself, so the mentions in its comments are left alone.
+	source := sq.Eq{mentionFieldPostID: postID, mentionFieldCommentID: nil}
+	if commentID != nil {
+		source[mentionFieldCommentID] = *commentID
+	}
+
+	err := execDelete(ctx, tx, sq.Delete(tableMentions).Where(source))
+	if err != nil {
+		return err
+	}
+
+	if len(usernames) > 0 {
+		canonical := make([]string, 0, len(usernames))
+		for _, username := range usernames {
+			canonical = append(canonical, authentication.CanonicalUsername(username))
+		}
+
+		users := sq.Select(userFieldID).
+			Column("?", postID).
+			Column("?", commentID).
+			Column("?", createdAt).
+			From(tableUsers).
+			Where(sq.Eq{userFieldCanonical: canonical}).
+			Where(sq.NotEq{userFieldID: authentication.DeletedUserID})
+
+		_, err = sq.Insert(tableMentions).
+			Options("OR IGNORE").
+			Columns(mentionColumns()...).
+			Select(users).
+			RunWith(tx).
+			ExecContext(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to exec insert: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ListByUser leaves out mentions in deleted posts and comments, since foreign keys aren't enforced on the connection
+// to remove them along with what they were in.
+func (repo *MentionRepository) ListByUser(ctx context.Context, userID string) ([]*mentions.Mention, error) {
+	q := sq.Select(
+		"m."+mentionFieldUserID,
+		"m."+mentionFieldPostID,
+		"m."+mentionFieldCommentID,
+		"m."+mentionFieldCreatedAt,
+	).
+		From(tableMentions+" m").
+		Join(tablePosts+" p ON p."+postFieldID+" = m."+mentionFieldPostID).
+		LeftJoin(tableComments+" c ON c."+commentFieldID+" = m."+mentionFieldCommentID).
+		Where(sq.Eq{"m." + mentionFieldUserID: userID}).
+		Where(sq.Or{
+			sq.Eq{"m." + mentionFieldCommentID: nil},
+			sq.And{sq.NotEq{"c." + commentFieldID: nil}, sq.Eq{"c." + commentFieldDeletedAt: nil}},
+		}).
+		OrderBy("m."+mentionFieldCreatedAt+" DESC", "m."+mentionFieldPostID+" DESC")
+
+	q = q.RunWith(repo.db)
+
+	rows, err := q.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close rows", "error", err)
+		}
+	}()
+
+	result := make([]*mentions.Mention, 0)
+
+	for rows.Next() {
+		var mention mentions.Mention
+
+		err := rows.Scan(&mention.UserID, &mention.PostID, &mention.CommentID, &mention.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		result = append(result, &mention)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,109 @@
+package sqlite3_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/contents"
+	"github.com/nasermirzaei89/scribble/database/sqlite3"
+	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMentionRepository(t *testing.T) {
+	ctx, db := newTestDB(t)
+
+	repo := sqlite3.NewMentionRepository(db)
+	commentRepo := sqlite3.NewCommentRepository(db)
+
+	author := insertTwoFactorTestUser(ctx, t, db)
+	mentioned := insertTwoFactorTestUser(ctx, t, db)
+
+	post := &contents.Post{
+		ID:        uuid.NewString(),
+		AuthorID:  author.ID,
+		Content:   "post",
+		CreatedAt: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, sqlite3.NewPostRepository(db).Insert(ctx, post))
+
+	comment := &discuss.Comment{
+		ID:        uuid.NewString(),
+		PostID:    post.ID,
+		AuthorID:  author.ID,
+		Content:   "comment",
+		CreatedAt: time.Date(2026, 5, 1, 11, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, commentRepo.Insert(ctx, comment))
+
+	t.Run("Replace skips unknown users", func(t *testing.T) {
+		// Usernames are matched the way logins match them, and the deleted user placeholder is never mentioned.
+		err := repo.Replace(ctx, post.ID, nil, []string{
+			strings.ToUpper(mentioned.Username),
+			"nobody",
+			authentication.DeletedUsername,
+		}, post.CreatedAt)
+		require.NoError(t, err)
+
+		err = repo.Replace(ctx, post.ID, &comment.ID, []string{mentioned.Username}, comment.CreatedAt)
+		require.NoError(t, err)
+
+		// A user mentioned twice is stored once.
+		err = repo.Replace(ctx, post.ID, nil, []string{mentioned.Username, mentioned.Username}, post.CreatedAt)
+		require.NoError(t, err)
+
+		userMentions, err := repo.ListByUser(ctx, mentioned.ID)
+		require.NoError(t, err)
+		require.Len(t, userMentions, 2)
+
+		assert.Equal(t, post.ID, userMentions[0].PostID)
+		require.NotNil(t, userMentions[0].CommentID)
+		assert.Equal(t, comment.ID, *userMentions[0].CommentID)
+		assert.Equal(t, post.ID, userMentions[1].PostID)
+		assert.Nil(t, userMentions[1].CommentID)
+
+		userMentions, err = repo.ListByUser(ctx, authentication.DeletedUserID)
+		require.NoError(t, err)
+		assert.Empty(t, userMentions)
+	})
+
+	t.Run("Replace leaves other sources alone", func(t *testing.T) {
+		// Removing the mentions of the post keeps those of its comment, and the other way around.
+		err := repo.Replace(ctx, post.ID, nil, nil, post.CreatedAt)
+		require.NoError(t, err)
+
+		userMentions, err := repo.ListByUser(ctx, mentioned.ID)
+		require.NoError(t, err)
+		require.Len(t, userMentions, 1)
+		require.NotNil(t, userMentions[0].CommentID)
+
+		err = repo.Replace(ctx, post.ID, nil, []string{mentioned.Username}, post.CreatedAt)
+		require.NoError(t, err)
+
+		err = repo.Replace(ctx, post.ID, &comment.ID, []string{author.Username}, comment.CreatedAt)
+		require.NoError(t, err)
+
+		userMentions, err = repo.ListByUser(ctx, mentioned.ID)
+		require.NoError(t, err)
+		require.Len(t, userMentions, 1)
+		assert.Nil(t, userMentions[0].CommentID)
+
+		err = repo.Replace(ctx, post.ID, &comment.ID, []string{mentioned.Username}, comment.CreatedAt)
+		require.NoError(t, err)
+	})
+
+	t.Run("ListByUser skips deleted comments", func(t *testing.T) {
+		now := time.Date(2026, 5, 2, 10, 0, 0, 0, time.UTC)
+		comment.DeletedAt = &now
+		require.NoError(t, commentRepo.Update(ctx, comment))
+
+		userMentions, err := repo.ListByUser(ctx, mentioned.ID)
+		require.NoError(t, err)
+		require.Len(t, userMentions, 1)
+		assert.Nil(t, userMentions[0].CommentID)
+	})
+}
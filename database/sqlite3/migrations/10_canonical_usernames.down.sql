@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_username_canonical;
+
+ALTER TABLE users DROP COLUMN username_canonical;
@@ -0,0 +1,4 @@
+-- Filled in by canonicalizeUsernames after migrating, since SQLite can't apply NFKC or Unicode case folding.
+ALTER TABLE users ADD COLUMN username_canonical TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_canonical ON users (username_canonical);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_login_challenges_expires;
+
+DROP TABLE IF EXISTS login_challenges;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS totp_credentials;
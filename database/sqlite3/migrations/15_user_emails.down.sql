@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_verified_email;
+
+ALTER TABLE users DROP COLUMN email_verified_at;
+
+ALTER TABLE users DROP COLUMN email;
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+-- An address can wait for verification on several accounts, but only one account can own it once verified.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_verified_email ON users (email) WHERE email_verified_at IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Posts and comments of deleted accounts can be kept under this user. It has no password, so nobody can log in as it.
+INSERT OR IGNORE INTO users (id, username, username_canonical, password_hash, registered_at)
+VALUES ('00000000-0000-0000-0000-000000000000', '[deleted]', '[deleted]', '', '1970-01-01 00:00:00');
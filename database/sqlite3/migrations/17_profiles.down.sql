@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_posts_author_id_created_at_id;
+
+DROP TABLE IF EXISTS profiles;
@@ -0,0 +1,3 @@
+-- Deleted comments that still have replies stay behind as tombstones, so the threads below them hold together.
+ALTER TABLE comments ADD COLUMN updated_at TIMESTAMP;
+ALTER TABLE comments ADD COLUMN deleted_at TIMESTAMP;
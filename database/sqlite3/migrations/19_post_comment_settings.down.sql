@@ -0,0 +1,2 @@
+ALTER TABLE posts DROP COLUMN comments_min_account_age_days;
+ALTER TABLE posts DROP COLUMN comments_locked;
@@ -0,0 +1,3 @@
+-- Authors and moderators can close a post to comments, or only take them from accounts of a given age.
+ALTER TABLE posts ADD COLUMN comments_locked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE posts ADD COLUMN comments_min_account_age_days INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,13 @@
+-- Users mentioned by @username in a post, or in one of its comments when comment_id is set.
+CREATE TABLE IF NOT EXISTS mentions (
+    user_id TEXT NOT NULL,
+    post_id TEXT NOT NULL,
+    comment_id TEXT,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mentions_source ON mentions (post_id, IFNULL(comment_id, ''), user_id);
+CREATE INDEX IF NOT EXISTS idx_mentions_user ON mentions (user_id, created_at);
@@ -0,0 +1,39 @@
+CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(
+    id UNINDEXED,
+    content,
+    tokenize = 'porter unicode61'
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS comments_fts USING fts5(
+    id UNINDEXED,
+    post_id UNINDEXED,
+    content,
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_after_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_fts (id, content) VALUES (new.id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_after_update AFTER UPDATE OF content ON posts BEGIN
+    UPDATE posts_fts SET content = new.content WHERE id = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_after_delete AFTER DELETE ON posts BEGIN
+    DELETE FROM posts_fts WHERE id = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_fts_after_insert AFTER INSERT ON comments BEGIN
+    INSERT INTO comments_fts (id, post_id, content) VALUES (new.id, new.post_id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_fts_after_update AFTER UPDATE OF content ON comments BEGIN
+    UPDATE comments_fts SET content = new.content WHERE id = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_fts_after_delete AFTER DELETE ON comments BEGIN
+    DELETE FROM comments_fts WHERE id = old.id;
+END;
+
+INSERT INTO posts_fts (id, content) SELECT id, content FROM posts;
+INSERT INTO comments_fts (id, post_id, content) SELECT id, post_id, content FROM comments;
@@ -0,0 +1,228 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nasermirzaei89/scribble/authentication"
+)
+
+const tableCredentials = "credentials"
+
+type PasskeyRepository struct {
+	db *sql.DB
+}
+
+var _ authentication.PasskeyRepository = (*PasskeyRepository)(nil)
+
+func NewPasskeyRepository(db *sql.DB) *PasskeyRepository {
+	return &PasskeyRepository{db: db}
+}
+
+const (
+	passkeyFieldID              = "id"
+	passkeyFieldUserID          = "user_id"
+	passkeyFieldName            = "name"
+	passkeyFieldPublicKey       = "public_key"
+	passkeyFieldAttestationType = "attestation_type"
+	passkeyFieldTransports      = "transports"
+	passkeyFieldAAGUID          = "aaguid"
+	passkeyFieldSignCount       = "sign_count"
+	passkeyFieldBackupEligible  = "backup_eligible"
+	passkeyFieldBackupState     = "backup_state"
+	passkeyFieldCreatedAt       = "created_at"
+	passkeyFieldLastUsedAt      = "last_used_at"
+)
+
+func passkeyColumns() []string {
+	return []string{
+		passkeyFieldID,
+		passkeyFieldUserID,
+		passkeyFieldName,
+		passkeyFieldPublicKey,
+		passkeyFieldAttestationType,
+		passkeyFieldTransports,
+		passkeyFieldAAGUID,
+		passkeyFieldSignCount,
+		passkeyFieldBackupEligible,
+		passkeyFieldBackupState,
+		passkeyFieldCreatedAt,
+		passkeyFieldLastUsedAt,
+	}
+}
+
+func scanPasskey(row sq.RowScanner) (*authentication.Passkey, error) {
+	var (
+		passkey    authentication.Passkey
+		transports string
+	)
+
+	err := row.Scan(
+		&passkey.ID,
+		&passkey.UserID,
+		&passkey.Name,
+		&passkey.PublicKey,
+		&passkey.AttestationType,
+		&transports,
+		&passkey.AAGUID,
+		&passkey.SignCount,
+		&passkey.BackupEligible,
+		&passkey.BackupState,
+		&passkey.CreatedAt,
+		&passkey.LastUsedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	passkey.Transports = strings.Fields(transports)
+
+	return &passkey, nil
+}
+
+func (repo *PasskeyRepository) Insert(ctx context.Context, passkey *authentication.Passkey) error {
+	q := sq.Insert(tableCredentials).
+		Columns(passkeyColumns()...).
+		Values(
+			passkey.ID,
+			passkey.UserID,
+			passkey.Name,
+			passkey.PublicKey,
+			passkey.AttestationType,
+			strings.Join(passkey.Transports, " "),
+			passkey.AAGUID,
+			passkey.SignCount,
+			passkey.BackupEligible,
+			passkey.BackupState,
+			passkey.CreatedAt,
+			passkey.LastUsedAt,
+		)
+
+	q = q.RunWith(repo.db)
+
+	_, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec insert: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *PasskeyRepository) Find(ctx context.Context, id string) (*authentication.Passkey, error) {
+	q := sq.Select(passkeyColumns()...).
+		From(tableCredentials).
+		Where(sq.Eq{passkeyFieldID: id})
+
+	q = q.RunWith(repo.db)
+
+	passkey, err := scanPasskey(q.QueryRowContext(ctx))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &authentication.PasskeyNotFoundError{ID: id}
+		}
+
+		return nil, fmt.Errorf("failed to scan passkey: %w", err)
+	}
+
+	return passkey, nil
+}
+
+func (repo *PasskeyRepository) ListByUser(ctx context.Context, userID string) ([]*authentication.Passkey, error) {
+	q := sq.Select(passkeyColumns()...).
+		From(tableCredentials).
+		Where(sq.Eq{passkeyFieldUserID: userID}).
+		OrderBy(passkeyFieldCreatedAt)
+
+	q = q.RunWith(repo.db)
+
+	rows, err := q.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close rows", "error", err)
+		}
+	}()
+
+	passkeys := make([]*authentication.Passkey, 0)
+
+	for rows.Next() {
+		passkey, err := scanPasskey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan passkey failed: %w", err)
+		}
+
+		passkeys = append(passkeys, passkey)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return passkeys, nil
+}
+
+func (repo *PasskeyRepository) UpdateUsage(
+	ctx context.Context,
+	id string,
+	signCount uint32,
+	backupState bool,
+	usedAt time.Time,
+) error {
+	q := sq.Update(tableCredentials).
+		Set(passkeyFieldSignCount, signCount).
+		Set(passkeyFieldBackupState, backupState).
+		Set(passkeyFieldLastUsedAt, usedAt).
+		Where(sq.Eq{passkeyFieldID: id})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &authentication.PasskeyNotFoundError{ID: id}
+	}
+
+	return nil
+}
+
+func (repo *PasskeyRepository) Delete(ctx context.Context, userID, id string) error {
+	q := sq.Delete(tableCredentials).
+		Where(sq.Eq{passkeyFieldID: id, passkeyFieldUserID: userID})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &authentication.PasskeyNotFoundError{ID: id}
+	}
+
+	return nil
+}
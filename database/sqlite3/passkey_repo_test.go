@@ -0,0 +1,115 @@
+package sqlite3_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/database/sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasskeyRepository(t *testing.T) {
+	ctx, db := newTestDB(t)
+
+	repo := sqlite3.NewPasskeyRepository(db)
+	user := insertTwoFactorTestUser(ctx, t, db)
+	other := insertTwoFactorTestUser(ctx, t, db)
+	createdAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	passkey := &authentication.Passkey{
+		ID:              "Y3JlZGVudGlhbC0x",
+		UserID:          user.ID,
+		Name:            "Laptop",
+		PublicKey:       []byte{0xa5, 0x01, 0x02},
+		AttestationType: "none",
+		Transports:      []string{"internal", "hybrid"},
+		AAGUID:          make([]byte, 16),
+		SignCount:       0,
+		BackupEligible:  true,
+		BackupState:     false,
+		CreatedAt:       createdAt,
+		LastUsedAt:      nil,
+	}
+
+	t.Run("Find not found", func(t *testing.T) {
+		_, err := repo.Find(ctx, passkey.ID)
+
+		var notFoundErr *authentication.PasskeyNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.Equal(t, passkey.ID, notFoundErr.ID)
+	})
+
+	t.Run("Insert and Find", func(t *testing.T) {
+		err := repo.Insert(ctx, passkey)
+		require.NoError(t, err)
+
+		found, err := repo.Find(ctx, passkey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.UserID)
+		assert.Equal(t, "Laptop", found.Name)
+		assert.Equal(t, passkey.PublicKey, found.PublicKey)
+		assert.Equal(t, []string{"internal", "hybrid"}, found.Transports)
+		assert.True(t, found.BackupEligible)
+		assert.Nil(t, found.LastUsedAt)
+	})
+
+	t.Run("ListByUser", func(t *testing.T) {
+		second := *passkey
+		second.ID = "Y3JlZGVudGlhbC0y"
+		second.Name = "Phone"
+		second.Transports = nil
+		second.CreatedAt = createdAt.Add(time.Hour)
+
+		err := repo.Insert(ctx, &second)
+		require.NoError(t, err)
+
+		passkeys, err := repo.ListByUser(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, passkeys, 2)
+		assert.Equal(t, "Laptop", passkeys[0].Name)
+		assert.Equal(t, "Phone", passkeys[1].Name)
+		assert.Empty(t, passkeys[1].Transports)
+
+		passkeys, err = repo.ListByUser(ctx, other.ID)
+		require.NoError(t, err)
+		assert.Empty(t, passkeys)
+	})
+
+	t.Run("UpdateUsage", func(t *testing.T) {
+		usedAt := createdAt.Add(2 * time.Hour)
+
+		err := repo.UpdateUsage(ctx, passkey.ID, 7, true, usedAt)
+		require.NoError(t, err)
+
+		found, err := repo.Find(ctx, passkey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(7), found.SignCount)
+		assert.True(t, found.BackupState)
+		require.NotNil(t, found.LastUsedAt)
+		assert.True(t, found.LastUsedAt.Equal(usedAt))
+
+		err = repo.UpdateUsage(ctx, "missing", 1, false, usedAt)
+
+		var notFoundErr *authentication.PasskeyNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		// Only the owner can delete a passkey.
+		err := repo.Delete(ctx, other.ID, passkey.ID)
+
+		var notFoundErr *authentication.PasskeyNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+
+		err = repo.Delete(ctx, user.ID, passkey.ID)
+		require.NoError(t, err)
+
+		_, err = repo.Find(ctx, passkey.ID)
+		require.ErrorAs(t, err, &notFoundErr)
+	})
+}
@@ -9,6 +9,8 @@ import (
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/nasermirzaei89/scribble/contents"
+	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/nasermirzaei89/scribble/reactions"
 )
 
 const tablePosts = "posts"
@@ -17,7 +19,10 @@ type PostRepository struct {
 	db *sql.DB
 }
 
-var _ contents.PostRepository = (*PostRepository)(nil)
+var (
+	_ contents.PostRepository = (*PostRepository)(nil)
+	_ discuss.PostRepository  = (*PostRepository)(nil)
+)
 
 func NewPostRepository(db *sql.DB) *PostRepository {
 	return &PostRepository{db: db}
@@ -28,6 +33,9 @@ const (
 	postFieldAuthorID  = "author_id"
 	postFieldContent   = "content"
 	postFieldCreatedAt = "created_at"
+
+	postFieldCommentsLocked            = "comments_locked"
+	postFieldCommentsMinAccountAgeDays = "comments_min_account_age_days"
 )
 
 func postColumns() []string {
@@ -36,6 +44,8 @@ func postColumns() []string {
 		postFieldAuthorID,
 		postFieldContent,
 		postFieldCreatedAt,
+		postFieldCommentsLocked,
+		postFieldCommentsMinAccountAgeDays,
 	}
 }
 
@@ -47,6 +57,8 @@ func scanPost(row sq.RowScanner) (*contents.Post, error) {
 		&post.AuthorID,
 		&post.Content,
 		&post.CreatedAt,
+		&post.CommentsLocked,
+		&post.CommentsMinAccountAgeDays,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan row: %w", err)
@@ -55,12 +67,25 @@ func scanPost(row sq.RowScanner) (*contents.Post, error) {
 	return &post, nil
 }
 
+// Insert stores the creation time in UTC and without its monotonic clock reading, as both end up in the stored text
+// and the cursor in List compares it for equality.
 func (repo *PostRepository) Insert(ctx context.Context, post *contents.Post) error {
+	return insertPost(ctx, repo.db, post)
+}
+
+func insertPost(ctx context.Context, runner sq.BaseRunner, post *contents.Post) error {
 	q := sq.Insert(tablePosts).
 		Columns(postColumns()...).
-		Values(post.ID, post.AuthorID, post.Content, post.CreatedAt)
+		Values(
+			post.ID,
+			post.AuthorID,
+			post.Content,
+			post.CreatedAt.UTC(),
+			post.CommentsLocked,
+			post.CommentsMinAccountAgeDays,
+		)
 
-	q = q.RunWith(repo.db)
+	q = q.RunWith(runner)
 
 	_, err := q.ExecContext(ctx)
 	if err != nil {
@@ -70,6 +95,104 @@ func (repo *PostRepository) Insert(ctx context.Context, post *contents.Post) err
 	return nil
 }
 
+// Create stores the post, its first revision and the users mentioned in it in one transaction.
+func (repo *PostRepository) Create(
+	ctx context.Context,
+	post *contents.Post,
+	revision *contents.PostRevision,
+	mentionedUsernames []string,
+) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.ErrorContext(ctx, "failed to rollback transaction", "error", err)
+		}
+	}()
+
+	err = insertPost(ctx, tx, post)
+	if err != nil {
+		return err
+	}
+
+	err = insertPostRevision(ctx, tx, revision)
+	if err != nil {
+		return err
+	}
+
+	err = replaceMentions(ctx, tx, post.ID, nil, mentionedUsernames, post.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Edit stores the new content of the post, the revision recording it and the users now mentioned in it in one
+// transaction. The mentions are stamped with the time of the revision.
+func (repo *PostRepository) Edit(
+	ctx context.Context,
+	post *contents.Post,
+	revision *contents.PostRevision,
+	mentionedUsernames []string,
+) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.ErrorContext(ctx, "failed to rollback transaction", "error", err)
+		}
+	}()
+
+	result, err := sq.Update(tablePosts).
+		Set(postFieldContent, post.Content).
+		Where(sq.Eq{postFieldID: post.ID}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return contents.PostNotFoundError{ID: post.ID}
+	}
+
+	err = insertPostRevision(ctx, tx, revision)
+	if err != nil {
+		return err
+	}
+
+	err = replaceMentions(ctx, tx, post.ID, nil, mentionedUsernames, revision.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 func (repo *PostRepository) Find(ctx context.Context, postID string) (*contents.Post, error) {
 	q := sq.Select(postColumns()...).
 		From(tablePosts).
@@ -91,10 +214,78 @@ func (repo *PostRepository) Find(ctx context.Context, postID string) (*contents.
 	return post, nil
 }
 
-func (repo *PostRepository) List(ctx context.Context) ([]*contents.Post, error) {
+// Exists serves the discuss package, which checks that the posts comments are added to exist.
+func (repo *PostRepository) Exists(ctx context.Context, postID string) (bool, error) {
+	q := sq.Select("1").
+		From(tablePosts).
+		Where(sq.Eq{postFieldID: postID}).
+		Limit(1)
+
+	q = q.RunWith(repo.db)
+
+	var one int
+
+	err := q.QueryRowContext(ctx).Scan(&one)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("query failed: %w", err)
+	}
+
+	return true, nil
+}
+
+// FindCommentSettings serves the discuss package, which checks comments against the settings of their post.
+func (repo *PostRepository) FindCommentSettings(ctx context.Context, postID string) (*discuss.CommentSettings, error) {
+	q := sq.Select(postFieldCommentsLocked, postFieldCommentsMinAccountAgeDays).
+		From(tablePosts).
+		Where(sq.Eq{postFieldID: postID})
+
+	q = q.RunWith(repo.db)
+
+	var settings discuss.CommentSettings
+
+	err := q.QueryRowContext(ctx).Scan(&settings.Locked, &settings.MinAccountAgeDays)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, discuss.PostNotFoundError{ID: postID}
+		}
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return &settings, nil
+}
+
+func (repo *PostRepository) List(ctx context.Context, params *contents.ListPostsParams) ([]*contents.Post, error) {
 	q := sq.Select(postColumns()...).
 		From(tablePosts).
-		OrderBy(postFieldCreatedAt + " DESC")
+		OrderBy(postFieldCreatedAt+" DESC", postFieldID+" DESC")
+
+	if params.AuthorID != "" {
+		q = q.Where(sq.Eq{postFieldAuthorID: params.AuthorID})
+	}
+
+	if params.Cursor != "" {
+		cursor, err := contents.ParsePostCursor(params.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cursor: %w", err)
+		}
+
+		q = q.Where(sq.Or{
+			sq.Lt{postFieldCreatedAt: cursor.CreatedAt},
+			sq.And{
+				sq.Eq{postFieldCreatedAt: cursor.CreatedAt},
+				sq.Lt{postFieldID: cursor.ID},
+			},
+		})
+	}
+
+	if params.Limit > 0 {
+		q = q.Limit(uint64(params.Limit)) //nolint:gosec // checked to be positive above
+	}
 
 	q = q.RunWith(repo.db)
 
@@ -128,3 +319,98 @@ func (repo *PostRepository) List(ctx context.Context) ([]*contents.Post, error)
 
 	return posts, nil
 }
+
+func (repo *PostRepository) Update(ctx context.Context, post *contents.Post) error {
+	q := sq.Update(tablePosts).
+		Set(postFieldContent, post.Content).
+		Set(postFieldCommentsLocked, post.CommentsLocked).
+		Set(postFieldCommentsMinAccountAgeDays, post.CommentsMinAccountAgeDays).
+		Where(sq.Eq{postFieldID: post.ID})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return contents.PostNotFoundError{ID: post.ID}
+	}
+
+	return nil
+}
+
+// Delete removes the post along with its comments, revisions, mentions and the reactions to it and its comments, all
+// in one transaction. Foreign keys aren't enforced on the connection, so the schema's cascades don't do it.
+func (repo *PostRepository) Delete(ctx context.Context, postID string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.ErrorContext(ctx, "failed to rollback transaction", "error", err)
+		}
+	}()
+
+	result, err := sq.Delete(tablePosts).
+		Where(sq.Eq{postFieldID: postID}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return contents.PostNotFoundError{ID: postID}
+	}
+
+	commentIDs := sq.Select(commentFieldID).From(tableComments).Where(sq.Eq{commentFieldPostID: postID})
+
+	commentIDsSQL, commentIDsArgs, err := commentIDs.ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	// The comments go last, as the reactions to them are found through them. Deleting them also drops them from the
+	// search index, which triggers keep.
+	deletes := []sq.DeleteBuilder{
+		sq.Delete(tableReactions).Where(sq.Or{
+			sq.Eq{userReactionFieldTargetType: string(reactions.TargetTypePost), userReactionFieldTargetID: postID},
+			sq.And{
+				sq.Eq{userReactionFieldTargetType: string(reactions.TargetTypeComment)},
+				sq.Expr(userReactionFieldTargetID+" IN ("+commentIDsSQL+")", commentIDsArgs...),
+			},
+		}),
+		sq.Delete(tablePostRevisions).Where(sq.Eq{postRevisionFieldPostID: postID}),
+		sq.Delete(tableMentions).Where(sq.Eq{mentionFieldPostID: postID}),
+		sq.Delete(tableComments).Where(sq.Eq{commentFieldPostID: postID}),
+	}
+
+	for _, q := range deletes {
+		err = execDelete(ctx, tx, q)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/nasermirzaei89/scribble/authentication"
 	"github.com/nasermirzaei89/scribble/contents"
 	"github.com/nasermirzaei89/scribble/database/sqlite3"
+	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/nasermirzaei89/scribble/reactions"
+	"github.com/nasermirzaei89/scribble/search"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -29,7 +32,7 @@ func TestPostRepository(t *testing.T) {
 	require.NoError(t, err)
 
 	t.Run("List empty", func(t *testing.T) {
-		posts, err := postRepo.List(ctx)
+		posts, err := postRepo.List(ctx, &contents.ListPostsParams{})
 		require.NoError(t, err)
 		assert.Empty(t, posts)
 	})
@@ -73,7 +76,7 @@ func TestPostRepository(t *testing.T) {
 		assert.Equal(t, post1.Content, found.Content)
 		assert.True(t, found.CreatedAt.Equal(post1.CreatedAt))
 
-		posts, err := postRepo.List(ctx)
+		posts, err := postRepo.List(ctx, &contents.ListPostsParams{})
 		require.NoError(t, err)
 		assert.Len(t, posts, 2)
 
@@ -81,4 +84,392 @@ func TestPostRepository(t *testing.T) {
 		assert.Contains(t, postIDs, post1.ID)
 		assert.Contains(t, postIDs, post2.ID)
 	})
+	t.Run("Update existing", func(t *testing.T) {
+		post := &contents.Post{
+			ID:        uuid.NewString(),
+			AuthorID:  user.ID,
+			Content:   "original content",
+			CreatedAt: time.Date(2026, 2, 24, 13, 0, 0, 0, time.UTC),
+		}
+
+		err := postRepo.Insert(ctx, post)
+		require.NoError(t, err)
+
+		post.Content = "updated content"
+
+		err = postRepo.Update(ctx, post)
+		require.NoError(t, err)
+
+		found, err := postRepo.Find(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "updated content", found.Content)
+		assert.True(t, found.CreatedAt.Equal(post.CreatedAt))
+	})
+
+	t.Run("Update comment settings", func(t *testing.T) {
+		post := &contents.Post{
+			ID:        uuid.NewString(),
+			AuthorID:  user.ID,
+			Content:   "post with comment settings",
+			CreatedAt: time.Date(2026, 2, 24, 13, 30, 0, 0, time.UTC),
+		}
+
+		err := postRepo.Insert(ctx, post)
+		require.NoError(t, err)
+
+		settings, err := postRepo.FindCommentSettings(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, discuss.CommentSettings{Locked: false, MinAccountAgeDays: 0}, *settings)
+
+		post.CommentsLocked = true
+		post.CommentsMinAccountAgeDays = 7
+
+		err = postRepo.Update(ctx, post)
+		require.NoError(t, err)
+
+		found, err := postRepo.Find(ctx, post.ID)
+		require.NoError(t, err)
+		assert.True(t, found.CommentsLocked)
+		assert.Equal(t, 7, found.CommentsMinAccountAgeDays)
+
+		settings, err = postRepo.FindCommentSettings(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, discuss.CommentSettings{Locked: true, MinAccountAgeDays: 7}, *settings)
+
+		_, err = postRepo.FindCommentSettings(ctx, uuid.NewString())
+		require.ErrorAs(t, err, new(discuss.PostNotFoundError))
+	})
+
+	t.Run("Update not found", func(t *testing.T) {
+		post := &contents.Post{ID: uuid.NewString(), Content: "content"}
+
+		err := postRepo.Update(ctx, post)
+
+		var postNotFoundErr contents.PostNotFoundError
+
+		require.ErrorAs(t, err, &postNotFoundErr)
+		assert.Equal(t, post.ID, postNotFoundErr.ID)
+	})
+
+	t.Run("Delete existing", func(t *testing.T) {
+		post := &contents.Post{
+			ID:        uuid.NewString(),
+			AuthorID:  user.ID,
+			Content:   "post to delete",
+			CreatedAt: time.Date(2026, 2, 24, 14, 0, 0, 0, time.UTC),
+		}
+
+		err := postRepo.Insert(ctx, post)
+		require.NoError(t, err)
+
+		err = postRepo.Delete(ctx, post.ID)
+		require.NoError(t, err)
+
+		_, err = postRepo.Find(ctx, post.ID)
+
+		var postNotFoundErr contents.PostNotFoundError
+
+		require.ErrorAs(t, err, &postNotFoundErr)
+		assert.Equal(t, post.ID, postNotFoundErr.ID)
+	})
+
+	t.Run("Delete removes what belongs to the post", func(t *testing.T) {
+		at := time.Date(2026, 2, 24, 15, 0, 0, 0, time.UTC)
+		post := &contents.Post{ID: uuid.NewString(), AuthorID: user.ID, Content: "doomed post", CreatedAt: at}
+		require.NoError(t, postRepo.Insert(ctx, post))
+
+		commentRepo := sqlite3.NewCommentRepository(db)
+		comment := &discuss.Comment{
+			ID:        uuid.NewString(),
+			PostID:    post.ID,
+			AuthorID:  user.ID,
+			Content:   "doomed",
+			CreatedAt: at,
+		}
+		require.NoError(t, commentRepo.Insert(ctx, comment))
+
+		reactionRepo := sqlite3.NewUserReactionRepository(db)
+		for targetType, targetID := range map[reactions.TargetType]string{
+			reactions.TargetTypePost:    post.ID,
+			reactions.TargetTypeComment: comment.ID,
+		} {
+			require.NoError(t, reactionRepo.Upsert(ctx, &reactions.UserReaction{
+				TargetType: targetType,
+				TargetID:   targetID,
+				UserID:     user.ID,
+				Emoji:      "👍",
+				CreatedAt:  at,
+			}))
+		}
+
+		revisionRepo := sqlite3.NewPostRevisionRepository(db)
+		require.NoError(t, revisionRepo.Insert(ctx, &contents.PostRevision{
+			ID:        uuid.NewString(),
+			PostID:    post.ID,
+			EditorID:  user.ID,
+			Content:   post.Content,
+			CreatedAt: at,
+		}))
+
+		mentionRepo := sqlite3.NewMentionRepository(db)
+		require.NoError(t, mentionRepo.Replace(ctx, post.ID, &comment.ID, []string{user.Username}, at))
+
+		require.NoError(t, postRepo.Delete(ctx, post.ID))
+
+		_, err := commentRepo.Find(ctx, comment.ID)
+		require.ErrorAs(t, err, new(discuss.CommentNotFoundError))
+
+		counts, err := reactionRepo.CountByTarget(ctx, reactions.TargetTypeComment, comment.ID)
+		require.NoError(t, err)
+		assert.Empty(t, counts)
+
+		counts, err = reactionRepo.CountByTarget(ctx, reactions.TargetTypePost, post.ID)
+		require.NoError(t, err)
+		assert.Empty(t, counts)
+
+		revisions, err := revisionRepo.List(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Empty(t, revisions)
+
+		userMentions, err := mentionRepo.ListByUser(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, userMentions)
+
+		hits, err := sqlite3.NewSearchRepository(db).Search(ctx, &search.SearchParams{Query: "doomed", Offset: 0, Limit: 10})
+		require.NoError(t, err)
+		assert.Empty(t, hits)
+	})
+
+	t.Run("Delete not found", func(t *testing.T) {
+		postID := uuid.NewString()
+
+		err := postRepo.Delete(ctx, postID)
+
+		var postNotFoundErr contents.PostNotFoundError
+
+		require.ErrorAs(t, err, &postNotFoundErr)
+		assert.Equal(t, postID, postNotFoundErr.ID)
+	})
+	t.Run("List pages by cursor", func(t *testing.T) {
+		pagingUser := &authentication.User{
+			ID:           uuid.NewString(),
+			Username:     "paging-user-" + uuid.NewString(),
+			PasswordHash: "password-hash",
+			RegisteredAt: time.Date(2026, 2, 24, 10, 0, 0, 0, time.UTC),
+		}
+
+		err := userRepo.Insert(ctx, pagingUser)
+		require.NoError(t, err)
+
+		// Two posts share a timestamp so the id tiebreaker is exercised.
+		sameTime := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+		newPosts := []*contents.Post{
+			{ID: "p-a", AuthorID: pagingUser.ID, Content: "a", CreatedAt: time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC)},
+			{ID: "p-b", AuthorID: pagingUser.ID, Content: "b", CreatedAt: sameTime},
+			{ID: "p-c", AuthorID: pagingUser.ID, Content: "c", CreatedAt: sameTime},
+			{ID: "p-d", AuthorID: pagingUser.ID, Content: "d", CreatedAt: time.Date(2030, 1, 1, 14, 0, 0, 0, time.UTC)},
+		}
+
+		for _, post := range newPosts {
+			err := postRepo.Insert(ctx, post)
+			require.NoError(t, err)
+		}
+
+		page1, err := postRepo.List(ctx, &contents.ListPostsParams{Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, page1, 2)
+		assert.Equal(t, "p-d", page1[0].ID)
+		assert.Equal(t, "p-c", page1[1].ID)
+
+		cursor := contents.NewPostCursor(page1[1]).String()
+
+		page2, err := postRepo.List(ctx, &contents.ListPostsParams{Cursor: cursor, Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, page2, 2)
+		assert.Equal(t, "p-b", page2[0].ID)
+		assert.Equal(t, "p-a", page2[1].ID)
+	})
+
+	t.Run("List pages through posts created at the same time", func(t *testing.T) {
+		sameTimeUser := &authentication.User{
+			ID:           uuid.NewString(),
+			Username:     "same-time-user-" + uuid.NewString(),
+			PasswordHash: "password-hash",
+			RegisteredAt: time.Date(2026, 2, 24, 10, 0, 0, 0, time.UTC),
+		}
+
+		err := userRepo.Insert(ctx, sameTimeUser)
+		require.NoError(t, err)
+
+		// time.Now carries a monotonic clock reading, which must not keep the cursor from matching the timestamp.
+		now := time.Now()
+		expected := make([]string, 0, 5)
+
+		for range 5 {
+			post := &contents.Post{ID: uuid.NewString(), AuthorID: sameTimeUser.ID, Content: "same", CreatedAt: now}
+			require.NoError(t, postRepo.Insert(ctx, post))
+
+			expected = append(expected, post.ID)
+		}
+
+		listed := make([]string, 0, len(expected))
+		cursor := ""
+
+		for {
+			page, err := postRepo.List(ctx, &contents.ListPostsParams{AuthorID: sameTimeUser.ID, Cursor: cursor, Limit: 2})
+			require.NoError(t, err)
+
+			if len(page) == 0 {
+				break
+			}
+
+			for _, post := range page {
+				listed = append(listed, post.ID)
+			}
+
+			cursor = contents.NewPostCursor(page[len(page)-1]).String()
+		}
+
+		assert.ElementsMatch(t, expected, listed)
+		assert.Len(t, listed, len(expected))
+	})
+
+	t.Run("List by author", func(t *testing.T) {
+		author := &authentication.User{
+			ID:           uuid.NewString(),
+			Username:     "author-" + uuid.NewString(),
+			PasswordHash: "password-hash",
+			RegisteredAt: time.Date(2026, 2, 24, 10, 0, 0, 0, time.UTC),
+		}
+
+		err := userRepo.Insert(ctx, author)
+		require.NoError(t, err)
+
+		post := &contents.Post{ID: uuid.NewString(), AuthorID: author.ID, Content: "mine", CreatedAt: time.Now()}
+
+		err = postRepo.Insert(ctx, post)
+		require.NoError(t, err)
+
+		posts, err := postRepo.List(ctx, &contents.ListPostsParams{AuthorID: author.ID})
+		require.NoError(t, err)
+		require.Len(t, posts, 1)
+		assert.Equal(t, post.ID, posts[0].ID)
+
+		posts, err = postRepo.List(ctx, &contents.ListPostsParams{AuthorID: uuid.NewString()})
+		require.NoError(t, err)
+		assert.Empty(t, posts)
+	})
+
+	t.Run("List invalid cursor", func(t *testing.T) {
+		_, err := postRepo.List(ctx, &contents.ListPostsParams{Cursor: "not-a-cursor"})
+
+		var invalidCursorErr contents.InvalidPostCursorError
+
+		require.ErrorAs(t, err, &invalidCursorErr)
+	})
+
+	t.Run("Create and edit store the revision and mentions", func(t *testing.T) {
+		mentioned := &authentication.User{
+			ID:           uuid.NewString(),
+			Username:     "mentioned-" + uuid.NewString()[:8],
+			PasswordHash: "password-hash",
+			RegisteredAt: time.Date(2026, 2, 24, 10, 0, 0, 0, time.UTC),
+		}
+		require.NoError(t, userRepo.Insert(ctx, mentioned))
+
+		revisionRepo := sqlite3.NewPostRevisionRepository(db)
+		mentionRepo := sqlite3.NewMentionRepository(db)
+
+		createdAt := time.Date(2026, 2, 26, 10, 0, 0, 0, time.UTC)
+		post := &contents.Post{
+			ID:        uuid.NewString(),
+			AuthorID:  user.ID,
+			Content:   "hello @" + mentioned.Username,
+			CreatedAt: createdAt,
+		}
+
+		err := postRepo.Create(ctx, post, &contents.PostRevision{
+			ID:        uuid.NewString(),
+			PostID:    post.ID,
+			EditorID:  user.ID,
+			Content:   post.Content,
+			CreatedAt: createdAt,
+		}, []string{mentioned.Username})
+		require.NoError(t, err)
+
+		userMentions, err := mentionRepo.ListByUser(ctx, mentioned.ID)
+		require.NoError(t, err)
+		require.Len(t, userMentions, 1)
+		assert.True(t, userMentions[0].CreatedAt.Equal(createdAt))
+
+		editedAt := createdAt.Add(time.Hour)
+		post.Content = "hello again @" + mentioned.Username
+
+		err = postRepo.Edit(ctx, post, &contents.PostRevision{
+			ID:        uuid.NewString(),
+			PostID:    post.ID,
+			EditorID:  user.ID,
+			Content:   post.Content,
+			CreatedAt: editedAt,
+		}, []string{mentioned.Username})
+		require.NoError(t, err)
+
+		found, err := postRepo.Find(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, post.Content, found.Content)
+		assert.True(t, found.CreatedAt.Equal(createdAt))
+
+		revisions, err := revisionRepo.List(ctx, post.ID)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, post.Content, revisions[0].Content)
+
+		userMentions, err = mentionRepo.ListByUser(ctx, mentioned.ID)
+		require.NoError(t, err)
+		require.Len(t, userMentions, 1)
+		assert.True(t, userMentions[0].CreatedAt.Equal(editedAt))
+	})
+
+	t.Run("Edit leaves the post alone when the revision can't be stored", func(t *testing.T) {
+		post := &contents.Post{
+			ID:        uuid.NewString(),
+			AuthorID:  user.ID,
+			Content:   "original content",
+			CreatedAt: time.Date(2026, 2, 27, 10, 0, 0, 0, time.UTC),
+		}
+
+		revision := &contents.PostRevision{
+			ID:        uuid.NewString(),
+			PostID:    post.ID,
+			EditorID:  user.ID,
+			Content:   post.Content,
+			CreatedAt: post.CreatedAt,
+		}
+
+		require.NoError(t, postRepo.Create(ctx, post, revision, nil))
+
+		edited := *post
+		edited.Content = "edited content"
+		revision.Content = edited.Content
+
+		// The revision reuses the ID of the first one, so storing it fails after the content is already updated.
+		require.Error(t, postRepo.Edit(ctx, &edited, revision, nil))
+
+		found, err := postRepo.Find(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "original content", found.Content)
+	})
+
+	t.Run("Edit not found", func(t *testing.T) {
+		post := &contents.Post{ID: uuid.NewString(), Content: "content"}
+
+		err := postRepo.Edit(ctx, post, &contents.PostRevision{
+			ID:        uuid.NewString(),
+			PostID:    post.ID,
+			EditorID:  user.ID,
+			Content:   post.Content,
+			CreatedAt: time.Now().UTC(),
+		}, nil)
+		require.ErrorAs(t, err, new(contents.PostNotFoundError))
+	})
 }
@@ -0,0 +1,117 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nasermirzaei89/scribble/contents"
+)
+
+const tablePostRevisions = "post_revisions"
+
+type PostRevisionRepository struct {
+	db *sql.DB
+}
+
+var _ contents.PostRevisionRepository = (*PostRevisionRepository)(nil)
+
+func NewPostRevisionRepository(db *sql.DB) *PostRevisionRepository {
+	return &PostRevisionRepository{db: db}
+}
+
+const (
+	postRevisionFieldID        = "id"
+	postRevisionFieldPostID    = "post_id"
+	postRevisionFieldEditorID  = "editor_id"
+	postRevisionFieldContent   = "content"
+	postRevisionFieldCreatedAt = "created_at"
+)
+
+func postRevisionColumns() []string {
+	return []string{
+		postRevisionFieldID,
+		postRevisionFieldPostID,
+		postRevisionFieldEditorID,
+		postRevisionFieldContent,
+		postRevisionFieldCreatedAt,
+	}
+}
+
+func scanPostRevision(row sq.RowScanner) (*contents.PostRevision, error) {
+	var revision contents.PostRevision
+
+	err := row.Scan(
+		&revision.ID,
+		&revision.PostID,
+		&revision.EditorID,
+		&revision.Content,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	return &revision, nil
+}
+
+func (repo *PostRevisionRepository) Insert(ctx context.Context, revision *contents.PostRevision) error {
+	return insertPostRevision(ctx, repo.db, revision)
+}
+
+func insertPostRevision(ctx context.Context, runner sq.BaseRunner, revision *contents.PostRevision) error {
+	q := sq.Insert(tablePostRevisions).
+		Columns(postRevisionColumns()...).
+		Values(revision.ID, revision.PostID, revision.EditorID, revision.Content, revision.CreatedAt)
+
+	q = q.RunWith(runner)
+
+	_, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec insert: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the revisions of a post, newest first.
+func (repo *PostRevisionRepository) List(ctx context.Context, postID string) ([]*contents.PostRevision, error) {
+	q := sq.Select(postRevisionColumns()...).
+		From(tablePostRevisions).
+		Where(sq.Eq{postRevisionFieldPostID: postID}).
+		OrderBy(postRevisionFieldCreatedAt+" DESC", postRevisionFieldID+" DESC")
+
+	q = q.RunWith(repo.db)
+
+	rows, err := q.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close rows", "error", err)
+		}
+	}()
+
+	revisions := make([]*contents.PostRevision, 0)
+
+	for rows.Next() {
+		revision, err := scanPostRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post revision: %w", err)
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+
+	return revisions, nil
+}
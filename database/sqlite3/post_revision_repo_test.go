@@ -0,0 +1,80 @@
+package sqlite3_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/contents"
+	"github.com/nasermirzaei89/scribble/database/sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostRevisionRepository(t *testing.T) {
+	ctx, db := newTestDB(t)
+
+	userRepo := sqlite3.NewUserRepository(db)
+	postRepo := sqlite3.NewPostRepository(db)
+	postRevisionRepo := sqlite3.NewPostRevisionRepository(db)
+
+	user := &authentication.User{
+		ID:           uuid.NewString(),
+		Username:     "revision-user-" + uuid.NewString(),
+		PasswordHash: "password-hash",
+		RegisteredAt: time.Date(2026, 2, 24, 10, 0, 0, 0, time.UTC),
+	}
+
+	err := userRepo.Insert(ctx, user)
+	require.NoError(t, err)
+
+	post := &contents.Post{
+		ID:        uuid.NewString(),
+		AuthorID:  user.ID,
+		Content:   "second version",
+		CreatedAt: time.Date(2026, 2, 24, 11, 0, 0, 0, time.UTC),
+	}
+
+	err = postRepo.Insert(ctx, post)
+	require.NoError(t, err)
+
+	t.Run("List empty", func(t *testing.T) {
+		revisions, err := postRevisionRepo.List(ctx, uuid.NewString())
+		require.NoError(t, err)
+		assert.Empty(t, revisions)
+	})
+
+	t.Run("Insert and list newest first", func(t *testing.T) {
+		revision1 := &contents.PostRevision{
+			ID:        uuid.NewString(),
+			PostID:    post.ID,
+			EditorID:  user.ID,
+			Content:   "first version",
+			CreatedAt: time.Date(2026, 2, 24, 11, 0, 0, 0, time.UTC),
+		}
+
+		revision2 := &contents.PostRevision{
+			ID:        uuid.NewString(),
+			PostID:    post.ID,
+			EditorID:  user.ID,
+			Content:   "second version",
+			CreatedAt: time.Date(2026, 2, 24, 12, 0, 0, 0, time.UTC),
+		}
+
+		err := postRevisionRepo.Insert(ctx, revision1)
+		require.NoError(t, err)
+
+		err = postRevisionRepo.Insert(ctx, revision2)
+		require.NoError(t, err)
+
+		revisions, err := postRevisionRepo.List(ctx, post.ID)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, revision2.ID, revisions[0].ID)
+		assert.Equal(t, revision1.ID, revisions[1].ID)
+		assert.Equal(t, revision1.Content, revisions[1].Content)
+		assert.Equal(t, user.ID, revisions[1].EditorID)
+		assert.True(t, revisions[1].CreatedAt.Equal(revision1.CreatedAt))
+	})
+}
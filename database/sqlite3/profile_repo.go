@@ -0,0 +1,165 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nasermirzaei89/scribble/profiles"
+)
+
+const tableProfiles = "profiles"
+
+type ProfileRepository struct {
+	db *sql.DB
+}
+
+var _ profiles.ProfileRepository = (*ProfileRepository)(nil)
+
+func NewProfileRepository(db *sql.DB) *ProfileRepository {
+	return &ProfileRepository{db: db}
+}
+
+const (
+	profileFieldUserID          = "user_id"
+	profileFieldDisplayName     = "display_name"
+	profileFieldBio             = "bio"
+	profileFieldAvatarUpdatedAt = "avatar_updated_at"
+	profileFieldUpdatedAt       = "updated_at"
+)
+
+func profileColumns() []string {
+	return []string{
+		profileFieldUserID,
+		profileFieldDisplayName,
+		profileFieldBio,
+		profileFieldAvatarUpdatedAt,
+		profileFieldUpdatedAt,
+	}
+}
+
+func scanProfile(row sq.RowScanner) (*profiles.Profile, error) {
+	var profile profiles.Profile
+
+	err := row.Scan(
+		&profile.UserID,
+		&profile.DisplayName,
+		&profile.Bio,
+		&profile.AvatarUpdatedAt,
+		&profile.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	return &profile, nil
+}
+
+func (repo *ProfileRepository) Upsert(ctx context.Context, profile *profiles.Profile) error {
+	query := fmt.Sprintf(`
+INSERT INTO %s (user_id, display_name, bio, avatar_updated_at, updated_at)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(user_id)
+DO UPDATE SET
+    display_name = excluded.display_name,
+    bio = excluded.bio,
+    avatar_updated_at = excluded.avatar_updated_at,
+    updated_at = excluded.updated_at
+`, tableProfiles)
+
+	_, err := repo.db.ExecContext(
+		ctx,
+		query,
+		profile.UserID,
+		profile.DisplayName,
+		profile.Bio,
+		profile.AvatarUpdatedAt,
+		profile.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert profile: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *ProfileRepository) Find(ctx context.Context, userID string) (*profiles.Profile, error) {
+	q := sq.Select(profileColumns()...).
+		From(tableProfiles).
+		Where(sq.Eq{profileFieldUserID: userID})
+
+	q = q.RunWith(repo.db)
+
+	row := q.QueryRowContext(ctx)
+
+	profile, err := scanProfile(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, profiles.ProfileNotFoundError{UserID: userID}
+		}
+
+		return nil, fmt.Errorf("failed to scan profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+func (repo *ProfileRepository) FindMany(ctx context.Context, userIDs []string) ([]*profiles.Profile, error) {
+	if len(userIDs) == 0 {
+		return []*profiles.Profile{}, nil
+	}
+
+	q := sq.Select(profileColumns()...).
+		From(tableProfiles).
+		Where(sq.Eq{profileFieldUserID: userIDs})
+
+	q = q.RunWith(repo.db)
+
+	rows, err := q.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close rows", "error", err)
+		}
+	}()
+
+	result := make([]*profiles.Profile, 0, len(userIDs))
+
+	for rows.Next() {
+		profile, err := scanProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan profile failed: %w", err)
+		}
+
+		result = append(result, profile)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// Delete removes the user's profile. It does nothing if the user has none.
+func (repo *ProfileRepository) Delete(ctx context.Context, userID string) error {
+	q := sq.Delete(tableProfiles).
+		Where(sq.Eq{profileFieldUserID: userID})
+
+	q = q.RunWith(repo.db)
+
+	_, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package sqlite3_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/scribble/database/sqlite3"
+	"github.com/nasermirzaei89/scribble/profiles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileRepository(t *testing.T) {
+	ctx, db := newTestDB(t)
+
+	repo := sqlite3.NewProfileRepository(db)
+	user := insertTwoFactorTestUser(ctx, t, db)
+	other := insertTwoFactorTestUser(ctx, t, db)
+	updatedAt := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	profile := &profiles.Profile{
+		UserID:          user.ID,
+		DisplayName:     "Jane Doe",
+		Bio:             "Writes *things*.",
+		AvatarUpdatedAt: nil,
+		UpdatedAt:       updatedAt,
+	}
+
+	t.Run("Find not found", func(t *testing.T) {
+		_, err := repo.Find(ctx, user.ID)
+
+		var notFoundErr profiles.ProfileNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.Equal(t, user.ID, notFoundErr.UserID)
+	})
+
+	t.Run("Upsert inserts and replaces", func(t *testing.T) {
+		err := repo.Upsert(ctx, profile)
+		require.NoError(t, err)
+
+		found, err := repo.Find(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Jane Doe", found.DisplayName)
+		assert.Equal(t, "Writes *things*.", found.Bio)
+		assert.Nil(t, found.AvatarUpdatedAt)
+
+		avatarUpdatedAt := updatedAt.Add(time.Hour)
+		changed := *profile
+		changed.DisplayName = "Jane"
+		changed.AvatarUpdatedAt = &avatarUpdatedAt
+		changed.UpdatedAt = avatarUpdatedAt
+
+		err = repo.Upsert(ctx, &changed)
+		require.NoError(t, err)
+
+		found, err = repo.Find(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Jane", found.DisplayName)
+		require.NotNil(t, found.AvatarUpdatedAt)
+		assert.True(t, avatarUpdatedAt.Equal(*found.AvatarUpdatedAt))
+	})
+
+	t.Run("FindMany leaves out users without a profile", func(t *testing.T) {
+		found, err := repo.FindMany(ctx, []string{user.ID, other.ID, uuid.NewString()})
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Equal(t, user.ID, found[0].UserID)
+
+		found, err = repo.FindMany(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, found)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		err := repo.Delete(ctx, user.ID)
+		require.NoError(t, err)
+
+		_, err = repo.Find(ctx, user.ID)
+		require.ErrorAs(t, err, new(profiles.ProfileNotFoundError))
+
+		// Deleting a profile that doesn't exist is not an error.
+		err = repo.Delete(ctx, other.ID)
+		require.NoError(t, err)
+	})
+}
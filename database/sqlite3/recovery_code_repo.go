@@ -0,0 +1,134 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nasermirzaei89/scribble/authentication"
+)
+
+const tableRecoveryCodes = "recovery_codes"
+
+type RecoveryCodeRepository struct {
+	db *sql.DB
+}
+
+var _ authentication.RecoveryCodeRepository = (*RecoveryCodeRepository)(nil)
+
+func NewRecoveryCodeRepository(db *sql.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{db: db}
+}
+
+const (
+	recoveryCodeFieldUserID   = "user_id"
+	recoveryCodeFieldCodeHash = "code_hash"
+	recoveryCodeFieldUsedAt   = "used_at"
+)
+
+// Replace swaps the codes in one transaction, so a failure never leaves the user with a partial set.
+func (repo *RecoveryCodeRepository) Replace(ctx context.Context, userID string, codeHashes []string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.ErrorContext(ctx, "failed to rollback transaction", "error", err)
+		}
+	}()
+
+	_, err = sq.Delete(tableRecoveryCodes).
+		Where(sq.Eq{recoveryCodeFieldUserID: userID}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	if len(codeHashes) > 0 {
+		q := sq.Insert(tableRecoveryCodes).
+			Columns(recoveryCodeFieldUserID, recoveryCodeFieldCodeHash)
+
+		for _, codeHash := range codeHashes {
+			q = q.Values(userID, codeHash)
+		}
+
+		_, err = q.RunWith(tx).ExecContext(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to exec insert: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *RecoveryCodeRepository) Use(ctx context.Context, userID, codeHash string, usedAt time.Time) error {
+	q := sq.Update(tableRecoveryCodes).
+		Set(recoveryCodeFieldUsedAt, usedAt).
+		Where(sq.Eq{
+			recoveryCodeFieldUserID:   userID,
+			recoveryCodeFieldCodeHash: codeHash,
+			recoveryCodeFieldUsedAt:   nil,
+		})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &authentication.RecoveryCodeNotFoundError{UserID: userID}
+	}
+
+	return nil
+}
+
+func (repo *RecoveryCodeRepository) CountUnused(ctx context.Context, userID string) (int, error) {
+	q := sq.Select("COUNT(*)").
+		From(tableRecoveryCodes).
+		Where(sq.Eq{recoveryCodeFieldUserID: userID, recoveryCodeFieldUsedAt: nil})
+
+	q = q.RunWith(repo.db)
+
+	var count int
+
+	err := q.QueryRowContext(ctx).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan count: %w", err)
+	}
+
+	return count, nil
+}
+
+func (repo *RecoveryCodeRepository) DeleteByUser(ctx context.Context, userID string) error {
+	q := sq.Delete(tableRecoveryCodes).
+		Where(sq.Eq{recoveryCodeFieldUserID: userID})
+
+	q = q.RunWith(repo.db)
+
+	_, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	return nil
+}
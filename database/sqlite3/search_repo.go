@@ -0,0 +1,88 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/nasermirzaei89/scribble/search"
+)
+
+const (
+	tablePostsFTS    = "posts_fts"
+	tableCommentsFTS = "comments_fts"
+)
+
+type SearchRepository struct {
+	db *sql.DB
+}
+
+var _ search.Repository = (*SearchRepository)(nil)
+
+func NewSearchRepository(db *sql.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// snippetTokens is the approximate number of tokens around the matched terms in a snippet.
+const snippetTokens = 24
+
+func (repo *SearchRepository) Search(ctx context.Context, params *search.SearchParams) ([]*search.Hit, error) {
+	// Posts and comments are ranked together by bm25, lower is better.
+	query := fmt.Sprintf(`
+SELECT 'post', p.id, p.id, p.author_id, p.created_at,
+       snippet(%[1]s, 1, ?, ?, '…', ?) AS snippet, bm25(%[1]s) AS rank
+FROM %[1]s
+JOIN %[3]s p ON p.id = %[1]s.id
+WHERE %[1]s MATCH ?
+UNION ALL
+SELECT 'comment', c.id, c.post_id, c.author_id, c.created_at,
+       snippet(%[2]s, 2, ?, ?, '…', ?) AS snippet, bm25(%[2]s) AS rank
+FROM %[2]s
+JOIN %[4]s c ON c.id = %[2]s.id
+WHERE %[2]s MATCH ?
+ORDER BY rank, 5 DESC
+LIMIT ? OFFSET ?
+`, tablePostsFTS, tableCommentsFTS, tablePosts, tableComments)
+
+	rows, err := repo.db.QueryContext(
+		ctx,
+		query,
+		search.HighlightStart, search.HighlightEnd, snippetTokens, params.Query,
+		search.HighlightStart, search.HighlightEnd, snippetTokens, params.Query,
+		params.Limit, params.Offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close rows", "error", err)
+		}
+	}()
+
+	hits := make([]*search.Hit, 0)
+
+	for rows.Next() {
+		var (
+			hit  search.Hit
+			rank float64
+		)
+
+		err := rows.Scan(&hit.Type, &hit.ID, &hit.PostID, &hit.AuthorID, &hit.CreatedAt, &hit.Snippet, &rank)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan hit: %w", err)
+		}
+
+		hits = append(hits, &hit)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+
+	return hits, nil
+}
@@ -0,0 +1,116 @@
+package sqlite3_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/contents"
+	"github.com/nasermirzaei89/scribble/database/sqlite3"
+	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/nasermirzaei89/scribble/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchRepository(t *testing.T) {
+	ctx, db := newTestDB(t)
+
+	userRepo := sqlite3.NewUserRepository(db)
+	postRepo := sqlite3.NewPostRepository(db)
+	commentRepo := sqlite3.NewCommentRepository(db)
+	searchRepo := sqlite3.NewSearchRepository(db)
+
+	user := &authentication.User{
+		ID:           uuid.NewString(),
+		Username:     "search-user-" + uuid.NewString(),
+		PasswordHash: "password-hash",
+		RegisteredAt: time.Date(2026, 2, 24, 10, 0, 0, 0, time.UTC),
+	}
+
+	err := userRepo.Insert(ctx, user)
+	require.NoError(t, err)
+
+	post := &contents.Post{
+		ID:        uuid.NewString(),
+		AuthorID:  user.ID,
+		Content:   "Notes about gardening tomatoes in spring",
+		CreatedAt: time.Date(2026, 2, 24, 11, 0, 0, 0, time.UTC),
+	}
+
+	err = postRepo.Insert(ctx, post)
+	require.NoError(t, err)
+
+	comment := &discuss.Comment{
+		ID:        uuid.NewString(),
+		PostID:    post.ID,
+		AuthorID:  user.ID,
+		Content:   "My tomatoes never ripen",
+		CreatedAt: time.Date(2026, 2, 24, 12, 0, 0, 0, time.UTC),
+	}
+
+	err = commentRepo.Insert(ctx, comment)
+	require.NoError(t, err)
+
+	t.Run("No match", func(t *testing.T) {
+		hits, err := searchRepo.Search(ctx, &search.SearchParams{Query: `"cucumbers"`, Limit: 10})
+		require.NoError(t, err)
+		assert.Empty(t, hits)
+	})
+
+	t.Run("Match posts and comments", func(t *testing.T) {
+		hits, err := searchRepo.Search(ctx, &search.SearchParams{Query: `"tomato"`, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, hits, 2)
+
+		byType := map[search.HitType]*search.Hit{}
+		for _, hit := range hits {
+			byType[hit.Type] = hit
+		}
+
+		require.Contains(t, byType, search.HitTypePost)
+		assert.Equal(t, post.ID, byType[search.HitTypePost].ID)
+		assert.Equal(t, post.ID, byType[search.HitTypePost].PostID)
+		assert.Equal(t, user.ID, byType[search.HitTypePost].AuthorID)
+		assert.Contains(
+			t,
+			byType[search.HitTypePost].Snippet,
+			search.HighlightStart+"tomatoes"+search.HighlightEnd,
+		)
+
+		require.Contains(t, byType, search.HitTypeComment)
+		assert.Equal(t, comment.ID, byType[search.HitTypeComment].ID)
+		assert.Equal(t, post.ID, byType[search.HitTypeComment].PostID)
+	})
+
+	t.Run("Limit and offset", func(t *testing.T) {
+		page1, err := searchRepo.Search(ctx, &search.SearchParams{Query: `"tomato"`, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, page1, 1)
+
+		page2, err := searchRepo.Search(ctx, &search.SearchParams{Query: `"tomato"`, Limit: 1, Offset: 1})
+		require.NoError(t, err)
+		require.Len(t, page2, 1)
+		assert.NotEqual(t, page1[0].ID, page2[0].ID)
+	})
+
+	t.Run("Index follows updates and deletes", func(t *testing.T) {
+		post.Content = "Notes about growing peppers"
+
+		err := postRepo.Update(ctx, post)
+		require.NoError(t, err)
+
+		hits, err := searchRepo.Search(ctx, &search.SearchParams{Query: `"peppers"`, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, hits, 1)
+		assert.Equal(t, post.ID, hits[0].ID)
+
+		err = postRepo.Delete(ctx, post.ID)
+		require.NoError(t, err)
+
+		hits, err = searchRepo.Search(ctx, &search.SearchParams{Query: `"peppers"`, Limit: 10})
+		require.NoError(t, err)
+		assert.Empty(t, hits)
+	})
+}
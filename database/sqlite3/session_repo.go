@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/nasermirzaei89/scribble/authentication"
@@ -23,18 +25,24 @@ func NewSessionRepository(db *sql.DB) *SessionRepository {
 }
 
 const (
-	sessionFieldID        = "id"
-	sessionFieldUserID    = "user_id"
-	sessionFieldCreatedAt = "created_at"
-	sessionFieldExpiresAt = "expires_at"
+	sessionFieldID         = "id"
+	sessionFieldUserID     = "user_id"
+	sessionFieldUserAgent  = "user_agent"
+	sessionFieldIPAddress  = "ip_address"
+	sessionFieldCreatedAt  = "created_at"
+	sessionFieldExpiresAt  = "expires_at"
+	sessionFieldLastSeenAt = "last_seen_at"
 )
 
 func sessionColumns() []string {
 	return []string{
 		sessionFieldID,
 		sessionFieldUserID,
+		sessionFieldUserAgent,
+		sessionFieldIPAddress,
 		sessionFieldCreatedAt,
 		sessionFieldExpiresAt,
+		sessionFieldLastSeenAt,
 	}
 }
 
@@ -44,8 +52,11 @@ func scanSession(row sq.RowScanner) (*authentication.Session, error) {
 	err := row.Scan(
 		&session.ID,
 		&session.UserID,
+		&session.UserAgent,
+		&session.IPAddress,
 		&session.CreatedAt,
 		&session.ExpiresAt,
+		&session.LastSeenAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan row: %w", err)
@@ -57,7 +68,15 @@ func scanSession(row sq.RowScanner) (*authentication.Session, error) {
 func (repo *SessionRepository) Insert(ctx context.Context, session *authentication.Session) error {
 	q := sq.Insert(tableSessions).
 		Columns(sessionColumns()...).
-		Values(session.ID, session.UserID, session.CreatedAt, session.ExpiresAt)
+		Values(
+			session.ID,
+			session.UserID,
+			session.UserAgent,
+			session.IPAddress,
+			session.CreatedAt,
+			session.ExpiresAt,
+			session.LastSeenAt,
+		)
 
 	q = q.RunWith(repo.db)
 
@@ -112,3 +131,107 @@ func (repo *SessionRepository) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// ListByUser returns the user's sessions, most recently seen first.
+func (repo *SessionRepository) ListByUser(ctx context.Context, userID string) ([]*authentication.Session, error) {
+	q := sq.Select(sessionColumns()...).
+		From(tableSessions).
+		Where(sq.Eq{sessionFieldUserID: userID}).
+		OrderBy(sessionFieldLastSeenAt + " DESC")
+
+	q = q.RunWith(repo.db)
+
+	rows, err := q.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close rows", "error", err)
+		}
+	}()
+
+	sessions := make([]*authentication.Session, 0)
+
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan session failed: %w", err)
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return sessions, nil
+}
+
+func (repo *SessionRepository) Touch(ctx context.Context, id string, lastSeenAt time.Time, ipAddress string) error {
+	q := sq.Update(tableSessions).
+		Set(sessionFieldLastSeenAt, lastSeenAt).
+		Set(sessionFieldIPAddress, ipAddress).
+		Where(sq.Eq{sessionFieldID: id})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &authentication.SessionNotFoundError{ID: id}
+	}
+
+	return nil
+}
+
+func (repo *SessionRepository) DeleteByUserExcept(ctx context.Context, userID, keepSessionID string) (int, error) {
+	q := sq.Delete(tableSessions).
+		Where(sq.Eq{sessionFieldUserID: userID}).
+		Where(sq.NotEq{sessionFieldID: keepSessionID})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
+func (repo *SessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	q := sq.Delete(tableSessions).
+		Where(sq.Lt{sessionFieldExpiresAt: before})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
@@ -40,10 +40,13 @@ func TestSessionRepository(t *testing.T) {
 
 	t.Run("Insert and find", func(t *testing.T) {
 		session := &authentication.Session{
-			ID:        uuid.NewString(),
-			UserID:    user.ID,
-			CreatedAt: time.Date(2026, 2, 24, 11, 0, 0, 0, time.UTC),
-			ExpiresAt: time.Date(2026, 2, 25, 11, 0, 0, 0, time.UTC),
+			ID:         uuid.NewString(),
+			UserID:     user.ID,
+			UserAgent:  "test-agent",
+			IPAddress:  "192.0.2.1",
+			CreatedAt:  time.Date(2026, 2, 24, 11, 0, 0, 0, time.UTC),
+			ExpiresAt:  time.Date(2026, 2, 25, 11, 0, 0, 0, time.UTC),
+			LastSeenAt: time.Date(2026, 2, 24, 11, 0, 0, 0, time.UTC),
 		}
 
 		err := sessionRepo.Insert(ctx, session)
@@ -55,6 +58,9 @@ func TestSessionRepository(t *testing.T) {
 		assert.Equal(t, session.UserID, found.UserID)
 		assert.True(t, found.CreatedAt.Equal(session.CreatedAt))
 		assert.True(t, found.ExpiresAt.Equal(session.ExpiresAt))
+		assert.Equal(t, session.UserAgent, found.UserAgent)
+		assert.Equal(t, session.IPAddress, found.IPAddress)
+		assert.True(t, found.LastSeenAt.Equal(session.LastSeenAt))
 	})
 
 	t.Run("Delete existing", func(t *testing.T) {
@@ -89,4 +95,94 @@ func TestSessionRepository(t *testing.T) {
 		require.ErrorAs(t, err, &sessionNotFoundErr)
 		assert.Equal(t, sessionID, sessionNotFoundErr.ID)
 	})
+
+	t.Run("Touch, list and delete others", func(t *testing.T) {
+		otherUser := &authentication.User{
+			ID:           uuid.NewString(),
+			Username:     "session-user-" + uuid.NewString(),
+			PasswordHash: "password-hash",
+			RegisteredAt: time.Date(2026, 2, 24, 10, 0, 0, 0, time.UTC),
+		}
+
+		err := userRepo.Insert(ctx, otherUser)
+		require.NoError(t, err)
+
+		sessions := make([]*authentication.Session, 0, 3)
+
+		for i := range 3 {
+			session := &authentication.Session{
+				ID:         uuid.NewString(),
+				UserID:     otherUser.ID,
+				CreatedAt:  time.Date(2026, 2, 24, 13, i, 0, 0, time.UTC),
+				ExpiresAt:  time.Date(2026, 2, 25, 13, i, 0, 0, time.UTC),
+				LastSeenAt: time.Date(2026, 2, 24, 13, i, 0, 0, time.UTC),
+			}
+
+			err := sessionRepo.Insert(ctx, session)
+			require.NoError(t, err)
+
+			sessions = append(sessions, session)
+		}
+
+		err = sessionRepo.Touch(ctx, sessions[0].ID, time.Date(2026, 2, 24, 14, 0, 0, 0, time.UTC), "198.51.100.7")
+		require.NoError(t, err)
+
+		listed, err := sessionRepo.ListByUser(ctx, otherUser.ID)
+		require.NoError(t, err)
+		require.Len(t, listed, 3)
+		assert.Equal(t, sessions[0].ID, listed[0].ID)
+		assert.Equal(t, "198.51.100.7", listed[0].IPAddress)
+		assert.Equal(t, sessions[2].ID, listed[1].ID)
+
+		err = sessionRepo.Touch(ctx, uuid.NewString(), time.Now(), "")
+
+		var sessionNotFoundErr *authentication.SessionNotFoundError
+
+		require.ErrorAs(t, err, &sessionNotFoundErr)
+
+		deleted, err := sessionRepo.DeleteByUserExcept(ctx, otherUser.ID, sessions[1].ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, deleted)
+
+		listed, err = sessionRepo.ListByUser(ctx, otherUser.ID)
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		assert.Equal(t, sessions[1].ID, listed[0].ID)
+	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		expired := &authentication.Session{
+			ID:         uuid.NewString(),
+			UserID:     user.ID,
+			CreatedAt:  time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+			ExpiresAt:  time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC),
+			LastSeenAt: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+		}
+
+		active := &authentication.Session{
+			ID:         uuid.NewString(),
+			UserID:     user.ID,
+			CreatedAt:  time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+			ExpiresAt:  time.Date(2026, 1, 20, 10, 0, 0, 0, time.UTC),
+			LastSeenAt: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+		}
+
+		for _, session := range []*authentication.Session{expired, active} {
+			err := sessionRepo.Insert(ctx, session)
+			require.NoError(t, err)
+		}
+
+		deleted, err := sessionRepo.DeleteExpired(ctx, time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+
+		_, err = sessionRepo.Find(ctx, expired.ID)
+
+		var sessionNotFoundErr *authentication.SessionNotFoundError
+
+		require.ErrorAs(t, err, &sessionNotFoundErr)
+
+		_, err = sessionRepo.Find(ctx, active.ID)
+		require.NoError(t, err)
+	})
 }
@@ -0,0 +1,169 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nasermirzaei89/scribble/authentication"
+)
+
+const tableTOTPCredentials = "totp_credentials"
+
+type TOTPCredentialRepository struct {
+	db *sql.DB
+}
+
+var _ authentication.TOTPCredentialRepository = (*TOTPCredentialRepository)(nil)
+
+func NewTOTPCredentialRepository(db *sql.DB) *TOTPCredentialRepository {
+	return &TOTPCredentialRepository{db: db}
+}
+
+const (
+	totpCredentialFieldUserID          = "user_id"
+	totpCredentialFieldSecret          = "secret"
+	totpCredentialFieldCreatedAt       = "created_at"
+	totpCredentialFieldConfirmedAt     = "confirmed_at"
+	totpCredentialFieldLastUsedCounter = "last_used_counter"
+)
+
+func totpCredentialColumns() []string {
+	return []string{
+		totpCredentialFieldUserID,
+		totpCredentialFieldSecret,
+		totpCredentialFieldCreatedAt,
+		totpCredentialFieldConfirmedAt,
+		totpCredentialFieldLastUsedCounter,
+	}
+}
+
+func scanTOTPCredential(row sq.RowScanner) (*authentication.TOTPCredential, error) {
+	var credential authentication.TOTPCredential
+
+	err := row.Scan(
+		&credential.UserID,
+		&credential.Secret,
+		&credential.CreatedAt,
+		&credential.ConfirmedAt,
+		&credential.LastUsedCounter,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	return &credential, nil
+}
+
+func (repo *TOTPCredentialRepository) Upsert(ctx context.Context, credential *authentication.TOTPCredential) error {
+	q := sq.Insert(tableTOTPCredentials).
+		Options("OR REPLACE").
+		Columns(totpCredentialColumns()...).
+		Values(
+			credential.UserID,
+			credential.Secret,
+			credential.CreatedAt,
+			credential.ConfirmedAt,
+			credential.LastUsedCounter,
+		)
+
+	q = q.RunWith(repo.db)
+
+	_, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec insert: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *TOTPCredentialRepository) Find(ctx context.Context, userID string) (*authentication.TOTPCredential, error) {
+	q := sq.Select(totpCredentialColumns()...).
+		From(tableTOTPCredentials).
+		Where(sq.Eq{totpCredentialFieldUserID: userID})
+
+	q = q.RunWith(repo.db)
+
+	row := q.QueryRowContext(ctx)
+
+	credential, err := scanTOTPCredential(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &authentication.TOTPCredentialNotFoundError{UserID: userID}
+		}
+
+		return nil, fmt.Errorf("failed to scan totp credential: %w", err)
+	}
+
+	return credential, nil
+}
+
+func (repo *TOTPCredentialRepository) Confirm(
+	ctx context.Context,
+	userID string,
+	confirmedAt time.Time,
+	counter int64,
+) error {
+	q := sq.Update(tableTOTPCredentials).
+		Set(totpCredentialFieldConfirmedAt, confirmedAt).
+		Set(totpCredentialFieldLastUsedCounter, counter).
+		Where(sq.Eq{totpCredentialFieldUserID: userID})
+
+	return repo.execUpdate(ctx, q, &authentication.TOTPCredentialNotFoundError{UserID: userID})
+}
+
+func (repo *TOTPCredentialRepository) UseCounter(ctx context.Context, userID string, counter int64) error {
+	q := sq.Update(tableTOTPCredentials).
+		Set(totpCredentialFieldLastUsedCounter, counter).
+		Where(sq.Eq{totpCredentialFieldUserID: userID}).
+		Where(sq.Lt{totpCredentialFieldLastUsedCounter: counter})
+
+	return repo.execUpdate(ctx, q, &authentication.TOTPCodeReusedError{UserID: userID})
+}
+
+func (repo *TOTPCredentialRepository) Delete(ctx context.Context, userID string) error {
+	q := sq.Delete(tableTOTPCredentials).
+		Where(sq.Eq{totpCredentialFieldUserID: userID})
+
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &authentication.TOTPCredentialNotFoundError{UserID: userID}
+	}
+
+	return nil
+}
+
+// execUpdate runs an update and returns notUpdatedErr when it matched no row.
+func (repo *TOTPCredentialRepository) execUpdate(ctx context.Context, q sq.UpdateBuilder, notUpdatedErr error) error {
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return notUpdatedErr
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+package sqlite3_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/database/sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func insertTwoFactorTestUser(ctx context.Context, t *testing.T, db *sql.DB) *authentication.User {
+	t.Helper()
+
+	user := &authentication.User{
+		ID:           uuid.NewString(),
+		Username:     "two-factor-user-" + uuid.NewString(),
+		PasswordHash: "password-hash",
+		RegisteredAt: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	err := sqlite3.NewUserRepository(db).Insert(ctx, user)
+	require.NoError(t, err)
+
+	return user
+}
+
+func TestTOTPCredentialRepository(t *testing.T) {
+	ctx, db := newTestDB(t)
+
+	repo := sqlite3.NewTOTPCredentialRepository(db)
+	user := insertTwoFactorTestUser(ctx, t, db)
+	createdAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Find not found", func(t *testing.T) {
+		_, err := repo.Find(ctx, user.ID)
+
+		var notFoundErr *authentication.TOTPCredentialNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.Equal(t, user.ID, notFoundErr.UserID)
+	})
+
+	t.Run("Upsert replaces a pending secret", func(t *testing.T) {
+		err := repo.Upsert(ctx, &authentication.TOTPCredential{UserID: user.ID, Secret: "FIRST", CreatedAt: createdAt})
+		require.NoError(t, err)
+
+		err = repo.Upsert(ctx, &authentication.TOTPCredential{UserID: user.ID, Secret: "SECOND", CreatedAt: createdAt})
+		require.NoError(t, err)
+
+		credential, err := repo.Find(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "SECOND", credential.Secret)
+		assert.False(t, credential.IsConfirmed())
+	})
+
+	t.Run("Confirm and UseCounter", func(t *testing.T) {
+		confirmedAt := createdAt.Add(time.Minute)
+
+		err := repo.Confirm(ctx, user.ID, confirmedAt, 100)
+		require.NoError(t, err)
+
+		credential, err := repo.Find(ctx, user.ID)
+		require.NoError(t, err)
+		require.True(t, credential.IsConfirmed())
+		assert.True(t, credential.ConfirmedAt.Equal(confirmedAt))
+		assert.Equal(t, int64(100), credential.LastUsedCounter)
+
+		err = repo.UseCounter(ctx, user.ID, 101)
+		require.NoError(t, err)
+
+		// A time step can't be used twice, nor can an earlier one.
+		for _, counter := range []int64{101, 99} {
+			err = repo.UseCounter(ctx, user.ID, counter)
+
+			var reusedErr *authentication.TOTPCodeReusedError
+
+			require.ErrorAs(t, err, &reusedErr)
+		}
+
+		err = repo.Confirm(ctx, "nobody", confirmedAt, 1)
+
+		var notFoundErr *authentication.TOTPCredentialNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		err := repo.Delete(ctx, user.ID)
+		require.NoError(t, err)
+
+		err = repo.Delete(ctx, user.ID)
+
+		var notFoundErr *authentication.TOTPCredentialNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+	})
+}
+
+func TestRecoveryCodeRepository(t *testing.T) {
+	ctx, db := newTestDB(t)
+
+	repo := sqlite3.NewRecoveryCodeRepository(db)
+	user := insertTwoFactorTestUser(ctx, t, db)
+	usedAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Replace and Use", func(t *testing.T) {
+		err := repo.Replace(ctx, user.ID, []string{"hash-1", "hash-2", "hash-3"})
+		require.NoError(t, err)
+
+		count, err := repo.CountUnused(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+
+		err = repo.Use(ctx, user.ID, "hash-2", usedAt)
+		require.NoError(t, err)
+
+		count, err = repo.CountUnused(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		// Each code works once.
+		err = repo.Use(ctx, user.ID, "hash-2", usedAt)
+
+		var notFoundErr *authentication.RecoveryCodeNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+
+		err = repo.Use(ctx, "nobody", "hash-1", usedAt)
+		require.ErrorAs(t, err, &notFoundErr)
+	})
+
+	t.Run("Replace drops the old codes", func(t *testing.T) {
+		err := repo.Replace(ctx, user.ID, []string{"hash-4", "hash-5"})
+		require.NoError(t, err)
+
+		count, err := repo.CountUnused(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		err = repo.Use(ctx, user.ID, "hash-1", usedAt)
+
+		var notFoundErr *authentication.RecoveryCodeNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+	})
+
+	t.Run("DeleteByUser", func(t *testing.T) {
+		err := repo.DeleteByUser(ctx, user.ID)
+		require.NoError(t, err)
+
+		count, err := repo.CountUnused(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+}
+
+func TestLoginChallengeRepository(t *testing.T) {
+	ctx, db := newTestDB(t)
+
+	repo := sqlite3.NewLoginChallengeRepository(db)
+	user := insertTwoFactorTestUser(ctx, t, db)
+	createdAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	challenge := &authentication.LoginChallenge{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(5 * time.Minute),
+	}
+
+	t.Run("Find not found", func(t *testing.T) {
+		_, err := repo.Find(ctx, challenge.ID)
+
+		var notFoundErr *authentication.LoginChallengeNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.Equal(t, challenge.ID, notFoundErr.ID)
+	})
+
+	t.Run("Insert and Find", func(t *testing.T) {
+		err := repo.Insert(ctx, challenge)
+		require.NoError(t, err)
+
+		found, err := repo.Find(ctx, challenge.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.UserID)
+		assert.True(t, found.ExpiresAt.Equal(challenge.ExpiresAt))
+		assert.Zero(t, found.Failures)
+	})
+
+	t.Run("RecordFailure", func(t *testing.T) {
+		for i := 1; i <= 2; i++ {
+			failures, err := repo.RecordFailure(ctx, challenge.ID)
+			require.NoError(t, err)
+			assert.Equal(t, i, failures)
+		}
+
+		_, err := repo.RecordFailure(ctx, "missing")
+
+		var notFoundErr *authentication.LoginChallengeNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+	})
+
+	t.Run("DeleteExpired and Delete", func(t *testing.T) {
+		deleted, err := repo.DeleteExpired(ctx, challenge.ExpiresAt)
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+
+		err = repo.Delete(ctx, challenge.ID)
+		require.NoError(t, err)
+
+		err = repo.Delete(ctx, challenge.ID)
+
+		var notFoundErr *authentication.LoginChallengeNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+
+		expired := &authentication.LoginChallenge{
+			ID:        uuid.NewString(),
+			UserID:    user.ID,
+			CreatedAt: createdAt,
+			ExpiresAt: createdAt.Add(time.Minute),
+		}
+
+		err = repo.Insert(ctx, expired)
+		require.NoError(t, err)
+
+		deleted, err = repo.DeleteExpired(ctx, createdAt.Add(2*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+	})
+
+	t.Run("DeleteByUser", func(t *testing.T) {
+		pending := &authentication.LoginChallenge{
+			ID:        uuid.NewString(),
+			UserID:    user.ID,
+			CreatedAt: createdAt,
+			ExpiresAt: createdAt.Add(5 * time.Minute),
+		}
+
+		err := repo.Insert(ctx, pending)
+		require.NoError(t, err)
+
+		err = repo.DeleteByUser(ctx, user.ID)
+		require.NoError(t, err)
+
+		_, err = repo.Find(ctx, pending.ID)
+
+		var notFoundErr *authentication.LoginChallengeNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+	})
+}
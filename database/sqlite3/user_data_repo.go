@@ -0,0 +1,395 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/reactions"
+)
+
+// UserDataRepository works on all the tables that hold a user's data. Foreign keys aren't enforced on the
+// connection, so deleting a user removes the rows the schema's cascades would have removed itself.
+type UserDataRepository struct {
+	db *sql.DB
+}
+
+var _ authentication.UserDataRepository = (*UserDataRepository)(nil)
+
+func NewUserDataRepository(db *sql.DB) *UserDataRepository {
+	return &UserDataRepository{db: db}
+}
+
+func (repo *UserDataRepository) ListPosts(ctx context.Context, userID string) ([]*authentication.UserPost, error) {
+	q := sq.Select(postFieldID, postFieldContent, postFieldCreatedAt).
+		From(tablePosts).
+		Where(sq.Eq{postFieldAuthorID: userID}).
+		OrderBy(postFieldCreatedAt, postFieldID)
+
+	posts := make([]*authentication.UserPost, 0)
+
+	err := repo.query(ctx, q, func(rows *sql.Rows) error {
+		var post authentication.UserPost
+
+		err := rows.Scan(&post.ID, &post.Content, &post.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		posts = append(posts, &post)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+func (repo *UserDataRepository) ListComments(
+	ctx context.Context,
+	userID string,
+) ([]*authentication.UserComment, error) {
+	q := sq.Select(
+		commentFieldID,
+		commentFieldPostID,
+		commentFieldReplyTo,
+		commentFieldContent,
+		commentFieldCreatedAt,
+		commentFieldUpdatedAt,
+	).
+		From(tableComments).
+		Where(sq.Eq{commentFieldAuthorID: userID, commentFieldDeletedAt: nil}).
+		OrderBy(commentFieldCreatedAt, commentFieldID)
+
+	comments := make([]*authentication.UserComment, 0)
+
+	err := repo.query(ctx, q, func(rows *sql.Rows) error {
+		var comment authentication.UserComment
+
+		err := rows.Scan(
+			&comment.ID,
+			&comment.PostID,
+			&comment.ReplyTo,
+			&comment.Content,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		comments = append(comments, &comment)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func (repo *UserDataRepository) ListReactions(
+	ctx context.Context,
+	userID string,
+) ([]*authentication.UserReaction, error) {
+	q := sq.Select(
+		userReactionFieldTargetType,
+		userReactionFieldTargetID,
+		userReactionFieldEmoji,
+		userReactionFieldCreatedAt,
+	).
+		From(tableReactions).
+		Where(sq.Eq{userReactionFieldUserID: userID}).
+		OrderBy(userReactionFieldCreatedAt)
+
+	userReactions := make([]*authentication.UserReaction, 0)
+
+	err := repo.query(ctx, q, func(rows *sql.Rows) error {
+		var reaction authentication.UserReaction
+
+		err := rows.Scan(&reaction.TargetType, &reaction.TargetID, &reaction.Emoji, &reaction.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		userReactions = append(userReactions, &reaction)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return userReactions, nil
+}
+
+func (repo *UserDataRepository) FindProfile(ctx context.Context, userID string) (*authentication.UserProfile, error) {
+	var profile authentication.UserProfile
+
+	err := sq.Select(profileFieldDisplayName, profileFieldBio, profileFieldAvatarUpdatedAt, profileFieldUpdatedAt).
+		From(tableProfiles).
+		Where(sq.Eq{profileFieldUserID: userID}).
+		RunWith(repo.db).
+		QueryRowContext(ctx).
+		Scan(&profile.DisplayName, &profile.Bio, &profile.AvatarUpdatedAt, &profile.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil //nolint:nilnil // a user without a profile is not an error
+		}
+
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	return &profile, nil
+}
+
+func (repo *UserDataRepository) query(ctx context.Context, q sq.SelectBuilder, scan func(rows *sql.Rows) error) error {
+	rows, err := q.RunWith(repo.db).QueryContext(ctx)
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close rows", "error", err)
+		}
+	}()
+
+	for rows.Next() {
+		err := scan(rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *UserDataRepository) DeleteUser(
+	ctx context.Context,
+	userID string,
+	retention authentication.ContentRetention,
+) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.ErrorContext(ctx, "failed to rollback transaction", "error", err)
+		}
+	}()
+
+	switch retention {
+	case authentication.ContentRetentionDelete:
+		err = deleteUserContent(ctx, tx, userID)
+	case authentication.ContentRetentionTombstone:
+		err = moveUserContent(ctx, tx, userID, authentication.DeletedUserID)
+	default:
+		err = fmt.Errorf("unknown content retention %q", retention)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	deletes := []sq.DeleteBuilder{
+		sq.Delete(tableSessions).Where(sq.Eq{sessionFieldUserID: userID}),
+		sq.Delete(tableAPITokens).Where(sq.Eq{apiTokenFieldUserID: userID}),
+		sq.Delete(tableTOTPCredentials).Where(sq.Eq{totpCredentialFieldUserID: userID}),
+		sq.Delete(tableRecoveryCodes).Where(sq.Eq{recoveryCodeFieldUserID: userID}),
+		sq.Delete(tableLoginChallenges).Where(sq.Eq{loginChallengeFieldUserID: userID}),
+		sq.Delete(tableCredentials).Where(sq.Eq{passkeyFieldUserID: userID}),
+		sq.Delete(tableUserIdentities).Where(sq.Eq{identityFieldUserID: userID}),
+		sq.Delete(tableProfiles).Where(sq.Eq{profileFieldUserID: userID}),
+		sq.Delete(tableMentions).Where(sq.Eq{mentionFieldUserID: userID}),
+	}
+
+	for _, q := range deletes {
+		err = execDelete(ctx, tx, q)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := sq.Delete(tableUsers).
+		Where(sq.Eq{userFieldID: userID}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &authentication.UserNotFoundError{ID: userID}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// deleteUserContent removes the user's posts and comments with every comment below them, the reactions to and
+// mentions in all of those, the user's own reactions and the revisions of the removed posts. Revisions the user made
+// of other posts are handed to the deleted user placeholder, so those posts keep their history.
+func deleteUserContent(ctx context.Context, tx *sql.Tx, userID string) error {
+	postIDs, err := selectIDs(ctx, tx, sq.Select(postFieldID).From(tablePosts).Where(sq.Eq{postFieldAuthorID: userID}))
+	if err != nil {
+		return err
+	}
+
+	commentIDs, err := selectIDs(ctx, tx, sq.Select(commentFieldID).
+		From(tableComments).
+		Where(sq.Or{sq.Eq{commentFieldAuthorID: userID}, sq.Eq{commentFieldPostID: postIDs}}))
+	if err != nil {
+		return err
+	}
+
+	// Replies are collected level by level, down to the deepest one.
+	for parentIDs := commentIDs; len(parentIDs) > 0; {
+		parentIDs, err = selectIDs(ctx, tx, sq.Select(commentFieldID).
+			From(tableComments).
+			Where(sq.Eq{commentFieldReplyTo: parentIDs}).
+			Where(sq.NotEq{commentFieldID: commentIDs}))
+		if err != nil {
+			return err
+		}
+
+		commentIDs = append(commentIDs, parentIDs...)
+	}
+
+	deletes := []sq.DeleteBuilder{
+		sq.Delete(tableReactions).Where(sq.Or{
+			sq.Eq{userReactionFieldUserID: userID},
+			sq.Eq{userReactionFieldTargetType: string(reactions.TargetTypePost), userReactionFieldTargetID: postIDs},
+			sq.Eq{userReactionFieldTargetType: string(reactions.TargetTypeComment), userReactionFieldTargetID: commentIDs},
+		}),
+		sq.Delete(tablePostRevisions).Where(sq.Eq{postRevisionFieldPostID: postIDs}),
+		sq.Delete(tableMentions).Where(sq.Or{
+			sq.Eq{mentionFieldPostID: postIDs},
+			sq.Eq{mentionFieldCommentID: commentIDs},
+		}),
+		sq.Delete(tableComments).Where(sq.Eq{commentFieldID: commentIDs}),
+		sq.Delete(tablePosts).Where(sq.Eq{postFieldID: postIDs}),
+	}
+
+	for _, q := range deletes {
+		err = execDelete(ctx, tx, q)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = sq.Update(tablePostRevisions).
+		Set(postRevisionFieldEditorID, authentication.DeletedUserID).
+		Where(sq.Eq{postRevisionFieldEditorID: userID}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	return nil
+}
+
+// moveUserContent hands the user's posts, comments, revisions and reactions over to another user. A reaction is
+// dropped where the other user already reacted to the same target, since there can only be one per user.
+func moveUserContent(ctx context.Context, tx *sql.Tx, userID, toUserID string) error {
+	err := execDelete(ctx, tx, sq.Delete(tableReactions).
+		Where(sq.Eq{userReactionFieldUserID: userID}).
+		Where(
+			"("+userReactionFieldTargetType+", "+userReactionFieldTargetID+") IN (SELECT "+
+				userReactionFieldTargetType+", "+userReactionFieldTargetID+" FROM "+tableReactions+
+				" WHERE "+userReactionFieldUserID+" = ?)",
+			toUserID,
+		))
+	if err != nil {
+		return err
+	}
+
+	updates := []sq.UpdateBuilder{
+		sq.Update(tablePosts).Set(postFieldAuthorID, toUserID).Where(sq.Eq{postFieldAuthorID: userID}),
+		sq.Update(tableComments).Set(commentFieldAuthorID, toUserID).Where(sq.Eq{commentFieldAuthorID: userID}),
+		sq.Update(tablePostRevisions).
+			Set(postRevisionFieldEditorID, toUserID).
+			Where(sq.Eq{postRevisionFieldEditorID: userID}),
+		sq.Update(tableReactions).
+			Set(userReactionFieldUserID, toUserID).
+			Where(sq.Eq{userReactionFieldUserID: userID}),
+	}
+
+	for _, q := range updates {
+		_, err = q.RunWith(tx).ExecContext(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to exec update: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func selectIDs(ctx context.Context, tx *sql.Tx, q sq.SelectBuilder) ([]string, error) {
+	rows, err := q.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close rows", "error", err)
+		}
+	}()
+
+	ids := make([]string, 0)
+
+	for rows.Next() {
+		var id string
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return ids, nil
+}
+
+func execDelete(ctx context.Context, tx *sql.Tx, q sq.DeleteBuilder) error {
+	_, err := q.RunWith(tx).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete: %w", err)
+	}
+
+	return nil
+}
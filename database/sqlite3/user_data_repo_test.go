@@ -0,0 +1,265 @@
+package sqlite3_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/contents"
+	"github.com/nasermirzaei89/scribble/database/sqlite3"
+	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/nasermirzaei89/scribble/profiles"
+	"github.com/nasermirzaei89/scribble/reactions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// userDataFixture is a discussion between two users: Alice posts and Bob comments on it, Bob posts and Alice
+// comments on it, Bob replies twice below Alice's comment, and both react to each other.
+type userDataFixture struct {
+	alice, bob                      *authentication.User
+	alicePost, bobPost              *contents.Post
+	bobComment, aliceComment        *discuss.Comment
+	bobReply, bobNestedReply        *discuss.Comment
+	aliceSession, bobSession        *authentication.Session
+	aliceRevisionOfBob, bobRevision *contents.PostRevision
+}
+
+func newUserDataFixture(ctx context.Context, t *testing.T, db *sql.DB) *userDataFixture {
+	t.Helper()
+
+	at := time.Date(2026, 4, 1, 10, 0, 0, 0, time.UTC)
+	postRepo := sqlite3.NewPostRepository(db)
+	commentRepo := sqlite3.NewCommentRepository(db)
+	reactionRepo := sqlite3.NewUserReactionRepository(db)
+	revisionRepo := sqlite3.NewPostRevisionRepository(db)
+	sessionRepo := sqlite3.NewSessionRepository(db)
+
+	fixture := &userDataFixture{
+		alice: insertTwoFactorTestUser(ctx, t, db),
+		bob:   insertTwoFactorTestUser(ctx, t, db),
+	}
+
+	post := func(author *authentication.User) *contents.Post {
+		p := &contents.Post{ID: uuid.NewString(), AuthorID: author.ID, Content: "post", CreatedAt: at}
+		require.NoError(t, postRepo.Insert(ctx, p))
+
+		return p
+	}
+
+	comment := func(author *authentication.User, post *contents.Post, replyTo *discuss.Comment) *discuss.Comment {
+		c := &discuss.Comment{ID: uuid.NewString(), PostID: post.ID, AuthorID: author.ID, Content: "comment", CreatedAt: at}
+		if replyTo != nil {
+			c.ReplyTo = &replyTo.ID
+		}
+
+		require.NoError(t, commentRepo.Insert(ctx, c))
+
+		return c
+	}
+
+	react := func(user *authentication.User, targetType reactions.TargetType, targetID string) {
+		require.NoError(t, reactionRepo.Upsert(ctx, &reactions.UserReaction{
+			TargetType: targetType,
+			TargetID:   targetID,
+			UserID:     user.ID,
+			Emoji:      "👍",
+			CreatedAt:  at,
+		}))
+	}
+
+	session := func(user *authentication.User) *authentication.Session {
+		s := &authentication.Session{ID: uuid.NewString(), UserID: user.ID, CreatedAt: at, ExpiresAt: at.Add(time.Hour)}
+		require.NoError(t, sessionRepo.Insert(ctx, s))
+
+		return s
+	}
+
+	revision := func(editor *authentication.User, post *contents.Post) *contents.PostRevision {
+		r := &contents.PostRevision{ID: uuid.NewString(), PostID: post.ID, EditorID: editor.ID, Content: "old", CreatedAt: at}
+		require.NoError(t, revisionRepo.Insert(ctx, r))
+
+		return r
+	}
+
+	fixture.alicePost = post(fixture.alice)
+	fixture.bobPost = post(fixture.bob)
+	fixture.bobComment = comment(fixture.bob, fixture.alicePost, nil)
+	fixture.aliceComment = comment(fixture.alice, fixture.bobPost, nil)
+	fixture.bobReply = comment(fixture.bob, fixture.bobPost, fixture.aliceComment)
+	fixture.bobNestedReply = comment(fixture.bob, fixture.bobPost, fixture.bobReply)
+	fixture.aliceSession = session(fixture.alice)
+	fixture.bobSession = session(fixture.bob)
+	fixture.aliceRevisionOfBob = revision(fixture.alice, fixture.bobPost)
+	fixture.bobRevision = revision(fixture.bob, fixture.bobPost)
+
+	require.NoError(t, sqlite3.NewProfileRepository(db).Upsert(ctx, &profiles.Profile{
+		UserID:      fixture.alice.ID,
+		DisplayName: "Alice",
+		UpdatedAt:   at,
+	}))
+
+	react(fixture.alice, reactions.TargetTypePost, fixture.bobPost.ID)
+	react(fixture.alice, reactions.TargetTypeComment, fixture.bobComment.ID)
+	react(fixture.bob, reactions.TargetTypePost, fixture.alicePost.ID)
+	react(fixture.bob, reactions.TargetTypeComment, fixture.aliceComment.ID)
+
+	return fixture
+}
+
+func TestUserDataRepositoryList(t *testing.T) {
+	ctx, db := newTestDB(t)
+
+	repo := sqlite3.NewUserDataRepository(db)
+	fixture := newUserDataFixture(ctx, t, db)
+
+	posts, err := repo.ListPosts(ctx, fixture.alice.ID)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	assert.Equal(t, fixture.alicePost.ID, posts[0].ID)
+
+	comments, err := repo.ListComments(ctx, fixture.bob.ID)
+	require.NoError(t, err)
+	require.Len(t, comments, 3)
+
+	for _, comment := range comments {
+		if comment.ID == fixture.bobReply.ID {
+			require.NotNil(t, comment.ReplyTo)
+			assert.Equal(t, fixture.aliceComment.ID, *comment.ReplyTo)
+		}
+	}
+
+	userReactions, err := repo.ListReactions(ctx, fixture.alice.ID)
+	require.NoError(t, err)
+	assert.Len(t, userReactions, 2)
+
+	profile, err := repo.FindProfile(ctx, fixture.alice.ID)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, "Alice", profile.DisplayName)
+
+	profile, err = repo.FindProfile(ctx, fixture.bob.ID)
+	require.NoError(t, err)
+	assert.Nil(t, profile)
+
+	posts, err = repo.ListPosts(ctx, uuid.NewString())
+	require.NoError(t, err)
+	assert.Empty(t, posts)
+}
+
+func TestUserDataRepositoryDeleteUser(t *testing.T) {
+	t.Run("delete content", func(t *testing.T) {
+		ctx, db := newTestDB(t)
+
+		repo := sqlite3.NewUserDataRepository(db)
+		fixture := newUserDataFixture(ctx, t, db)
+
+		err := repo.DeleteUser(ctx, fixture.alice.ID, authentication.ContentRetentionDelete)
+		require.NoError(t, err)
+
+		_, err = sqlite3.NewUserRepository(db).Find(ctx, fixture.alice.ID)
+		require.ErrorAs(t, err, new(*authentication.UserNotFoundError))
+
+		_, err = sqlite3.NewSessionRepository(db).Find(ctx, fixture.aliceSession.ID)
+		require.Error(t, err)
+
+		_, err = sqlite3.NewProfileRepository(db).Find(ctx, fixture.alice.ID)
+		require.ErrorAs(t, err, new(profiles.ProfileNotFoundError))
+
+		_, err = sqlite3.NewPostRepository(db).Find(ctx, fixture.alicePost.ID)
+		require.ErrorAs(t, err, new(contents.PostNotFoundError))
+
+		_, err = sqlite3.NewPostRepository(db).Find(ctx, fixture.bobPost.ID)
+		require.NoError(t, err)
+
+		// Bob's comment was on Alice's post, and his replies were below her comment.
+		comments, err := repo.ListComments(ctx, fixture.bob.ID)
+		require.NoError(t, err)
+		assert.Empty(t, comments)
+
+		// Alice's revision of Bob's post stays in its history, under the deleted user.
+		revisions, err := sqlite3.NewPostRevisionRepository(db).List(ctx, fixture.bobPost.ID)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+
+		for _, revision := range revisions {
+			if revision.ID == fixture.aliceRevisionOfBob.ID {
+				assert.Equal(t, authentication.DeletedUserID, revision.EditorID)
+			} else {
+				assert.Equal(t, fixture.bob.ID, revision.EditorID)
+			}
+		}
+
+		// Bob's reactions were to Alice's post and comment.
+		userReactions, err := repo.ListReactions(ctx, fixture.bob.ID)
+		require.NoError(t, err)
+		assert.Empty(t, userReactions)
+
+		_, err = sqlite3.NewSessionRepository(db).Find(ctx, fixture.bobSession.ID)
+		require.NoError(t, err)
+
+		err = repo.DeleteUser(ctx, fixture.alice.ID, authentication.ContentRetentionDelete)
+		require.ErrorAs(t, err, new(*authentication.UserNotFoundError))
+	})
+
+	t.Run("tombstone content", func(t *testing.T) {
+		ctx, db := newTestDB(t)
+
+		repo := sqlite3.NewUserDataRepository(db)
+		fixture := newUserDataFixture(ctx, t, db)
+
+		// The deleted user already reacted to Bob's post, so Alice's reaction to it can't be moved.
+		err := sqlite3.NewUserReactionRepository(db).Upsert(ctx, &reactions.UserReaction{
+			TargetType: reactions.TargetTypePost,
+			TargetID:   fixture.bobPost.ID,
+			UserID:     authentication.DeletedUserID,
+			Emoji:      "🎉",
+			CreatedAt:  time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC),
+		})
+		require.NoError(t, err)
+
+		err = repo.DeleteUser(ctx, fixture.alice.ID, authentication.ContentRetentionTombstone)
+		require.NoError(t, err)
+
+		_, err = sqlite3.NewUserRepository(db).Find(ctx, fixture.alice.ID)
+		require.ErrorAs(t, err, new(*authentication.UserNotFoundError))
+
+		deleted, err := sqlite3.NewUserRepository(db).Find(ctx, authentication.DeletedUserID)
+		require.NoError(t, err)
+		assert.Equal(t, authentication.DeletedUsername, deleted.Username)
+
+		posts, err := repo.ListPosts(ctx, authentication.DeletedUserID)
+		require.NoError(t, err)
+		require.Len(t, posts, 1)
+		assert.Equal(t, fixture.alicePost.ID, posts[0].ID)
+
+		comments, err := repo.ListComments(ctx, authentication.DeletedUserID)
+		require.NoError(t, err)
+		require.Len(t, comments, 1)
+		assert.Equal(t, fixture.aliceComment.ID, comments[0].ID)
+
+		comments, err = repo.ListComments(ctx, fixture.bob.ID)
+		require.NoError(t, err)
+		assert.Len(t, comments, 3)
+
+		userReactions, err := repo.ListReactions(ctx, authentication.DeletedUserID)
+		require.NoError(t, err)
+		require.Len(t, userReactions, 2)
+
+		for _, reaction := range userReactions {
+			if reaction.TargetID == fixture.bobPost.ID {
+				assert.Equal(t, "🎉", reaction.Emoji)
+			}
+		}
+
+		revisions, err := sqlite3.NewPostRevisionRepository(db).List(ctx, fixture.bobPost.ID)
+		require.NoError(t, err)
+		assert.Len(t, revisions, 2)
+
+		_, err = sqlite3.NewSessionRepository(db).Find(ctx, fixture.aliceSession.ID)
+		require.Error(t, err)
+	})
+}
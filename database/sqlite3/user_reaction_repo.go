@@ -90,6 +90,56 @@ func (repo *UserReactionRepository) FindByUserTarget(
 	return reaction, nil
 }
 
+func (repo *UserReactionRepository) FindByUserTargets(
+	ctx context.Context,
+	targetType reactions.TargetType,
+	targetIDs []string,
+	userID string,
+) ([]*reactions.UserReaction, error) {
+	if len(targetIDs) == 0 {
+		return []*reactions.UserReaction{}, nil
+	}
+
+	q := sq.Select(reactionColumns()...).
+		From(tableReactions).
+		Where(sq.Eq{
+			userReactionFieldTargetType: targetType,
+			userReactionFieldTargetID:   targetIDs,
+			userReactionFieldUserID:     userID,
+		}).
+		RunWith(repo.db)
+
+	rows, err := q.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reactions by user targets: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close reaction rows", "error", err)
+		}
+	}()
+
+	result := make([]*reactions.UserReaction, 0, len(targetIDs))
+
+	for rows.Next() {
+		reaction, err := scanUserReaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reaction: %w", err)
+		}
+
+		result = append(result, reaction)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate reaction rows: %w", err)
+	}
+
+	return result, nil
+}
+
 func (repo *UserReactionRepository) Upsert(ctx context.Context, reaction *reactions.UserReaction) error {
 	query := fmt.Sprintf(`
 INSERT INTO %s (target_type, target_id, user_id, emoji, created_at)
@@ -186,3 +236,60 @@ func (repo *UserReactionRepository) CountByTarget(
 
 	return counts, nil
 }
+
+func (repo *UserReactionRepository) CountByTargets(
+	ctx context.Context,
+	targetType reactions.TargetType,
+	targetIDs []string,
+) (map[string]map[string]int, error) {
+	counts := make(map[string]map[string]int, len(targetIDs))
+
+	if len(targetIDs) == 0 {
+		return counts, nil
+	}
+
+	q := sq.Select(userReactionFieldTargetID, userReactionFieldEmoji, "COUNT(*)").
+		From(tableReactions).
+		Where(sq.Eq{
+			userReactionFieldTargetType: targetType,
+			userReactionFieldTargetID:   targetIDs,
+		}).
+		GroupBy(userReactionFieldTargetID, userReactionFieldEmoji).
+		RunWith(repo.db)
+
+	rows, err := q.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reaction counts: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close reaction rows", "error", err)
+		}
+	}()
+
+	for rows.Next() {
+		var targetID, emoji string
+
+		var count int
+
+		err := rows.Scan(&targetID, &emoji, &count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reaction count row: %w", err)
+		}
+
+		if counts[targetID] == nil {
+			counts[targetID] = make(map[string]int)
+		}
+
+		counts[targetID][emoji] = count
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate reaction count rows: %w", err)
+	}
+
+	return counts, nil
+}
@@ -117,6 +117,44 @@ func TestUserReactionRepository(t *testing.T) {
 		assert.Equal(t, 0, counts["🔥"])
 	})
 
+	t.Run("CountByTargets and FindByUserTargets", func(t *testing.T) {
+		targetIDs := []string{uuid.NewString(), uuid.NewString(), uuid.NewString()}
+
+		for i, reaction := range []*reactions.UserReaction{
+			{TargetID: targetIDs[0], UserID: user1.ID, Emoji: "👍"},
+			{TargetID: targetIDs[0], UserID: user2.ID, Emoji: "👍"},
+			{TargetID: targetIDs[1], UserID: user2.ID, Emoji: "😂"},
+		} {
+			reaction.TargetType = reactions.TargetTypeComment
+			reaction.CreatedAt = time.Date(2026, 2, 24, 14, i, 0, 0, time.UTC)
+
+			err := repo.Upsert(ctx, reaction)
+			require.NoError(t, err)
+		}
+
+		counts, err := repo.CountByTargets(ctx, reactions.TargetTypeComment, targetIDs)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]map[string]int{
+			targetIDs[0]: {"👍": 2},
+			targetIDs[1]: {"😂": 1},
+		}, counts)
+
+		counts, err = repo.CountByTargets(ctx, reactions.TargetTypePost, targetIDs)
+		require.NoError(t, err)
+		assert.Empty(t, counts)
+
+		found, err := repo.FindByUserTargets(ctx, reactions.TargetTypeComment, targetIDs, user2.ID)
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+
+		emojis := map[string]string{found[0].TargetID: found[0].Emoji, found[1].TargetID: found[1].Emoji}
+		assert.Equal(t, map[string]string{targetIDs[0]: "👍", targetIDs[1]: "😂"}, emojis)
+
+		found, err = repo.FindByUserTargets(ctx, reactions.TargetTypeComment, nil, user2.ID)
+		require.NoError(t, err)
+		assert.Empty(t, found)
+	})
+
 	t.Run("DeleteByUserTarget", func(t *testing.T) {
 		err := repo.DeleteByUserTarget(ctx, reactions.TargetTypePost, targetID, user1.ID)
 		require.NoError(t, err)
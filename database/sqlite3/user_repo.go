@@ -5,9 +5,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/discuss"
 )
 
 const tableUsers = "users"
@@ -16,17 +20,23 @@ type UserRepository struct {
 	db *sql.DB
 }
 
-var _ authentication.UserRepository = (*UserRepository)(nil)
+var (
+	_ authentication.UserRepository = (*UserRepository)(nil)
+	_ discuss.UserRepository        = (*UserRepository)(nil)
+)
 
 func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
 const (
-	userFieldID           = "id"
-	userFieldUsername     = "username"
-	userFieldPasswordHash = "password_hash"
-	userFieldRegisteredAt = "registered_at"
+	userFieldID              = "id"
+	userFieldUsername        = "username"
+	userFieldCanonical       = "username_canonical"
+	userFieldPasswordHash    = "password_hash"
+	userFieldRegisteredAt    = "registered_at"
+	userFieldEmail           = "email"
+	userFieldEmailVerifiedAt = "email_verified_at"
 )
 
 func userColumns() []string {
@@ -35,6 +45,8 @@ func userColumns() []string {
 		userFieldUsername,
 		userFieldPasswordHash,
 		userFieldRegisteredAt,
+		userFieldEmail,
+		userFieldEmailVerifiedAt,
 	}
 }
 
@@ -46,6 +58,8 @@ func scanUser(row sq.RowScanner) (*authentication.User, error) {
 		&user.Username,
 		&user.PasswordHash,
 		&user.RegisteredAt,
+		&user.Email,
+		&user.EmailVerifiedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan row: %w", err)
@@ -56,8 +70,16 @@ func scanUser(row sq.RowScanner) (*authentication.User, error) {
 
 func (repo *UserRepository) Insert(ctx context.Context, user *authentication.User) error {
 	q := sq.Insert(tableUsers).
-		Columns(userColumns()...).
-		Values(user.ID, user.Username, user.PasswordHash, user.RegisteredAt)
+		Columns(append(userColumns(), userFieldCanonical)...).
+		Values(
+			user.ID,
+			user.Username,
+			user.PasswordHash,
+			user.RegisteredAt,
+			normalizeEmail(user.Email),
+			user.EmailVerifiedAt,
+			authentication.CanonicalUsername(user.Username),
+		)
 
 	q = q.RunWith(repo.db)
 
@@ -90,10 +112,102 @@ func (repo *UserRepository) Find(ctx context.Context, userID string) (*authentic
 	return user, nil
 }
 
+// FindRegisteredAt serves the discuss package, which checks how old the accounts of commenters are.
+func (repo *UserRepository) FindRegisteredAt(ctx context.Context, userID string) (time.Time, error) {
+	q := sq.Select(userFieldRegisteredAt).
+		From(tableUsers).
+		Where(sq.Eq{userFieldID: userID})
+
+	q = q.RunWith(repo.db)
+
+	var registeredAt time.Time
+
+	err := q.QueryRowContext(ctx).Scan(&registeredAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, &authentication.UserNotFoundError{ID: userID}
+		}
+
+		return time.Time{}, fmt.Errorf("query failed: %w", err)
+	}
+
+	return registeredAt, nil
+}
+
+func (repo *UserRepository) FindMany(ctx context.Context, userIDs []string) ([]*authentication.User, error) {
+	if len(userIDs) == 0 {
+		return []*authentication.User{}, nil
+	}
+
+	q := sq.Select(userColumns()...).
+		From(tableUsers).
+		Where(sq.Eq{userFieldID: userIDs})
+
+	return repo.findMany(ctx, q, len(userIDs))
+}
+
+func (repo *UserRepository) FindManyByUsernames(
+	ctx context.Context,
+	usernames []string,
+) ([]*authentication.User, error) {
+	if len(usernames) == 0 {
+		return []*authentication.User{}, nil
+	}
+
+	canonical := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		canonical = append(canonical, authentication.CanonicalUsername(username))
+	}
+
+	q := sq.Select(userColumns()...).
+		From(tableUsers).
+		Where(sq.Eq{userFieldCanonical: canonical})
+
+	return repo.findMany(ctx, q, len(usernames))
+}
+
+func (repo *UserRepository) findMany(
+	ctx context.Context,
+	q sq.SelectBuilder,
+	capacity int,
+) ([]*authentication.User, error) {
+	q = q.RunWith(repo.db)
+
+	rows, err := q.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close rows", "error", err)
+		}
+	}()
+
+	users := make([]*authentication.User, 0, capacity)
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan user failed: %w", err)
+		}
+
+		users = append(users, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return users, nil
+}
+
 func (repo *UserRepository) FindByUsername(ctx context.Context, username string) (*authentication.User, error) {
 	q := sq.Select(userColumns()...).
 		From(tableUsers).
-		Where(sq.Eq{userFieldUsername: username})
+		Where(sq.Eq{userFieldCanonical: authentication.CanonicalUsername(username)})
 
 	q = q.RunWith(repo.db)
 
@@ -110,3 +224,76 @@ func (repo *UserRepository) FindByUsername(ctx context.Context, username string)
 
 	return user, nil
 }
+
+// normalizeEmail lowercases the whole address. The local part is case-sensitive by the letter of RFC 5321, but no
+// mail provider treats it that way, and matching case-insensitively keeps one mailbox from verifying two accounts.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (repo *UserRepository) FindByEmail(ctx context.Context, email string) (*authentication.User, error) {
+	q := sq.Select(userColumns()...).
+		From(tableUsers).
+		Where(sq.Eq{userFieldEmail: normalizeEmail(email)}).
+		Where(sq.NotEq{userFieldEmailVerifiedAt: nil})
+
+	q = q.RunWith(repo.db)
+
+	row := q.QueryRowContext(ctx)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &authentication.UserByEmailNotFoundError{Email: email}
+		}
+
+		return nil, fmt.Errorf("failed to scan user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (repo *UserRepository) SetEmail(ctx context.Context, userID, email string) error {
+	q := sq.Update(tableUsers).
+		Set(userFieldEmail, normalizeEmail(email)).
+		Set(userFieldEmailVerifiedAt, nil).
+		Where(sq.Eq{userFieldID: userID})
+
+	return repo.execUpdate(ctx, q, userID)
+}
+
+func (repo *UserRepository) MarkEmailVerified(ctx context.Context, userID, email string, verifiedAt time.Time) error {
+	q := sq.Update(tableUsers).
+		Set(userFieldEmailVerifiedAt, verifiedAt).
+		Where(sq.Eq{userFieldID: userID, userFieldEmail: normalizeEmail(email)})
+
+	return repo.execUpdate(ctx, q, userID)
+}
+
+func (repo *UserRepository) UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error {
+	q := sq.Update(tableUsers).
+		Set(userFieldPasswordHash, passwordHash).
+		Where(sq.Eq{userFieldID: userID})
+
+	return repo.execUpdate(ctx, q, userID)
+}
+
+func (repo *UserRepository) execUpdate(ctx context.Context, q sq.UpdateBuilder, userID string) error {
+	q = q.RunWith(repo.db)
+
+	result, err := q.ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec update: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &authentication.UserNotFoundError{ID: userID}
+	}
+
+	return nil
+}
@@ -61,6 +61,13 @@ func TestUserRepository(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, user.ID, foundByUsername.ID)
 		assert.Equal(t, user.Username, foundByUsername.Username)
+
+		foundRegisteredAt, err := repo.FindRegisteredAt(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, foundRegisteredAt.Equal(registeredAt))
+
+		_, err = repo.FindRegisteredAt(ctx, uuid.NewString())
+		require.ErrorAs(t, err, new(*authentication.UserNotFoundError))
 	})
 
 	t.Run("Insert duplicate username", func(t *testing.T) {
@@ -74,4 +81,142 @@ func TestUserRepository(t *testing.T) {
 		err := repo.Insert(ctx, user)
 		require.Error(t, err)
 	})
+
+	t.Run("Insert username differing only in case or width", func(t *testing.T) {
+		for _, username := range []string{"JohnDoe", "ｊｏｈｎｄｏｅ"} {
+			user := &authentication.User{
+				ID:           uuid.NewString(),
+				Username:     username,
+				PasswordHash: "another-hash",
+				RegisteredAt: time.Date(2026, 2, 24, 11, 0, 0, 0, time.UTC),
+			}
+
+			err := repo.Insert(ctx, user)
+			require.Error(t, err, username)
+		}
+	})
+
+	t.Run("FindByUsername is case-insensitive and keeps the display form", func(t *testing.T) {
+		user := &authentication.User{
+			ID:           uuid.NewString(),
+			Username:     "MaryJane",
+			PasswordHash: "password-hash",
+			RegisteredAt: time.Date(2026, 2, 24, 11, 30, 0, 0, time.UTC),
+		}
+
+		err := repo.Insert(ctx, user)
+		require.NoError(t, err)
+
+		found, err := repo.FindByUsername(ctx, "maryjane")
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.ID)
+		assert.Equal(t, "MaryJane", found.Username)
+	})
+
+	t.Run("FindMany", func(t *testing.T) {
+		user := &authentication.User{
+			ID:           uuid.NewString(),
+			Username:     "janedoe",
+			PasswordHash: "password-hash",
+			RegisteredAt: time.Date(2026, 2, 24, 12, 0, 0, 0, time.UTC),
+		}
+
+		err := repo.Insert(ctx, user)
+		require.NoError(t, err)
+
+		johndoe, err := repo.FindByUsername(ctx, "johndoe")
+		require.NoError(t, err)
+
+		users, err := repo.FindMany(ctx, []string{user.ID, johndoe.ID, uuid.NewString()})
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+		assert.ElementsMatch(t, []string{user.ID, johndoe.ID}, []string{users[0].ID, users[1].ID})
+
+		users, err = repo.FindMany(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, users)
+	})
+
+	t.Run("FindManyByUsernames", func(t *testing.T) {
+		users, err := repo.FindManyByUsernames(ctx, []string{"JohnDoe", "maryjane", "missing-username"})
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+		assert.ElementsMatch(t, []string{"johndoe", "MaryJane"}, []string{users[0].Username, users[1].Username})
+
+		users, err = repo.FindManyByUsernames(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, users)
+	})
+
+	t.Run("SetEmail and MarkEmailVerified", func(t *testing.T) {
+		johndoe, err := repo.FindByUsername(ctx, "johndoe")
+		require.NoError(t, err)
+		assert.Empty(t, johndoe.Email)
+		assert.Nil(t, johndoe.EmailVerifiedAt)
+
+		err = repo.SetEmail(ctx, johndoe.ID, "John@Example.com")
+		require.NoError(t, err)
+
+		// Unverified addresses don't identify anyone.
+		_, err = repo.FindByEmail(ctx, "john@example.com")
+
+		var notFoundErr *authentication.UserByEmailNotFoundError
+
+		require.ErrorAs(t, err, &notFoundErr)
+
+		err = repo.MarkEmailVerified(ctx, johndoe.ID, "someone@example.com", time.Now())
+
+		var userNotFoundErr *authentication.UserNotFoundError
+
+		require.ErrorAs(t, err, &userNotFoundErr)
+
+		verifiedAt := time.Date(2026, 2, 25, 9, 0, 0, 0, time.UTC)
+
+		err = repo.MarkEmailVerified(ctx, johndoe.ID, "john@example.com", verifiedAt)
+		require.NoError(t, err)
+
+		found, err := repo.FindByEmail(ctx, "JOHN@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, johndoe.ID, found.ID)
+		assert.Equal(t, "john@example.com", found.Email)
+		require.NotNil(t, found.EmailVerifiedAt)
+		assert.True(t, found.EmailVerifiedAt.Equal(verifiedAt))
+
+		// Another account may claim the address, but can't verify it too.
+		janedoe, err := repo.FindByUsername(ctx, "janedoe")
+		require.NoError(t, err)
+
+		err = repo.SetEmail(ctx, janedoe.ID, "john@example.com")
+		require.NoError(t, err)
+
+		err = repo.MarkEmailVerified(ctx, janedoe.ID, "john@example.com", verifiedAt)
+		require.Error(t, err)
+
+		// Changing the address drops the verification.
+		err = repo.SetEmail(ctx, johndoe.ID, "john@example.org")
+		require.NoError(t, err)
+
+		found, err = repo.Find(ctx, johndoe.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "john@example.org", found.Email)
+		assert.Nil(t, found.EmailVerifiedAt)
+	})
+
+	t.Run("UpdatePasswordHash", func(t *testing.T) {
+		johndoe, err := repo.FindByUsername(ctx, "johndoe")
+		require.NoError(t, err)
+
+		err = repo.UpdatePasswordHash(ctx, johndoe.ID, "new-hash")
+		require.NoError(t, err)
+
+		found, err := repo.Find(ctx, johndoe.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "new-hash", found.PasswordHash)
+
+		err = repo.UpdatePasswordHash(ctx, uuid.NewString(), "new-hash")
+
+		var userNotFoundErr *authentication.UserNotFoundError
+
+		require.ErrorAs(t, err, &userNotFoundErr)
+	})
 }
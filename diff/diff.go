@@ -0,0 +1,176 @@
+// Package diff computes line-based differences between two texts.
+package diff
+
+import "strings"
+
+type Operation string
+
+const (
+	OperationEqual  Operation = "equal"
+	OperationInsert Operation = "insert"
+	OperationDelete Operation = "delete"
+)
+
+// Line is a single line of a diff, tagged with what happened to it.
+type Line struct {
+	Operation Operation
+	Text      string
+}
+
+// Lines returns the shortest edit script that turns a into b, one entry per line.
+func Lines(a, b string) []Line {
+	return compute(splitLines(a), splitLines(b))
+}
+
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, "\n")
+}
+
+// compute implements the linear space variant of Myers' O(ND) algorithm. Rather than keeping the frontier of every
+// edit distance to walk the path back, it finds the middle of the path from both ends and diffs the two halves on
+// either side of it, so memory stays proportional to the input however different the texts are.
+func compute(a, b []string) []Line {
+	lines := make([]Line, 0, len(a)+len(b))
+
+	return appendDiff(lines, a, b)
+}
+
+func appendDiff(lines []Line, a, b []string) []Line {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		lines = append(lines, Line{Operation: OperationEqual, Text: a[0]})
+		a, b = a[1:], b[1:]
+	}
+
+	common := 0
+	for common < len(a) && common < len(b) && a[len(a)-1-common] == b[len(b)-1-common] {
+		common++
+	}
+
+	suffix := a[len(a)-common:]
+	a, b = a[:len(a)-common], b[:len(b)-common]
+
+	switch {
+	case len(a) == 0:
+		lines = appendAll(lines, OperationInsert, b)
+	case len(b) == 0:
+		lines = appendAll(lines, OperationDelete, a)
+	default:
+		x, y, ok := bisect(a, b)
+		if ok {
+			lines = appendDiff(lines, a[:x], b[:y])
+			lines = appendDiff(lines, a[x:], b[y:])
+		} else {
+			lines = appendAll(lines, OperationDelete, a)
+			lines = appendAll(lines, OperationInsert, b)
+		}
+	}
+
+	return appendAll(lines, OperationEqual, suffix)
+}
+
+func appendAll(lines []Line, operation Operation, texts []string) []Line {
+	for _, text := range texts {
+		lines = append(lines, Line{Operation: operation, Text: text})
+	}
+
+	return lines
+}
+
+// bisect finds where the shortest edit script of a and b can be split in two, by searching forward from the start and
+// backward from the end until the paths meet. a and b must neither be empty nor share a first or last line, which
+// keeps the split from landing on either end. It reports false if the texts have nothing in common.
+func bisect(a, b []string) (int, int, bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	size := 2*maxD + 3
+
+	forward := make([]int, size)
+	backward := make([]int, size)
+
+	for i := range size {
+		forward[i], backward[i] = -1, -1
+	}
+
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	// With an odd delta the paths meet on a forward step, with an even one on a backward step.
+	odd := delta%2 != 0
+
+	// Diagonals that ran off the edit graph are left out of the next rounds.
+	forwardStart, forwardEnd, backwardStart, backwardEnd := 0, 0, 0, 0
+
+	for d := range maxD {
+		for k := -d + forwardStart; k <= d-forwardEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+
+			y := x - k
+
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			forward[offset+k] = x
+
+			switch {
+			case x > n:
+				forwardEnd += 2
+			case y > m:
+				forwardStart += 2
+			case odd:
+				i := offset + delta - k
+				if i >= 0 && i < size && backward[i] != -1 && x >= n-backward[i] {
+					return x, y, true
+				}
+			}
+		}
+
+		for k := -d + backwardStart; k <= d-backwardEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+
+			y := x - k
+
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+
+			backward[offset+k] = x
+
+			switch {
+			case x > n:
+				backwardEnd += 2
+			case y > m:
+				backwardStart += 2
+			case !odd:
+				i := offset + delta - k
+				if i >= 0 && i < size && forward[i] != -1 && forward[i] >= n-x {
+					fx := forward[i]
+
+					return fx, fx - (delta - k), true
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
@@ -0,0 +1,172 @@
+package diff_test
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nasermirzaei89/scribble/diff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLines(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		a        string
+		b        string
+		expected []diff.Line
+	}{
+		{
+			name:     "both empty",
+			a:        "",
+			b:        "",
+			expected: []diff.Line{},
+		},
+		{
+			name: "identical",
+			a:    "one\ntwo",
+			b:    "one\ntwo\n",
+			expected: []diff.Line{
+				{Operation: diff.OperationEqual, Text: "one"},
+				{Operation: diff.OperationEqual, Text: "two"},
+			},
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "one\ntwo",
+			expected: []diff.Line{
+				{Operation: diff.OperationInsert, Text: "one"},
+				{Operation: diff.OperationInsert, Text: "two"},
+			},
+		},
+		{
+			name: "to empty",
+			a:    "one\ntwo",
+			b:    "",
+			expected: []diff.Line{
+				{Operation: diff.OperationDelete, Text: "one"},
+				{Operation: diff.OperationDelete, Text: "two"},
+			},
+		},
+		{
+			name: "changed line in the middle",
+			a:    "one\ntwo\nthree",
+			b:    "one\n2\nthree",
+			expected: []diff.Line{
+				{Operation: diff.OperationEqual, Text: "one"},
+				{Operation: diff.OperationDelete, Text: "two"},
+				{Operation: diff.OperationInsert, Text: "2"},
+				{Operation: diff.OperationEqual, Text: "three"},
+			},
+		},
+		{
+			name: "crlf line endings",
+			a:    "one\r\ntwo",
+			b:    "one\ntwo\nthree",
+			expected: []diff.Line{
+				{Operation: diff.OperationEqual, Text: "one"},
+				{Operation: diff.OperationEqual, Text: "two"},
+				{Operation: diff.OperationInsert, Text: "three"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := diff.Lines(tc.a, tc.b)
+
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+// TestLinesIsShortest checks random edits against the length of the longest common subsequence, which every shortest
+// edit script keeps as its equal lines.
+func TestLinesIsShortest(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // deterministic test input
+
+	for range 500 {
+		a := randomLines(rng)
+		b := randomLines(rng)
+
+		result := diff.Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+
+		var gotA, gotB []string
+
+		equal := 0
+
+		for _, line := range result {
+			switch line.Operation {
+			case diff.OperationEqual:
+				gotA, gotB = append(gotA, line.Text), append(gotB, line.Text)
+				equal++
+			case diff.OperationDelete:
+				gotA = append(gotA, line.Text)
+			case diff.OperationInsert:
+				gotB = append(gotB, line.Text)
+			}
+		}
+
+		require.Equal(t, a, gotA)
+		require.Equal(t, b, gotB)
+		require.Equal(t, longestCommonSubsequence(a, b), equal)
+	}
+}
+
+func TestLinesLargeRewrite(t *testing.T) {
+	t.Parallel()
+
+	a := make([]string, 2000)
+	b := make([]string, 2000)
+
+	for i := range a {
+		a[i] = "old " + strconv.Itoa(i)
+		b[i] = "new " + strconv.Itoa(i)
+	}
+
+	result := testing.Benchmark(func(bench *testing.B) {
+		for range bench.N {
+			diff.Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+		}
+	})
+
+	// The edit script alone is 4000 lines; anything quadratic in the input would take hundreds of megabytes.
+	assert.Less(t, result.AllocedBytesPerOp(), int64(4<<20))
+}
+
+func randomLines(rng *rand.Rand) []string {
+	lines := make([]string, rng.IntN(12)+1)
+	for i := range lines {
+		lines[i] = string(rune('a' + rng.IntN(4)))
+	}
+
+	return lines
+}
+
+func longestCommonSubsequence(a, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				lengths[i+1][j+1] = lengths[i][j] + 1
+			} else {
+				lengths[i+1][j+1] = max(lengths[i][j+1], lengths[i+1][j])
+			}
+		}
+	}
+
+	return lengths[len(a)][len(b)]
+}
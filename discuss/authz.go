@@ -2,15 +2,22 @@ package discuss
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
 	"github.com/nasermirzaei89/scribble/authorization"
 )
 
 const (
 	ActionCreateComment = "createComment"
+	ActionGetComment    = "getComment"
+	ActionUpdateComment = "updateComment"
+	ActionDeleteComment = "deleteComment"
 	ActionListComments  = "listComments"
 	ActionCountComments = "countComments"
+	// ActionModerateComments lets comments be added to posts whatever their CommentSettings say.
+	ActionModerateComments = "moderateComments"
 )
 
 type AuthorizationMiddleware struct {
@@ -33,6 +40,11 @@ func (mw *AuthorizationMiddleware) CreateComment(ctx context.Context, req Create
 		return nil, fmt.Errorf("failed to check authorization: %w", err)
 	}
 
+	err = mw.checkCommentSettings(ctx, req.PostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization: %w", err)
+	}
+
 	comment, err := mw.next.CreateComment(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call next method: %w", err)
@@ -41,18 +53,97 @@ func (mw *AuthorizationMiddleware) CreateComment(ctx context.Context, req Create
 	return comment, nil
 }
 
-func (mw *AuthorizationMiddleware) ListComments(ctx context.Context, postID string) ([]*Comment, error) {
+func (mw *AuthorizationMiddleware) GetComment(ctx context.Context, commentID string) (*Comment, error) {
+	err := mw.authzClient.CheckAccess(ctx, ServiceName, commentID, ActionGetComment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	comment, err := mw.next.GetComment(ctx, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return comment, nil
+}
+
+// checkCommentOwnerAccess allows the author of the comment unconditionally and falls back to the policy for everyone
+// else, so moderators and root can still manage comments they don't own.
+func (mw *AuthorizationMiddleware) checkCommentOwnerAccess(ctx context.Context, commentID, action string) error {
+	comment, err := mw.next.GetComment(ctx, commentID)
+	if err != nil {
+		return fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	if comment.AuthorID == authcontext.GetSubject(ctx) {
+		return nil
+	}
+
+	err = mw.authzClient.CheckAccess(ctx, ServiceName, commentID, action)
+	if err != nil {
+		return fmt.Errorf("failed to check access: %w", err)
+	}
+
+	return nil
+}
+
+func (mw *AuthorizationMiddleware) UpdateComment(ctx context.Context, req UpdateCommentRequest) (*Comment, error) {
+	err := mw.checkCommentOwnerAccess(ctx, req.CommentID, ActionUpdateComment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	comment, err := mw.next.UpdateComment(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return comment, nil
+}
+
+func (mw *AuthorizationMiddleware) DeleteComment(ctx context.Context, commentID string) error {
+	err := mw.checkCommentOwnerAccess(ctx, commentID, ActionDeleteComment)
+	if err != nil {
+		return fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	err = mw.next.DeleteComment(ctx, commentID)
+	if err != nil {
+		return fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return nil
+}
+
+func (mw *AuthorizationMiddleware) ListComments(
+	ctx context.Context,
+	params ListCommentsParams,
+) (*ListCommentsResult, error) {
+	err := mw.authzClient.CheckAccess(ctx, ServiceName, "", ActionListComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	result, err := mw.next.ListComments(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return result, nil
+}
+
+func (mw *AuthorizationMiddleware) ListCommentAncestors(ctx context.Context, commentID string) ([]*Comment, error) {
 	err := mw.authzClient.CheckAccess(ctx, ServiceName, "", ActionListComments)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check authorization: %w", err)
 	}
 
-	comments, err := mw.next.ListComments(ctx, postID)
+	ancestors, err := mw.next.ListCommentAncestors(ctx, commentID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call next method: %w", err)
 	}
 
-	return comments, nil
+	return ancestors, nil
 }
 
 func (mw *AuthorizationMiddleware) CountComments(ctx context.Context, postID string) (int, error) {
@@ -68,3 +159,46 @@ func (mw *AuthorizationMiddleware) CountComments(ctx context.Context, postID str
 
 	return count, nil
 }
+
+func (mw *AuthorizationMiddleware) CountCommentsByPosts(ctx context.Context, postIDs []string) (map[string]int, error) {
+	err := mw.authzClient.CheckAccess(ctx, ServiceName, "", ActionCountComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	counts, err := mw.next.CountCommentsByPosts(ctx, postIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return counts, nil
+}
+
+// CheckCommentAccess is open to whoever can see the comments, so the comment form can tell why it's closed.
+func (mw *AuthorizationMiddleware) CheckCommentAccess(ctx context.Context, postID string) error {
+	err := mw.authzClient.CheckAccess(ctx, ServiceName, "", ActionListComments)
+	if err != nil {
+		return fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	return mw.checkCommentSettings(ctx, postID)
+}
+
+// checkCommentSettings allows moderators unconditionally and holds everyone else to the CommentSettings of the post.
+func (mw *AuthorizationMiddleware) checkCommentSettings(ctx context.Context, postID string) error {
+	err := mw.authzClient.CheckAccess(ctx, ServiceName, postID, ActionModerateComments)
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := errors.AsType[*authorization.AccessDeniedError](err); !ok {
+		return fmt.Errorf("failed to check access: %w", err)
+	}
+
+	err = mw.next.CheckCommentAccess(ctx, postID)
+	if err != nil {
+		return fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return nil
+}
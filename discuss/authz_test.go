@@ -15,7 +15,10 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-type stubService struct{}
+type stubService struct {
+	authorID     string
+	lockedPostID string
+}
 
 func (s *stubService) CreateComment(ctx context.Context, req discuss.CreateCommentRequest) (*discuss.Comment, error) {
 	return &discuss.Comment{
@@ -26,7 +29,26 @@ func (s *stubService) CreateComment(ctx context.Context, req discuss.CreateComme
 	}, nil
 }
 
-func (s *stubService) ListComments(ctx context.Context, postID string) ([]*discuss.Comment, error) {
+func (s *stubService) GetComment(ctx context.Context, commentID string) (*discuss.Comment, error) {
+	return &discuss.Comment{ID: commentID, PostID: "post1", AuthorID: s.authorID, Content: "comment"}, nil
+}
+
+func (s *stubService) UpdateComment(ctx context.Context, req discuss.UpdateCommentRequest) (*discuss.Comment, error) {
+	return &discuss.Comment{ID: req.CommentID, PostID: "post1", AuthorID: s.authorID, Content: req.Content}, nil
+}
+
+func (s *stubService) DeleteComment(ctx context.Context, commentID string) error {
+	return nil
+}
+
+func (s *stubService) ListComments(
+	ctx context.Context,
+	params discuss.ListCommentsParams,
+) (*discuss.ListCommentsResult, error) {
+	return &discuss.ListCommentsResult{}, nil
+}
+
+func (s *stubService) ListCommentAncestors(ctx context.Context, commentID string) ([]*discuss.Comment, error) {
 	return []*discuss.Comment{}, nil
 }
 
@@ -34,6 +56,18 @@ func (s *stubService) CountComments(ctx context.Context, postID string) (int, er
 	return 0, nil
 }
 
+func (s *stubService) CountCommentsByPosts(ctx context.Context, postIDs []string) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+func (s *stubService) CheckCommentAccess(ctx context.Context, postID string) error {
+	if postID == s.lockedPostID {
+		return discuss.CommentsLockedError{PostID: postID}
+	}
+
+	return nil
+}
+
 func TestAuthorizationMiddleware(t *testing.T) {
 	ctx := context.Background()
 
@@ -41,7 +75,11 @@ func TestAuthorizationMiddleware(t *testing.T) {
 	tmpFile := filepath.Join(tmpDir, "policy.csv")
 	content := []byte(`g, system:anonymous, system:unauthenticated
 
+p, system:group:root, *, *, *
+
 p, system:authenticated, github.com/nasermirzaei89/scribble/discuss, -, createComment
+p, system:authenticated, github.com/nasermirzaei89/scribble/discuss, *, getComment
+p, system:unauthenticated, github.com/nasermirzaei89/scribble/discuss, *, getComment
 p, system:authenticated, github.com/nasermirzaei89/scribble/discuss, -, listComments
 p, system:unauthenticated, github.com/nasermirzaei89/scribble/discuss, -, listComments
 p, system:authenticated, github.com/nasermirzaei89/scribble/discuss, -, countComments
@@ -59,18 +97,29 @@ p, system:unauthenticated, github.com/nasermirzaei89/scribble/discuss, -, countC
 	authzSvc, err := authorization.NewService(provider)
 	require.NoError(t, err)
 
+	authorID := uuid.NewString()
+	lockedPostID := uuid.NewString()
+
 	client := authorization.NewClient(authzSvc)
-	svc := discuss.NewAuthorizationMiddleware(client, &stubService{})
+	svc := discuss.NewAuthorizationMiddleware(client, &stubService{authorID: authorID, lockedPostID: lockedPostID})
 
 	userID := uuid.NewString()
 	err = client.AddToGroup(ctx, userID, authcontext.Authenticated)
 	require.NoError(t, err)
 
-	authorID := uuid.NewString()
+	err = client.AddToGroup(ctx, authorID, authcontext.Authenticated)
+	require.NoError(t, err)
+
+	rootID := uuid.NewString()
+	err = client.AddToGroup(ctx, rootID, "system:group:root")
+	require.NoError(t, err)
+
 	postID := uuid.NewString()
 
 	anonymousCtx := ctx
 	authenticatedCtx := authcontext.WithSubject(ctx, userID)
+	authorCtx := authcontext.WithSubject(ctx, authorID)
+	rootCtx := authcontext.WithSubject(ctx, rootID)
 
 	t.Run("anonymous", func(t *testing.T) {
 		_, err := svc.CreateComment(anonymousCtx, discuss.CreateCommentRequest{
@@ -83,11 +132,32 @@ p, system:unauthenticated, github.com/nasermirzaei89/scribble/discuss, -, countC
 		accessDeniedErr := &authorization.AccessDeniedError{}
 		require.ErrorAs(t, err, &accessDeniedErr)
 
-		_, err = svc.ListComments(anonymousCtx, postID)
+		_, err = svc.GetComment(anonymousCtx, "comment1")
+		require.NoError(t, err)
+
+		_, err = svc.UpdateComment(anonymousCtx, discuss.UpdateCommentRequest{CommentID: "comment1", Content: "edited"})
+		require.ErrorAs(t, err, &accessDeniedErr)
+
+		err = svc.DeleteComment(anonymousCtx, "comment1")
+		require.ErrorAs(t, err, &accessDeniedErr)
+
+		_, err = svc.ListComments(anonymousCtx, discuss.ListCommentsParams{PostID: postID})
+		require.NoError(t, err)
+
+		_, err = svc.ListCommentAncestors(anonymousCtx, "comment1")
 		require.NoError(t, err)
 
 		_, err = svc.CountComments(anonymousCtx, postID)
 		require.NoError(t, err)
+
+		_, err = svc.CountCommentsByPosts(anonymousCtx, []string{postID})
+		require.NoError(t, err)
+
+		err = svc.CheckCommentAccess(anonymousCtx, postID)
+		require.NoError(t, err)
+
+		err = svc.CheckCommentAccess(anonymousCtx, lockedPostID)
+		require.ErrorAs(t, err, new(discuss.CommentsLockedError))
 	})
 
 	t.Run("authenticated", func(t *testing.T) {
@@ -98,10 +168,59 @@ p, system:unauthenticated, github.com/nasermirzaei89/scribble/discuss, -, countC
 		})
 		require.NoError(t, err)
 
-		_, err = svc.ListComments(authenticatedCtx, postID)
+		_, err = svc.ListComments(authenticatedCtx, discuss.ListCommentsParams{PostID: postID})
+		require.NoError(t, err)
+
+		_, err = svc.ListCommentAncestors(authenticatedCtx, "comment1")
 		require.NoError(t, err)
 
 		_, err = svc.CountComments(authenticatedCtx, postID)
 		require.NoError(t, err)
+
+		_, err = svc.CountCommentsByPosts(authenticatedCtx, []string{postID})
+		require.NoError(t, err)
+	})
+
+	t.Run("authenticated non-author", func(t *testing.T) {
+		accessDeniedErr := &authorization.AccessDeniedError{}
+
+		_, err := svc.UpdateComment(authenticatedCtx, discuss.UpdateCommentRequest{CommentID: "comment1", Content: "edited"})
+		require.ErrorAs(t, err, &accessDeniedErr)
+
+		err = svc.DeleteComment(authenticatedCtx, "comment1")
+		require.ErrorAs(t, err, &accessDeniedErr)
+	})
+
+	t.Run("author", func(t *testing.T) {
+		_, err := svc.UpdateComment(authorCtx, discuss.UpdateCommentRequest{CommentID: "comment1", Content: "edited"})
+		require.NoError(t, err)
+
+		err = svc.DeleteComment(authorCtx, "comment1")
+		require.NoError(t, err)
+	})
+
+	t.Run("root", func(t *testing.T) {
+		_, err := svc.UpdateComment(rootCtx, discuss.UpdateCommentRequest{CommentID: "comment1", Content: "edited"})
+		require.NoError(t, err)
+
+		err = svc.DeleteComment(rootCtx, "comment1")
+		require.NoError(t, err)
+	})
+
+	t.Run("locked comments", func(t *testing.T) {
+		req := discuss.CreateCommentRequest{PostID: lockedPostID, AuthorID: userID, Content: "comment"}
+
+		_, err := svc.CreateComment(authenticatedCtx, req)
+		require.ErrorAs(t, err, new(discuss.CommentsLockedError))
+
+		err = svc.CheckCommentAccess(authenticatedCtx, lockedPostID)
+		require.ErrorAs(t, err, new(discuss.CommentsLockedError))
+
+		// Moderators can still comment.
+		_, err = svc.CreateComment(rootCtx, req)
+		require.NoError(t, err)
+
+		err = svc.CheckCommentAccess(rootCtx, lockedPostID)
+		require.NoError(t, err)
 	})
 }
@@ -2,9 +2,15 @@ package discuss
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Comment is a comment on a post or a reply to another comment. A deleted comment that still has replies is kept
+// as a tombstone with DeletedAt set and its content cleared, so the replies below it keep their place in the thread.
 type Comment struct {
 	ID        string
 	PostID    string
@@ -12,18 +18,228 @@ type Comment struct {
 	ReplyTo   *string
 	Content   string
 	CreatedAt time.Time
+	UpdatedAt *time.Time
+	DeletedAt *time.Time
+}
+
+func (comment *Comment) IsDeleted() bool {
+	return comment.DeletedAt != nil
 }
 
 type CommentRepository interface {
 	Insert(ctx context.Context, comment *Comment) (err error)
+	Find(ctx context.Context, commentID string) (comment *Comment, err error)
+	// List returns a page of comments of a single level, the comments on a post or the replies to a comment.
 	List(ctx context.Context, params *ListCommentsParams) (comments []*Comment, err error)
+	// ListReplies returns every reply to the given comments, oldest first.
+	ListReplies(ctx context.Context, parentIDs []string) (replies []*Comment, err error)
+	// ListAncestors returns the comments the given one is a reply below, from the comment on the post down to its
+	// parent.
+	ListAncestors(ctx context.Context, commentID string) (ancestors []*Comment, err error)
+	// Count and CountByPosts leave tombstones out.
 	Count(ctx context.Context, params *CountCommentsParams) (count int, err error)
+	CountByPosts(ctx context.Context, postIDs []string) (counts map[string]int, err error)
+	// CountReplies returns the number of replies to each given comment, tombstones included. Comments without
+	// replies are left out of the map.
+	CountReplies(ctx context.Context, commentIDs []string) (counts map[string]int, err error)
+	Update(ctx context.Context, comment *Comment) (err error)
+	// Tombstone clears the content of a comment and marks it deleted, and Delete removes it. Both remove the
+	// reactions to the comment and the mentions in it along with it.
+	Tombstone(ctx context.Context, commentID string, deletedAt time.Time) (err error)
+	Delete(ctx context.Context, commentID string) (err error)
 }
 
-type ListCommentsParams struct {
+// PostRepository is the part of the posts, which the contents package owns, that comments are checked against.
+type PostRepository interface {
+	Exists(ctx context.Context, postID string) (exists bool, err error)
+	FindCommentSettings(ctx context.Context, postID string) (settings *CommentSettings, err error)
+}
+
+// UserRepository is the part of the users, which the authentication package owns, that commenters are checked
+// against.
+type UserRepository interface {
+	FindRegisteredAt(ctx context.Context, userID string) (registeredAt time.Time, err error)
+}
+
+// MentionRepository is the part of the mentions, which the mentions package owns, that comments keep up to date.
+type MentionRepository interface {
+	Replace(ctx context.Context, postID string, commentID *string, usernames []string, createdAt time.Time) (err error)
+}
+
+// CommentSettings is who may comment on a post, as its author or a moderator set it.
+type CommentSettings struct {
+	// Locked closes the post to new comments and replies.
+	Locked bool
+	// MinAccountAgeDays only lets users registered at least that many days ago comment. Zero lets everyone.
+	MinAccountAgeDays int
+}
+
+// CommentsLockedError is returned for a comment on a post whose comments are locked.
+type CommentsLockedError struct {
 	PostID string
 }
 
+func (err CommentsLockedError) Error() string {
+	return fmt.Sprintf("comments on post %q are locked", err.PostID)
+}
+
+// AccountTooNewError is returned for a comment on a post that only takes comments from older accounts.
+type AccountTooNewError struct {
+	PostID            string
+	MinAccountAgeDays int
+}
+
+func (err AccountTooNewError) Error() string {
+	return fmt.Sprintf("comments on post %q need an account older than %d days", err.PostID, err.MinAccountAgeDays)
+}
+
+type CommentNotFoundError struct {
+	ID string
+}
+
+func (err CommentNotFoundError) Error() string {
+	return fmt.Sprintf("comment with id %q not found", err.ID)
+}
+
+type PostNotFoundError struct {
+	ID string
+}
+
+func (err PostNotFoundError) Error() string {
+	return fmt.Sprintf("post with id %q not found", err.ID)
+}
+
+// ParentCommentNotFoundError is returned for a reply to a comment that doesn't exist or was deleted.
+type ParentCommentNotFoundError struct {
+	ID string
+}
+
+func (err ParentCommentNotFoundError) Error() string {
+	return fmt.Sprintf("parent comment with id %q not found", err.ID)
+}
+
+// CrossPostReplyError is returned for a reply to a comment on another post than the reply itself.
+type CrossPostReplyError struct {
+	ParentID     string
+	ParentPostID string
+	PostID       string
+}
+
+func (err CrossPostReplyError) Error() string {
+	return fmt.Sprintf(
+		"parent comment %q is on post %q, not on post %q",
+		err.ParentID,
+		err.ParentPostID,
+		err.PostID,
+	)
+}
+
+// ThreadTooDeepError is returned for a reply that would be nested deeper than the ThreadPolicy allows.
+type ThreadTooDeepError struct {
+	MaxDepth int
+}
+
+func (err ThreadTooDeepError) Error() string {
+	return fmt.Sprintf("replies can't be nested more than %d levels deep", err.MaxDepth)
+}
+
+// CommentSort is the order a level of comments is listed in. Replies below the listed comments are always oldest
+// first, so conversations read in order.
+type CommentSort string
+
+const (
+	CommentSortOldest CommentSort = "oldest"
+	CommentSortNewest CommentSort = "newest"
+	// CommentSortTop puts the comments with the most reactions first.
+	CommentSortTop CommentSort = "top"
+)
+
+func (sort CommentSort) IsValid() bool {
+	switch sort {
+	case CommentSortOldest, CommentSortNewest, CommentSortTop:
+		return true
+	default:
+		return false
+	}
+}
+
+type InvalidCommentSortError struct {
+	Sort CommentSort
+}
+
+func (err InvalidCommentSortError) Error() string {
+	return fmt.Sprintf("invalid comment sort %q", err.Sort)
+}
+
+const (
+	DefaultListCommentsLimit = 20
+	MaxListCommentsLimit     = 100
+)
+
+// ListCommentsParams selects a page of comments on a post, or of replies to ParentID when it's set. An empty Sort
+// lists the oldest first, and an empty Cursor starts from the first comment.
+type ListCommentsParams struct {
+	PostID   string
+	ParentID string
+	Sort     CommentSort
+	Cursor   string
+	Limit    int
+	// Depth is how many levels of replies are loaded below the listed comments. Zero loads all of them.
+	// Repositories ignore it.
+	Depth int
+}
+
+// CommentCursor is the position of a comment in a listing. Comments listed by time are paged by their
+// (CreatedAt, ID) keyset. Top comments are paged by Offset instead, since their order shifts as reactions come in.
+type CommentCursor struct {
+	Offset    int
+	CreatedAt time.Time
+	ID        string
+}
+
+func NewCommentCursor(comment *Comment) CommentCursor {
+	return CommentCursor{Offset: 0, CreatedAt: comment.CreatedAt, ID: comment.ID}
+}
+
+// String encodes the cursor into an opaque, URL-safe token.
+func (cursor CommentCursor) String() string {
+	raw := strconv.Itoa(cursor.Offset) + "|" + cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParseCommentCursor(s string) (*CommentCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, InvalidCommentCursorError{Cursor: s}
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return nil, InvalidCommentCursorError{Cursor: s}
+	}
+
+	offset, err := strconv.Atoi(parts[0])
+	if err != nil || offset < 0 {
+		return nil, InvalidCommentCursorError{Cursor: s}
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return nil, InvalidCommentCursorError{Cursor: s}
+	}
+
+	return &CommentCursor{Offset: offset, CreatedAt: createdAt, ID: parts[2]}, nil
+}
+
+type InvalidCommentCursorError struct {
+	Cursor string
+}
+
+func (err InvalidCommentCursorError) Error() string {
+	return fmt.Sprintf("invalid comment cursor %q", err.Cursor)
+}
+
 type CountCommentsParams struct {
 	PostID string
 }
@@ -2,34 +2,76 @@ package discuss
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
 	"github.com/nasermirzaei89/scribble/authorization"
+	"github.com/nasermirzaei89/scribble/mentions"
 )
 
 const ServiceName = "github.com/nasermirzaei89/scribble/discuss"
 
 type Service interface {
 	CreateComment(ctx context.Context, req CreateCommentRequest) (*Comment, error)
-	ListComments(ctx context.Context, postID string) ([]*Comment, error)
+	GetComment(ctx context.Context, commentID string) (*Comment, error)
+	UpdateComment(ctx context.Context, req UpdateCommentRequest) (*Comment, error)
+	DeleteComment(ctx context.Context, commentID string) error
+	ListComments(ctx context.Context, params ListCommentsParams) (*ListCommentsResult, error)
+	ListCommentAncestors(ctx context.Context, commentID string) ([]*Comment, error)
 	CountComments(ctx context.Context, postID string) (int, error)
+	CountCommentsByPosts(ctx context.Context, postIDs []string) (map[string]int, error)
+	CheckCommentAccess(ctx context.Context, postID string) error
+}
+
+// ThreadPolicy limits how comment threads grow.
+type ThreadPolicy struct {
+	// MaxDepth is how many levels of replies a comment on a post can have below it. Zero disables the limit.
+	MaxDepth int
+}
+
+func DefaultThreadPolicy() ThreadPolicy {
+	return ThreadPolicy{
+		MaxDepth: 8,
+	}
 }
 
 type BaseService struct {
 	commentRepo CommentRepository
+	postRepo    PostRepository
+	userRepo    UserRepository
+	mentionRepo MentionRepository
+	thread      ThreadPolicy
 }
 
 var _ Service = (*BaseService)(nil)
 
-func NewService(commentRepo CommentRepository, authzClient *authorization.Client) Service { //nolint:ireturn
-	return NewAuthorizationMiddleware(authzClient, NewBaseService(commentRepo))
+func NewService( //nolint:ireturn
+	commentRepo CommentRepository,
+	postRepo PostRepository,
+	userRepo UserRepository,
+	mentionRepo MentionRepository,
+	authzClient *authorization.Client,
+	thread ThreadPolicy,
+) Service {
+	return NewAuthorizationMiddleware(authzClient, NewBaseService(commentRepo, postRepo, userRepo, mentionRepo, thread))
 }
 
-func NewBaseService(commentRepo CommentRepository) *BaseService {
+func NewBaseService(
+	commentRepo CommentRepository,
+	postRepo PostRepository,
+	userRepo UserRepository,
+	mentionRepo MentionRepository,
+	thread ThreadPolicy,
+) *BaseService {
 	return &BaseService{
 		commentRepo: commentRepo,
+		postRepo:    postRepo,
+		userRepo:    userRepo,
+		mentionRepo: mentionRepo,
+		thread:      thread,
 	}
 }
 
@@ -40,36 +82,330 @@ type CreateCommentRequest struct {
 	ReplyTo  string
 }
 
+// CreateComment adds a comment to a post, or a reply to a comment when ReplyTo is set. The post must exist, and the
+// comment replied to must be on the same post, not be deleted and not be nested as deep as the ThreadPolicy allows.
 func (svc *BaseService) CreateComment(ctx context.Context, req CreateCommentRequest) (*Comment, error) {
+	exists, err := svc.postRepo.Exists(ctx, req.PostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check post: %w", err)
+	}
+
+	if !exists {
+		return nil, PostNotFoundError{ID: req.PostID}
+	}
+
 	var replyTo *string
 	if req.ReplyTo != "" {
+		err = svc.checkReplyTo(ctx, req.PostID, req.ReplyTo)
+		if err != nil {
+			return nil, err
+		}
+
 		replyTo = &req.ReplyTo
 	}
 
+	// CreatedAt is kept in UTC, which also drops the monotonic clock reading, so it is stored the same way cursors
+	// hold it and pages are split where they should be.
 	comment := &Comment{
 		ID:        uuid.NewString(),
 		PostID:    req.PostID,
 		AuthorID:  req.AuthorID,
 		ReplyTo:   replyTo,
 		Content:   req.Content,
-		CreatedAt: time.Now(),
+		CreatedAt: time.Now().UTC(),
 	}
 
-	err := svc.commentRepo.Insert(ctx, comment)
+	err = svc.commentRepo.Insert(ctx, comment)
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert comment: %w", err)
 	}
 
+	err = svc.mentionRepo.Replace(ctx, comment.PostID, &comment.ID, mentions.Extract(comment.Content), comment.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record mentions: %w", err)
+	}
+
+	return comment, nil
+}
+
+// checkReplyTo makes sure a reply on the given post can be added below the parent comment. The depth of the parent is
+// found by walking up its ancestors, which stops at the limit, so a reply costs at most MaxDepth lookups.
+func (svc *BaseService) checkReplyTo(ctx context.Context, postID, parentID string) error {
+	parent, err := svc.commentRepo.Find(ctx, parentID)
+	if err != nil {
+		if _, ok := errors.AsType[CommentNotFoundError](err); ok {
+			return ParentCommentNotFoundError{ID: parentID}
+		}
+
+		return fmt.Errorf("failed to find parent comment: %w", err)
+	}
+
+	if parent.IsDeleted() {
+		return ParentCommentNotFoundError{ID: parentID}
+	}
+
+	if parent.PostID != postID {
+		return CrossPostReplyError{ParentID: parentID, ParentPostID: parent.PostID, PostID: postID}
+	}
+
+	if svc.thread.MaxDepth <= 0 {
+		return nil
+	}
+
+	// The reply is one level below its parent, and every ancestor of the parent adds another.
+	depth := 1
+
+	for ancestor := parent; ancestor.ReplyTo != nil; depth++ {
+		if depth >= svc.thread.MaxDepth {
+			return ThreadTooDeepError{MaxDepth: svc.thread.MaxDepth}
+		}
+
+		ancestor, err = svc.commentRepo.Find(ctx, *ancestor.ReplyTo)
+		if err != nil {
+			return fmt.Errorf("failed to find ancestor comment: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (svc *BaseService) GetComment(ctx context.Context, commentID string) (*Comment, error) {
+	comment, err := svc.commentRepo.Find(ctx, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find comment: %w", err)
+	}
+
 	return comment, nil
 }
 
-func (svc *BaseService) ListComments(ctx context.Context, postID string) ([]*Comment, error) {
-	comments, err := svc.commentRepo.List(ctx, &ListCommentsParams{PostID: postID})
+type UpdateCommentRequest struct {
+	CommentID string
+	Content   string
+}
+
+// UpdateComment replaces the content of a comment. Tombstones can't be edited and are reported as not found.
+func (svc *BaseService) UpdateComment(ctx context.Context, req UpdateCommentRequest) (*Comment, error) {
+	comment, err := svc.commentRepo.Find(ctx, req.CommentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find comment: %w", err)
+	}
+
+	if comment.IsDeleted() {
+		return nil, CommentNotFoundError{ID: req.CommentID}
+	}
+
+	if comment.Content == req.Content {
+		return comment, nil
+	}
+
+	now := time.Now().UTC()
+	comment.Content = req.Content
+	comment.UpdatedAt = &now
+
+	err = svc.commentRepo.Update(ctx, comment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	err = svc.mentionRepo.Replace(ctx, comment.PostID, &comment.ID, mentions.Extract(comment.Content), comment.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record mentions: %w", err)
+	}
+
+	return comment, nil
+}
+
+// DeleteComment removes a comment. A comment with replies is turned into a tombstone instead, since removing it
+// would take the replies along. Removing the last reply of a tombstone removes the tombstone too, and so on up the
+// thread, so threads don't end in tombstones nobody can see a reason for.
+func (svc *BaseService) DeleteComment(ctx context.Context, commentID string) error {
+	comment, err := svc.commentRepo.Find(ctx, commentID)
+	if err != nil {
+		return fmt.Errorf("failed to find comment: %w", err)
+	}
+
+	if comment.IsDeleted() {
+		return CommentNotFoundError{ID: commentID}
+	}
+
+	hasReplies, err := svc.hasReplies(ctx, commentID)
+	if err != nil {
+		return err
+	}
+
+	if hasReplies {
+		err = svc.commentRepo.Tombstone(ctx, commentID, time.Now().UTC())
+		if err != nil {
+			return fmt.Errorf("failed to tombstone comment: %w", err)
+		}
+
+		return nil
+	}
+
+	err = svc.commentRepo.Delete(ctx, commentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+
+	err = svc.pruneTombstones(ctx, comment.ReplyTo)
+	if err != nil {
+		return fmt.Errorf("failed to prune tombstones: %w", err)
+	}
+
+	return nil
+}
+
+// pruneTombstones removes the tombstones left without replies, starting from the given parent and going up.
+func (svc *BaseService) pruneTombstones(ctx context.Context, parentID *string) error {
+	for parentID != nil {
+		parent, err := svc.commentRepo.Find(ctx, *parentID)
+		if err != nil {
+			return fmt.Errorf("failed to find comment: %w", err)
+		}
+
+		if !parent.IsDeleted() {
+			return nil
+		}
+
+		hasReplies, err := svc.hasReplies(ctx, parent.ID)
+		if err != nil {
+			return err
+		}
+
+		if hasReplies {
+			return nil
+		}
+
+		err = svc.commentRepo.Delete(ctx, parent.ID)
+		if err != nil {
+			return fmt.Errorf("failed to delete comment: %w", err)
+		}
+
+		parentID = parent.ReplyTo
+	}
+
+	return nil
+}
+
+func (svc *BaseService) hasReplies(ctx context.Context, commentID string) (bool, error) {
+	counts, err := svc.commentRepo.CountReplies(ctx, []string{commentID})
+	if err != nil {
+		return false, fmt.Errorf("failed to count replies: %w", err)
+	}
+
+	return counts[commentID] > 0, nil
+}
+
+type ListCommentsResult struct {
+	// Comments has the page of comments followed by the replies loaded below them, every reply after its parent.
+	Comments []*Comment
+	// UnloadedReplies has the number of replies to the comments at the Depth limit, whose replies weren't loaded.
+	// Comments without replies are left out of the map.
+	UnloadedReplies map[string]int
+	// NextCursor points right after the last comment of this page. It is empty on the last page.
+	NextCursor string
+}
+
+func (svc *BaseService) ListComments(ctx context.Context, params ListCommentsParams) (*ListCommentsResult, error) {
+	if params.Sort == "" {
+		params.Sort = CommentSortOldest
+	}
+
+	if !params.Sort.IsValid() {
+		return nil, InvalidCommentSortError{Sort: params.Sort}
+	}
+
+	offset := 0
+
+	if params.Cursor != "" {
+		cursor, err := ParseCommentCursor(params.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cursor: %w", err)
+		}
+
+		// A cursor only fits the sort it was made for.
+		if (params.Sort == CommentSortTop) != (cursor.ID == "") {
+			return nil, InvalidCommentCursorError{Cursor: params.Cursor}
+		}
+
+		offset = cursor.Offset
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = DefaultListCommentsLimit
+	}
+
+	limit = min(limit, MaxListCommentsLimit)
+
+	// Fetch one extra comment to find out whether there is a next page without a separate count query.
+	comments, err := svc.commentRepo.List(ctx, &ListCommentsParams{
+		PostID:   params.PostID,
+		ParentID: params.ParentID,
+		Sort:     params.Sort,
+		Cursor:   params.Cursor,
+		Limit:    limit + 1,
+		Depth:    0,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list comments: %w", err)
 	}
 
-	return comments, nil
+	result := &ListCommentsResult{Comments: comments, UnloadedReplies: map[string]int{}, NextCursor: ""}
+
+	if len(comments) > limit {
+		result.Comments = comments[:limit]
+
+		if params.Sort == CommentSortTop {
+			result.NextCursor = CommentCursor{Offset: offset + limit, CreatedAt: time.Time{}, ID: ""}.String()
+		} else {
+			result.NextCursor = NewCommentCursor(result.Comments[limit-1]).String()
+		}
+	}
+
+	// Replies are loaded level by level, down to the requested depth.
+	parentIDs := commentIDs(result.Comments)
+
+	for depth := 0; len(parentIDs) > 0; depth++ {
+		if params.Depth > 0 && depth == params.Depth {
+			result.UnloadedReplies, err = svc.commentRepo.CountReplies(ctx, parentIDs)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count replies: %w", err)
+			}
+
+			break
+		}
+
+		replies, err := svc.commentRepo.ListReplies(ctx, parentIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list replies: %w", err)
+		}
+
+		result.Comments = append(result.Comments, replies...)
+		parentIDs = commentIDs(replies)
+	}
+
+	return result, nil
+}
+
+func commentIDs(comments []*Comment) []string {
+	ids := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+
+	return ids
+}
+
+// ListCommentAncestors returns the comments the given one is a reply below, from the comment on the post down to its
+// parent. It is empty for comments on the post itself.
+func (svc *BaseService) ListCommentAncestors(ctx context.Context, commentID string) ([]*Comment, error) {
+	ancestors, err := svc.commentRepo.ListAncestors(ctx, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ancestors: %w", err)
+	}
+
+	return ancestors, nil
 }
 
 func (svc *BaseService) CountComments(ctx context.Context, postID string) (int, error) {
@@ -80,3 +416,43 @@ func (svc *BaseService) CountComments(ctx context.Context, postID string) (int,
 
 	return count, nil
 }
+
+// CountCommentsByPosts returns the number of comments of each given post, keyed by post ID. Posts without comments
+// are left out of the map, so a lookup yields zero for them.
+func (svc *BaseService) CountCommentsByPosts(ctx context.Context, postIDs []string) (map[string]int, error) {
+	counts, err := svc.commentRepo.CountByPosts(ctx, postIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count comments by posts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// CheckCommentAccess tells whether the current user may comment on a post, as far as its CommentSettings go. It
+// returns a CommentsLockedError or an AccountTooNewError if not. Guests only learn whether comments are locked.
+func (svc *BaseService) CheckCommentAccess(ctx context.Context, postID string) error {
+	settings, err := svc.postRepo.FindCommentSettings(ctx, postID)
+	if err != nil {
+		return fmt.Errorf("failed to find comment settings: %w", err)
+	}
+
+	if settings.Locked {
+		return CommentsLockedError{PostID: postID}
+	}
+
+	subject := authcontext.GetSubject(ctx)
+	if settings.MinAccountAgeDays <= 0 || subject == authcontext.Anonymous {
+		return nil
+	}
+
+	registeredAt, err := svc.userRepo.FindRegisteredAt(ctx, subject)
+	if err != nil {
+		return fmt.Errorf("failed to find registration time: %w", err)
+	}
+
+	if time.Since(registeredAt) < time.Duration(settings.MinAccountAgeDays)*24*time.Hour {
+		return AccountTooNewError{PostID: postID, MinAccountAgeDays: settings.MinAccountAgeDays}
+	}
+
+	return nil
+}
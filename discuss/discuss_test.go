@@ -0,0 +1,630 @@
+package discuss_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryCommentRepository keeps comments in insertion order, which is the order the tests create them in.
+type memoryCommentRepository struct {
+	mu       sync.Mutex
+	comments []discuss.Comment
+}
+
+func (repo *memoryCommentRepository) index(commentID string) int {
+	return slices.IndexFunc(repo.comments, func(comment discuss.Comment) bool { return comment.ID == commentID })
+}
+
+func (repo *memoryCommentRepository) Insert(_ context.Context, comment *discuss.Comment) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.comments = append(repo.comments, *comment)
+
+	return nil
+}
+
+func (repo *memoryCommentRepository) Find(_ context.Context, commentID string) (*discuss.Comment, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	i := repo.index(commentID)
+	if i < 0 {
+		return nil, discuss.CommentNotFoundError{ID: commentID}
+	}
+
+	comment := repo.comments[i]
+
+	return &comment, nil
+}
+
+// List only lists the oldest first, paging by the ID of the cursor.
+func (repo *memoryCommentRepository) List(
+	_ context.Context,
+	params *discuss.ListCommentsParams,
+) ([]*discuss.Comment, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	start := 0
+
+	if params.Cursor != "" {
+		cursor, err := discuss.ParseCommentCursor(params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		start = repo.index(cursor.ID) + 1
+	}
+
+	comments := make([]*discuss.Comment, 0)
+
+	for _, comment := range repo.comments[start:] {
+		if params.Limit > 0 && len(comments) == params.Limit {
+			break
+		}
+
+		if params.PostID != "" && comment.PostID != params.PostID {
+			continue
+		}
+
+		if (params.ParentID == "" && comment.ReplyTo == nil) ||
+			(comment.ReplyTo != nil && *comment.ReplyTo == params.ParentID) {
+			comments = append(comments, &comment)
+		}
+	}
+
+	return comments, nil
+}
+
+func (repo *memoryCommentRepository) ListReplies(_ context.Context, parentIDs []string) ([]*discuss.Comment, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	replies := make([]*discuss.Comment, 0)
+
+	for _, comment := range repo.comments {
+		if comment.ReplyTo != nil && slices.Contains(parentIDs, *comment.ReplyTo) {
+			replies = append(replies, &comment)
+		}
+	}
+
+	return replies, nil
+}
+
+func (repo *memoryCommentRepository) ListAncestors(_ context.Context, commentID string) ([]*discuss.Comment, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	ancestors := make([]*discuss.Comment, 0)
+
+	for i := repo.index(commentID); i >= 0 && repo.comments[i].ReplyTo != nil; {
+		i = repo.index(*repo.comments[i].ReplyTo)
+		if i < 0 {
+			break
+		}
+
+		ancestor := repo.comments[i]
+		ancestors = append([]*discuss.Comment{&ancestor}, ancestors...)
+	}
+
+	return ancestors, nil
+}
+
+func (repo *memoryCommentRepository) Count(_ context.Context, params *discuss.CountCommentsParams) (int, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	count := 0
+
+	for _, comment := range repo.comments {
+		if !comment.IsDeleted() && (params.PostID == "" || comment.PostID == params.PostID) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (repo *memoryCommentRepository) CountByPosts(_ context.Context, postIDs []string) (map[string]int, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	counts := make(map[string]int, len(postIDs))
+
+	for _, comment := range repo.comments {
+		if !comment.IsDeleted() && slices.Contains(postIDs, comment.PostID) {
+			counts[comment.PostID]++
+		}
+	}
+
+	return counts, nil
+}
+
+func (repo *memoryCommentRepository) CountReplies(_ context.Context, commentIDs []string) (map[string]int, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	counts := make(map[string]int, len(commentIDs))
+
+	for _, comment := range repo.comments {
+		if comment.ReplyTo != nil && slices.Contains(commentIDs, *comment.ReplyTo) {
+			counts[*comment.ReplyTo]++
+		}
+	}
+
+	return counts, nil
+}
+
+func (repo *memoryCommentRepository) Update(_ context.Context, comment *discuss.Comment) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	i := repo.index(comment.ID)
+	if i < 0 {
+		return discuss.CommentNotFoundError{ID: comment.ID}
+	}
+
+	repo.comments[i] = *comment
+
+	return nil
+}
+
+func (repo *memoryCommentRepository) Tombstone(_ context.Context, commentID string, deletedAt time.Time) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	i := repo.index(commentID)
+	if i < 0 {
+		return discuss.CommentNotFoundError{ID: commentID}
+	}
+
+	repo.comments[i].Content = ""
+	repo.comments[i].DeletedAt = &deletedAt
+
+	return nil
+}
+
+func (repo *memoryCommentRepository) Delete(_ context.Context, commentID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	i := repo.index(commentID)
+	if i < 0 {
+		return discuss.CommentNotFoundError{ID: commentID}
+	}
+
+	repo.comments = slices.Delete(repo.comments, i, i+1)
+
+	return nil
+}
+
+type memoryPostRepository struct {
+	postIDs  []string
+	settings map[string]discuss.CommentSettings
+}
+
+func (repo *memoryPostRepository) Exists(_ context.Context, postID string) (bool, error) {
+	return slices.Contains(repo.postIDs, postID), nil
+}
+
+func (repo *memoryPostRepository) FindCommentSettings(
+	_ context.Context,
+	postID string,
+) (*discuss.CommentSettings, error) {
+	if !slices.Contains(repo.postIDs, postID) {
+		return nil, discuss.PostNotFoundError{ID: postID}
+	}
+
+	settings := repo.settings[postID]
+
+	return &settings, nil
+}
+
+type memoryUserRepository struct {
+	registeredAt map[string]time.Time
+}
+
+func (repo *memoryUserRepository) FindRegisteredAt(_ context.Context, userID string) (time.Time, error) {
+	return repo.registeredAt[userID], nil
+}
+
+// memoryMentionRepository keeps the usernames each comment mentions, as they were extracted. Comments without
+// mentions are left out.
+type memoryMentionRepository struct {
+	mu        sync.Mutex
+	usernames map[string][]string
+}
+
+func (repo *memoryMentionRepository) Replace(
+	_ context.Context,
+	_ string,
+	commentID *string,
+	usernames []string,
+	_ time.Time,
+) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if len(usernames) == 0 {
+		delete(repo.usernames, *commentID)
+
+		return nil
+	}
+
+	repo.usernames[*commentID] = usernames
+
+	return nil
+}
+
+// newTestService returns a service that knows of the given posts only.
+func newTestService(t *testing.T, thread discuss.ThreadPolicy, postIDs ...string) *discuss.BaseService {
+	t.Helper()
+
+	return discuss.NewBaseService(
+		&memoryCommentRepository{mu: sync.Mutex{}, comments: nil},
+		&memoryPostRepository{postIDs: postIDs, settings: nil},
+		&memoryUserRepository{registeredAt: nil},
+		&memoryMentionRepository{mu: sync.Mutex{}, usernames: make(map[string][]string)},
+		thread,
+	)
+}
+
+func createComment(
+	ctx context.Context,
+	t *testing.T,
+	svc *discuss.BaseService,
+	postID, replyTo string,
+) *discuss.Comment {
+	t.Helper()
+
+	comment, err := svc.CreateComment(ctx, discuss.CreateCommentRequest{
+		PostID:   postID,
+		AuthorID: uuid.NewString(),
+		Content:  "comment",
+		ReplyTo:  replyTo,
+	})
+	require.NoError(t, err)
+
+	return comment
+}
+
+func commentIDs(ctx context.Context, t *testing.T, svc *discuss.BaseService, postID string) []string {
+	t.Helper()
+
+	result, err := svc.ListComments(ctx, discuss.ListCommentsParams{PostID: postID})
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(result.Comments))
+	for _, comment := range result.Comments {
+		ids = append(ids, comment.ID)
+	}
+
+	return ids
+}
+
+func TestCreateComment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("post not found", func(t *testing.T) {
+		svc := newTestService(t, discuss.DefaultThreadPolicy())
+
+		_, err := svc.CreateComment(ctx, discuss.CreateCommentRequest{
+			PostID:   uuid.NewString(),
+			AuthorID: uuid.NewString(),
+			Content:  "comment",
+		})
+		require.ErrorAs(t, err, new(discuss.PostNotFoundError))
+	})
+
+	t.Run("parent comment not found", func(t *testing.T) {
+		postID := uuid.NewString()
+		svc := newTestService(t, discuss.DefaultThreadPolicy(), postID)
+
+		_, err := svc.CreateComment(ctx, discuss.CreateCommentRequest{
+			PostID:   postID,
+			AuthorID: uuid.NewString(),
+			Content:  "reply",
+			ReplyTo:  uuid.NewString(),
+		})
+		require.ErrorAs(t, err, new(discuss.ParentCommentNotFoundError))
+
+		// Tombstones can't be replied to either.
+		parent := createComment(ctx, t, svc, postID, "")
+		createComment(ctx, t, svc, postID, parent.ID)
+		require.NoError(t, svc.DeleteComment(ctx, parent.ID))
+
+		_, err = svc.CreateComment(ctx, discuss.CreateCommentRequest{
+			PostID:   postID,
+			AuthorID: uuid.NewString(),
+			Content:  "reply",
+			ReplyTo:  parent.ID,
+		})
+		require.ErrorAs(t, err, new(discuss.ParentCommentNotFoundError))
+	})
+
+	t.Run("cross post reply", func(t *testing.T) {
+		postID, otherPostID := uuid.NewString(), uuid.NewString()
+		svc := newTestService(t, discuss.DefaultThreadPolicy(), postID, otherPostID)
+
+		parent := createComment(ctx, t, svc, otherPostID, "")
+
+		_, err := svc.CreateComment(ctx, discuss.CreateCommentRequest{
+			PostID:   postID,
+			AuthorID: uuid.NewString(),
+			Content:  "reply",
+			ReplyTo:  parent.ID,
+		})
+
+		var crossPostErr discuss.CrossPostReplyError
+		require.ErrorAs(t, err, &crossPostErr)
+		assert.Equal(t, otherPostID, crossPostErr.ParentPostID)
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		postID := uuid.NewString()
+		svc := newTestService(t, discuss.ThreadPolicy{MaxDepth: 2}, postID)
+
+		root := createComment(ctx, t, svc, postID, "")
+		reply := createComment(ctx, t, svc, postID, root.ID)
+		nested := createComment(ctx, t, svc, postID, reply.ID)
+
+		_, err := svc.CreateComment(ctx, discuss.CreateCommentRequest{
+			PostID:   postID,
+			AuthorID: uuid.NewString(),
+			Content:  "too deep",
+			ReplyTo:  nested.ID,
+		})
+
+		var tooDeepErr discuss.ThreadTooDeepError
+		require.ErrorAs(t, err, &tooDeepErr)
+		assert.Equal(t, 2, tooDeepErr.MaxDepth)
+
+		// Replies further up the thread are still fine.
+		createComment(ctx, t, svc, postID, reply.ID)
+	})
+
+	t.Run("unlimited depth", func(t *testing.T) {
+		postID := uuid.NewString()
+		svc := newTestService(t, discuss.ThreadPolicy{MaxDepth: 0}, postID)
+
+		parentID := ""
+		for range 20 {
+			parentID = createComment(ctx, t, svc, postID, parentID).ID
+		}
+	})
+
+	t.Run("mentions", func(t *testing.T) {
+		postID := uuid.NewString()
+		mentionRepo := &memoryMentionRepository{mu: sync.Mutex{}, usernames: make(map[string][]string)}
+		svc := discuss.NewBaseService(
+			&memoryCommentRepository{mu: sync.Mutex{}, comments: nil},
+			&memoryPostRepository{postIDs: []string{postID}, settings: nil},
+			&memoryUserRepository{registeredAt: nil},
+			mentionRepo,
+			discuss.DefaultThreadPolicy(),
+		)
+
+		comment, err := svc.CreateComment(ctx, discuss.CreateCommentRequest{
+			PostID:   postID,
+			AuthorID: uuid.NewString(),
+			Content:  "@alice and @Bob, see `@carol` and @alice.",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alice", "Bob"}, mentionRepo.usernames[comment.ID])
+
+		_, err = svc.UpdateComment(ctx, discuss.UpdateCommentRequest{CommentID: comment.ID, Content: "only @carol"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"carol"}, mentionRepo.usernames[comment.ID])
+	})
+}
+
+func TestUpdateComment(t *testing.T) {
+	ctx := context.Background()
+	postID := uuid.NewString()
+	svc := newTestService(t, discuss.DefaultThreadPolicy(), postID)
+
+	comment := createComment(ctx, t, svc, postID, "")
+
+	updated, err := svc.UpdateComment(ctx, discuss.UpdateCommentRequest{CommentID: comment.ID, Content: "edited"})
+	require.NoError(t, err)
+	assert.Equal(t, "edited", updated.Content)
+	assert.NotNil(t, updated.UpdatedAt)
+
+	found, err := svc.GetComment(ctx, comment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "edited", found.Content)
+
+	_, err = svc.UpdateComment(ctx, discuss.UpdateCommentRequest{CommentID: uuid.NewString(), Content: "edited"})
+	require.ErrorAs(t, err, new(discuss.CommentNotFoundError))
+}
+
+func TestDeleteComment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("leaf", func(t *testing.T) {
+		postID := uuid.NewString()
+		svc := newTestService(t, discuss.DefaultThreadPolicy(), postID)
+
+		comment := createComment(ctx, t, svc, postID, "")
+		other := createComment(ctx, t, svc, postID, "")
+
+		err := svc.DeleteComment(ctx, comment.ID)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{other.ID}, commentIDs(ctx, t, svc, postID))
+	})
+
+	t.Run("with replies", func(t *testing.T) {
+		postID := uuid.NewString()
+		svc := newTestService(t, discuss.DefaultThreadPolicy(), postID)
+
+		parent := createComment(ctx, t, svc, postID, "")
+		reply := createComment(ctx, t, svc, postID, parent.ID)
+
+		err := svc.DeleteComment(ctx, parent.ID)
+		require.NoError(t, err)
+
+		tombstone, err := svc.GetComment(ctx, parent.ID)
+		require.NoError(t, err)
+		assert.True(t, tombstone.IsDeleted())
+		assert.Empty(t, tombstone.Content)
+
+		assert.Equal(t, []string{parent.ID, reply.ID}, commentIDs(ctx, t, svc, postID))
+
+		count, err := svc.CountComments(ctx, postID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		_, err = svc.UpdateComment(ctx, discuss.UpdateCommentRequest{CommentID: parent.ID, Content: "edited"})
+		require.ErrorAs(t, err, new(discuss.CommentNotFoundError))
+
+		err = svc.DeleteComment(ctx, parent.ID)
+		require.ErrorAs(t, err, new(discuss.CommentNotFoundError))
+	})
+
+	t.Run("last reply of tombstones", func(t *testing.T) {
+		postID := uuid.NewString()
+		svc := newTestService(t, discuss.DefaultThreadPolicy(), postID)
+
+		root := createComment(ctx, t, svc, postID, "")
+		parent := createComment(ctx, t, svc, postID, root.ID)
+		sibling := createComment(ctx, t, svc, postID, root.ID)
+		reply := createComment(ctx, t, svc, postID, parent.ID)
+
+		require.NoError(t, svc.DeleteComment(ctx, root.ID))
+		require.NoError(t, svc.DeleteComment(ctx, parent.ID))
+
+		// The parent tombstone goes with its last reply, and the root one stays for the sibling.
+		err := svc.DeleteComment(ctx, reply.ID)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{root.ID, sibling.ID}, commentIDs(ctx, t, svc, postID))
+
+		err = svc.DeleteComment(ctx, sibling.ID)
+		require.NoError(t, err)
+
+		assert.Empty(t, commentIDs(ctx, t, svc, postID))
+	})
+}
+
+func TestListComments(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("pages and depth", func(t *testing.T) {
+		postID := uuid.NewString()
+		svc := newTestService(t, discuss.DefaultThreadPolicy(), postID)
+
+		first := createComment(ctx, t, svc, postID, "")
+		reply := createComment(ctx, t, svc, postID, first.ID)
+		nested := createComment(ctx, t, svc, postID, reply.ID)
+		createComment(ctx, t, svc, postID, nested.ID)
+		second := createComment(ctx, t, svc, postID, "")
+		third := createComment(ctx, t, svc, postID, "")
+
+		result, err := svc.ListComments(ctx, discuss.ListCommentsParams{PostID: postID, Limit: 2, Depth: 2})
+		require.NoError(t, err)
+
+		ids := make([]string, 0, len(result.Comments))
+		for _, comment := range result.Comments {
+			ids = append(ids, comment.ID)
+		}
+
+		assert.Equal(t, []string{first.ID, second.ID, reply.ID, nested.ID}, ids)
+		assert.Equal(t, map[string]int{nested.ID: 1}, result.UnloadedReplies)
+		require.NotEmpty(t, result.NextCursor)
+
+		result, err = svc.ListComments(ctx, discuss.ListCommentsParams{
+			PostID: postID,
+			Cursor: result.NextCursor,
+			Limit:  2,
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Comments, 1)
+		assert.Equal(t, third.ID, result.Comments[0].ID)
+		assert.Empty(t, result.NextCursor)
+
+		result, err = svc.ListComments(ctx, discuss.ListCommentsParams{PostID: postID, ParentID: reply.ID})
+		require.NoError(t, err)
+		require.Len(t, result.Comments, 2)
+		assert.Equal(t, nested.ID, result.Comments[0].ID)
+
+		ancestors, err := svc.ListCommentAncestors(ctx, nested.ID)
+		require.NoError(t, err)
+		require.Len(t, ancestors, 2)
+		assert.Equal(t, first.ID, ancestors[0].ID)
+		assert.Equal(t, reply.ID, ancestors[1].ID)
+	})
+
+	t.Run("invalid sort", func(t *testing.T) {
+		svc := newTestService(t, discuss.DefaultThreadPolicy())
+
+		_, err := svc.ListComments(ctx, discuss.ListCommentsParams{PostID: uuid.NewString(), Sort: "best"})
+		require.ErrorAs(t, err, new(discuss.InvalidCommentSortError))
+	})
+
+	t.Run("cursor of another sort", func(t *testing.T) {
+		svc := newTestService(t, discuss.DefaultThreadPolicy())
+
+		_, err := svc.ListComments(ctx, discuss.ListCommentsParams{
+			PostID: uuid.NewString(),
+			Sort:   discuss.CommentSortTop,
+			Cursor: discuss.CommentCursor{Offset: 0, CreatedAt: time.Now(), ID: uuid.NewString()}.String(),
+		})
+		require.ErrorAs(t, err, new(discuss.InvalidCommentCursorError))
+
+		_, err = svc.ListComments(ctx, discuss.ListCommentsParams{PostID: uuid.NewString(), Cursor: "not a cursor"})
+		require.ErrorAs(t, err, new(discuss.InvalidCommentCursorError))
+	})
+}
+
+func TestCheckCommentAccess(t *testing.T) {
+	ctx := context.Background()
+
+	openPostID, lockedPostID, agedPostID := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	newUserID, oldUserID := uuid.NewString(), uuid.NewString()
+
+	svc := discuss.NewBaseService(
+		&memoryCommentRepository{mu: sync.Mutex{}, comments: nil},
+		&memoryPostRepository{
+			postIDs: []string{openPostID, lockedPostID, agedPostID},
+			settings: map[string]discuss.CommentSettings{
+				lockedPostID: {Locked: true, MinAccountAgeDays: 0},
+				agedPostID:   {Locked: false, MinAccountAgeDays: 7},
+			},
+		},
+		&memoryUserRepository{registeredAt: map[string]time.Time{
+			newUserID: time.Now().Add(-time.Hour),
+			oldUserID: time.Now().AddDate(0, 0, -30),
+		}},
+		&memoryMentionRepository{mu: sync.Mutex{}, usernames: make(map[string][]string)},
+		discuss.DefaultThreadPolicy(),
+	)
+
+	newUserCtx := authcontext.WithSubject(ctx, newUserID)
+	oldUserCtx := authcontext.WithSubject(ctx, oldUserID)
+
+	require.NoError(t, svc.CheckCommentAccess(newUserCtx, openPostID))
+	require.ErrorAs(t, svc.CheckCommentAccess(oldUserCtx, lockedPostID), new(discuss.CommentsLockedError))
+
+	var tooNewErr discuss.AccountTooNewError
+	require.ErrorAs(t, svc.CheckCommentAccess(newUserCtx, agedPostID), &tooNewErr)
+	assert.Equal(t, 7, tooNewErr.MinAccountAgeDays)
+
+	require.NoError(t, svc.CheckCommentAccess(oldUserCtx, agedPostID))
+
+	// Guests can't comment anyway, so they only learn whether comments are locked.
+	require.NoError(t, svc.CheckCommentAccess(ctx, agedPostID))
+	require.ErrorAs(t, svc.CheckCommentAccess(ctx, lockedPostID), new(discuss.CommentsLockedError))
+
+	require.ErrorAs(t, svc.CheckCommentAccess(ctx, uuid.NewString()), new(discuss.PostNotFoundError))
+}
@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Job is a maintenance task that runs on a fixed interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Runner runs periodic jobs in the background until its context is canceled.
+type Runner struct {
+	jobs []Job
+}
+
+func NewRunner() *Runner {
+	return &Runner{jobs: make([]Job, 0)}
+}
+
+// Add registers a job. Jobs must be added before Run is called.
+func (runner *Runner) Add(job Job) {
+	runner.jobs = append(runner.jobs, job)
+}
+
+// Run starts every job in its own goroutine and blocks until ctx is canceled and all running jobs have returned.
+// Each job runs once at start and then on every tick of its interval. A failing run is logged and does not stop
+// the job.
+func (runner *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, job := range runner.jobs {
+		wg.Go(func() {
+			runJob(ctx, job)
+		})
+	}
+
+	wg.Wait()
+}
+
+func runJob(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+
+		err := job.Run(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "job failed", "job", job.Name, "error", err)
+		} else {
+			slog.DebugContext(ctx, "job finished", "job", job.Name, "duration", time.Since(start))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nasermirzaei89/scribble/jobs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var succeeded, failed atomic.Int32
+
+	runner := jobs.NewRunner()
+	runner.Add(jobs.Job{
+		Name:     "succeeding",
+		Interval: time.Millisecond,
+		Run: func(context.Context) error {
+			succeeded.Add(1)
+
+			return nil
+		},
+	})
+	runner.Add(jobs.Job{
+		Name:     "failing",
+		Interval: time.Millisecond,
+		Run: func(context.Context) error {
+			failed.Add(1)
+
+			return errors.New("boom")
+		},
+	})
+
+	done := make(chan struct{})
+
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return succeeded.Load() >= 3 && failed.Load() >= 3
+	}, time.Second, time.Millisecond, "jobs should keep running on their interval, even after failures")
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runner did not stop after context cancellation")
+	}
+}
+
+func TestRunnerRunsImmediately(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ran := make(chan struct{}, 1)
+
+	runner := jobs.NewRunner()
+	runner.Add(jobs.Job{
+		Name:     "hourly",
+		Interval: time.Hour,
+		Run: func(context.Context) error {
+			ran <- struct{}{}
+
+			return nil
+		},
+	})
+
+	go runner.Run(ctx)
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("job did not run at start")
+	}
+}
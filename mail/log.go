@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// LogSender writes every message in full to a writer instead of delivering it, such as a file or the standard
+// output. It is meant for development, where the links in a message can be copied from the output.
+type LogSender struct {
+	From string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+var _ Sender = (*LogSender)(nil)
+
+func NewLogSender(w io.Writer, from string) *LogSender {
+	return &LogSender{From: from, mu: sync.Mutex{}, w: w}
+}
+
+func (sender *LogSender) Send(_ context.Context, msg *Message) error {
+	data, err := format(sender.From, msg, time.Now(), encoding8Bit)
+	if err != nil {
+		return err
+	}
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	_, err = fmt.Fprintf(sender.w, "%s\r\n\r\n", data)
+	if err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+
+	return nil
+}
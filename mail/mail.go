@@ -0,0 +1,85 @@
+// Package mail sends plain text email through a pluggable Sender.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Sender delivers a message, or records it somewhere in place of delivery.
+type Sender interface {
+	Send(ctx context.Context, msg *Message) (err error)
+}
+
+// Content transfer encodings of the message body.
+const (
+	encodingQuotedPrintable = "quoted-printable"
+	// encoding8Bit leaves the text as is, so links stay on one line where people read the message raw.
+	encoding8Bit = "8bit"
+)
+
+// format renders msg as an RFC 5322 message from the given address.
+func format(from string, msg *Message, now time.Time, encoding string) ([]byte, error) {
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address: %w", err)
+	}
+
+	toAddr, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to address: %w", err)
+	}
+
+	// Header values come from callers, so line breaks are stripped to keep them from adding headers.
+	subject := strings.NewReplacer("\r", "", "\n", " ").Replace(msg.Subject)
+
+	_, domain, _ := strings.Cut(fromAddr.Address, "@")
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", fromAddr.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", toAddr.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", strings.ToLower(rand.Text()), domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(&buf, "Content-Transfer-Encoding: %s\r\n", encoding)
+	buf.WriteString("\r\n")
+
+	text := strings.ReplaceAll(msg.Text, "\n", "\r\n")
+
+	if encoding != encodingQuotedPrintable {
+		buf.WriteString(text)
+
+		return buf.Bytes(), nil
+	}
+
+	qp := quotedprintable.NewWriter(&buf)
+
+	_, err = qp.Write([]byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode body: %w", err)
+	}
+
+	err = qp.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode body: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
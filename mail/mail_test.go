@@ -0,0 +1,203 @@
+package mail_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	scribblemail "github.com/nasermirzaei89/scribble/mail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogSender(t *testing.T) {
+	var buf bytes.Buffer
+
+	sender := scribblemail.NewLogSender(&buf, "Scribble <no-reply@example.com>")
+
+	err := sender.Send(context.Background(), &scribblemail.Message{
+		To:      "jane@example.com",
+		Subject: "Héllo\r\nBcc: someone@example.com",
+		Text:    "Follow this link:\n\nhttps://example.com/reset-password?token=abc=def\n",
+	})
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, `"Scribble" <no-reply@example.com>`, msg.Header.Get("From"))
+	assert.Equal(t, "<jane@example.com>", msg.Header.Get("To"))
+	assert.Empty(t, msg.Header.Get("Bcc"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Héllo Bcc: someone@example.com", subject)
+
+	_, err = msg.Header.Date()
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(msg.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "https://example.com/reset-password?token=abc=def\r\n")
+
+	err = sender.Send(context.Background(), &scribblemail.Message{To: "not an address", Subject: "", Text: ""})
+	require.Error(t, err)
+}
+
+func TestMemorySender(t *testing.T) {
+	sender := scribblemail.NewMemorySender()
+
+	_, ok := sender.Last()
+	assert.False(t, ok)
+
+	for _, to := range []string{"jane@example.com", "john@example.com"} {
+		err := sender.Send(context.Background(), &scribblemail.Message{To: to, Subject: "Hi", Text: "Hello"})
+		require.NoError(t, err)
+	}
+
+	messages := sender.Messages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "jane@example.com", messages[0].To)
+
+	last, ok := sender.Last()
+	require.True(t, ok)
+	assert.Equal(t, "john@example.com", last.To)
+}
+
+// smtpServer accepts a single SMTP session without TLS or authentication and records the envelope and data.
+type smtpServer struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     string
+	done     chan struct{}
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &smtpServer{listener: listener, from: "", to: nil, data: "", done: make(chan struct{})}
+
+	go server.serve(t)
+
+	t.Cleanup(func() {
+		assert.NoError(t, listener.Close())
+	})
+
+	return server
+}
+
+func (server *smtpServer) serve(t *testing.T) {
+	defer close(server.done)
+
+	conn, err := server.listener.Accept()
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		assert.NoError(t, conn.Close())
+	}()
+
+	text := textproto.NewConn(conn)
+
+	reply := func(line string) bool {
+		return text.PrintfLine("%s", line) == nil
+	}
+
+	if !reply("220 localhost ESMTP") {
+		return
+	}
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			server.from = arg
+			reply("250 OK")
+		case "RCPT":
+			server.to = append(server.to, arg)
+			reply("250 OK")
+		case "DATA":
+			reply("354 Go ahead")
+
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+
+			server.data = string(data)
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+
+			return
+		default:
+			reply("502 Not implemented")
+		}
+	}
+}
+
+func TestSMTPSender(t *testing.T) {
+	server := newSMTPServer(t)
+
+	sender := scribblemail.NewSMTPSender(server.listener.Addr().String(), "", "", "Scribble <no-reply@example.com>")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := sender.Send(ctx, &scribblemail.Message{
+		To:      "Jane <jane@example.com>",
+		Subject: "Reset your password",
+		Text:    "Follow this link.\n",
+	})
+	require.NoError(t, err)
+
+	<-server.done
+
+	assert.Equal(t, "FROM:<no-reply@example.com>", server.from)
+	assert.Equal(t, []string{"TO:<jane@example.com>"}, server.to)
+
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(server.data)))
+	require.NoError(t, err)
+	assert.Equal(t, "Reset your password", msg.Header.Get("Subject"))
+	assert.Equal(t, "quoted-printable", msg.Header.Get("Content-Transfer-Encoding"))
+
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	require.NoError(t, err)
+	// The server side reads the data with bare line feeds.
+	assert.Equal(t, "Follow this link.\n", string(body))
+}
+
+func TestSMTPSenderUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	sender := scribblemail.NewSMTPSender(addr, "", "", "no-reply@example.com")
+
+	err = sender.Send(context.Background(), &scribblemail.Message{To: "jane@example.com", Subject: "Hi", Text: ""})
+	require.Error(t, err)
+}
@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// MemorySender keeps sent messages in memory, so tests can read them.
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+var _ Sender = (*MemorySender)(nil)
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{mu: sync.Mutex{}, messages: make([]Message, 0)}
+}
+
+func (sender *MemorySender) Send(_ context.Context, msg *Message) error {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	sender.messages = append(sender.messages, *msg)
+
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (sender *MemorySender) Messages() []Message {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	messages := make([]Message, len(sender.messages))
+	copy(messages, sender.messages)
+
+	return messages
+}
+
+// Last returns the most recently sent message, and false if none was sent.
+func (sender *MemorySender) Last() (Message, bool) {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	if len(sender.messages) == 0 {
+		return Message{}, false
+	}
+
+	return sender.messages[len(sender.messages)-1], true
+}
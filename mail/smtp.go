@@ -0,0 +1,125 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPSender delivers messages to an SMTP server. The connection is upgraded with STARTTLS when the server offers
+// it, and credentials are only sent over TLS.
+type SMTPSender struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+var _ Sender = (*SMTPSender)(nil)
+
+func NewSMTPSender(addr, username, password, from string) *SMTPSender {
+	return &SMTPSender{Addr: addr, Username: username, Password: password, From: from}
+}
+
+func (sender *SMTPSender) Send(ctx context.Context, msg *Message) error {
+	data, err := format(sender.From, msg, time.Now(), encodingQuotedPrintable)
+	if err != nil {
+		return err
+	}
+
+	fromAddr, err := mail.ParseAddress(sender.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+
+	toAddr, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid to address: %w", err)
+	}
+
+	host, _, err := net.SplitHostPort(sender.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid smtp address: %w", err)
+	}
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", sender.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+
+	// The deadline bounds the whole conversation, since net/smtp doesn't take a context.
+	if deadline, ok := ctx.Deadline(); ok {
+		err = conn.SetDeadline(deadline)
+		if err != nil {
+			return errors.Join(fmt.Errorf("failed to set deadline: %w", err), conn.Close())
+		}
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to start smtp session: %w", err), conn.Close())
+	}
+
+	err = sender.deliver(client, host, fromAddr.Address, toAddr.Address, data)
+	if err != nil {
+		return errors.Join(err, client.Close())
+	}
+
+	return nil
+}
+
+func (sender *SMTPSender) deliver(client *smtp.Client, host, from, to string, data []byte) error {
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err := client.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12})
+		if err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+
+	if sender.Username != "" {
+		// PlainAuth refuses to send the password over an unencrypted connection, except to localhost.
+		err := client.Auth(smtp.PlainAuth("", sender.Username, sender.Password, host))
+		if err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	err := client.Mail(from)
+	if err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+
+	err = client.Rcpt(to)
+	if err != nil {
+		return fmt.Errorf("failed to set recipient: %w", err)
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message data: %w", err)
+	}
+
+	_, err = writer.Write(data)
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to write message: %w", err), writer.Close())
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return fmt.Errorf("failed to finish message: %w", err)
+	}
+
+	err = client.Quit()
+	if err != nil {
+		return fmt.Errorf("failed to quit smtp session: %w", err)
+	}
+
+	return nil
+}
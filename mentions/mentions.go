@@ -0,0 +1,197 @@
+// Package mentions finds the @username mentions in user-written Markdown. It links them when rendering, through a
+// goldmark extension, and lists them for storing, so users can find the posts and comments that mention them.
+package mentions
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Mention is a user mentioned in a post, or in a comment on it when CommentID is set.
+type Mention struct {
+	UserID    string
+	PostID    string
+	CommentID *string
+	CreatedAt time.Time
+}
+
+type Repository interface {
+	// Replace swaps the mentions of a post, or of a comment on it when commentID is set, for one mention of each of
+	// the usernames that belongs to a user. The rest are skipped, the way unknown names are left as text when
+	// rendered. Without usernames it removes the mentions.
+	Replace(ctx context.Context, postID string, commentID *string, usernames []string, createdAt time.Time) (err error)
+	// ListByUser returns the mentions of a user in posts and comments that still exist, newest first.
+	ListByUser(ctx context.Context, userID string) (mentions []*Mention, err error)
+}
+
+// KindMention is the kind of the Node a mention is parsed into.
+var KindMention = ast.NewNodeKind("Mention")
+
+// Node is a mention in a Markdown document, with the username as it was written after the @. Href is the escaped URL
+// it links to, empty when the username isn't known to belong to a user.
+type Node struct {
+	ast.BaseInline
+
+	Username string
+	Href     string
+}
+
+func (n *Node) Kind() ast.NodeKind {
+	return KindMention
+}
+
+func (n *Node) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Username": n.Username, "Href": n.Href}, nil)
+}
+
+// Resolver tells whether a username belongs to a user, and if it does, the escaped URL the mention links to.
+type Resolver func(username string) (href string, ok bool)
+
+var resolverKey = parser.NewContextKey()
+
+// NewContext returns a parser context that resolves mentions with resolve. Documents parsed without one render every
+// mention as text.
+func NewContext(resolve Resolver) parser.Context {
+	pc := parser.NewContext()
+	pc.Set(resolverKey, resolve)
+
+	return pc
+}
+
+// Extension parses mentions and renders the ones resolved through the parser context, see NewContext, as links.
+// Mentions of unknown names are rendered as the text they were written as.
+type Extension struct{}
+
+var _ goldmark.Extender = (*Extension)(nil)
+
+func (e *Extension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(mentionParser{}, 500)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(mentionRenderer{}, 500)))
+}
+
+// extractor parses documents the way web renders them, so the same mentions are found.
+var extractor = goldmark.New(goldmark.WithExtensions(extension.GFM, &Extension{}))
+
+// Extract returns the usernames mentioned in a Markdown document, each once and in the order they first appear.
+// Code and link labels are skipped, as they are when rendering.
+func Extract(source string) []string {
+	doc := extractor.Parser().Parse(text.NewReader([]byte(source)))
+
+	usernames := make([]string, 0)
+	seen := make(map[string]bool)
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		mention, ok := n.(*Node)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+
+		// Mentions only match ASCII names, so lowercasing is enough to tell the same name apart.
+		key := strings.ToLower(mention.Username)
+		if !seen[key] {
+			seen[key] = true
+			usernames = append(usernames, mention.Username)
+		}
+
+		return ast.WalkSkipChildren, nil
+	})
+
+	return usernames
+}
+
+// isUsernameChar matches the characters usernames are made of. A username starts and ends with a letter or digit.
+func isUsernameChar(c byte) bool {
+	return c < unicode.MaxASCII && (util.IsAlphaNumeric(c) || c == '_' || c == '.' || c == '-')
+}
+
+type mentionParser struct{}
+
+var _ parser.InlineParser = mentionParser{}
+
+func (mentionParser) Trigger() []byte {
+	return []byte{'@'}
+}
+
+func (mentionParser) Parse(_ ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	// A mention inside a link label would nest links, and one right after a word is more likely an email address.
+	if pc.IsInLinkLabel() {
+		return nil
+	}
+
+	before := block.PrecendingCharacter()
+	if unicode.IsLetter(before) || unicode.IsDigit(before) || strings.ContainsRune("_@/", before) {
+		return nil
+	}
+
+	line, _ := block.PeekLine()
+	if len(line) < 2 || !util.IsAlphaNumeric(line[1]) {
+		return nil
+	}
+
+	end := 1
+	for end < len(line) && isUsernameChar(line[end]) {
+		end++
+	}
+
+	// Trailing punctuation ends the sentence rather than the username.
+	for end > 1 && !util.IsAlphaNumeric(line[end-1]) {
+		end--
+	}
+
+	block.Advance(end)
+
+	node := &Node{BaseInline: ast.BaseInline{}, Username: string(line[1:end]), Href: ""}
+
+	if resolve, ok := pc.Get(resolverKey).(Resolver); ok && resolve != nil {
+		if href, ok := resolve(node.Username); ok {
+			node.Href = href
+		}
+	}
+
+	return node
+}
+
+type mentionRenderer struct{}
+
+var _ renderer.NodeRenderer = mentionRenderer{}
+
+func (r mentionRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMention, r.renderMention)
+}
+
+func (mentionRenderer) renderMention(
+	w util.BufWriter,
+	_ []byte,
+	n ast.Node,
+	entering bool,
+) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	mention := n.(*Node) //nolint:forcetypeassert // only registered for KindMention
+
+	if mention.Href == "" {
+		_, _ = w.Write(util.EscapeHTML([]byte("@" + mention.Username)))
+
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = w.WriteString(`<a href="`)
+	_, _ = w.Write(util.EscapeHTML([]byte(mention.Href)))
+	_, _ = w.WriteString(`">`)
+	_, _ = w.Write(util.EscapeHTML([]byte("@" + mention.Username)))
+	_, _ = w.WriteString(`</a>`)
+
+	return ast.WalkContinue, nil
+}
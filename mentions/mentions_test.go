@@ -0,0 +1,102 @@
+package mentions_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nasermirzaei89/scribble/mentions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+)
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "no mentions",
+			input:    "hello world",
+			expected: []string{},
+		},
+		{
+			name:     "mentions in order",
+			input:    "@bob, ask @alice_1 and **@carol.d**!",
+			expected: []string{"bob", "alice_1", "carol.d"},
+		},
+		{
+			name:     "repeated mention",
+			input:    "@bob @Bob @bob",
+			expected: []string{"bob"},
+		},
+		{
+			name:     "trailing punctuation",
+			input:    "thanks @bob. and @alice-",
+			expected: []string{"bob", "alice"},
+		},
+		{
+			name:     "email address",
+			input:    "mail bob@example.com",
+			expected: []string{},
+		},
+		{
+			name:     "code",
+			input:    "`@bob`\n\n```\n@alice\n```",
+			expected: []string{},
+		},
+		{
+			name:     "link label",
+			input:    "[@bob](https://example.com) https://example.com/@alice",
+			expected: []string{},
+		},
+		{
+			name:     "bare at sign",
+			input:    "meet @ noon, @_x",
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, mentions.Extract(tc.input))
+		})
+	}
+}
+
+func TestExtensionRendersKnownUsers(t *testing.T) {
+	t.Parallel()
+
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM, &mentions.Extension{}))
+
+	pc := mentions.NewContext(func(username string) (string, bool) {
+		return "/u/" + username, username == "bob"
+	})
+
+	var buf bytes.Buffer
+
+	err := md.Convert([]byte("hi @bob and @nobody (@bob)"), &buf, parser.WithContext(pc))
+	require.NoError(t, err)
+
+	assert.Equal(t, `<p>hi <a href="/u/bob">@bob</a> and @nobody (<a href="/u/bob">@bob</a>)</p>`+"\n", buf.String())
+}
+
+func TestExtensionWithoutResolver(t *testing.T) {
+	t.Parallel()
+
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM, &mentions.Extension{}))
+
+	var buf bytes.Buffer
+
+	err := md.Convert([]byte("hi @bob"), &buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, "<p>hi @bob</p>\n", buf.String())
+}
@@ -0,0 +1,148 @@
+package profiles
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/nasermirzaei89/scribble/authorization"
+)
+
+const (
+	ActionListProfiles  = "listProfiles"
+	ActionGetProfile    = "getProfile"
+	ActionUpdateProfile = "updateProfile"
+	ActionDeleteProfile = "deleteProfile"
+)
+
+type AuthorizationMiddleware struct {
+	authzClient *authorization.Client
+	next        Service
+}
+
+var _ Service = (*AuthorizationMiddleware)(nil)
+
+func NewAuthorizationMiddleware(authzClient *authorization.Client, next Service) *AuthorizationMiddleware {
+	return &AuthorizationMiddleware{
+		authzClient: authzClient,
+		next:        next,
+	}
+}
+
+func (mw *AuthorizationMiddleware) GetProfile(ctx context.Context, userID string) (*Profile, error) {
+	err := mw.authzClient.CheckAccess(ctx, ServiceName, userID, ActionGetProfile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	profile, err := mw.next.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return profile, nil
+}
+
+func (mw *AuthorizationMiddleware) GetProfiles(ctx context.Context, userIDs []string) (map[string]*Profile, error) {
+	err := mw.authzClient.CheckAccess(ctx, ServiceName, "", ActionListProfiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	profiles, err := mw.next.GetProfiles(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return profiles, nil
+}
+
+// checkOwnerAccess allows users to change their own profile unconditionally and falls back to the policy for everyone
+// else, so root can still clean up profiles.
+func (mw *AuthorizationMiddleware) checkOwnerAccess(ctx context.Context, userID, action string) error {
+	if authcontext.GetSubject(ctx) == userID {
+		return nil
+	}
+
+	err := mw.authzClient.CheckAccess(ctx, ServiceName, userID, action)
+	if err != nil {
+		return fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	return nil
+}
+
+func (mw *AuthorizationMiddleware) UpdateProfile(ctx context.Context, req UpdateProfileRequest) (*Profile, error) {
+	err := mw.checkOwnerAccess(ctx, req.UserID, ActionUpdateProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := mw.next.UpdateProfile(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return profile, nil
+}
+
+func (mw *AuthorizationMiddleware) SetAvatar(ctx context.Context, userID string, r io.Reader) (*Profile, error) {
+	err := mw.checkOwnerAccess(ctx, userID, ActionUpdateProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := mw.next.SetAvatar(ctx, userID, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return profile, nil
+}
+
+func (mw *AuthorizationMiddleware) RemoveAvatar(ctx context.Context, userID string) (*Profile, error) {
+	err := mw.checkOwnerAccess(ctx, userID, ActionUpdateProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := mw.next.RemoveAvatar(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return profile, nil
+}
+
+func (mw *AuthorizationMiddleware) OpenAvatar(
+	ctx context.Context,
+	userID string,
+	size AvatarSize,
+) (io.ReadCloser, error) {
+	err := mw.authzClient.CheckAccess(ctx, ServiceName, userID, ActionGetProfile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	rc, err := mw.next.OpenAvatar(ctx, userID, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return rc, nil
+}
+
+func (mw *AuthorizationMiddleware) DeleteProfile(ctx context.Context, userID string) error {
+	err := mw.checkOwnerAccess(ctx, userID, ActionDeleteProfile)
+	if err != nil {
+		return err
+	}
+
+	err = mw.next.DeleteProfile(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package profiles_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	fileadapter "github.com/casbin/casbin/v3/persist/file-adapter"
+	"github.com/google/uuid"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/nasermirzaei89/scribble/authorization"
+	"github.com/nasermirzaei89/scribble/authorization/casbin"
+	"github.com/nasermirzaei89/scribble/profiles"
+	"github.com/stretchr/testify/require"
+)
+
+type stubService struct{}
+
+func (s *stubService) GetProfile(ctx context.Context, userID string) (*profiles.Profile, error) {
+	return &profiles.Profile{UserID: userID}, nil
+}
+
+func (s *stubService) GetProfiles(ctx context.Context, userIDs []string) (map[string]*profiles.Profile, error) {
+	return map[string]*profiles.Profile{}, nil
+}
+
+func (s *stubService) UpdateProfile(ctx context.Context, req profiles.UpdateProfileRequest) (*profiles.Profile, error) {
+	return &profiles.Profile{UserID: req.UserID, DisplayName: req.DisplayName, Bio: req.Bio}, nil
+}
+
+func (s *stubService) SetAvatar(ctx context.Context, userID string, r io.Reader) (*profiles.Profile, error) {
+	return &profiles.Profile{UserID: userID}, nil
+}
+
+func (s *stubService) RemoveAvatar(ctx context.Context, userID string) (*profiles.Profile, error) {
+	return &profiles.Profile{UserID: userID}, nil
+}
+
+func (s *stubService) OpenAvatar(
+	ctx context.Context,
+	userID string,
+	size profiles.AvatarSize,
+) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (s *stubService) DeleteProfile(ctx context.Context, userID string) error {
+	return nil
+}
+
+func TestAuthorizationMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "policy.csv")
+	content := []byte(`g, system:anonymous, system:unauthenticated
+
+p, system:group:root, *, *, *
+
+p, system:authenticated, github.com/nasermirzaei89/scribble/profiles, -, listProfiles
+p, system:unauthenticated, github.com/nasermirzaei89/scribble/profiles, -, listProfiles
+p, system:authenticated, github.com/nasermirzaei89/scribble/profiles, *, getProfile
+p, system:unauthenticated, github.com/nasermirzaei89/scribble/profiles, *, getProfile
+`)
+
+	err := os.WriteFile(tmpFile, content, 0o600)
+	require.NoError(t, err)
+
+	adapter := fileadapter.NewAdapter(tmpFile)
+
+	provider, err := casbin.NewAuthorizationProvider(adapter)
+	require.NoError(t, err)
+
+	authzSvc, err := authorization.NewService(provider)
+	require.NoError(t, err)
+
+	client := authorization.NewClient(authzSvc)
+	svc := profiles.NewAuthorizationMiddleware(client, &stubService{})
+
+	ownerID := uuid.NewString()
+	err = client.AddToGroup(ctx, ownerID, authcontext.Authenticated)
+	require.NoError(t, err)
+
+	userID := uuid.NewString()
+	err = client.AddToGroup(ctx, userID, authcontext.Authenticated)
+	require.NoError(t, err)
+
+	rootID := uuid.NewString()
+	err = client.AddToGroup(ctx, rootID, "system:group:root")
+	require.NoError(t, err)
+
+	anonymousCtx := ctx
+	authenticatedCtx := authcontext.WithSubject(ctx, userID)
+	ownerCtx := authcontext.WithSubject(ctx, ownerID)
+	rootCtx := authcontext.WithSubject(ctx, rootID)
+
+	update := profiles.UpdateProfileRequest{UserID: ownerID, DisplayName: "Owner", Bio: ""}
+
+	t.Run("anonymous", func(t *testing.T) {
+		_, err := svc.GetProfile(anonymousCtx, ownerID)
+		require.NoError(t, err)
+
+		_, err = svc.GetProfiles(anonymousCtx, []string{ownerID})
+		require.NoError(t, err)
+
+		_, err = svc.OpenAvatar(anonymousCtx, ownerID, profiles.AvatarSizeSmall)
+		require.NoError(t, err)
+
+		accessDeniedErr := &authorization.AccessDeniedError{}
+
+		_, err = svc.UpdateProfile(anonymousCtx, update)
+		require.ErrorAs(t, err, &accessDeniedErr)
+
+		_, err = svc.SetAvatar(anonymousCtx, ownerID, strings.NewReader(""))
+		require.ErrorAs(t, err, &accessDeniedErr)
+	})
+
+	t.Run("authenticated non-owner", func(t *testing.T) {
+		_, err := svc.GetProfile(authenticatedCtx, ownerID)
+		require.NoError(t, err)
+
+		accessDeniedErr := &authorization.AccessDeniedError{}
+
+		_, err = svc.UpdateProfile(authenticatedCtx, update)
+		require.ErrorAs(t, err, &accessDeniedErr)
+
+		_, err = svc.SetAvatar(authenticatedCtx, ownerID, strings.NewReader(""))
+		require.ErrorAs(t, err, &accessDeniedErr)
+
+		_, err = svc.RemoveAvatar(authenticatedCtx, ownerID)
+		require.ErrorAs(t, err, &accessDeniedErr)
+
+		err = svc.DeleteProfile(authenticatedCtx, ownerID)
+		require.ErrorAs(t, err, &accessDeniedErr)
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		_, err := svc.UpdateProfile(ownerCtx, update)
+		require.NoError(t, err)
+
+		_, err = svc.SetAvatar(ownerCtx, ownerID, strings.NewReader(""))
+		require.NoError(t, err)
+
+		_, err = svc.RemoveAvatar(ownerCtx, ownerID)
+		require.NoError(t, err)
+
+		err = svc.DeleteProfile(ownerCtx, ownerID)
+		require.NoError(t, err)
+	})
+
+	t.Run("root", func(t *testing.T) {
+		_, err := svc.UpdateProfile(rootCtx, update)
+		require.NoError(t, err)
+
+		err = svc.DeleteProfile(rootCtx, ownerID)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,170 @@
+package profiles
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"  // register the GIF decoder for uploads
+	_ "image/jpeg" // register the JPEG decoder for uploads
+	"image/png"
+	"io"
+)
+
+// AvatarSize names one of the fixed sizes every avatar is stored in.
+type AvatarSize string
+
+const (
+	AvatarSizeSmall AvatarSize = "small"
+	AvatarSizeLarge AvatarSize = "large"
+)
+
+// AvatarSizes lists every size an uploaded avatar is resized to.
+var AvatarSizes = []AvatarSize{AvatarSizeSmall, AvatarSizeLarge}
+
+func (size AvatarSize) IsValid() bool {
+	switch size {
+	case AvatarSizeSmall, AvatarSizeLarge:
+		return true
+	default:
+		return false
+	}
+}
+
+// Pixels is the width and height of the square avatar image of this size. Both are twice what the templates show at
+// most, so avatars stay sharp on high density screens.
+func (size AvatarSize) Pixels() int {
+	switch size {
+	case AvatarSizeSmall:
+		return 96
+	case AvatarSizeLarge:
+		return 320
+	default:
+		return 0
+	}
+}
+
+const (
+	// MaxAvatarBytes is the largest avatar upload accepted.
+	MaxAvatarBytes = 5 << 20
+	// maxAvatarPixels caps the decoded size of an upload, since a small file can still claim huge dimensions.
+	maxAvatarPixels = 25_000_000
+)
+
+// AvatarContentType is the type of every stored avatar, whatever format was uploaded.
+const AvatarContentType = "image/png"
+
+var ErrInvalidAvatar = errors.New("invalid avatar")
+
+type AvatarNotFoundError struct {
+	UserID string
+	Size   AvatarSize
+}
+
+func (err AvatarNotFoundError) Error() string {
+	return fmt.Sprintf("%s avatar of user %q not found", err.Size, err.UserID)
+}
+
+func avatarKey(userID string, size AvatarSize) string {
+	return "avatars/" + userID + "/" + string(size) + ".png"
+}
+
+// processAvatar decodes an uploaded JPEG, PNG or GIF image, crops the largest centered square out of it and encodes
+// it as PNG in every avatar size. Re-encoding also drops whatever metadata the upload carried.
+func processAvatar(r io.Reader) (map[AvatarSize][]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxAvatarBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read avatar: %w", err)
+	}
+
+	if len(data) > MaxAvatarBytes {
+		return nil, fmt.Errorf("%w: larger than %d MiB", ErrInvalidAvatar, MaxAvatarBytes>>20)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAvatar, err)
+	}
+
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxAvatarPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels is too large", ErrInvalidAvatar, config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAvatar, err)
+	}
+
+	square := cropSquare(img)
+	result := make(map[AvatarSize][]byte, len(AvatarSizes))
+
+	for _, size := range AvatarSizes {
+		var buf bytes.Buffer
+
+		err = png.Encode(&buf, resize(square, size.Pixels()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s avatar: %w", size, err)
+		}
+
+		result[size] = buf.Bytes()
+	}
+
+	return result, nil
+}
+
+// cropSquare copies the largest square centered in img.
+func cropSquare(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	offset := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), img, offset, draw.Src)
+
+	return square
+}
+
+// resize scales the square src to side by side pixels. Each pixel is the average of the source pixels it covers, which
+// is all the filtering downscaling needs. Smaller sources are scaled up by repeating pixels.
+func resize(src *image.RGBA, side int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	srcSide := src.Bounds().Dx()
+
+	// span returns the range of source rows or columns that destination row or column i covers.
+	span := func(i int) (int, int) {
+		start := i * srcSide / side
+		end := max((i+1)*srcSide/side, start+1)
+
+		return start, end
+	}
+
+	for dy := range side {
+		y0, y1 := span(dy)
+
+		for dx := range side {
+			x0, x1 := span(dx)
+
+			var sum [4]int
+
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride:]
+
+				for x := x0; x < x1; x++ {
+					for c := range sum {
+						sum[c] += int(row[x*4+c])
+					}
+				}
+			}
+
+			count := (y1 - y0) * (x1 - x0)
+			pixel := dst.Pix[dy*dst.Stride+dx*4:]
+
+			for c := range sum {
+				pixel[c] = uint8((sum[c] + count/2) / count) //nolint:gosec // an average of bytes is a byte
+			}
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,39 @@
+package profiles
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Profile is what a user tells others about themselves. A user without a stored profile has an empty one.
+type Profile struct {
+	UserID      string
+	DisplayName string
+	// Bio is written in Markdown.
+	Bio string
+	// AvatarUpdatedAt is when the current avatar was uploaded, or nil if the user has none.
+	AvatarUpdatedAt *time.Time
+	UpdatedAt       time.Time
+}
+
+func (profile *Profile) HasAvatar() bool {
+	return profile.AvatarUpdatedAt != nil
+}
+
+type ProfileRepository interface {
+	// Upsert inserts the profile or replaces the one stored for the same user.
+	Upsert(ctx context.Context, profile *Profile) (err error)
+	Find(ctx context.Context, userID string) (profile *Profile, err error)
+	// FindMany returns the stored profiles among the given users. Users without one are left out.
+	FindMany(ctx context.Context, userIDs []string) (profiles []*Profile, err error)
+	Delete(ctx context.Context, userID string) (err error)
+}
+
+type ProfileNotFoundError struct {
+	UserID string
+}
+
+func (err ProfileNotFoundError) Error() string {
+	return fmt.Sprintf("profile of user %q not found", err.UserID)
+}
@@ -0,0 +1,217 @@
+// Package profiles keeps the display names, bios and avatars users show to others.
+package profiles
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nasermirzaei89/scribble/authorization"
+	"github.com/nasermirzaei89/scribble/blob"
+)
+
+const ServiceName = "github.com/nasermirzaei89/scribble/profiles"
+
+type Service interface {
+	GetProfile(ctx context.Context, userID string) (*Profile, error)
+	GetProfiles(ctx context.Context, userIDs []string) (map[string]*Profile, error)
+	UpdateProfile(ctx context.Context, req UpdateProfileRequest) (*Profile, error)
+	SetAvatar(ctx context.Context, userID string, r io.Reader) (*Profile, error)
+	RemoveAvatar(ctx context.Context, userID string) (*Profile, error)
+	OpenAvatar(ctx context.Context, userID string, size AvatarSize) (io.ReadCloser, error)
+	DeleteProfile(ctx context.Context, userID string) error
+}
+
+type BaseService struct {
+	profileRepo ProfileRepository
+	blobStore   blob.Store
+}
+
+var _ Service = (*BaseService)(nil)
+
+func NewService( //nolint:ireturn
+	profileRepo ProfileRepository,
+	blobStore blob.Store,
+	authzClient *authorization.Client,
+) Service {
+	return NewAuthorizationMiddleware(authzClient, NewBaseService(profileRepo, blobStore))
+}
+
+func NewBaseService(profileRepo ProfileRepository, blobStore blob.Store) *BaseService {
+	return &BaseService{
+		profileRepo: profileRepo,
+		blobStore:   blobStore,
+	}
+}
+
+// GetProfile returns the user's profile, or an empty one if the user hasn't set it up.
+func (svc *BaseService) GetProfile(ctx context.Context, userID string) (*Profile, error) {
+	profile, err := svc.profileRepo.Find(ctx, userID)
+	if err != nil {
+		if errors.As(err, new(ProfileNotFoundError)) {
+			return &Profile{UserID: userID}, nil
+		}
+
+		return nil, fmt.Errorf("failed to find profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// GetProfiles returns the profiles of the given users keyed by user ID, loading them all in one query. Users who
+// haven't set up a profile get an empty one.
+func (svc *BaseService) GetProfiles(ctx context.Context, userIDs []string) (map[string]*Profile, error) {
+	profiles, err := svc.profileRepo.FindMany(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find profiles: %w", err)
+	}
+
+	result := make(map[string]*Profile, len(userIDs))
+
+	for _, profile := range profiles {
+		result[profile.UserID] = profile
+	}
+
+	for _, userID := range userIDs {
+		if _, ok := result[userID]; !ok {
+			result[userID] = &Profile{UserID: userID}
+		}
+	}
+
+	return result, nil
+}
+
+type UpdateProfileRequest struct {
+	UserID      string
+	DisplayName string
+	Bio         string
+}
+
+// UpdateProfile sets the display name and bio, leaving the avatar as it is. Surrounding whitespace is trimmed, and
+// empty values clear the fields.
+func (svc *BaseService) UpdateProfile(ctx context.Context, req UpdateProfileRequest) (*Profile, error) {
+	displayName := strings.TrimSpace(req.DisplayName)
+	bio := strings.TrimSpace(req.Bio)
+
+	err := validateProfile(displayName, bio)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := svc.GetProfile(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile.DisplayName = displayName
+	profile.Bio = bio
+	profile.UpdatedAt = time.Now()
+
+	err = svc.profileRepo.Upsert(ctx, profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// SetAvatar replaces the user's avatar with the uploaded image, cropped and resized to every AvatarSize. An image
+// that can't be used is reported as ErrInvalidAvatar.
+func (svc *BaseService) SetAvatar(ctx context.Context, userID string, r io.Reader) (*Profile, error) {
+	images, err := processAvatar(r)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := svc.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, size := range AvatarSizes {
+		err = svc.blobStore.Put(ctx, avatarKey(userID, size), bytes.NewReader(images[size]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to store %s avatar: %w", size, err)
+		}
+	}
+
+	now := time.Now()
+	profile.AvatarUpdatedAt = &now
+	profile.UpdatedAt = now
+
+	err = svc.profileRepo.Upsert(ctx, profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+func (svc *BaseService) RemoveAvatar(ctx context.Context, userID string) (*Profile, error) {
+	profile, err := svc.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !profile.HasAvatar() {
+		return profile, nil
+	}
+
+	profile.AvatarUpdatedAt = nil
+	profile.UpdatedAt = time.Now()
+
+	err = svc.profileRepo.Upsert(ctx, profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert profile: %w", err)
+	}
+
+	err = svc.deleteAvatar(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+// OpenAvatar returns the stored PNG image of the user's avatar in the given size. The caller must close it.
+func (svc *BaseService) OpenAvatar(ctx context.Context, userID string, size AvatarSize) (io.ReadCloser, error) {
+	if !size.IsValid() {
+		return nil, AvatarNotFoundError{UserID: userID, Size: size}
+	}
+
+	rc, err := svc.blobStore.Get(ctx, avatarKey(userID, size))
+	if err != nil {
+		if errors.As(err, new(*blob.NotFoundError)) {
+			return nil, AvatarNotFoundError{UserID: userID, Size: size}
+		}
+
+		return nil, fmt.Errorf("failed to get avatar: %w", err)
+	}
+
+	return rc, nil
+}
+
+// DeleteProfile removes the user's profile along with the avatar images.
+func (svc *BaseService) DeleteProfile(ctx context.Context, userID string) error {
+	err := svc.profileRepo.Delete(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete profile: %w", err)
+	}
+
+	return svc.deleteAvatar(ctx, userID)
+}
+
+func (svc *BaseService) deleteAvatar(ctx context.Context, userID string) error {
+	for _, size := range AvatarSizes {
+		err := svc.blobStore.Delete(ctx, avatarKey(userID, size))
+		if err != nil {
+			return fmt.Errorf("failed to delete %s avatar: %w", size, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package profiles_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nasermirzaei89/scribble/blob"
+	"github.com/nasermirzaei89/scribble/profiles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryProfileRepository struct {
+	mu       sync.Mutex
+	profiles map[string]profiles.Profile
+}
+
+func (repo *memoryProfileRepository) Upsert(_ context.Context, profile *profiles.Profile) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.profiles[profile.UserID] = *profile
+
+	return nil
+}
+
+func (repo *memoryProfileRepository) Find(_ context.Context, userID string) (*profiles.Profile, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	profile, ok := repo.profiles[userID]
+	if !ok {
+		return nil, profiles.ProfileNotFoundError{UserID: userID}
+	}
+
+	return &profile, nil
+}
+
+func (repo *memoryProfileRepository) FindMany(_ context.Context, userIDs []string) ([]*profiles.Profile, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	result := make([]*profiles.Profile, 0, len(userIDs))
+
+	for _, userID := range userIDs {
+		if profile, ok := repo.profiles[userID]; ok {
+			result = append(result, &profile)
+		}
+	}
+
+	return result, nil
+}
+
+func (repo *memoryProfileRepository) Delete(_ context.Context, userID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	delete(repo.profiles, userID)
+
+	return nil
+}
+
+func newTestService(t *testing.T) *profiles.BaseService {
+	t.Helper()
+
+	return profiles.NewBaseService(
+		&memoryProfileRepository{mu: sync.Mutex{}, profiles: make(map[string]profiles.Profile)},
+		blob.NewMemoryStore(),
+	)
+}
+
+func TestUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	userID := uuid.NewString()
+
+	profile, err := svc.GetProfile(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, userID, profile.UserID)
+	assert.Empty(t, profile.DisplayName)
+	assert.False(t, profile.HasAvatar())
+
+	profile, err = svc.UpdateProfile(ctx, profiles.UpdateProfileRequest{
+		UserID:      userID,
+		DisplayName: "  Jane Doe ",
+		Bio:         "Writes *things*.\n",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", profile.DisplayName)
+	assert.Equal(t, "Writes *things*.", profile.Bio)
+
+	other := uuid.NewString()
+
+	found, err := svc.GetProfiles(ctx, []string{userID, other})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, "Jane Doe", found[userID].DisplayName)
+	assert.Equal(t, other, found[other].UserID)
+
+	_, err = svc.UpdateProfile(ctx, profiles.UpdateProfileRequest{
+		UserID:      userID,
+		DisplayName: strings.Repeat("a", profiles.DisplayNameMaxLength+1) + "\n",
+		Bio:         strings.Repeat("é", profiles.BioMaxLength+1),
+	})
+
+	var validationErr *profiles.ValidationError
+
+	require.ErrorAs(t, err, &validationErr)
+	assert.Len(t, validationErr.Fields[profiles.FieldDisplayName], 1)
+	assert.Len(t, validationErr.Fields[profiles.FieldBio], 1)
+
+	_, err = svc.UpdateProfile(ctx, profiles.UpdateProfileRequest{UserID: userID, DisplayName: "Jane\tDoe"})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Contains(t, validationErr.Fields, profiles.FieldDisplayName)
+}
+
+func TestSetAvatar(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	userID := uuid.NewString()
+
+	// A wide image whose left and right thirds are red and whose middle is blue, so only blue is left after cropping.
+	src := image.NewRGBA(image.Rect(0, 0, 600, 200))
+
+	for y := range 200 {
+		for x := range 600 {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 200 && x < 400 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+
+			src.Set(x, y, c)
+		}
+	}
+
+	var upload bytes.Buffer
+
+	require.NoError(t, jpeg.Encode(&upload, src, &jpeg.Options{Quality: 95}))
+
+	profile, err := svc.SetAvatar(ctx, userID, &upload)
+	require.NoError(t, err)
+	require.True(t, profile.HasAvatar())
+
+	for _, size := range profiles.AvatarSizes {
+		img := openAvatar(ctx, t, svc, userID, size)
+
+		assert.Equal(t, image.Rect(0, 0, size.Pixels(), size.Pixels()), img.Bounds())
+
+		for _, pt := range []image.Point{{0, 0}, {size.Pixels() - 1, size.Pixels() / 2}} {
+			r, _, b, _ := img.At(pt.X, pt.Y).RGBA()
+			assert.Less(t, r, uint32(0x2000), "red at %v of %s avatar", pt, size)
+			assert.Greater(t, b, uint32(0xe000), "blue at %v of %s avatar", pt, size)
+		}
+	}
+
+	_, err = svc.OpenAvatar(ctx, userID, "huge")
+	require.ErrorAs(t, err, new(profiles.AvatarNotFoundError))
+
+	profile, err = svc.RemoveAvatar(ctx, userID)
+	require.NoError(t, err)
+	assert.False(t, profile.HasAvatar())
+
+	_, err = svc.OpenAvatar(ctx, userID, profiles.AvatarSizeSmall)
+	require.ErrorAs(t, err, new(profiles.AvatarNotFoundError))
+}
+
+func TestSetAvatarRejectsInvalidImages(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	userID := uuid.NewString()
+
+	var tooLarge bytes.Buffer
+
+	require.NoError(t, png.Encode(&tooLarge, image.NewGray(image.Rect(0, 0, 1, 1))))
+	tooLarge.Write(make([]byte, profiles.MaxAvatarBytes))
+
+	for name, upload := range map[string]io.Reader{
+		"not an image": strings.NewReader("<svg></svg>"),
+		"too large":    &tooLarge,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.SetAvatar(ctx, userID, upload)
+			require.ErrorIs(t, err, profiles.ErrInvalidAvatar)
+		})
+	}
+
+	profile, err := svc.GetProfile(ctx, userID)
+	require.NoError(t, err)
+	assert.False(t, profile.HasAvatar())
+}
+
+func openAvatar(
+	ctx context.Context,
+	t *testing.T,
+	svc *profiles.BaseService,
+	userID string,
+	size profiles.AvatarSize,
+) image.Image {
+	t.Helper()
+
+	rc, err := svc.OpenAvatar(ctx, userID, size)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, rc.Close())
+	}()
+
+	img, err := png.Decode(rc)
+	require.NoError(t, err)
+
+	return img
+}
@@ -0,0 +1,68 @@
+package profiles
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	FieldDisplayName = "display_name"
+	FieldBio         = "bio"
+)
+
+const (
+	DisplayNameMaxLength = 50
+	BioMaxLength         = 1000
+)
+
+// ValidationError reports every rule the input broke, keyed by field name.
+type ValidationError struct {
+	Fields map[string][]string
+}
+
+func (err ValidationError) Error() string {
+	parts := make([]string, 0, len(err.Fields))
+
+	for _, field := range slices.Sorted(maps.Keys(err.Fields)) {
+		parts = append(parts, field+": "+strings.Join(err.Fields[field], ", "))
+	}
+
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+func (err *ValidationError) add(field, message string) {
+	if err.Fields == nil {
+		err.Fields = make(map[string][]string)
+	}
+
+	err.Fields[field] = append(err.Fields[field], message)
+}
+
+// validateProfile checks the display name and bio, which are expected to be trimmed already.
+func validateProfile(displayName, bio string) error {
+	var validationErr ValidationError
+
+	if utf8.RuneCountInString(displayName) > DisplayNameMaxLength {
+		validationErr.add(FieldDisplayName, fmt.Sprintf("Display name must be at most %d characters.", DisplayNameMaxLength))
+	}
+
+	// The display name is shown on a single line next to the username, so it can't hold line breaks or other
+	// control characters.
+	if strings.ContainsFunc(displayName, unicode.IsControl) {
+		validationErr.add(FieldDisplayName, "Display name can't contain line breaks.")
+	}
+
+	if utf8.RuneCountInString(bio) > BioMaxLength {
+		validationErr.add(FieldBio, fmt.Sprintf("Bio must be at most %d characters.", BioMaxLength))
+	}
+
+	if len(validationErr.Fields) > 0 {
+		return &validationErr
+	}
+
+	return nil
+}
@@ -75,3 +75,21 @@ func (mw *AuthorizationMiddleware) GetMyReactions(
 
 	return res, nil
 }
+
+func (mw *AuthorizationMiddleware) GetMyReactionsByTargets(
+	ctx context.Context,
+	targetType TargetType,
+	targetIDs []string,
+) (map[string]*TargetReactions, error) {
+	err := mw.authzClient.CheckAccess(ctx, ServiceName, "", ActionGetMyReactions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	res, err := mw.next.GetMyReactionsByTargets(ctx, targetType, targetIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return res, nil
+}
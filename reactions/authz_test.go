@@ -46,6 +46,14 @@ func (s *stubService) GetMyReactions(
 	}, nil
 }
 
+func (s *stubService) GetMyReactionsByTargets(
+	ctx context.Context,
+	targetType reactions.TargetType,
+	targetIDs []string,
+) (map[string]*reactions.TargetReactions, error) {
+	return map[string]*reactions.TargetReactions{}, nil
+}
+
 func TestAuthorizationMiddleware(t *testing.T) {
 	ctx := context.Background()
 
@@ -91,6 +99,10 @@ p, system:authenticated, github.com/nasermirzaei89/scribble/reactions, -, getMyR
 		_, err = svc.GetMyReactions(anonymousCtx, targetType, targetID)
 		require.Error(t, err)
 		require.ErrorAs(t, err, &accessDeniedErr)
+
+		_, err = svc.GetMyReactionsByTargets(anonymousCtx, targetType, []string{targetID})
+		require.Error(t, err)
+		require.ErrorAs(t, err, &accessDeniedErr)
 	})
 
 	t.Run("authenticated", func(t *testing.T) {
@@ -99,5 +111,8 @@ p, system:authenticated, github.com/nasermirzaei89/scribble/reactions, -, getMyR
 
 		_, err = svc.GetMyReactions(authenticatedCtx, targetType, targetID)
 		require.NoError(t, err)
+
+		_, err = svc.GetMyReactionsByTargets(authenticatedCtx, targetType, []string{targetID})
+		require.NoError(t, err)
 	})
 }
@@ -21,6 +21,11 @@ type Service interface {
 		targetType TargetType,
 		targetID string,
 	) (*TargetReactions, error)
+	GetMyReactionsByTargets(
+		ctx context.Context,
+		targetType TargetType,
+		targetIDs []string,
+	) (map[string]*TargetReactions, error)
 }
 
 type BaseService struct {
@@ -151,6 +156,66 @@ func (svc *BaseService) GetMyReactions(
 		}
 	}
 
+	return buildTargetReactions(targetType, targetID, allowedEmojis, counts, selectedEmoji), nil
+}
+
+// GetMyReactionsByTargets is the batch form of GetMyReactions. It loads the counts and the current user's
+// selections for all targets with two queries, regardless of how many targets are given.
+func (svc *BaseService) GetMyReactionsByTargets(
+	ctx context.Context,
+	targetType TargetType,
+	targetIDs []string,
+) (map[string]*TargetReactions, error) {
+	if !targetType.IsValid() {
+		return nil, InvalidTargetTypeError{TargetType: targetType}
+	}
+
+	counts, err := svc.userReactionRepo.CountByTargets(ctx, targetType, targetIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get counts by targets: %w", err)
+	}
+
+	selectedEmojis := make(map[string]string)
+	currentUserID := authcontext.GetSubject(ctx)
+
+	if currentUserID != "" && currentUserID != authcontext.Anonymous {
+		userReactions, err := svc.userReactionRepo.FindByUserTargets(ctx, targetType, targetIDs, currentUserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user reactions: %w", err)
+		}
+
+		for _, userReaction := range userReactions {
+			selectedEmojis[userReaction.TargetID] = userReaction.Emoji
+		}
+	}
+
+	result := make(map[string]*TargetReactions, len(targetIDs))
+
+	for _, targetID := range targetIDs {
+		allowedEmojis, err := svc.AllowedEmojis(ctx, targetType, targetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get allowed emojis: %w", err)
+		}
+
+		result[targetID] = buildTargetReactions(
+			targetType,
+			targetID,
+			allowedEmojis,
+			counts[targetID],
+			selectedEmojis[targetID],
+		)
+	}
+
+	return result, nil
+}
+
+func buildTargetReactions(
+	targetType TargetType,
+	targetID string,
+	allowedEmojis []string,
+	counts map[string]int,
+	selectedEmoji string,
+) *TargetReactions {
 	options := make([]ReactionOption, 0, len(allowedEmojis))
 	availableEmojiSet := make(map[string]struct{}, len(allowedEmojis))
 
@@ -194,5 +259,5 @@ func (svc *BaseService) GetMyReactions(
 		TargetType: targetType,
 		TargetID:   targetID,
 		Options:    options,
-	}, nil
+	}
 }
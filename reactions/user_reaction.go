@@ -37,9 +37,20 @@ type UserReactionRepository interface {
 		targetID string,
 		userID string,
 	) (reaction *UserReaction, err error)
+	FindByUserTargets(
+		ctx context.Context,
+		targetType TargetType,
+		targetIDs []string,
+		userID string,
+	) (reactions []*UserReaction, err error)
 	Upsert(ctx context.Context, reaction *UserReaction) (err error)
 	DeleteByUserTarget(ctx context.Context, targetType TargetType, targetID string, userID string) (err error)
 	CountByTarget(ctx context.Context, targetType TargetType, targetID string) (counts map[string]int, err error)
+	CountByTargets(
+		ctx context.Context,
+		targetType TargetType,
+		targetIDs []string,
+	) (counts map[string]map[string]int, err error)
 }
 
 type UserReactionNotFoundError struct {
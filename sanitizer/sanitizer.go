@@ -0,0 +1,273 @@
+// Package sanitizer cleans untrusted HTML down to an allowlist of elements and attributes. It is meant for HTML
+// rendered from user-written Markdown, which may embed raw HTML.
+package sanitizer
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Policy controls which optional content survives sanitization. The zero value is the strictest policy.
+type Policy struct {
+	// AllowImages keeps <img> elements with http(s), relative or base64 raster data sources.
+	AllowImages bool
+}
+
+// elements maps every allowed element to its allowed attributes. Anything else is unwrapped, keeping its text.
+var elements = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Blockquote: nil,
+	atom.Br:         nil,
+	atom.Code:       nil,
+	atom.Del:        nil,
+	atom.Em:         nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Input:      {"type", "checked", "disabled"},
+	atom.Kbd:        nil,
+	atom.Li:         nil,
+	atom.Mark:       nil,
+	atom.Ol:         {"start"},
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.S:          nil,
+	atom.Strong:     nil,
+	atom.B:          nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"align", "style"},
+	atom.Th:         {"align", "style"},
+	atom.Thead:      nil,
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+var imageAttributes = []string{"src", "alt", "title", "width", "height"}
+
+// droppedWithContent lists elements whose content is never meaningful as text and must disappear entirely.
+var droppedWithContent = []atom.Atom{
+	atom.Script,
+	atom.Style,
+	atom.Iframe,
+	atom.Object,
+	atom.Embed,
+	atom.Noscript,
+	atom.Noembed,
+	atom.Noframes,
+	atom.Template,
+	atom.Textarea,
+	atom.Title,
+	atom.Svg,
+	atom.Math,
+	atom.Select,
+	atom.Xmp,
+	atom.Plaintext,
+}
+
+var (
+	linkSchemes  = []string{"http", "https", "mailto"}
+	imageSchemes = []string{"http", "https"}
+
+	dataImagePattern = regexp.MustCompile(`^data:image/(png|gif|jpeg|webp);base64,[a-zA-Z0-9+/]+=*$`)
+	textAlignPattern = regexp.MustCompile(`^text-align:\s*(left|right|center);?$`)
+	numberPattern    = regexp.MustCompile(`^[0-9]{1,5}$`)
+)
+
+// Sanitize returns input reduced to the allowed elements and attributes. The result is always well-formed.
+func (policy Policy) Sanitize(input string) string {
+	context := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+
+	nodes, err := html.ParseFragment(strings.NewReader(input), context)
+	if err != nil {
+		// The parser only fails on reader errors, which a strings.Reader never returns. Fail closed regardless.
+		return html.EscapeString(input)
+	}
+
+	var sb strings.Builder
+
+	for _, node := range nodes {
+		policy.render(&sb, node)
+	}
+
+	return sb.String()
+}
+
+func (policy Policy) render(sb *strings.Builder, node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		sb.WriteString(html.EscapeString(node.Data))
+	case html.ElementNode:
+		policy.renderElement(sb, node)
+	case html.DocumentNode:
+		policy.renderChildren(sb, node)
+	case html.ErrorNode, html.CommentNode, html.DoctypeNode, html.RawNode:
+		// dropped
+	}
+}
+
+func (policy Policy) renderChildren(sb *strings.Builder, node *html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		policy.render(sb, child)
+	}
+}
+
+func (policy Policy) renderElement(sb *strings.Builder, node *html.Node) {
+	if node.Namespace != "" || slices.Contains(droppedWithContent, node.DataAtom) {
+		return
+	}
+
+	allowedAttributes, ok := policy.allowedAttributes(node.DataAtom)
+	if !ok {
+		policy.renderChildren(sb, node)
+
+		return
+	}
+
+	if node.DataAtom == atom.Input && !isCheckbox(node) {
+		return
+	}
+
+	sb.WriteString("<")
+	sb.WriteString(node.DataAtom.String())
+
+	for _, attr := range node.Attr {
+		if attr.Namespace != "" || !slices.Contains(allowedAttributes, attr.Key) {
+			continue
+		}
+
+		value, ok := cleanAttribute(node.DataAtom, attr.Key, attr.Val)
+		if !ok {
+			continue
+		}
+
+		sb.WriteString(" ")
+		sb.WriteString(attr.Key)
+		sb.WriteString(`="`)
+		sb.WriteString(html.EscapeString(value))
+		sb.WriteString(`"`)
+	}
+
+	switch node.DataAtom {
+	case atom.A:
+		sb.WriteString(` rel="nofollow ugc noopener noreferrer"`)
+	case atom.Input:
+		// Task list checkboxes are display only.
+		if !hasAttribute(node, "disabled") {
+			sb.WriteString(` disabled=""`)
+		}
+	}
+
+	sb.WriteString(">")
+
+	if isVoid(node.DataAtom) {
+		return
+	}
+
+	policy.renderChildren(sb, node)
+
+	sb.WriteString("</")
+	sb.WriteString(node.DataAtom.String())
+	sb.WriteString(">")
+}
+
+func (policy Policy) allowedAttributes(element atom.Atom) ([]string, bool) {
+	if element == atom.Img {
+		return imageAttributes, policy.AllowImages
+	}
+
+	attributes, ok := elements[element]
+
+	return attributes, ok
+}
+
+func cleanAttribute(element atom.Atom, key, value string) (string, bool) {
+	switch key {
+	case "href":
+		return cleanURL(value, linkSchemes, false)
+	case "src":
+		return cleanURL(value, imageSchemes, element == atom.Img)
+	case "style":
+		value = strings.TrimSpace(value)
+
+		return value, textAlignPattern.MatchString(value)
+	case "align":
+		return value, value == "left" || value == "right" || value == "center"
+	case "width", "height", "start":
+		return value, numberPattern.MatchString(value)
+	case "type":
+		return value, value == "checkbox"
+	case "checked", "disabled":
+		return "", true
+	default:
+		return value, true
+	}
+}
+
+// cleanURL removes characters browsers ignore inside URLs before checking the scheme, so tricks like
+// "java\tscript:" are seen for what they are.
+func cleanURL(value string, schemes []string, allowDataImage bool) (string, bool) {
+	value = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+
+		return r
+	}, value)
+
+	if value == "" {
+		return "", false
+	}
+
+	if allowDataImage && dataImagePattern.MatchString(value) {
+		return value, true
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return "", false
+	}
+
+	if parsed.Scheme == "" {
+		// Relative URLs are fine as long as they can't be read as protocol-relative ones.
+		return value, !strings.HasPrefix(value, "//") && !strings.HasPrefix(value, `\`)
+	}
+
+	return value, slices.Contains(schemes, strings.ToLower(parsed.Scheme))
+}
+
+func isCheckbox(node *html.Node) bool {
+	for _, attr := range node.Attr {
+		if attr.Key == "type" {
+			return strings.EqualFold(attr.Val, "checkbox")
+		}
+	}
+
+	return false
+}
+
+func hasAttribute(node *html.Node, key string) bool {
+	return slices.ContainsFunc(node.Attr, func(attr html.Attribute) bool { return attr.Key == key })
+}
+
+func isVoid(element atom.Atom) bool {
+	switch element {
+	case atom.Br, atom.Hr, atom.Img, atom.Input:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,204 @@
+package sanitizer_test
+
+import (
+	"testing"
+
+	"github.com/nasermirzaei89/scribble/sanitizer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeXSS(t *testing.T) {
+	t.Parallel()
+
+	policy := sanitizer.Policy{AllowImages: true}
+
+	tt := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "script element",
+			input:    `<p>hi</p><script>alert(1)</script>`,
+			expected: `<p>hi</p>`,
+		},
+		{
+			name:     "uppercase script element",
+			input:    `<SCRIPT SRC=//evil.example/x.js></SCRIPT>ok`,
+			expected: `ok`,
+		},
+		{
+			name:     "event handler attribute",
+			input:    `<img src="x.png" onerror="alert(1)">`,
+			expected: `<img src="x.png">`,
+		},
+		{
+			name:     "unquoted event handler",
+			input:    `<img src=x onerror=alert(1)//`,
+			expected: ``,
+		},
+		{
+			name:     "javascript link",
+			input:    `<a href="javascript:alert(1)">x</a>`,
+			expected: `<a rel="nofollow ugc noopener noreferrer">x</a>`,
+		},
+		{
+			name:     "mixed case javascript link",
+			input:    `<a href="JaVaScRiPt:alert(1)">x</a>`,
+			expected: `<a rel="nofollow ugc noopener noreferrer">x</a>`,
+		},
+		{
+			name:     "entity encoded javascript link",
+			input:    `<a href="&#106;avascript&#58;alert(1)">x</a>`,
+			expected: `<a rel="nofollow ugc noopener noreferrer">x</a>`,
+		},
+		{
+			name:     "whitespace obfuscated javascript link",
+			input:    "<a href=\" java\tscr&#x0A;ipt:alert(1)\">x</a>",
+			expected: `<a rel="nofollow ugc noopener noreferrer">x</a>`,
+		},
+		{
+			name:     "data html link",
+			input:    `<a href="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">x</a>`,
+			expected: `<a rel="nofollow ugc noopener noreferrer">x</a>`,
+		},
+		{
+			name:     "vbscript image source",
+			input:    `<img src="vbscript:msgbox(1)">`,
+			expected: `<img>`,
+		},
+		{
+			name:     "svg image data",
+			input:    `<img src="data:image/svg+xml;base64,PHN2Zz48L3N2Zz4=">`,
+			expected: `<img>`,
+		},
+		{
+			name:     "protocol relative link",
+			input:    `<a href="//evil.example">x</a>`,
+			expected: `<a rel="nofollow ugc noopener noreferrer">x</a>`,
+		},
+		{
+			name:     "iframe",
+			input:    `<iframe src="https://evil.example"></iframe>after`,
+			expected: `after`,
+		},
+		{
+			name:     "object and embed",
+			input:    `<object data="x.swf"><embed src="x.swf"></object>`,
+			expected: ``,
+		},
+		{
+			name:     "style element and attribute",
+			input:    `<style>body{display:none}</style><p style="background:url(javascript:alert(1))">x</p>`,
+			expected: `<p>x</p>`,
+		},
+		{
+			name:     "svg onload",
+			input:    `<svg onload="alert(1)"><circle r="1"/></svg>`,
+			expected: ``,
+		},
+		{
+			name:     "math namespace confusion",
+			input:    `<math><mtext><table><mglyph><style><img src=x onerror=alert(1)>`,
+			expected: ``,
+		},
+		{
+			name:     "attribute breakout",
+			input:    `<a title="&quot;><script>alert(1)</script>">x</a>`,
+			expected: `<a title="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;" rel="nofollow ugc noopener noreferrer">x</a>`,
+		},
+		{
+			name:     "comment hiding markup",
+			input:    `<!--<img src=x onerror=alert(1)>-->ok`,
+			expected: `ok`,
+		},
+		{
+			name:     "unknown element is unwrapped",
+			input:    `<div onclick="alert(1)"><form action="/x"><button>go</button></form></div>`,
+			expected: `go`,
+		},
+		{
+			name:     "non checkbox input",
+			input:    `<input type="text" autofocus onfocus="alert(1)">`,
+			expected: ``,
+		},
+		{
+			name:     "text outside elements is escaped",
+			input:    `1 < 2 & 3 > 2`,
+			expected: `1 &lt; 2 &amp; 3 &gt; 2`,
+		},
+		{
+			name:     "unclosed elements are closed",
+			input:    `<p><strong>bold`,
+			expected: `<p><strong>bold</strong></p>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, policy.Sanitize(tc.input))
+		})
+	}
+}
+
+func TestSanitizeKeepsMarkdownOutput(t *testing.T) {
+	t.Parallel()
+
+	policy := sanitizer.Policy{AllowImages: true}
+
+	tt := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "formatting",
+			input:    `<h2>Title</h2><p><strong>a</strong> <em>b</em> <u>c</u> <del>d</del> <code>e</code></p>`,
+			expected: `<h2>Title</h2><p><strong>a</strong> <em>b</em> <u>c</u> <del>d</del> <code>e</code></p>`,
+		},
+		{
+			name:  "links",
+			input: `<a href="https://example.com/a?b=c&amp;d=e" title="t">x</a> <a href="/p/1">y</a>`,
+			expected: `<a href="https://example.com/a?b=c&amp;d=e" title="t" rel="nofollow ugc noopener noreferrer">x</a>` +
+				` <a href="/p/1" rel="nofollow ugc noopener noreferrer">y</a>`,
+		},
+		{
+			name:     "images",
+			input:    `<img src="https://example.com/a.png" alt="a" width="120"><img src="data:image/png;base64,iVBORw0KGgo=">`,
+			expected: `<img src="https://example.com/a.png" alt="a" width="120"><img src="data:image/png;base64,iVBORw0KGgo=">`,
+		},
+		{
+			name:     "lists and code blocks",
+			input:    "<ol start=\"3\"><li>one</li></ol><pre><code>if a &lt; b {}\n</code></pre>",
+			expected: "<ol start=\"3\"><li>one</li></ol><pre><code>if a &lt; b {}\n</code></pre>",
+		},
+		{
+			name:     "tables",
+			input:    `<table><thead><tr><th style="text-align:center">h</th></tr></thead><tbody><tr><td>c</td></tr></tbody></table>`,
+			expected: `<table><thead><tr><th style="text-align:center">h</th></tr></thead><tbody><tr><td>c</td></tr></tbody></table>`,
+		},
+		{
+			name:     "task list",
+			input:    `<ul><li><input checked="" disabled="" type="checkbox"> done</li></ul>`,
+			expected: `<ul><li><input checked="" disabled="" type="checkbox"> done</li></ul>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, policy.Sanitize(tc.input))
+		})
+	}
+}
+
+func TestSanitizeImagesDisallowed(t *testing.T) {
+	t.Parallel()
+
+	var policy sanitizer.Policy
+
+	assert.Equal(t, `<p>before  after</p>`, policy.Sanitize(`<p>before <img src="https://example.com/a.png"> after</p>`))
+}
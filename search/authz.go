@@ -0,0 +1,40 @@
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nasermirzaei89/scribble/authorization"
+)
+
+const (
+	ActionSearch = "search"
+)
+
+type AuthorizationMiddleware struct {
+	authzClient *authorization.Client
+	next        Service
+}
+
+var _ Service = (*AuthorizationMiddleware)(nil)
+
+func NewAuthorizationMiddleware(authzClient *authorization.Client, next Service) *AuthorizationMiddleware {
+	return &AuthorizationMiddleware{
+		authzClient: authzClient,
+		next:        next,
+	}
+}
+
+func (mw *AuthorizationMiddleware) Search(ctx context.Context, req SearchRequest) (*SearchResult, error) {
+	err := mw.authzClient.CheckAccess(ctx, ServiceName, "", ActionSearch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check authorization: %w", err)
+	}
+
+	result, err := mw.next.Search(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call next method: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package search_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	fileadapter "github.com/casbin/casbin/v3/persist/file-adapter"
+	"github.com/google/uuid"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/nasermirzaei89/scribble/authorization"
+	"github.com/nasermirzaei89/scribble/authorization/casbin"
+	"github.com/nasermirzaei89/scribble/search"
+	"github.com/stretchr/testify/require"
+)
+
+type stubService struct{}
+
+func (s *stubService) Search(ctx context.Context, req search.SearchRequest) (*search.SearchResult, error) {
+	return &search.SearchResult{Hits: []*search.Hit{}}, nil
+}
+
+func TestAuthorizationMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "policy.csv")
+	content := []byte(`g, system:anonymous, system:unauthenticated
+
+p, system:authenticated, github.com/nasermirzaei89/scribble/search, -, search
+`)
+
+	err := os.WriteFile(tmpFile, content, 0o600)
+	require.NoError(t, err)
+
+	adapter := fileadapter.NewAdapter(tmpFile)
+
+	provider, err := casbin.NewAuthorizationProvider(adapter)
+	require.NoError(t, err)
+
+	authzSvc, err := authorization.NewService(provider)
+	require.NoError(t, err)
+
+	client := authorization.NewClient(authzSvc)
+	svc := search.NewAuthorizationMiddleware(client, &stubService{})
+
+	userID := uuid.NewString()
+	err = client.AddToGroup(ctx, userID, authcontext.Authenticated)
+	require.NoError(t, err)
+
+	anonymousCtx := ctx
+	authenticatedCtx := authcontext.WithSubject(ctx, userID)
+
+	t.Run("anonymous", func(t *testing.T) {
+		_, err := svc.Search(anonymousCtx, search.SearchRequest{Query: "hello"})
+		require.Error(t, err)
+
+		accessDeniedErr := &authorization.AccessDeniedError{}
+		require.ErrorAs(t, err, &accessDeniedErr)
+	})
+
+	t.Run("authenticated", func(t *testing.T) {
+		_, err := svc.Search(authenticatedCtx, search.SearchRequest{Query: "hello"})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,40 @@
+package search
+
+import (
+	"context"
+	"time"
+)
+
+type HitType string
+
+const (
+	HitTypePost    HitType = "post"
+	HitTypeComment HitType = "comment"
+)
+
+// Snippet markers wrap the matched terms in Hit.Snippet. They are control characters so they cannot clash with
+// user content once rendered, and the web layer replaces them with highlight markup.
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+type Hit struct {
+	Type      HitType
+	ID        string
+	PostID    string
+	AuthorID  string
+	Snippet   string
+	CreatedAt time.Time
+}
+
+type Repository interface {
+	Search(ctx context.Context, params *SearchParams) (hits []*Hit, err error)
+}
+
+type SearchParams struct {
+	// Query is an FTS5 match expression. BaseService builds it from user input; repositories use it as is.
+	Query  string
+	Offset int
+	Limit  int
+}
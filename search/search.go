@@ -0,0 +1,90 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nasermirzaei89/scribble/authorization"
+)
+
+const ServiceName = "github.com/nasermirzaei89/scribble/search"
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+type Service interface {
+	Search(ctx context.Context, req SearchRequest) (*SearchResult, error)
+}
+
+type BaseService struct {
+	repo Repository
+}
+
+var _ Service = (*BaseService)(nil)
+
+func NewService(repo Repository, authzClient *authorization.Client) Service { //nolint:ireturn
+	return NewAuthorizationMiddleware(authzClient, NewBaseService(repo))
+}
+
+func NewBaseService(repo Repository) *BaseService {
+	return &BaseService{repo: repo}
+}
+
+type SearchRequest struct {
+	Query  string
+	Offset int
+	Limit  int
+}
+
+type SearchResult struct {
+	Hits    []*Hit
+	HasMore bool
+}
+
+func (svc *BaseService) Search(ctx context.Context, req SearchRequest) (*SearchResult, error) {
+	matchQuery := BuildMatchQuery(req.Query)
+	if matchQuery == "" {
+		return &SearchResult{Hits: []*Hit{}, HasMore: false}, nil
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	limit = min(limit, MaxSearchLimit)
+
+	hits, err := svc.repo.Search(ctx, &SearchParams{
+		Query:  matchQuery,
+		Offset: max(req.Offset, 0),
+		Limit:  limit + 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+
+	result := &SearchResult{Hits: hits, HasMore: false}
+
+	if len(hits) > limit {
+		result.Hits = hits[:limit]
+		result.HasMore = true
+	}
+
+	return result, nil
+}
+
+// BuildMatchQuery turns free text into an FTS5 expression that matches documents containing every word. Each word is
+// quoted, so operators and punctuation typed by users are searched for literally instead of breaking the query.
+func BuildMatchQuery(query string) string {
+	terms := strings.Fields(query)
+	quoted := make([]string, 0, len(terms))
+
+	for _, term := range terms {
+		quoted = append(quoted, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+
+	return strings.Join(quoted, " ")
+}
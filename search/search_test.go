@@ -0,0 +1,34 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/nasermirzaei89/scribble/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMatchQuery(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "empty", input: "", expected: ""},
+		{name: "whitespace only", input: "  \t ", expected: ""},
+		{name: "single word", input: "hello", expected: `"hello"`},
+		{name: "several words", input: " hello   world ", expected: `"hello" "world"`},
+		{name: "operators are literal", input: "cats OR dogs", expected: `"cats" "OR" "dogs"`},
+		{name: "quotes are escaped", input: `say "hi"`, expected: `"say" """hi"""`},
+		{name: "syntax characters are quoted", input: "col:val (x* ^y", expected: `"col:val" "(x*" "^y"`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, search.BuildMatchQuery(tc.input))
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Package totp implements time-based one-time passwords as described in RFC 6238, using HMAC-SHA1 as authenticator
+// apps expect.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // RFC 6238 and authenticator apps use HMAC-SHA1.
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nasermirzaei89/scribble/random"
+)
+
+const (
+	DefaultDigits = 6
+	DefaultPeriod = 30 * time.Second
+	// SecretSize is the length of generated secrets in bytes, the size RFC 4226 recommends.
+	SecretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Key is a shared TOTP secret with the parameters codes are generated with.
+type Key struct {
+	Secret []byte
+	Digits int
+	Period time.Duration
+}
+
+// NewKey returns a key with a random secret and the default parameters.
+func NewKey() Key {
+	return Key{
+		Secret: random.Bytes(SecretSize),
+		Digits: DefaultDigits,
+		Period: DefaultPeriod,
+	}
+}
+
+// ParseKey returns a key with the default parameters for a base32 encoded secret, as produced by EncodedSecret.
+func ParseKey(encodedSecret string) (Key, error) {
+	secret, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(encodedSecret, "=")))
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to decode secret: %w", err)
+	}
+
+	return Key{Secret: secret, Digits: DefaultDigits, Period: DefaultPeriod}, nil
+}
+
+// EncodedSecret returns the secret in unpadded base32, the form users type into authenticator apps.
+func (key Key) EncodedSecret() string {
+	return encoding.EncodeToString(key.Secret)
+}
+
+// Counter returns the time step t falls in.
+func (key Key) Counter(t time.Time) uint64 {
+	return uint64(t.Unix() / int64(key.Period/time.Second)) //nolint:gosec // Times before 1970 are not supported.
+}
+
+// Code returns the code for the time step t falls in.
+func (key Key) Code(t time.Time) string {
+	return key.hotp(key.Counter(t))
+}
+
+// Verify checks code against the time step of t and up to skew steps on either side, to allow for clock drift. It
+// returns the matched step, which callers should remember to refuse the same code twice.
+func (key Key) Verify(code string, t time.Time, skew int) (uint64, bool) {
+	if len(code) != key.Digits {
+		return 0, false
+	}
+
+	current := key.Counter(t)
+
+	for offset := -skew; offset <= skew; offset++ {
+		counter := current + uint64(offset) //nolint:gosec // Wraps only for offsets before 1970.
+
+		if subtle.ConstantTimeCompare([]byte(key.hotp(counter)), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+
+	return 0, false
+}
+
+// URI returns the otpauth:// URI authenticator apps import, usually through a QR code.
+func (key Key) URI(issuer, accountName string) string {
+	query := url.Values{}
+	query.Set("secret", key.EncodedSecret())
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(key.Digits))
+	query.Set("period", strconv.Itoa(int(key.Period/time.Second)))
+
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}
+
+	return uri.String()
+}
+
+// hotp computes the RFC 4226 code for a counter value.
+func (key Key) hotp(counter uint64) string {
+	var message [8]byte
+
+	binary.BigEndian.PutUint64(message[:], counter)
+
+	mac := hmac.New(sha1.New, key.Secret)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for range key.Digits {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", key.Digits, value%modulo)
+}
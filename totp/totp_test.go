@@ -0,0 +1,104 @@
+package totp_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nasermirzaei89/scribble/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcKey is the SHA1 key from the test vectors in RFC 6238, appendix B.
+func rfcKey() totp.Key {
+	return totp.Key{
+		Secret: []byte("12345678901234567890"),
+		Digits: 8,
+		Period: 30 * time.Second,
+	}
+}
+
+func TestKeyCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "94287082"},
+		{unix: 1111111109, expected: "07081804"},
+		{unix: 1111111111, expected: "14050471"},
+		{unix: 1234567890, expected: "89005924"},
+		{unix: 2000000000, expected: "69279037"},
+		{unix: 20000000000, expected: "65353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, rfcKey().Code(time.Unix(tt.unix, 0)))
+		})
+	}
+}
+
+func TestKeyVerify(t *testing.T) {
+	t.Parallel()
+
+	key := rfcKey()
+	now := time.Unix(1111111111, 0)
+
+	counter, ok := key.Verify("14050471", now, 0)
+	require.True(t, ok)
+	assert.Equal(t, key.Counter(now), counter)
+
+	// The previous step's code is accepted only with skew.
+	previous := key.Code(now.Add(-key.Period))
+
+	_, ok = key.Verify(previous, now, 0)
+	assert.False(t, ok)
+
+	counter, ok = key.Verify(previous, now, 1)
+	require.True(t, ok)
+	assert.Equal(t, key.Counter(now)-1, counter)
+
+	_, ok = key.Verify(key.Code(now.Add(-2*key.Period)), now, 1)
+	assert.False(t, ok)
+
+	_, ok = key.Verify("1405047", now, 1)
+	assert.False(t, ok)
+
+	_, ok = key.Verify("00000000", now, 1)
+	assert.False(t, ok)
+}
+
+func TestParseKey(t *testing.T) {
+	t.Parallel()
+
+	key := totp.NewKey()
+	assert.Len(t, key.Secret, totp.SecretSize)
+
+	parsed, err := totp.ParseKey(key.EncodedSecret())
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = totp.ParseKey("not base32!")
+	require.Error(t, err)
+}
+
+func TestKeyURI(t *testing.T) {
+	t.Parallel()
+
+	key := totp.Key{Secret: []byte("12345678901234567890"), Digits: 6, Period: 30 * time.Second}
+
+	uri, err := url.Parse(key.URI("Scribble", "alice"))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Scribble:alice", uri.Path)
+	assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri.Query().Get("secret"))
+	assert.Equal(t, "Scribble", uri.Query().Get("issuer"))
+	assert.Equal(t, "6", uri.Query().Get("digits"))
+	assert.Equal(t, "30", uri.Query().Get("period"))
+}
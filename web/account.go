@@ -0,0 +1,145 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/csrf"
+	"github.com/nasermirzaei89/scribble/authentication"
+)
+
+func (h *Handler) HandleAccountSettingsPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.renderAccountSettingsPage(w, r, http.StatusOK, nil)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) renderAccountSettingsPage(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	extraData map[string]any,
+) {
+	hasPassword, err := h.authSvc.HasPassword(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to check for password", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	data := map[string]any{
+		"HasPassword":     hasPassword,
+		"KeepsContent":    h.authSvc.AccountDeletionContent() == authentication.ContentRetentionTombstone,
+		csrf.TemplateTag:  csrf.TemplateField(r),
+		"SiteTitle":       "Account",
+		"SettingsSection": "account",
+	}
+
+	maps.Copy(data, extraData)
+
+	w.WriteHeader(status)
+	h.renderTemplate(w, r, "settings-account-page.gohtml", data)
+}
+
+func (h *Handler) HandleExportMyData() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := h.authSvc.GetCurrentUser(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get current user", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		// The archive is built in memory first, so a failure halfway still gets an error page, not a broken file.
+		var buf bytes.Buffer
+
+		err = h.authSvc.ExportMyData(r.Context(), &buf)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to export data", "userId", user.ID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		filename := fmt.Sprintf("scribble-%s-%s.zip", user.ID, time.Now().UTC().Format("2006-01-02"))
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("Cache-Control", "no-store")
+
+		_, err = buf.WriteTo(w)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to write data export", "error", err)
+		}
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) HandleDeleteAccount() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		user, err := h.authSvc.GetCurrentUser(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get current user", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		if r.FormValue("confirm") != user.Username {
+			h.renderAccountSettingsPage(w, r, http.StatusUnprocessableEntity, map[string]any{
+				"Error": "Type your username to confirm.",
+			})
+
+			return
+		}
+
+		err = h.authSvc.DeleteAccount(r.Context(), r.FormValue("password"))
+		if err != nil {
+			if errors.Is(err, authentication.ErrInvalidCredentials) {
+				h.renderAccountSettingsPage(w, r, http.StatusUnprocessableEntity, map[string]any{
+					"Error": "That password is incorrect.",
+				})
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to delete account", "userId", user.ID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		// The account is gone either way, so avatars left behind are only logged.
+		err = h.profilesSvc.DeleteProfile(r.Context(), user.ID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to delete profile", "userId", user.ID, "error", err)
+		}
+
+		err = h.deleteSessionValue(w, r, sessionIDKey)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "error on deleting session value", "key", sessionIDKey, "error", err)
+		}
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+
+	return h.SessionOnly(hf)
+}
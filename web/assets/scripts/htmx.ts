@@ -2,7 +2,7 @@ import htmx from "htmx.org";
 
 window.htmx = htmx;
 
-function showErrorMessage(text: string) {
+export function showErrorMessage(text: string) {
     const div = document.createElement("div");
     div.className = "as-message type-error";
     div.setAttribute("role", "alert");
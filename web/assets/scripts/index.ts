@@ -5,4 +5,5 @@ import "@fontsource/vazirmatn";
 import "@fontsource/source-code-pro";
 
 import "./htmx";
+import "./passkeys";
 import "./wysiwyg-editor";
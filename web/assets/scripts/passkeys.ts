@@ -0,0 +1,96 @@
+import { showErrorMessage } from "./htmx";
+
+// The server sends WebAuthn options in their JSON form, and the browser converts them and its answers itself.
+const supported = () =>
+    typeof PublicKeyCredential !== "undefined" &&
+    typeof PublicKeyCredential.parseCreationOptionsFromJSON === "function" &&
+    typeof PublicKeyCredential.parseRequestOptionsFromJSON === "function";
+
+const csrfToken = (form: HTMLFormElement) =>
+    (form.querySelector('input[name="gorilla.csrf.Token"]') as HTMLInputElement | null)?.value ?? "";
+
+const post = async (url: string, token: string, body?: unknown) => {
+    const response = await fetch(url, {
+        method: "POST",
+        headers: { "Content-Type": "application/json", "X-CSRF-Token": token },
+        body: body === undefined ? undefined : JSON.stringify(body),
+    });
+
+    if (!response.ok) {
+        throw new Error((await response.text()).trim() || `Error (${response.status})`);
+    }
+
+    return response;
+};
+
+const run = async (ceremony: () => Promise<void>) => {
+    if (!supported()) {
+        showErrorMessage("This browser doesn't support passkeys.");
+
+        return;
+    }
+
+    try {
+        await ceremony();
+    } catch (error) {
+        // The user closed the browser's passkey dialog.
+        if (error instanceof DOMException && error.name === "NotAllowedError") {
+            return;
+        }
+
+        showErrorMessage(error instanceof Error ? error.message : String(error));
+    }
+};
+
+const login = async (form: HTMLFormElement) => {
+    const token = csrfToken(form);
+    const options = await (await post("/login/passkey/options", token)).json();
+
+    const credential = (await navigator.credentials.get({
+        publicKey: PublicKeyCredential.parseRequestOptionsFromJSON(options.publicKey),
+    })) as PublicKeyCredential | null;
+    if (!credential) {
+        return;
+    }
+
+    const response = await post("/login/passkey", token, credential.toJSON());
+    window.location.assign(response.url);
+};
+
+const register = async (form: HTMLFormElement) => {
+    const token = csrfToken(form);
+    const options = await (await post("/settings/passkeys/options", token)).json();
+
+    const credential = (await navigator.credentials.create({
+        publicKey: PublicKeyCredential.parseCreationOptionsFromJSON(options.publicKey),
+    })) as PublicKeyCredential | null;
+    if (!credential) {
+        return;
+    }
+
+    const name = (form.elements.namedItem("name") as HTMLInputElement | null)?.value ?? "";
+    const response = await post("/settings/passkeys", token, { name, credential: credential.toJSON() });
+    window.location.assign(response.url);
+};
+
+// Listeners sit on the body, so they keep working after hx-boost swaps the page.
+document.body.addEventListener("click", (event) => {
+    const button = (event.target as Element).closest("[data-passkey-login]");
+    const form = button?.closest("form");
+    if (!form) {
+        return;
+    }
+
+    event.preventDefault();
+    run(() => login(form));
+});
+
+document.body.addEventListener("submit", (event) => {
+    const form = event.target as HTMLFormElement;
+    if (!form.matches("[data-passkey-register]")) {
+        return;
+    }
+
+    event.preventDefault();
+    run(() => register(form));
+});
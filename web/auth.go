@@ -4,14 +4,93 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"net"
 	"net/http"
+	"strings"
 
+	"github.com/gorilla/csrf"
 	"github.com/nasermirzaei89/scribble/authentication"
 	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
 )
 
+// apiTokenMiddleware authenticates requests carrying a personal access token in an "Authorization: Bearer" header.
+// Such requests don't come from a browser holding our cookies, so they are exempt from CSRF checks. It must wrap the
+// CSRF middleware for that to take effect. Tokens are only taken on content routes, see acceptsAPIToken.
+func (h *Handler) apiTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		if !acceptsAPIToken(r.URL.Path) {
+			http.Error(w, "Personal access tokens can't be used here", http.StatusForbidden)
+
+			return
+		}
+
+		token, err := h.authSvc.AuthenticateAPIToken(r.Context(), secret)
+		if err != nil {
+			_, expired := errors.AsType[*authentication.APITokenExpiredError](err)
+
+			if expired || errors.Is(err, authentication.ErrInvalidAPIToken) {
+				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "error on authenticating api token", "error", err)
+			http.Error(w, "error on authenticating api token", http.StatusInternalServerError)
+
+			return
+		}
+
+		if !isSafeMethod(r.Method) && !token.HasScope(authentication.ScopeWrite) {
+			http.Error(w, "Token is read-only", http.StatusForbidden)
+
+			return
+		}
+
+		r = csrf.UnsafeSkipCheck(r)
+		r = r.WithContext(authcontext.WithAPITokenID(authcontext.WithSubject(r.Context(), token.UserID), token.ID))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiTokenPathPrefixes are the routes that read and write posts, comments and reactions. Everything else, the
+// settings above all, is for signed-in users only, so a token can't be used to take over the account.
+var apiTokenPathPrefixes = []string{"/create-post", "/p/", "/react/", "/search", "/u/", "/avatars/"}
+
+func acceptsAPIToken(path string) bool {
+	if path == "/" {
+		return true
+	}
+
+	for _, prefix := range apiTokenPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
 func (h *Handler) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Already authenticated by an api token.
+		if isAuthenticatedRequest(r) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
 		sessionID, err := h.getSessionValue(r, sessionIDKey)
 		if err != nil {
 			if _, ok := errors.AsType[*SessionValueNotFoundError](err); !ok {
@@ -72,6 +151,11 @@ func (h *Handler) authMiddleware(next http.Handler) http.Handler {
 
 			r = r.WithContext(authcontext.WithSessionID(r.Context(), session.ID))
 
+			err = h.authSvc.TouchSession(r.Context(), session, clientInfo(r))
+			if err != nil {
+				slog.ErrorContext(r.Context(), "error on touching session", "sessionId", session.ID, "error", err)
+			}
+
 			user, err := h.authSvc.GetUser(r.Context(), session.UserID)
 			if err != nil {
 				if _, ok := errors.AsType[*authentication.UserNotFoundError](err); ok {
@@ -147,6 +231,20 @@ func (h *Handler) AuthenticatedOnly(next http.Handler) http.Handler {
 	})
 }
 
+// SessionOnly is AuthenticatedOnly for the pages that manage the account, which requests authenticated by a personal
+// access token may not reach.
+func (h *Handler) SessionOnly(next http.Handler) http.Handler {
+	return h.AuthenticatedOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := authcontext.APITokenIDFromContext(r.Context()); ok {
+			http.Error(w, "Personal access tokens can't be used here", http.StatusForbidden)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}))
+}
+
 func (h *Handler) GuestOnly(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if isAuthenticatedRequest(r) {
@@ -158,3 +256,16 @@ func (h *Handler) GuestOnly(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// clientInfo describes the client behind r. The address is the direct peer; forwarding headers are not trusted.
+func clientInfo(r *http.Request) authentication.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return authentication.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IPAddress: ip,
+	}
+}
@@ -10,6 +10,11 @@ import (
 	"log/slog"
 	"strings"
 	"time"
+
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/mentions"
+	"github.com/nasermirzaei89/scribble/search"
+	"github.com/yuin/goldmark/parser"
 )
 
 func (h *Handler) funcs() template.FuncMap {
@@ -17,21 +22,138 @@ func (h *Handler) funcs() template.FuncMap {
 		"html": func(s string) template.HTML {
 			return template.HTML(s) //nolint:gosec
 		},
-		"markdown": func(s string) template.HTML {
+		// markdown links the mentions found in mentionLinks, as loaded by loadMentionLinks.
+		"markdown": func(s string, mentionLinks map[string]string) template.HTML {
 			var buf bytes.Buffer
 
-			err := h.markdown.Convert([]byte(s), &buf)
+			pc := mentions.NewContext(func(username string) (string, bool) {
+				href, ok := mentionLinks[authentication.CanonicalUsername(username)]
+
+				return href, ok
+			})
+
+			err := h.markdown.Convert([]byte(s), &buf, parser.WithContext(pc))
 			if err != nil {
 				slog.Error("failed to convert markdown", "error", err)
 				return template.HTML("<p><em>Failed to render markdown content.</em></p>")
 			}
 
-			return template.HTML(buf.String()) //nolint:gosec
+			return template.HTML(h.sanitizer.Sanitize(buf.String())) //nolint:gosec
 		},
 		"formatTime": func(t time.Time, layout string) string {
 			return t.Format(layout)
 		},
-		"hashed": h.getAssetHashedURL,
+		"hasPrefix": strings.HasPrefix,
+		"hashed":    h.getAssetHashedURL,
+		"avatar":    h.avatarURL,
+		"highlight": highlight,
+		"userAgent": describeUserAgent,
+	}
+}
+
+// highlight escapes a search snippet and turns its match markers into <mark> elements. Stray or unbalanced markers
+// are dropped so they can never produce broken markup.
+func highlight(snippet string) template.HTML {
+	var sb strings.Builder
+
+	open := false
+
+	for snippet != "" {
+		i := strings.IndexAny(snippet, search.HighlightStart+search.HighlightEnd)
+		if i < 0 {
+			sb.WriteString(template.HTMLEscapeString(snippet))
+
+			break
+		}
+
+		sb.WriteString(template.HTMLEscapeString(snippet[:i]))
+
+		switch snippet[i : i+1] {
+		case search.HighlightStart:
+			if !open {
+				sb.WriteString("<mark>")
+
+				open = true
+			}
+		case search.HighlightEnd:
+			if open {
+				sb.WriteString("</mark>")
+
+				open = false
+			}
+		}
+
+		snippet = snippet[i+1:]
+	}
+
+	if open {
+		sb.WriteString("</mark>")
+	}
+
+	return template.HTML(sb.String()) //nolint:gosec
+}
+
+// userAgentBrowsers and userAgentPlatforms map user agent substrings to readable names. Order matters: many browsers
+// mention others in their user agent, so the more specific markers come first.
+var (
+	userAgentBrowsers = []struct{ marker, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	userAgentPlatforms = []struct{ marker, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// describeUserAgent turns a user agent string into something like "Firefox on Linux".
+func describeUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser, platform := "", ""
+
+	for _, candidate := range userAgentBrowsers {
+		if strings.Contains(userAgent, candidate.marker) {
+			browser = candidate.name
+
+			break
+		}
+	}
+
+	for _, candidate := range userAgentPlatforms {
+		if strings.Contains(userAgent, candidate.marker) {
+			platform = candidate.name
+
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return "Unknown browser on " + platform
+	default:
+		const maxLength = 40
+
+		if len(userAgent) > maxLength {
+			return userAgent[:maxLength] + "…"
+		}
+
+		return userAgent
 	}
 }
 
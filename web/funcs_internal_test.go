@@ -0,0 +1,150 @@
+package web
+
+import (
+	"html/template"
+	"testing"
+
+	"github.com/nasermirzaei89/scribble/sanitizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHighlight(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		input    string
+		expected template.HTML
+	}{
+		{
+			name:     "plain text",
+			input:    "hello world",
+			expected: "hello world",
+		},
+		{
+			name:     "marked term",
+			input:    "hello \x02world\x03!",
+			expected: "hello <mark>world</mark>!",
+		},
+		{
+			name:     "html is escaped",
+			input:    "<script>\x02alert\x03</script>",
+			expected: "&lt;script&gt;<mark>alert</mark>&lt;/script&gt;",
+		},
+		{
+			name:     "unclosed marker is closed",
+			input:    "\x02open",
+			expected: "<mark>open</mark>",
+		},
+		{
+			name:     "stray end marker is dropped",
+			input:    "a\x03b",
+			expected: "ab",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, highlight(tc.input))
+		})
+	}
+}
+
+func TestMarkdownIsSanitized(t *testing.T) {
+	t.Parallel()
+
+	h, err := NewHandler(
+		nil, nil, nil, nil, nil, nil, nil, nil, "test", []byte("test"), nil, sanitizer.Policy{AllowImages: false},
+	)
+	require.NoError(t, err)
+
+	render, ok := h.funcs()["markdown"].(func(string, map[string]string) template.HTML)
+	require.True(t, ok)
+
+	input := "**bold** <u>under</u>\n\n<script>alert(1)</script>\n\n[x](javascript:alert(1)) ![img](https://example.com/a.png)"
+
+	assert.Equal(
+		t,
+		template.HTML("<p><strong>bold</strong> <u>under</u></p>\n\n"+
+			`<p><a rel="nofollow ugc noopener noreferrer">x</a> </p>`+"\n"),
+		render(input, nil),
+	)
+}
+
+func TestMarkdownLinksMentions(t *testing.T) {
+	t.Parallel()
+
+	h, err := NewHandler(
+		nil, nil, nil, nil, nil, nil, nil, nil, "test", []byte("test"), nil, sanitizer.Policy{AllowImages: false},
+	)
+	require.NoError(t, err)
+
+	render, ok := h.funcs()["markdown"].(func(string, map[string]string) template.HTML)
+	require.True(t, ok)
+
+	assert.Equal(
+		t,
+		template.HTML(`<p><a href="/u/Bob" rel="nofollow ugc noopener noreferrer">@BOB</a> and @nobody</p>`+"\n"),
+		render("@BOB and @nobody", map[string]string{"bob": "/u/Bob"}),
+	)
+}
+
+func TestDescribeUserAgent(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name      string
+		userAgent string
+		expected  string
+	}{
+		{
+			name:      "empty",
+			userAgent: "",
+			expected:  "Unknown device",
+		},
+		{
+			name:      "firefox on linux",
+			userAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0",
+			expected:  "Firefox on Linux",
+		},
+		{
+			name: "chrome on windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) " +
+				"Chrome/126.0.0.0 Safari/537.36",
+			expected: "Chrome on Windows",
+		},
+		{
+			name: "edge mentions chrome and safari",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) " +
+				"Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0",
+			expected: "Edge on Windows",
+		},
+		{
+			name: "safari on iphone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 " +
+				"(KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+			expected: "Safari on iOS",
+		},
+		{
+			name:      "command line client",
+			userAgent: "curl/8.8.0",
+			expected:  "curl",
+		},
+		{
+			name:      "unknown client is truncated",
+			userAgent: "some-very-long-custom-client-name/1.2.3 (with extra details)",
+			expected:  "some-very-long-custom-client-name/1.2.3 …",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, describeUserAgent(tc.userAgent))
+		})
+	}
+}
@@ -12,15 +12,23 @@ import (
 	"net/http"
 	"net/url"
 	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/gorilla/csrf"
 	"github.com/gorilla/sessions"
 	"github.com/nasermirzaei89/scribble/authentication"
 	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/nasermirzaei89/scribble/authorization"
 	"github.com/nasermirzaei89/scribble/contents"
+	"github.com/nasermirzaei89/scribble/diff"
 	"github.com/nasermirzaei89/scribble/discuss"
+	"github.com/nasermirzaei89/scribble/mentions"
+	"github.com/nasermirzaei89/scribble/profiles"
 	"github.com/nasermirzaei89/scribble/reactions"
+	"github.com/nasermirzaei89/scribble/sanitizer"
+	"github.com/nasermirzaei89/scribble/search"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/renderer/html"
@@ -37,7 +45,12 @@ var (
 const (
 	defaultSiteTitle = "Scribble"
 
+	// commentReplyDepth is how many levels of replies are shown below a comment before they are left for
+	// "show more replies" to load.
+	commentReplyDepth = 3
+
 	htmxRequestHeader    = "HX-Request"
+	htmxBoostedHeader    = "HX-Boosted"
 	htmxRequestValueTrue = "true"
 )
 
@@ -50,10 +63,14 @@ type Handler struct {
 	contentsSvc  contents.Service
 	discussSvc   discuss.Service
 	reactionsSvc reactions.Service
+	searchSvc    search.Service
+	profilesSvc  profiles.Service
+	authzClient  *authorization.Client
 	cookieStore  *sessions.CookieStore
 	sessionName  string
 	assetHashes  map[string]string
 	markdown     goldmark.Markdown
+	sanitizer    sanitizer.Policy
 }
 
 var _ http.Handler = (*Handler)(nil)
@@ -63,10 +80,14 @@ func NewHandler(
 	contentsSvc contents.Service,
 	discussSvc discuss.Service,
 	reactionsSvc reactions.Service,
+	searchSvc search.Service,
+	profilesSvc profiles.Service,
+	authzClient *authorization.Client,
 	cookieStore *sessions.CookieStore,
 	sessionName string,
 	csrfAuthKeys []byte,
 	csrfTrustedOrigins []string,
+	markdownPolicy sanitizer.Policy,
 ) (*Handler, error) {
 	h := &Handler{
 		mux:          nil,
@@ -76,19 +97,24 @@ func NewHandler(
 		contentsSvc:  contentsSvc,
 		discussSvc:   discussSvc,
 		reactionsSvc: reactionsSvc,
+		searchSvc:    searchSvc,
+		profilesSvc:  profilesSvc,
+		authzClient:  authzClient,
 		cookieStore:  cookieStore,
 		sessionName:  sessionName,
 		assetHashes:  make(map[string]string),
 		markdown:     nil,
+		sanitizer:    markdownPolicy,
 	}
 
 	{
 		h.markdown = goldmark.New(
 			goldmark.WithExtensions(
 				extension.GFM, // tables, strikethrough, task lists
+				&mentions.Extension{},
 			),
 			goldmark.WithRendererOptions(
-				html.WithUnsafe(), // allow raw HTML (REMOVE if you want stricter)
+				html.WithUnsafe(), // raw HTML is kept here and cleaned by h.sanitizer after rendering
 			),
 		)
 	}
@@ -130,6 +156,8 @@ func NewHandler(
 			h.handler = csrfMiddleware(h.handler)
 		}
 
+		h.handler = h.apiTokenMiddleware(h.handler)
+
 		h.handler = recoverMiddleware(h.handler)
 	}
 
@@ -147,15 +175,69 @@ func (h *Handler) registerRoutes() {
 	h.mux.Handle("POST /register", h.HandleRegister())
 	h.mux.Handle("GET /login", h.HandleLoginPage())
 	h.mux.Handle("POST /login", h.HandleLogin())
+	h.mux.Handle("GET /login/two-factor", h.HandleLoginTwoFactorPage())
+	h.mux.Handle("POST /login/two-factor", h.HandleLoginTwoFactor())
+	h.mux.Handle("POST /login/passkey/options", h.HandleBeginPasskeyLogin())
+	h.mux.Handle("POST /login/passkey", h.HandleFinishPasskeyLogin())
+	h.mux.Handle("GET /login/oidc/{provider}", h.HandleBeginOIDCLogin())
+	h.mux.Handle("GET /login/oidc/{provider}/callback", h.HandleOIDCCallback())
+	h.mux.Handle("GET /forgot-password", h.HandleForgotPasswordPage())
+	h.mux.Handle("POST /forgot-password", h.HandleForgotPassword())
+	h.mux.Handle("GET /reset-password", h.HandleResetPasswordPage())
+	h.mux.Handle("POST /reset-password", h.HandleResetPassword())
+	h.mux.Handle("GET /verify-email", h.HandleVerifyEmail())
 	h.mux.Handle("GET /logout", h.HandleLogoutPage())
 	h.mux.Handle("POST /logout", h.HandleLogout())
 
 	h.mux.Handle("GET /create-post", h.HandleCreatePostPage())
 	h.mux.Handle("POST /create-post", h.HandleCreatePost())
 	h.mux.Handle("GET /p/{postId}", h.HandleViewPostPage())
+	h.mux.Handle("GET /p/{postId}/edit", h.HandleEditPostPage())
+	h.mux.Handle("POST /p/{postId}/edit", h.HandleEditPost())
+	h.mux.Handle("POST /p/{postId}/delete", h.HandleDeletePost())
+	h.mux.Handle("POST /p/{postId}/comment-settings", h.HandleUpdateCommentSettings())
+	h.mux.Handle("GET /p/{postId}/history", h.HandlePostHistoryPage())
 	h.mux.Handle("POST /p/{postId}/comment", h.HandlePostComment())
+	h.mux.Handle("GET /p/{postId}/c/{commentId}", h.HandleCommentThreadPage())
+	h.mux.Handle("GET /p/{postId}/c/{commentId}/replies", h.HandleCommentReplies())
 	h.mux.Handle("GET /p/{postId}/comments/{commentId}/reply", h.HandleReplyForm())
+	h.mux.Handle("GET /p/{postId}/comments/{commentId}/edit", h.HandleEditCommentPage())
+	h.mux.Handle("POST /p/{postId}/comments/{commentId}/edit", h.HandleEditComment())
+	h.mux.Handle("POST /p/{postId}/comments/{commentId}/delete", h.HandleDeleteComment())
 	h.mux.Handle("POST /react/{targetType}/{targetId}", h.HandleToggleReaction())
+	h.mux.Handle("GET /search", h.HandleSearchPage())
+	h.mux.Handle("GET /u/{username}", h.HandleUserPage())
+	h.mux.Handle("GET /avatars/{userId}/{size}", h.HandleAvatar())
+
+	h.mux.Handle("GET /settings/profile", h.HandleProfileSettingsPage())
+	h.mux.Handle("POST /settings/profile", h.HandleUpdateProfile())
+	h.mux.Handle("POST /settings/profile/avatar", h.HandleSetAvatar())
+	h.mux.Handle("POST /settings/profile/avatar/delete", h.HandleRemoveAvatar())
+	h.mux.Handle("GET /settings/email", h.HandleEmailSettingsPage())
+	h.mux.Handle("POST /settings/email", h.HandleSetEmail())
+	h.mux.Handle("POST /settings/email/resend", h.HandleResendEmailVerification())
+	h.mux.Handle("GET /settings/tokens", h.HandleAPITokensPage())
+	h.mux.Handle("POST /settings/tokens", h.HandleCreateAPIToken())
+	h.mux.Handle("POST /settings/tokens/{tokenId}/revoke", h.HandleRevokeAPIToken())
+	h.mux.Handle("GET /settings/sessions", h.HandleSessionsPage())
+	h.mux.Handle("POST /settings/sessions/revoke-others", h.HandleRevokeOtherSessions())
+	h.mux.Handle("POST /settings/sessions/{sessionId}/revoke", h.HandleRevokeSession())
+	h.mux.Handle("GET /settings/two-factor", h.HandleTwoFactorPage())
+	h.mux.Handle("GET /settings/two-factor/qr.svg", h.HandleTwoFactorQRCode())
+	h.mux.Handle("POST /settings/two-factor/enroll", h.HandleBeginTwoFactor())
+	h.mux.Handle("POST /settings/two-factor/confirm", h.HandleConfirmTwoFactor())
+	h.mux.Handle("POST /settings/two-factor/recovery-codes", h.HandleRegenerateRecoveryCodes())
+	h.mux.Handle("POST /settings/two-factor/disable", h.HandleDisableTwoFactor())
+	h.mux.Handle("GET /settings/passkeys", h.HandlePasskeysPage())
+	h.mux.Handle("POST /settings/passkeys/options", h.HandleBeginPasskeyRegistration())
+	h.mux.Handle("POST /settings/passkeys", h.HandleFinishPasskeyRegistration())
+	h.mux.Handle("POST /settings/passkeys/{passkeyId}/delete", h.HandleDeletePasskey())
+	h.mux.Handle("GET /settings/identities", h.HandleIdentitiesPage())
+	h.mux.Handle("POST /settings/identities/{provider}/link", h.HandleLinkIdentity())
+	h.mux.Handle("POST /settings/identities/{provider}/unlink", h.HandleUnlinkIdentity())
+	h.mux.Handle("GET /settings/account", h.HandleAccountSettingsPage())
+	h.mux.Handle("GET /settings/account/export", h.HandleExportMyData())
+	h.mux.Handle("POST /settings/account/delete", h.HandleDeleteAccount())
 }
 
 func recoverMiddleware(next http.Handler) http.Handler {
@@ -181,7 +263,10 @@ func recoverMiddleware(next http.Handler) http.Handler {
 
 func (h *Handler) renderTemplate(w http.ResponseWriter, r *http.Request, name string, extraData map[string]any,
 ) {
-	var currentUser *authentication.User
+	var (
+		currentUser   *authentication.User
+		currentAuthor *Author
+	)
 
 	if isAuthenticatedRequest(r) {
 		var err error
@@ -193,6 +278,16 @@ func (h *Handler) renderTemplate(w http.ResponseWriter, r *http.Request, name st
 
 			return
 		}
+
+		currentProfile, err := h.profilesSvc.GetProfile(r.Context(), currentUser.ID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get current profile", "error", err)
+			http.Error(w, "Failed to get current profile", http.StatusInternalServerError)
+
+			return
+		}
+
+		currentAuthor = &Author{User: currentUser, Profile: currentProfile}
 	}
 
 	data := map[string]any{
@@ -201,6 +296,7 @@ func (h *Handler) renderTemplate(w http.ResponseWriter, r *http.Request, name st
 		"Dir":             "ltr",
 		"IsAuthenticated": isAuthenticatedRequest(r),
 		"CurrentUser":     currentUser,
+		"CurrentAuthor":   currentAuthor,
 	}
 
 	maps.Copy(data, extraData)
@@ -237,17 +333,29 @@ func (h *Handler) HandleStatic(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) HandleHomePage(w http.ResponseWriter, r *http.Request) {
-	posts, err := h.contentsSvc.ListPosts(r.Context())
+	cursor := r.URL.Query().Get("cursor")
+
+	result, err := h.contentsSvc.ListPosts(r.Context(), contents.ListPostsParams{
+		Cursor: cursor,
+		Limit:  contents.DefaultListPostsLimit,
+	})
 	if err != nil {
-		slog.ErrorContext(r.Context(), "failed to list posts", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		var invalidCursorErr contents.InvalidPostCursorError
+
+		switch {
+		case errors.As(err, &invalidCursorErr):
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		default:
+			slog.ErrorContext(r.Context(), "failed to list posts", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
 
 		return
 	}
 
 	postsWithAuthors, err := h.preloadPostAuthor(
 		r.Context(),
-		posts,
+		result.Posts,
 		"/",
 		csrf.TemplateField(r),
 	)
@@ -260,28 +368,53 @@ func (h *Handler) HandleHomePage(w http.ResponseWriter, r *http.Request) {
 
 	data := map[string]any{
 		"Posts":          postsWithAuthors,
+		"NextCursor":     result.NextCursor,
+		"FeedPath":       "/",
 		csrf.TemplateTag: csrf.TemplateField(r),
 	}
 
+	// "Load more" swaps the next page in place of itself, so it only needs the posts, not the whole page.
+	if cursor != "" && isHTMXRequest(r) {
+		h.renderTemplate(w, r, "posts-loop.gohtml", data)
+
+		return
+	}
+
 	h.renderTemplate(w, r, "home-page.gohtml", data)
 }
 
+// isHTMXRequest reports whether the request was issued by an htmx attribute other than hx-boost. Boosted requests
+// expect a full page in return.
+func isHTMXRequest(r *http.Request) bool {
+	return r.Header.Get(htmxRequestHeader) == htmxRequestValueTrue &&
+		r.Header.Get(htmxBoostedHeader) != htmxRequestValueTrue
+}
+
 type FullPost struct {
 	contents.Post
 
-	Author        *authentication.User
+	Author        *Author
 	CommentsCount *int
-	Comments      []*CommentWithAuthor
 	Reactions     map[string]any
+	// MentionLinks links the users mentioned in the posts on the page, see loadMentionLinks.
+	MentionLinks map[string]string
 }
 
 type CommentWithAuthor struct {
 	discuss.Comment
 
-	Author *authentication.User
+	Author    *Author
+	IsAuthor  bool
+	IsFocused bool
 
-	Replies   []*CommentWithAuthor
-	Reactions map[string]any
+	Replies []*CommentWithAuthor
+	// UnloadedReplies is the number of replies that weren't loaded, as the comment is as deep as a page goes.
+	UnloadedReplies int
+	// RepliesCursor is set when only the first page of the loaded replies is shown.
+	RepliesCursor string
+	Reactions     map[string]any
+	// MentionLinks links the users mentioned in the comments on the page, see loadMentionLinks.
+	MentionLinks map[string]string
 }
 
 func (h *Handler) preloadPostAuthor(
@@ -290,55 +423,100 @@ func (h *Handler) preloadPostAuthor(
 	returnTo string,
 	csrfField template.HTML,
 ) ([]*FullPost, error) {
-	var result []*FullPost
+	postIDs := make([]string, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+
+	authors, err := h.loadAuthors(ctx, uniqueIDs(posts, func(post *contents.Post) string { return post.AuthorID }))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authors: %w", err)
+	}
+
+	commentsCounts, err := h.discussSvc.CountCommentsByPosts(ctx, postIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	reactionData, err := h.buildReactionWidgetsData(ctx, reactions.TargetTypePost, postIDs, returnTo, csrfField)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load post reactions: %w", err)
+	}
 
-	// TODO: optimize this by batching user retrieval instead of doing it one by one
+	postContents := make([]string, 0, len(posts))
 	for _, post := range posts {
-		author, err := h.authSvc.GetUser(ctx, post.AuthorID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get author: %w", err)
-		}
+		postContents = append(postContents, post.Content)
+	}
 
-		commentsCount, err := h.discussSvc.CountComments(ctx, post.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to count comments: %w", err)
-		}
+	mentionLinks, err := h.loadMentionLinks(ctx, postContents...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load post mentions: %w", err)
+	}
 
-		reactionData, err := h.buildReactionWidgetData(
-			ctx,
-			reactions.TargetTypePost,
-			post.ID,
-			returnTo,
-			csrfField,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load post reactions: %w", err)
-		}
+	result := make([]*FullPost, 0, len(posts))
+
+	for _, post := range posts {
+		commentsCount := commentsCounts[post.ID]
 
 		result = append(result, &FullPost{
 			Post:          *post,
-			Author:        author,
+			Author:        authors[post.AuthorID],
 			CommentsCount: &commentsCount,
-			Reactions:     reactionData,
+			Reactions:     reactionData[post.ID],
+			MentionLinks:  mentionLinks,
 		})
 	}
 
 	return result, nil
 }
 
-func (h *Handler) HandleRegisterPage() http.Handler {
-	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		data := map[string]any{
-			csrf.TemplateTag: csrf.TemplateField(r),
-			"SiteTitle":      "Register",
+// uniqueIDs collects the distinct IDs picked from items, in order of first appearance.
+func uniqueIDs[T any](items []T, pick func(item T) string) []string {
+	ids := make([]string, 0, len(items))
+	seen := make(map[string]struct{}, len(items))
+
+	for _, item := range items {
+		id := pick(item)
+		if _, ok := seen[id]; ok {
+			continue
 		}
 
-		h.renderTemplate(w, r, "register-page.gohtml", data)
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func (h *Handler) HandleRegisterPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.renderRegisterPage(w, r, http.StatusOK, "", nil)
 	})
 
 	return h.GuestOnly(hf)
 }
 
+// renderRegisterPage renders the register form, keeping the submitted username and showing fieldErrors next to the
+// fields they belong to.
+func (h *Handler) renderRegisterPage(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	username string,
+	fieldErrors map[string][]string,
+) {
+	data := map[string]any{
+		csrf.TemplateTag: csrf.TemplateField(r),
+		"SiteTitle":      "Register",
+		"Username":       username,
+		"Errors":         fieldErrors,
+	}
+
+	w.WriteHeader(status)
+
+	h.renderTemplate(w, r, "register-page.gohtml", data)
+}
+
 func (h *Handler) HandleRegister() http.Handler {
 	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		err := r.ParseForm()
@@ -354,11 +532,18 @@ func (h *Handler) HandleRegister() http.Handler {
 
 		err = h.authSvc.Register(r.Context(), username, password)
 		if err != nil {
-			var userAlreadyExistsErr *authentication.UserAlreadyExistsError
+			var (
+				userAlreadyExistsErr *authentication.UserAlreadyExistsError
+				validationErr        *authentication.ValidationError
+			)
 
 			switch {
+			case errors.As(err, &validationErr):
+				h.renderRegisterPage(w, r, http.StatusUnprocessableEntity, username, validationErr.Fields)
 			case errors.As(err, &userAlreadyExistsErr):
-				http.Error(w, "Username already exists", http.StatusConflict)
+				h.renderRegisterPage(w, r, http.StatusConflict, username, map[string][]string{
+					authentication.FieldUsername: {"This username is already taken."},
+				})
 			default:
 				slog.ErrorContext(r.Context(), "failed to register user", "error", err)
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -375,17 +560,24 @@ func (h *Handler) HandleRegister() http.Handler {
 
 func (h *Handler) HandleLoginPage() http.Handler {
 	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		data := map[string]any{
-			csrf.TemplateTag: csrf.TemplateField(r),
-			"SiteTitle":      "Login",
-		}
-
-		h.renderTemplate(w, r, "login-page.gohtml", data)
+		h.renderLoginPage(w, r, http.StatusOK, "")
 	})
 
 	return h.GuestOnly(hf)
 }
 
+func (h *Handler) renderLoginPage(w http.ResponseWriter, r *http.Request, status int, formError string) {
+	data := map[string]any{
+		"Error":          formError,
+		"OIDCProviders":  h.authSvc.OIDCProviders(),
+		csrf.TemplateTag: csrf.TemplateField(r),
+		"SiteTitle":      "Login",
+	}
+
+	w.WriteHeader(status)
+	h.renderTemplate(w, r, "login-page.gohtml", data)
+}
+
 func (h *Handler) HandleLogin() http.Handler {
 	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		err := r.ParseForm()
@@ -399,11 +591,19 @@ func (h *Handler) HandleLogin() http.Handler {
 		username := r.FormValue("username")
 		password := r.FormValue("password")
 
-		session, err := h.authSvc.Login(r.Context(), username, password)
+		session, err := h.authSvc.Login(r.Context(), username, password, clientInfo(r))
 		if err != nil {
+			tooManyAttemptsErr, isTooManyAttempts := errors.AsType[*authentication.TooManyAttemptsError](err)
+			secondFactorErr, isSecondFactorRequired := errors.AsType[*authentication.SecondFactorRequiredError](err)
+
 			switch {
+			case isSecondFactorRequired:
+				h.redirectToSecondFactor(w, r, secondFactorErr.ChallengeID)
 			case errors.Is(err, authentication.ErrInvalidCredentials):
 				http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+			case isTooManyAttempts:
+				w.Header().Set("Retry-After", strconv.Itoa(tooManyAttemptsErr.RetryAfterSeconds()))
+				http.Error(w, "Too many failed login attempts. Try again later.", http.StatusTooManyRequests)
 			default:
 				slog.ErrorContext(r.Context(), "failed to login user", "error", err)
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -475,17 +675,24 @@ func (h *Handler) HandleLogout() http.Handler {
 
 func (h *Handler) HandleCreatePostPage() http.Handler {
 	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		data := map[string]any{
-			csrf.TemplateTag: csrf.TemplateField(r),
-			"SiteTitle":      "Create Post",
-		}
-
-		h.renderTemplate(w, r, "create-post-page.gohtml", data)
+		h.renderCreatePostPage(w, r, http.StatusOK, nil)
 	})
 
 	return h.AuthenticatedOnly(hf)
 }
 
+func (h *Handler) renderCreatePostPage(w http.ResponseWriter, r *http.Request, status int, extraData map[string]any) {
+	data := map[string]any{
+		csrf.TemplateTag: csrf.TemplateField(r),
+		"SiteTitle":      "Create Post",
+	}
+
+	maps.Copy(data, extraData)
+
+	w.WriteHeader(status)
+	h.renderTemplate(w, r, "create-post-page.gohtml", data)
+}
+
 func (h *Handler) HandleCreatePost() http.Handler {
 	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		err := r.ParseForm()
@@ -511,6 +718,14 @@ func (h *Handler) HandleCreatePost() http.Handler {
 			Content:  content,
 		})
 		if err != nil {
+			if errors.Is(err, contents.ErrEmptyPostContent) {
+				h.renderCreatePostPage(w, r, http.StatusUnprocessableEntity, map[string]any{
+					"ContentError": "Write something before creating the post.",
+				})
+
+				return
+			}
+
 			slog.ErrorContext(r.Context(), "failed to create post", "error", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 
@@ -527,6 +742,12 @@ func (h *Handler) HandleViewPostPage() http.Handler {
 	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		postID := r.PathValue("postId")
 		returnTo := "/p/" + postID
+		cursor := r.URL.Query().Get("cursor")
+
+		commentsSort := discuss.CommentSort(r.URL.Query().Get("sort"))
+		if commentsSort == "" {
+			commentsSort = discuss.CommentSortOldest
+		}
 
 		post, err := h.contentsSvc.GetPost(r.Context(), postID)
 		if err != nil {
@@ -536,27 +757,70 @@ func (h *Handler) HandleViewPostPage() http.Handler {
 			return
 		}
 
-		author, err := h.authSvc.GetUser(r.Context(), post.AuthorID)
+		result, err := h.discussSvc.ListComments(r.Context(), discuss.ListCommentsParams{
+			PostID:   post.ID,
+			ParentID: "",
+			Sort:     commentsSort,
+			Cursor:   cursor,
+			Limit:    discuss.DefaultListCommentsLimit,
+			Depth:    commentReplyDepth,
+		})
 		if err != nil {
-			slog.ErrorContext(r.Context(), "failed to get post author", "authorId", post.AuthorID, "error", err)
-			http.Error(w, "Failed to get post author", http.StatusInternalServerError)
+			var (
+				invalidSortErr   discuss.InvalidCommentSortError
+				invalidCursorErr discuss.InvalidCommentCursorError
+			)
+
+			switch {
+			case errors.As(err, &invalidSortErr):
+				http.Error(w, "Invalid sort", http.StatusBadRequest)
+			case errors.As(err, &invalidCursorErr):
+				http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			default:
+				slog.ErrorContext(r.Context(), "failed to list comments", "postId", post.ID, "error", err)
+				http.Error(w, "Failed to list comments", http.StatusInternalServerError)
+			}
 
 			return
 		}
 
-		comments, err := h.listCommentsWithAuthors(
+		comments, err := h.buildCommentTree(
 			r.Context(),
-			post.ID,
+			result.Comments,
+			result.UnloadedReplies,
 			returnTo,
 			csrf.TemplateField(r),
 		)
 		if err != nil {
-			slog.ErrorContext(r.Context(), "failed to list comments with authors", "postId", post.ID, "error", err)
+			slog.ErrorContext(r.Context(), "failed to build comment tree", "postId", post.ID, "error", err)
 			http.Error(w, "Failed to list comments", http.StatusInternalServerError)
 
 			return
 		}
 
+		data := map[string]any{
+			"Comments":       comments,
+			"NextCursor":     result.NextCursor,
+			"CommentsSort":   string(commentsSort),
+			"CommentsPath":   returnTo + "?sort=" + string(commentsSort),
+			csrf.TemplateTag: csrf.TemplateField(r),
+		}
+
+		// "Load more comments" swaps the next page in place of itself, so it only needs the comments.
+		if cursor != "" && isHTMXRequest(r) {
+			h.renderTemplate(w, r, "comments-list.gohtml", data)
+
+			return
+		}
+
+		authors, err := h.loadAuthors(r.Context(), []string{post.AuthorID})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get post author", "authorId", post.AuthorID, "error", err)
+			http.Error(w, "Failed to get post author", http.StatusInternalServerError)
+
+			return
+		}
+
 		reactionData, err := h.buildReactionWidgetData(
 			r.Context(),
 			reactions.TargetTypePost,
@@ -571,205 +835,987 @@ func (h *Handler) HandleViewPostPage() http.Handler {
 			return
 		}
 
-		data := map[string]any{
-			"Post": FullPost{Post: *post, Author: author, Comments: comments, Reactions: reactionData},
-			// "SiteTitle": "View Post", TODO: set post title as site title
-			csrf.TemplateTag: csrf.TemplateField(r),
-		}
-
-		h.renderTemplate(w, r, "view-post-page.gohtml", data)
-	})
-
-	return hf
-}
-
-func (h *Handler) listCommentsWithAuthors(
-	ctx context.Context,
-	postID string,
-	returnTo string,
-	csrfField template.HTML,
-) ([]*CommentWithAuthor, error) {
-	comments, err := h.discussSvc.ListComments(ctx, postID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list comments: %w", err)
-	}
-
-	result := make([]*CommentWithAuthor, 0, len(comments))
-	commentsByID := make(map[string]*CommentWithAuthor, len(comments))
-
-	for _, comment := range comments {
-		author, err := h.authSvc.GetUser(ctx, comment.AuthorID)
+		mentionLinks, err := h.loadMentionLinks(r.Context(), post.Content)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get comment author: %w", err)
-		}
+			slog.ErrorContext(r.Context(), "failed to load post mentions", "postId", post.ID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 
-		commentWithAuthor := &CommentWithAuthor{
-			Comment: *comment,
-			Author:  author,
+			return
 		}
 
-		reactionData, err := h.buildReactionWidgetData(
-			ctx,
-			reactions.TargetTypeComment,
-			comment.ID,
-			returnTo,
-			csrfField,
-		)
+		commentsClosed, err := h.checkCommentsClosed(r.Context(), post.ID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load comment reactions: %w", err)
-		}
-
-		// TODO: optimize this by batching comment reaction retrieval instead of doing it one by one
-		// Similar to post reactions, this creates N+1 queries for comment reactions.
-		// For a post with many comments, this compounds the performance issue.
-		// Each comment triggers 2 database queries for reaction data (counts + user reaction).
-		// The same batching optimization suggested for posts should be applied here
-		// to load all comment reactions in a single batch operation.
-		commentWithAuthor.Reactions = reactionData
-
-		result = append(result, commentWithAuthor)
-		commentsByID[comment.ID] = commentWithAuthor
-	}
-
-	roots := make([]*CommentWithAuthor, 0, len(result))
-
-	for _, comment := range result {
-		if comment.ReplyTo == nil {
-			roots = append(roots, comment)
+			slog.ErrorContext(r.Context(), "failed to check comment access", "postId", post.ID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 
-			continue
+			return
 		}
 
-		parent, found := commentsByID[*comment.ReplyTo]
-		if !found {
-			roots = append(roots, comment)
+		canEdit, err := h.checkCanManagePost(r.Context(), post, contents.ActionUpdatePost)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to check post access", "postId", post.ID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 
-			continue
+			return
 		}
 
-		parent.Replies = append(parent.Replies, comment)
-	}
-
-	return roots, nil
-}
+		canViewHistory, err := h.checkCanManagePost(r.Context(), post, contents.ActionListPostRevisions)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to check post access", "postId", post.ID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 
-func (h *Handler) buildReactionWidgetData(
-	ctx context.Context,
-	targetType reactions.TargetType,
-	targetID string,
-	returnTo string,
-	csrfField template.HTML,
-) (map[string]any, error) {
-	isAuthenticated := isAuthenticated(ctx)
+			return
+		}
 
-	if !isAuthenticated {
-		return map[string]any{
-			"TargetType":      targetType,
-			"TargetID":        targetID,
-			"Options":         []reactions.ReactionOption{},
-			"ReturnTo":        returnTo,
-			"IsAuthenticated": false,
-			csrf.TemplateTag:  csrfField,
-		}, nil
-	}
+		data["Post"] = FullPost{
+			Post:         *post,
+			Author:       authors[post.AuthorID],
+			Reactions:    reactionData,
+			MentionLinks: mentionLinks,
+		}
+		data["CommentsClosed"] = commentsClosed
+		data["CanEdit"] = canEdit
+		data["CanViewHistory"] = canViewHistory
+		// data["SiteTitle"] = "View Post" TODO: set post title as site title
 
-	targetReactions, err := h.reactionsSvc.GetMyReactions(ctx, targetType, targetID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get my reactions: %w", err)
-	}
+		h.renderTemplate(w, r, "view-post-page.gohtml", data)
+	})
 
-	return map[string]any{
-		"TargetType":      targetReactions.TargetType,
-		"TargetID":        targetReactions.TargetID,
-		"Options":         targetReactions.Options,
-		"ReturnTo":        returnTo,
-		"IsAuthenticated": isAuthenticated,
-		csrf.TemplateTag:  csrfField,
-	}, nil
+	return hf
 }
 
-func (h *Handler) HandlePostComment() http.Handler {
+// HandleCommentThreadPage shows a single comment with the comments it replies to above it and its replies below, so
+// it can be linked to wherever it is in the thread. Deleted comments are shown as the placeholders they are in
+// threads.
+func (h *Handler) HandleCommentThreadPage() http.Handler {
 	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		postID := r.PathValue("postId")
+		returnTo := "/p/" + postID + "/c/" + r.PathValue("commentId")
 
-		err := r.ParseForm()
+		comment, err := h.getThreadComment(r.Context(), postID, r.PathValue("commentId"))
 		if err != nil {
-			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
-			http.Error(w, "Bad Request", http.StatusBadRequest)
+			handleCommentError(w, r, err, "failed to get comment")
 
 			return
 		}
 
-		content := r.FormValue("comment")
-		replyToID := r.FormValue("reply_to_id")
-
-		currentUser, err := h.authSvc.GetCurrentUser(r.Context())
+		ancestors, err := h.discussSvc.ListCommentAncestors(r.Context(), comment.ID)
 		if err != nil {
-			slog.ErrorContext(r.Context(), "failed to get current user", "error", err)
-			http.Error(w, "Failed to get current user", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "failed to list comment ancestors", "commentId", comment.ID, "error", err)
+			http.Error(w, "Failed to list comments", http.StatusInternalServerError)
 
 			return
 		}
 
-		_, err = h.discussSvc.CreateComment(r.Context(), discuss.CreateCommentRequest{
+		replies, err := h.discussSvc.ListComments(r.Context(), discuss.ListCommentsParams{
 			PostID:   postID,
-			AuthorID: currentUser.ID,
-			Content:  content,
-			ReplyTo:  replyToID,
+			ParentID: comment.ID,
+			Sort:     discuss.CommentSortOldest,
+			Cursor:   "",
+			Limit:    discuss.DefaultListCommentsLimit,
+			Depth:    commentReplyDepth,
 		})
 		if err != nil {
-			slog.ErrorContext(r.Context(), "failed to create comment", "error", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "failed to list replies", "commentId", comment.ID, "error", err)
+			http.Error(w, "Failed to list comments", http.StatusInternalServerError)
 
 			return
 		}
 
-		http.Redirect(w, r, "/p/"+postID, http.StatusSeeOther)
-	})
+		// Each ancestor has the next one as its only reply, down to the comment, so the thread is a single root.
+		comments := slices.Concat(ancestors, []*discuss.Comment{comment}, replies.Comments)
 
-	return h.AuthenticatedOnly(hf)
-}
+		thread, err := h.buildCommentTree(r.Context(), comments, replies.UnloadedReplies, returnTo, csrf.TemplateField(r))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to build comment tree", "commentId", comment.ID, "error", err)
+			http.Error(w, "Failed to list comments", http.StatusInternalServerError)
 
-func (h *Handler) HandleReplyForm() http.Handler {
-	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		postID := r.PathValue("postId")
-		commentID := r.PathValue("commentId")
+			return
+		}
 
-		data := map[string]any{
-			csrf.TemplateTag: csrf.TemplateField(r),
-			"PostID":         postID,
-			"CommentID":      commentID,
-			"SiteTitle":      "Reply",
+		focused := thread[0]
+		for focused.ID != comment.ID {
+			focused = focused.Replies[0]
 		}
 
-		if r.Header.Get(htmxRequestHeader) == htmxRequestValueTrue {
-			h.renderTemplate(w, r, "reply-form.gohtml", data)
+		focused.IsFocused = true
+		focused.RepliesCursor = replies.NextCursor
 
-			return
+		data := map[string]any{
+			"PostID":         postID,
+			"Comment":        comment,
+			"Comments":       thread,
+			csrf.TemplateTag: csrf.TemplateField(r),
+			"SiteTitle":      "Comment",
 		}
 
-		h.renderTemplate(w, r, "reply-page.gohtml", data)
+		h.renderTemplate(w, r, "comment-thread-page.gohtml", data)
 	})
 
-	return h.AuthenticatedOnly(hf)
+	return hf
 }
 
-func (h *Handler) HandleToggleReaction() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		targetType := reactions.TargetType(r.PathValue("targetType"))
-		targetID := r.PathValue("targetId")
+// HandleCommentReplies renders a page of the replies to a comment, for "show more replies" to swap in below it.
+func (h *Handler) HandleCommentReplies() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID := r.PathValue("postId")
+		commentID := r.PathValue("commentId")
 
-		err := r.ParseForm()
-		if err != nil {
-			slog.ErrorContext(r.Context(), "failed to parse reaction form", "error", err)
-			http.Error(w, "Bad Request", http.StatusBadRequest)
+		// Without htmx the replies are shown on the page of the comment instead.
+		if !isHTMXRequest(r) {
+			http.Redirect(w, r, "/p/"+postID+"/c/"+commentID, http.StatusSeeOther)
 
 			return
 		}
 
-		returnTo := sanitizeReturnToPath(r.FormValue("return_to"))
+		comment, err := h.getThreadComment(r.Context(), postID, commentID)
+		if err != nil {
+			handleCommentError(w, r, err, "failed to get comment")
 
-		if !isAuthenticatedRequest(r) {
+			return
+		}
+
+		result, err := h.discussSvc.ListComments(r.Context(), discuss.ListCommentsParams{
+			PostID:   postID,
+			ParentID: comment.ID,
+			Sort:     discuss.CommentSortOldest,
+			Cursor:   r.URL.Query().Get("cursor"),
+			Limit:    discuss.DefaultListCommentsLimit,
+			Depth:    commentReplyDepth,
+		})
+		if err != nil {
+			var invalidCursorErr discuss.InvalidCommentCursorError
+
+			switch {
+			case errors.As(err, &invalidCursorErr):
+				http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			default:
+				slog.ErrorContext(r.Context(), "failed to list replies", "commentId", comment.ID, "error", err)
+				http.Error(w, "Failed to list comments", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		replies, err := h.buildCommentTree(
+			r.Context(),
+			result.Comments,
+			result.UnloadedReplies,
+			"/p/"+postID,
+			csrf.TemplateField(r),
+		)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to build comment tree", "commentId", comment.ID, "error", err)
+			http.Error(w, "Failed to list comments", http.StatusInternalServerError)
+
+			return
+		}
+
+		data := map[string]any{
+			"PostID":         postID,
+			"CommentID":      comment.ID,
+			"Comments":       replies,
+			"NextCursor":     result.NextCursor,
+			csrf.TemplateTag: csrf.TemplateField(r),
+		}
+
+		h.renderTemplate(w, r, "comment-replies.gohtml", data)
+	})
+
+	return hf
+}
+
+func (h *Handler) HandleEditPostPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID := r.PathValue("postId")
+
+		post, err := h.contentsSvc.GetPost(r.Context(), postID)
+		if err != nil {
+			handlePostError(w, r, err, "failed to get post")
+
+			return
+		}
+
+		canEdit, err := h.checkCanManagePost(r.Context(), post, contents.ActionUpdatePost)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to check post access", "postId", postID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		if !canEdit {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+
+			return
+		}
+
+		h.renderEditPostPage(w, r, http.StatusOK, post, nil)
+	})
+
+	return h.AuthenticatedOnly(hf)
+}
+
+func (h *Handler) renderEditPostPage(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	post *contents.Post,
+	extraData map[string]any,
+) {
+	data := map[string]any{
+		"Post":           post,
+		csrf.TemplateTag: csrf.TemplateField(r),
+		"SiteTitle":      "Edit Post",
+	}
+
+	maps.Copy(data, extraData)
+
+	w.WriteHeader(status)
+	h.renderTemplate(w, r, "edit-post-page.gohtml", data)
+}
+
+func (h *Handler) HandleEditPost() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID := r.PathValue("postId")
+
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		content := r.FormValue("content")
+
+		currentUser, err := h.authSvc.GetCurrentUser(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get current user", "error", err)
+			http.Error(w, "Failed to get current user", http.StatusInternalServerError)
+
+			return
+		}
+
+		_, err = h.contentsSvc.UpdatePost(r.Context(), contents.UpdatePostRequest{
+			PostID:   postID,
+			EditorID: currentUser.ID,
+			Content:  content,
+		})
+		if err != nil {
+			if errors.Is(err, contents.ErrEmptyPostContent) {
+				post, err := h.contentsSvc.GetPost(r.Context(), postID)
+				if err != nil {
+					handlePostError(w, r, err, "failed to get post")
+
+					return
+				}
+
+				h.renderEditPostPage(w, r, http.StatusUnprocessableEntity, post, map[string]any{
+					"ContentError": "Write something before saving the post.",
+				})
+
+				return
+			}
+
+			handlePostError(w, r, err, "failed to update post")
+
+			return
+		}
+
+		http.Redirect(w, r, "/p/"+postID, http.StatusSeeOther)
+	})
+
+	return h.AuthenticatedOnly(hf)
+}
+
+func (h *Handler) HandleDeletePost() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID := r.PathValue("postId")
+
+		err := h.contentsSvc.DeletePost(r.Context(), postID)
+		if err != nil {
+			handlePostError(w, r, err, "failed to delete post")
+
+			return
+		}
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+
+	return h.AuthenticatedOnly(hf)
+}
+
+type PostRevisionWithEditor struct {
+	contents.PostRevision
+
+	Editor *authentication.User
+}
+
+func (h *Handler) HandleUpdateCommentSettings() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID := r.PathValue("postId")
+
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		minAccountAgeDays, err := strconv.Atoi(r.FormValue("comments_min_account_age_days"))
+		if err != nil {
+			minAccountAgeDays = -1
+		}
+
+		_, err = h.contentsSvc.UpdateCommentSettings(r.Context(), contents.UpdateCommentSettingsRequest{
+			PostID:            postID,
+			Locked:            r.FormValue("comments_locked") == "true",
+			MinAccountAgeDays: minAccountAgeDays,
+		})
+		if err != nil {
+			if _, ok := errors.AsType[contents.InvalidMinAccountAgeError](err); ok {
+				post, err := h.contentsSvc.GetPost(r.Context(), postID)
+				if err != nil {
+					handlePostError(w, r, err, "failed to get post")
+
+					return
+				}
+
+				h.renderEditPostPage(w, r, http.StatusUnprocessableEntity, post, map[string]any{
+					"CommentSettingsError": "Enter the minimum account age as a whole number of days, 0 or more.",
+				})
+
+				return
+			}
+
+			handlePostError(w, r, err, "failed to update comment settings")
+
+			return
+		}
+
+		http.Redirect(w, r, "/p/"+postID+"#comments", http.StatusSeeOther)
+	})
+
+	return h.AuthenticatedOnly(hf)
+}
+
+func (h *Handler) HandlePostHistoryPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID := r.PathValue("postId")
+
+		post, err := h.contentsSvc.GetPost(r.Context(), postID)
+		if err != nil {
+			handlePostError(w, r, err, "failed to get post")
+
+			return
+		}
+
+		revisions, err := h.contentsSvc.ListPostRevisions(r.Context(), postID)
+		if err != nil {
+			handlePostError(w, r, err, "failed to list post revisions")
+
+			return
+		}
+
+		from, to, ok := selectRevisionsToCompare(revisions, r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+		if !ok {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+
+			return
+		}
+
+		revisionsWithEditors, err := h.preloadRevisionEditors(r.Context(), revisions)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to preload revision editors", "postId", postID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		var fromID, fromContent, toID, toContent string
+
+		if from != nil {
+			fromID, fromContent = from.ID, from.Content
+		}
+
+		if to != nil {
+			toID, toContent = to.ID, to.Content
+		}
+
+		data := map[string]any{
+			"Post":      post,
+			"Revisions": revisionsWithEditors,
+			"FromID":    fromID,
+			"ToID":      toID,
+			"Diff":      diff.Lines(fromContent, toContent),
+			"SiteTitle": "Post History",
+		}
+
+		h.renderTemplate(w, r, "post-history-page.gohtml", data)
+	})
+
+	return hf
+}
+
+// selectRevisionsToCompare picks the revisions named in the query, defaulting to the latest revision compared with
+// the one right before it. Revisions are expected newest first.
+func selectRevisionsToCompare(
+	revisions []*contents.PostRevision,
+	fromID string,
+	toID string,
+) (*contents.PostRevision, *contents.PostRevision, bool) {
+	if len(revisions) == 0 {
+		return nil, nil, fromID == "" && toID == ""
+	}
+
+	toIndex := 0
+
+	if toID != "" {
+		toIndex = slices.IndexFunc(revisions, func(revision *contents.PostRevision) bool {
+			return revision.ID == toID
+		})
+		if toIndex < 0 {
+			return nil, nil, false
+		}
+	}
+
+	if fromID == "" {
+		if toIndex+1 < len(revisions) {
+			return revisions[toIndex+1], revisions[toIndex], true
+		}
+
+		return nil, revisions[toIndex], true
+	}
+
+	fromIndex := slices.IndexFunc(revisions, func(revision *contents.PostRevision) bool {
+		return revision.ID == fromID
+	})
+	if fromIndex < 0 {
+		return nil, nil, false
+	}
+
+	return revisions[fromIndex], revisions[toIndex], true
+}
+
+func (h *Handler) preloadRevisionEditors(
+	ctx context.Context,
+	revisions []*contents.PostRevision,
+) ([]*PostRevisionWithEditor, error) {
+	editors, err := h.authSvc.GetUsers(
+		ctx,
+		uniqueIDs(revisions, func(revision *contents.PostRevision) string { return revision.EditorID }),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision editors: %w", err)
+	}
+
+	result := make([]*PostRevisionWithEditor, 0, len(revisions))
+
+	for _, revision := range revisions {
+		result = append(result, &PostRevisionWithEditor{PostRevision: *revision, Editor: editors[revision.EditorID]})
+	}
+
+	return result, nil
+}
+
+func handlePostError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	var (
+		postNotFoundErr contents.PostNotFoundError
+		accessDeniedErr *authorization.AccessDeniedError
+	)
+
+	switch {
+	case errors.As(err, &postNotFoundErr):
+		http.Error(w, "Post not found", http.StatusNotFound)
+	case errors.As(err, &accessDeniedErr):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	default:
+		slog.ErrorContext(r.Context(), msg, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// buildCommentTree loads the authors and reactions of comments and nests every reply below its parent. Comments
+// whose parent isn't among them are the roots, in the order they came in.
+func (h *Handler) buildCommentTree(
+	ctx context.Context,
+	comments []*discuss.Comment,
+	unloadedReplies map[string]int,
+	returnTo string,
+	csrfField template.HTML,
+) ([]*CommentWithAuthor, error) {
+	authors, err := h.loadAuthors(
+		ctx,
+		uniqueIDs(comments, func(comment *discuss.Comment) string { return comment.AuthorID }),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment authors: %w", err)
+	}
+
+	reactionData, err := h.buildReactionWidgetsData(
+		ctx,
+		reactions.TargetTypeComment,
+		uniqueIDs(comments, func(comment *discuss.Comment) string { return comment.ID }),
+		returnTo,
+		csrfField,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load comment reactions: %w", err)
+	}
+
+	commentContents := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		commentContents = append(commentContents, comment.Content)
+	}
+
+	mentionLinks, err := h.loadMentionLinks(ctx, commentContents...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load comment mentions: %w", err)
+	}
+
+	result := make([]*CommentWithAuthor, 0, len(comments))
+	commentsByID := make(map[string]*CommentWithAuthor, len(comments))
+	subject := authcontext.GetSubject(ctx)
+
+	for _, comment := range comments {
+		commentWithAuthor := &CommentWithAuthor{
+			Comment:         *comment,
+			Author:          authors[comment.AuthorID],
+			IsAuthor:        comment.AuthorID == subject,
+			UnloadedReplies: unloadedReplies[comment.ID],
+			Reactions:       reactionData[comment.ID],
+			MentionLinks:    mentionLinks,
+		}
+
+		result = append(result, commentWithAuthor)
+		commentsByID[comment.ID] = commentWithAuthor
+	}
+
+	roots := make([]*CommentWithAuthor, 0, len(result))
+
+	for _, comment := range result {
+		if comment.ReplyTo == nil {
+			roots = append(roots, comment)
+
+			continue
+		}
+
+		parent, found := commentsByID[*comment.ReplyTo]
+		if !found {
+			roots = append(roots, comment)
+
+			continue
+		}
+
+		parent.Replies = append(parent.Replies, comment)
+	}
+
+	return roots, nil
+}
+
+func (h *Handler) buildReactionWidgetData(
+	ctx context.Context,
+	targetType reactions.TargetType,
+	targetID string,
+	returnTo string,
+	csrfField template.HTML,
+) (map[string]any, error) {
+	data, err := h.buildReactionWidgetsData(ctx, targetType, []string{targetID}, returnTo, csrfField)
+	if err != nil {
+		return nil, err
+	}
+
+	return data[targetID], nil
+}
+
+// buildReactionWidgetsData prepares the reaction widget of every target at once, so the cost of a page does not grow
+// with the number of posts or comments on it.
+func (h *Handler) buildReactionWidgetsData(
+	ctx context.Context,
+	targetType reactions.TargetType,
+	targetIDs []string,
+	returnTo string,
+	csrfField template.HTML,
+) (map[string]map[string]any, error) {
+	isAuthenticated := isAuthenticated(ctx)
+	result := make(map[string]map[string]any, len(targetIDs))
+
+	if !isAuthenticated {
+		for _, targetID := range targetIDs {
+			result[targetID] = map[string]any{
+				"TargetType":      targetType,
+				"TargetID":        targetID,
+				"Options":         []reactions.ReactionOption{},
+				"ReturnTo":        returnTo,
+				"IsAuthenticated": false,
+				csrf.TemplateTag:  csrfField,
+			}
+		}
+
+		return result, nil
+	}
+
+	targetsReactions, err := h.reactionsSvc.GetMyReactionsByTargets(ctx, targetType, targetIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get my reactions: %w", err)
+	}
+
+	for targetID, targetReactions := range targetsReactions {
+		result[targetID] = map[string]any{
+			"TargetType":      targetReactions.TargetType,
+			"TargetID":        targetReactions.TargetID,
+			"Options":         targetReactions.Options,
+			"ReturnTo":        returnTo,
+			"IsAuthenticated": isAuthenticated,
+			csrf.TemplateTag:  csrfField,
+		}
+	}
+
+	return result, nil
+}
+
+func (h *Handler) HandlePostComment() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID := r.PathValue("postId")
+
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		content := r.FormValue("comment")
+		replyToID := r.FormValue("reply_to_id")
+
+		currentUser, err := h.authSvc.GetCurrentUser(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get current user", "error", err)
+			http.Error(w, "Failed to get current user", http.StatusInternalServerError)
+
+			return
+		}
+
+		comment, err := h.discussSvc.CreateComment(r.Context(), discuss.CreateCommentRequest{
+			PostID:   postID,
+			AuthorID: currentUser.ID,
+			Content:  content,
+			ReplyTo:  replyToID,
+		})
+		if err != nil {
+			if _, ok := errors.AsType[discuss.PostNotFoundError](err); ok {
+				http.Error(w, "Post not found", http.StatusNotFound)
+
+				return
+			}
+
+			if msg := commentAccessMessage(err); msg != "" {
+				h.renderReplyPage(w, r, http.StatusUnprocessableEntity, postID, replyToID, map[string]any{
+					"CommentsClosed": msg,
+				})
+
+				return
+			}
+
+			if msg := replyErrorMessage(err); msg != "" {
+				h.renderReplyPage(w, r, http.StatusUnprocessableEntity, postID, replyToID, map[string]any{
+					"Error":   msg,
+					"Content": content,
+				})
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to create comment", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		// Replies may be out of the first page of the post, or too deep to be shown on it.
+		if comment.ReplyTo != nil {
+			http.Redirect(w, r, "/p/"+postID+"/c/"+comment.ID, http.StatusSeeOther)
+
+			return
+		}
+
+		http.Redirect(w, r, "/p/"+postID, http.StatusSeeOther)
+	})
+
+	return h.AuthenticatedOnly(hf)
+}
+
+// checkCanManagePost tells whether the current user may take an action on a post that's held to its author, such as
+// editing it, so pages only offer it to those who can. It mirrors the contents service, which lets authors manage their
+// own posts and leaves everyone else to the policy.
+func (h *Handler) checkCanManagePost(ctx context.Context, post *contents.Post, action string) (bool, error) {
+	if post.AuthorID == authcontext.GetSubject(ctx) {
+		return true, nil
+	}
+
+	err := h.authzClient.CheckAccess(ctx, contents.ServiceName, post.ID, action)
+	if err == nil {
+		return true, nil
+	}
+
+	if _, ok := errors.AsType[*authorization.AccessDeniedError](err); ok {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("failed to check post access: %w", err)
+}
+
+// checkCommentsClosed tells why the current user can't comment on a post, or returns an empty string if they can.
+func (h *Handler) checkCommentsClosed(ctx context.Context, postID string) (string, error) {
+	err := h.discussSvc.CheckCommentAccess(ctx, postID)
+	if err == nil {
+		return "", nil
+	}
+
+	if msg := commentAccessMessage(err); msg != "" {
+		return msg, nil
+	}
+
+	return "", fmt.Errorf("failed to check comment access: %w", err)
+}
+
+// commentAccessMessage tells the user why the comment settings of a post keep them from commenting, or returns an
+// empty string for other errors.
+func commentAccessMessage(err error) string {
+	if _, ok := errors.AsType[discuss.CommentsLockedError](err); ok {
+		return "Comments are closed on this post."
+	}
+
+	if accountTooNewErr, ok := errors.AsType[discuss.AccountTooNewError](err); ok {
+		if accountTooNewErr.MinAccountAgeDays == 1 {
+			return "Only accounts registered at least a day ago can comment on this post."
+		}
+
+		return fmt.Sprintf(
+			"Only accounts registered at least %d days ago can comment on this post.",
+			accountTooNewErr.MinAccountAgeDays,
+		)
+	}
+
+	return ""
+}
+
+// replyErrorMessage tells the user why a reply was refused, or returns an empty string for errors they can't act on.
+func replyErrorMessage(err error) string {
+	if _, ok := errors.AsType[discuss.ParentCommentNotFoundError](err); ok {
+		return "The comment you are replying to no longer exists."
+	}
+
+	if _, ok := errors.AsType[discuss.CrossPostReplyError](err); ok {
+		return "You can only reply to comments on this post."
+	}
+
+	if _, ok := errors.AsType[discuss.ThreadTooDeepError](err); ok {
+		return "This thread is nested as deep as it can go. Reply to a comment further up instead."
+	}
+
+	return ""
+}
+
+func (h *Handler) HandleReplyForm() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID := r.PathValue("postId")
+
+		commentsClosed, err := h.checkCommentsClosed(r.Context(), postID)
+		if err != nil {
+			if _, ok := errors.AsType[discuss.PostNotFoundError](err); ok {
+				http.Error(w, "Post not found", http.StatusNotFound)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to check comment access", "postId", postID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		h.renderReplyPage(w, r, http.StatusOK, postID, r.PathValue("commentId"), map[string]any{
+			"CommentsClosed": commentsClosed,
+		})
+	})
+
+	return h.AuthenticatedOnly(hf)
+}
+
+// renderReplyPage renders only the reply form for requests from the Reply button, which loads it below the comment.
+func (h *Handler) renderReplyPage(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	postID string,
+	commentID string,
+	extraData map[string]any,
+) {
+	data := map[string]any{
+		csrf.TemplateTag: csrf.TemplateField(r),
+		"PostID":         postID,
+		"CommentID":      commentID,
+		"SiteTitle":      "Reply",
+	}
+
+	maps.Copy(data, extraData)
+
+	w.WriteHeader(status)
+
+	if isHTMXRequest(r) {
+		h.renderTemplate(w, r, "reply-form.gohtml", data)
+
+		return
+	}
+
+	h.renderTemplate(w, r, "reply-page.gohtml", data)
+}
+
+// getPostComment gets a comment and makes sure it belongs to the post in the URL.
+func (h *Handler) getPostComment(ctx context.Context, postID, commentID string) (*discuss.Comment, error) {
+	comment, err := h.getThreadComment(ctx, postID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment.IsDeleted() {
+		return nil, discuss.CommentNotFoundError{ID: commentID}
+	}
+
+	return comment, nil
+}
+
+// getThreadComment is getPostComment for showing a thread, where deleted comments still have their place.
+func (h *Handler) getThreadComment(ctx context.Context, postID, commentID string) (*discuss.Comment, error) {
+	comment, err := h.discussSvc.GetComment(ctx, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	if comment.PostID != postID {
+		return nil, discuss.CommentNotFoundError{ID: commentID}
+	}
+
+	return comment, nil
+}
+
+func (h *Handler) HandleEditCommentPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		comment, err := h.getPostComment(r.Context(), r.PathValue("postId"), r.PathValue("commentId"))
+		if err != nil {
+			handleCommentError(w, r, err, "failed to get comment")
+
+			return
+		}
+
+		data := map[string]any{
+			"Comment":        comment,
+			csrf.TemplateTag: csrf.TemplateField(r),
+			"SiteTitle":      "Edit Comment",
+		}
+
+		h.renderTemplate(w, r, "edit-comment-page.gohtml", data)
+	})
+
+	return h.AuthenticatedOnly(hf)
+}
+
+func (h *Handler) HandleEditComment() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID := r.PathValue("postId")
+
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		comment, err := h.getPostComment(r.Context(), postID, r.PathValue("commentId"))
+		if err != nil {
+			handleCommentError(w, r, err, "failed to get comment")
+
+			return
+		}
+
+		_, err = h.discussSvc.UpdateComment(r.Context(), discuss.UpdateCommentRequest{
+			CommentID: comment.ID,
+			Content:   r.FormValue("content"),
+		})
+		if err != nil {
+			handleCommentError(w, r, err, "failed to update comment")
+
+			return
+		}
+
+		http.Redirect(w, r, "/p/"+postID+"/c/"+comment.ID, http.StatusSeeOther)
+	})
+
+	return h.AuthenticatedOnly(hf)
+}
+
+func (h *Handler) HandleDeleteComment() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID := r.PathValue("postId")
+
+		comment, err := h.getPostComment(r.Context(), postID, r.PathValue("commentId"))
+		if err != nil {
+			handleCommentError(w, r, err, "failed to get comment")
+
+			return
+		}
+
+		err = h.discussSvc.DeleteComment(r.Context(), comment.ID)
+		if err != nil {
+			handleCommentError(w, r, err, "failed to delete comment")
+
+			return
+		}
+
+		http.Redirect(w, r, "/p/"+postID+"#comments", http.StatusSeeOther)
+	})
+
+	return h.AuthenticatedOnly(hf)
+}
+
+func handleCommentError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	var (
+		commentNotFoundErr discuss.CommentNotFoundError
+		accessDeniedErr    *authorization.AccessDeniedError
+	)
+
+	switch {
+	case errors.As(err, &commentNotFoundErr):
+		http.Error(w, "Comment not found", http.StatusNotFound)
+	case errors.As(err, &accessDeniedErr):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	default:
+		slog.ErrorContext(r.Context(), msg, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) HandleToggleReaction() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetType := reactions.TargetType(r.PathValue("targetType"))
+		targetID := r.PathValue("targetId")
+
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse reaction form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		returnTo := sanitizeReturnToPath(r.FormValue("return_to"))
+
+		if !isAuthenticatedRequest(r) {
 			if r.Header.Get(htmxRequestHeader) == htmxRequestValueTrue {
 				w.Header().Set("HX-Redirect", "/login")
 				w.WriteHeader(http.StatusUnauthorized)
@@ -828,6 +1874,66 @@ func (h *Handler) HandleToggleReaction() http.Handler {
 	})
 }
 
+type SearchHitWithAuthor struct {
+	search.Hit
+
+	Author *Author
+}
+
+func (h *Handler) HandleSearchPage() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+
+		result, err := h.searchSvc.Search(r.Context(), search.SearchRequest{
+			Query:  query,
+			Offset: (page - 1) * search.DefaultSearchLimit,
+			Limit:  search.DefaultSearchLimit,
+		})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to search", "query", query, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		authors, err := h.loadAuthors(r.Context(), uniqueIDs(result.Hits, func(hit *search.Hit) string {
+			return hit.AuthorID
+		}))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get hit authors", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		hits := make([]*SearchHitWithAuthor, 0, len(result.Hits))
+
+		for _, hit := range result.Hits {
+			hits = append(hits, &SearchHitWithAuthor{Hit: *hit, Author: authors[hit.AuthorID]})
+		}
+
+		data := map[string]any{
+			"Query":     query,
+			"Hits":      hits,
+			"Page":      page,
+			"PrevPage":  page - 1,
+			"NextPage":  0,
+			"SiteTitle": "Search",
+		}
+
+		if result.HasMore {
+			data["NextPage"] = page + 1
+		}
+
+		h.renderTemplate(w, r, "search-page.gohtml", data)
+	})
+}
+
 func sanitizeReturnToPath(returnTo string) string {
 	const defaultPath = "/"
 
@@ -96,3 +96,32 @@ func TestSanitizeReturnToPath(t *testing.T) {
 		})
 	}
 }
+
+func TestAcceptsAPIToken(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		path     string
+		expected bool
+	}{
+		{path: "/", expected: true},
+		{path: "/create-post", expected: true},
+		{path: "/p/123/edit", expected: true},
+		{path: "/react/post/123", expected: true},
+		{path: "/search", expected: true},
+		{path: "/u/alice", expected: true},
+		{path: "/settings/tokens", expected: false},
+		{path: "/settings/email", expected: false},
+		{path: "/settings/two-factor/disable", expected: false},
+		{path: "/settings/account/delete", expected: false},
+		{path: "/logout", expected: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, acceptsAPIToken(tc.path))
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package web
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+
+	"github.com/gorilla/csrf"
+	"github.com/nasermirzaei89/scribble/authentication"
+)
+
+// redirectToOIDCProvider keeps the authorization state in the cookie session and sends the user to the provider.
+func (h *Handler) redirectToOIDCProvider(
+	w http.ResponseWriter,
+	r *http.Request,
+	stateKey string,
+	authorization *authentication.OIDCAuthorization,
+) {
+	err := h.setSessionValue(w, r, stateKey, string(authorization.State))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to set oidc state", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	http.Redirect(w, r, authorization.URL, http.StatusSeeOther)
+}
+
+func (h *Handler) HandleBeginOIDCLogin() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization, err := h.authSvc.BeginOIDCLogin(r.Context(), r.PathValue("provider"))
+		if err != nil {
+			if _, ok := errors.AsType[*authentication.OIDCProviderNotFoundError](err); ok {
+				http.NotFound(w, r)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to begin oidc login", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		h.redirectToOIDCProvider(w, r, oidcLoginStateKey, authorization)
+	})
+
+	return h.GuestOnly(hf)
+}
+
+// HandleOIDCCallback is where providers send the user back to. It finishes a sign-in for guests and links the account
+// for signed-in users, so a single redirect URI is registered at each provider.
+func (h *Handler) HandleOIDCCallback() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAuthenticatedRequest(r) {
+			h.finishOIDCLink(w, r)
+
+			return
+		}
+
+		h.finishOIDCLogin(w, r)
+	})
+}
+
+func (h *Handler) finishOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	state, err := h.takeSessionState(w, r, oidcLoginStateKey)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get oidc state", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	query := r.URL.Query()
+
+	// The user turned the provider down, or never started from here.
+	if state == nil || query.Has("error") {
+		h.renderLoginPage(w, r, http.StatusUnauthorized, "Sign-in was cancelled. Please try again.")
+
+		return
+	}
+
+	session, err := h.authSvc.FinishOIDCLogin(r.Context(), state, query.Get("state"), query.Get("code"), clientInfo(r))
+	if err != nil {
+		secondFactorErr, isSecondFactorRequired := errors.AsType[*authentication.SecondFactorRequiredError](err)
+
+		switch {
+		case isSecondFactorRequired:
+			h.redirectToSecondFactor(w, r, secondFactorErr.ChallengeID)
+		case errors.Is(err, authentication.ErrInvalidOIDCResponse):
+			slog.InfoContext(r.Context(), "oidc login rejected", "error", err)
+			h.renderLoginPage(w, r, http.StatusUnauthorized, "Sign-in failed. Please try again.")
+		default:
+			slog.ErrorContext(r.Context(), "failed to finish oidc login", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	err = h.setSessionValue(w, r, sessionIDKey, session.ID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to set session ID", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (h *Handler) finishOIDCLink(w http.ResponseWriter, r *http.Request) {
+	state, err := h.takeSessionState(w, r, oidcLinkStateKey)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get oidc state", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	query := r.URL.Query()
+
+	if state == nil || query.Has("error") {
+		h.renderIdentitiesPage(w, r, http.StatusUnauthorized, map[string]any{
+			"Error": "Linking was cancelled. Please try again.",
+		})
+
+		return
+	}
+
+	_, err = h.authSvc.FinishOIDCLink(r.Context(), state, query.Get("state"), query.Get("code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, authentication.ErrInvalidOIDCResponse):
+			slog.InfoContext(r.Context(), "oidc link rejected", "error", err)
+			h.renderIdentitiesPage(w, r, http.StatusUnauthorized, map[string]any{
+				"Error": "Linking failed. Please try again.",
+			})
+		default:
+			if _, ok := errors.AsType[*authentication.IdentityAlreadyLinkedError](err); ok {
+				h.renderIdentitiesPage(w, r, http.StatusConflict, map[string]any{
+					"Error": "That account is already linked to a user.",
+				})
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to finish oidc link", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	http.Redirect(w, r, "/settings/identities", http.StatusSeeOther)
+}
+
+// linkedProvider is a row of the connected accounts page.
+type linkedProvider struct {
+	Provider *authentication.OIDCProvider
+	Identity *authentication.Identity
+}
+
+func (h *Handler) HandleIdentitiesPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.renderIdentitiesPage(w, r, http.StatusOK, nil)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) renderIdentitiesPage(w http.ResponseWriter, r *http.Request, status int, extraData map[string]any) {
+	identities, err := h.authSvc.ListIdentities(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list identities", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	providers := h.authSvc.OIDCProviders()
+	linked := make([]linkedProvider, 0, len(providers))
+
+	for _, provider := range providers {
+		row := linkedProvider{Provider: provider, Identity: nil}
+
+		for _, identity := range identities {
+			if identity.Provider == provider.Name {
+				row.Identity = identity
+			}
+		}
+
+		linked = append(linked, row)
+	}
+
+	data := map[string]any{
+		"Providers":       linked,
+		csrf.TemplateTag:  csrf.TemplateField(r),
+		"SiteTitle":       "Connected accounts",
+		"SettingsSection": "identities",
+	}
+
+	maps.Copy(data, extraData)
+
+	w.WriteHeader(status)
+	h.renderTemplate(w, r, "settings-identities-page.gohtml", data)
+}
+
+func (h *Handler) HandleLinkIdentity() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization, err := h.authSvc.BeginOIDCLink(r.Context(), r.PathValue("provider"))
+		if err != nil {
+			if _, ok := errors.AsType[*authentication.OIDCProviderNotFoundError](err); ok {
+				http.NotFound(w, r)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to begin oidc link", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		h.redirectToOIDCProvider(w, r, oidcLinkStateKey, authorization)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) HandleUnlinkIdentity() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h.authSvc.UnlinkIdentity(r.Context(), r.PathValue("provider"))
+		if err != nil {
+			if errors.Is(err, authentication.ErrLastSignInMethod) {
+				h.renderIdentitiesPage(w, r, http.StatusConflict, map[string]any{
+					"Error": "Add a passkey or link another account first, or you won't be able to sign in.",
+				})
+
+				return
+			}
+
+			if _, ok := errors.AsType[*authentication.IdentityNotFoundError](err); ok {
+				http.Error(w, "Account not linked", http.StatusNotFound)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to unlink identity", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		http.Redirect(w, r, "/settings/identities", http.StatusSeeOther)
+	})
+
+	return h.SessionOnly(hf)
+}
@@ -0,0 +1,222 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/csrf"
+	"github.com/nasermirzaei89/scribble/authentication"
+)
+
+// maxPasskeyResponseSize bounds the browser's answer to a ceremony, which is a few kilobytes at most.
+const maxPasskeyResponseSize = 64 << 10
+
+// writePasskeyOptions keeps the ceremony state in the cookie session and sends the options to the browser's script.
+func (h *Handler) writePasskeyOptions(
+	w http.ResponseWriter,
+	r *http.Request,
+	stateKey string,
+	ceremony *authentication.PasskeyCeremony,
+) {
+	err := h.setSessionValue(w, r, stateKey, string(ceremony.State))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to set passkey state", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+
+	_, err = w.Write(ceremony.Options)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to write passkey options", "error", err)
+	}
+}
+
+func (h *Handler) HandleBeginPasskeyLogin() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ceremony, err := h.authSvc.BeginPasskeyLogin(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to begin passkey login", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		h.writePasskeyOptions(w, r, passkeyLoginStateKey, ceremony)
+	})
+
+	return h.GuestOnly(hf)
+}
+
+func (h *Handler) HandleFinishPasskeyLogin() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPasskeyResponseSize))
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		state, err := h.takeSessionState(w, r, passkeyLoginStateKey)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get passkey state", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		if state == nil {
+			http.Error(w, "No passkey sign-in in progress. Please try again.", http.StatusBadRequest)
+
+			return
+		}
+
+		session, err := h.authSvc.FinishPasskeyLogin(r.Context(), state, response, clientInfo(r))
+		if err != nil {
+			if errors.Is(err, authentication.ErrInvalidPasskey) {
+				slog.InfoContext(r.Context(), "passkey login rejected", "error", err)
+				http.Error(w, "That passkey couldn't be verified.", http.StatusUnauthorized)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to finish passkey login", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		err = h.setSessionValue(w, r, sessionIDKey, session.ID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to set session ID", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+
+	return h.GuestOnly(hf)
+}
+
+func (h *Handler) HandlePasskeysPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		passkeys, err := h.authSvc.ListPasskeys(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to list passkeys", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		data := map[string]any{
+			"Passkeys":        passkeys,
+			csrf.TemplateTag:  csrf.TemplateField(r),
+			"SiteTitle":       "Passkeys",
+			"SettingsSection": "passkeys",
+		}
+
+		h.renderTemplate(w, r, "settings-passkeys-page.gohtml", data)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) HandleBeginPasskeyRegistration() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ceremony, err := h.authSvc.BeginPasskeyRegistration(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to begin passkey registration", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		h.writePasskeyOptions(w, r, passkeyRegistrationStateKey, ceremony)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+// passkeyRegistrationRequest is what the settings page posts once the browser has created a passkey.
+type passkeyRegistrationRequest struct {
+	Name       string          `json:"name"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+func (h *Handler) HandleFinishPasskeyRegistration() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req passkeyRegistrationRequest
+
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPasskeyResponseSize)).Decode(&req)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		state, err := h.takeSessionState(w, r, passkeyRegistrationStateKey)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get passkey state", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		if state == nil {
+			http.Error(w, "No passkey registration in progress. Please try again.", http.StatusBadRequest)
+
+			return
+		}
+
+		_, err = h.authSvc.FinishPasskeyRegistration(r.Context(), req.Name, state, req.Credential)
+		if err != nil {
+			if errors.Is(err, authentication.ErrInvalidPasskey) {
+				slog.InfoContext(r.Context(), "passkey registration rejected", "error", err)
+				http.Error(w, "That passkey couldn't be verified.", http.StatusUnprocessableEntity)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to finish passkey registration", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		http.Redirect(w, r, "/settings/passkeys", http.StatusSeeOther)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) HandleDeletePasskey() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		passkeyID := r.PathValue("passkeyId")
+
+		err := h.authSvc.DeletePasskey(r.Context(), passkeyID)
+		if err != nil {
+			if _, ok := errors.AsType[*authentication.PasskeyNotFoundError](err); ok {
+				http.Error(w, "Passkey not found", http.StatusNotFound)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to delete passkey", "passkeyId", passkeyID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		http.Redirect(w, r, "/settings/passkeys", http.StatusSeeOther)
+	})
+
+	return h.SessionOnly(hf)
+}
@@ -0,0 +1,397 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/csrf"
+	"github.com/nasermirzaei89/scribble/authentication"
+	"github.com/nasermirzaei89/scribble/contents"
+	"github.com/nasermirzaei89/scribble/mentions"
+	"github.com/nasermirzaei89/scribble/profiles"
+)
+
+// Author is a user shown next to something they wrote, along with their profile.
+type Author struct {
+	*authentication.User
+
+	Profile *profiles.Profile
+}
+
+// Name is the display name, or the username for users who haven't set one.
+func (author *Author) Name() string {
+	if author.Profile != nil && author.Profile.DisplayName != "" {
+		return author.Profile.DisplayName
+	}
+
+	return author.Username
+}
+
+// loadAuthors returns the users with the given IDs and their profiles keyed by user ID.
+func (h *Handler) loadAuthors(ctx context.Context, userIDs []string) (map[string]*Author, error) {
+	users, err := h.authSvc.GetUsers(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	userProfiles, err := h.profilesSvc.GetProfiles(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profiles: %w", err)
+	}
+
+	authors := make(map[string]*Author, len(users))
+
+	for userID, user := range users {
+		authors[userID] = &Author{User: user, Profile: userProfiles[userID]}
+	}
+
+	return authors, nil
+}
+
+// avatarURL links to the avatar in the given size, versioned by upload time so it can be cached for good. Users
+// without an avatar get the anonymous one.
+func (h *Handler) avatarURL(profile *profiles.Profile, size profiles.AvatarSize) string {
+	if profile == nil || !profile.HasAvatar() {
+		return h.getAssetHashedURL("/images/anonymous.png")
+	}
+
+	return fmt.Sprintf("/avatars/%s/%s?v=%d", url.PathEscape(profile.UserID), size, profile.AvatarUpdatedAt.Unix())
+}
+
+// loadMentionLinks finds the users mentioned in the given Markdown texts in one query, and returns the links to their
+// profiles keyed by canonical username, for the markdown template function to render mentions with.
+func (h *Handler) loadMentionLinks(ctx context.Context, texts ...string) (map[string]string, error) {
+	usernames := make([]string, 0)
+	for _, text := range texts {
+		usernames = append(usernames, mentions.Extract(text)...)
+	}
+
+	if len(usernames) == 0 {
+		return map[string]string{}, nil
+	}
+
+	users, err := h.authSvc.GetUsersByUsernames(ctx, usernames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mentioned users: %w", err)
+	}
+
+	links := make(map[string]string, len(users))
+
+	for canonical, user := range users {
+		if user.ID == authentication.DeletedUserID {
+			continue
+		}
+
+		links[canonical] = "/u/" + url.PathEscape(user.Username)
+	}
+
+	return links, nil
+}
+
+func (h *Handler) HandleProfileSettingsPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.renderProfileSettingsPage(w, r, http.StatusOK, nil)
+	})
+
+	return h.AuthenticatedOnly(hf)
+}
+
+func (h *Handler) renderProfileSettingsPage(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	extraData map[string]any,
+) {
+	user, err := h.authSvc.GetCurrentUser(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get current user", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	profile, err := h.profilesSvc.GetProfile(r.Context(), user.ID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get profile", "userId", user.ID, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	data := map[string]any{
+		"User":                 user,
+		"Profile":              profile,
+		"DisplayName":          profile.DisplayName,
+		"Bio":                  profile.Bio,
+		"DisplayNameMaxLength": profiles.DisplayNameMaxLength,
+		"BioMaxLength":         profiles.BioMaxLength,
+		csrf.TemplateTag:       csrf.TemplateField(r),
+		"SiteTitle":            "Profile",
+		"SettingsSection":      "profile",
+	}
+
+	maps.Copy(data, extraData)
+
+	w.WriteHeader(status)
+	h.renderTemplate(w, r, "settings-profile-page.gohtml", data)
+}
+
+func (h *Handler) HandleUpdateProfile() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		user, err := h.authSvc.GetCurrentUser(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get current user", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		displayName := r.FormValue("display_name")
+		bio := r.FormValue("bio")
+
+		_, err = h.profilesSvc.UpdateProfile(r.Context(), profiles.UpdateProfileRequest{
+			UserID:      user.ID,
+			DisplayName: displayName,
+			Bio:         bio,
+		})
+		if err != nil {
+			if validationErr, ok := errors.AsType[*profiles.ValidationError](err); ok {
+				h.renderProfileSettingsPage(w, r, http.StatusUnprocessableEntity, map[string]any{
+					"DisplayName": displayName,
+					"Bio":         bio,
+					"Errors":      validationErr.Fields,
+				})
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to update profile", "userId", user.ID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		h.renderProfileSettingsPage(w, r, http.StatusOK, map[string]any{"Saved": true})
+	})
+
+	return h.AuthenticatedOnly(hf)
+}
+
+func (h *Handler) HandleSetAvatar() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := h.authSvc.GetCurrentUser(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get current user", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		file, _, err := r.FormFile("avatar")
+		if err != nil {
+			if errors.Is(err, http.ErrMissingFile) {
+				h.renderProfileSettingsPage(w, r, http.StatusUnprocessableEntity, map[string]any{
+					"AvatarError": "Choose an image to upload.",
+				})
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		defer func() {
+			err := file.Close()
+			if err != nil {
+				slog.ErrorContext(r.Context(), "failed to close uploaded avatar", "error", err)
+			}
+		}()
+
+		_, err = h.profilesSvc.SetAvatar(r.Context(), user.ID, file)
+		if err != nil {
+			if errors.Is(err, profiles.ErrInvalidAvatar) {
+				h.renderProfileSettingsPage(w, r, http.StatusUnprocessableEntity, map[string]any{
+					"AvatarError": fmt.Sprintf(
+						"That image can't be used. Upload a JPEG, PNG or GIF image of up to %d MiB.",
+						profiles.MaxAvatarBytes>>20,
+					),
+				})
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to set avatar", "userId", user.ID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		http.Redirect(w, r, "/settings/profile", http.StatusSeeOther)
+	})
+
+	return h.AuthenticatedOnly(hf)
+}
+
+func (h *Handler) HandleRemoveAvatar() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := h.authSvc.GetCurrentUser(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get current user", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		_, err = h.profilesSvc.RemoveAvatar(r.Context(), user.ID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to remove avatar", "userId", user.ID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		http.Redirect(w, r, "/settings/profile", http.StatusSeeOther)
+	})
+
+	return h.AuthenticatedOnly(hf)
+}
+
+// HandleAvatar serves a stored avatar image. Avatar URLs change with every upload, so responses are cached for good.
+func (h *Handler) HandleAvatar() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := r.PathValue("userId")
+		size := profiles.AvatarSize(r.PathValue("size"))
+
+		rc, err := h.profilesSvc.OpenAvatar(r.Context(), userID, size)
+		if err != nil {
+			if _, ok := errors.AsType[profiles.AvatarNotFoundError](err); ok {
+				http.Error(w, "Avatar not found", http.StatusNotFound)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to open avatar", "userId", userID, "size", size, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		defer func() {
+			err := rc.Close()
+			if err != nil {
+				slog.ErrorContext(r.Context(), "failed to close avatar", "error", err)
+			}
+		}()
+
+		w.Header().Set("Content-Type", profiles.AvatarContentType)
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		_, err = io.Copy(w, rc)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to write avatar", "error", err)
+		}
+	})
+}
+
+func (h *Handler) HandleUserPage() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username := r.PathValue("username")
+		cursor := r.URL.Query().Get("cursor")
+
+		user, err := h.authSvc.GetUserByUsername(r.Context(), username)
+		if err != nil {
+			if _, ok := errors.AsType[*authentication.UserByUsernameNotFoundError](err); ok {
+				http.Error(w, "User not found", http.StatusNotFound)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to get user", "username", username, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		profile, err := h.profilesSvc.GetProfile(r.Context(), user.ID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get profile", "userId", user.ID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		result, err := h.contentsSvc.ListPosts(r.Context(), contents.ListPostsParams{
+			AuthorID: user.ID,
+			Cursor:   cursor,
+			Limit:    contents.DefaultListPostsLimit,
+		})
+		if err != nil {
+			if _, ok := errors.AsType[contents.InvalidPostCursorError](err); ok {
+				http.Error(w, "Invalid cursor", http.StatusBadRequest)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to list posts", "authorId", user.ID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		userPath := "/u/" + url.PathEscape(user.Username)
+
+		postsWithAuthors, err := h.preloadPostAuthor(r.Context(), result.Posts, userPath, csrf.TemplateField(r))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to preload post authors", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		bioMentionLinks, err := h.loadMentionLinks(r.Context(), profile.Bio)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to load mentions", "userId", user.ID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		author := &Author{User: user, Profile: profile}
+
+		data := map[string]any{
+			"Author":          author,
+			"BioMentionLinks": bioMentionLinks,
+			"Posts":           postsWithAuthors,
+			"NextCursor":      result.NextCursor,
+			"FeedPath":        userPath,
+			csrf.TemplateTag:  csrf.TemplateField(r),
+			"SiteTitle":       author.Name(),
+		}
+
+		if cursor != "" && isHTMXRequest(r) {
+			h.renderTemplate(w, r, "posts-loop.gohtml", data)
+
+			return
+		}
+
+		h.renderTemplate(w, r, "user-page.gohtml", data)
+	})
+}
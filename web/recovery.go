@@ -0,0 +1,278 @@
+package web
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+
+	"github.com/gorilla/csrf"
+	"github.com/nasermirzaei89/scribble/authentication"
+)
+
+func (h *Handler) HandleForgotPasswordPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.renderForgotPasswordPage(w, r, http.StatusOK, nil)
+	})
+
+	return h.GuestOnly(hf)
+}
+
+func (h *Handler) renderForgotPasswordPage(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	extraData map[string]any,
+) {
+	data := map[string]any{
+		csrf.TemplateTag: csrf.TemplateField(r),
+		"SiteTitle":      "Forgot password",
+	}
+
+	maps.Copy(data, extraData)
+
+	w.WriteHeader(status)
+	h.renderTemplate(w, r, "forgot-password-page.gohtml", data)
+}
+
+func (h *Handler) HandleForgotPassword() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		email := r.FormValue("email")
+
+		err = h.authSvc.RequestPasswordReset(r.Context(), email)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to request password reset", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		// The same answer is given whether or not the address belongs to an account.
+		h.renderForgotPasswordPage(w, r, http.StatusOK, map[string]any{
+			"Email": email,
+			"Sent":  true,
+		})
+	})
+
+	return h.GuestOnly(hf)
+}
+
+func (h *Handler) HandleResetPasswordPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+
+		user, err := h.authSvc.CheckPasswordResetToken(r.Context(), token)
+		if err != nil {
+			if errors.Is(err, authentication.ErrInvalidToken) {
+				h.renderResetPasswordPage(w, r, http.StatusBadRequest, map[string]any{"InvalidToken": true})
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to check password reset token", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		h.renderResetPasswordPage(w, r, http.StatusOK, map[string]any{
+			"Token":    token,
+			"Username": user.Username,
+		})
+	})
+
+	return h.GuestOnly(hf)
+}
+
+func (h *Handler) renderResetPasswordPage(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	extraData map[string]any,
+) {
+	data := map[string]any{
+		csrf.TemplateTag: csrf.TemplateField(r),
+		"SiteTitle":      "Reset password",
+	}
+
+	maps.Copy(data, extraData)
+
+	// The token is in the URL, so it must not leak to other sites through the Referer header.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	h.renderTemplate(w, r, "reset-password-page.gohtml", data)
+}
+
+func (h *Handler) HandleResetPassword() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		token := r.FormValue("token")
+
+		err = h.authSvc.ResetPassword(r.Context(), token, r.FormValue("password"))
+		if err != nil {
+			validationErr, isValidationErr := errors.AsType[*authentication.ValidationError](err)
+
+			switch {
+			case errors.Is(err, authentication.ErrInvalidToken):
+				h.renderResetPasswordPage(w, r, http.StatusBadRequest, map[string]any{"InvalidToken": true})
+			case isValidationErr:
+				h.renderResetPasswordPage(w, r, http.StatusUnprocessableEntity, map[string]any{
+					"Token":  token,
+					"Errors": validationErr.Fields,
+				})
+			default:
+				slog.ErrorContext(r.Context(), "failed to reset password", "error", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	})
+
+	return h.GuestOnly(hf)
+}
+
+// HandleVerifyEmail follows the link from a verification email. It works without logging in, since the link may be
+// opened in another browser than the one the address was added from.
+func (h *Handler) HandleVerifyEmail() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h.authSvc.VerifyEmail(r.Context(), r.URL.Query().Get("token"))
+
+		status := http.StatusOK
+		data := map[string]any{
+			"SiteTitle": "Verify email",
+		}
+
+		if err != nil {
+			_, isInUse := errors.AsType[*authentication.EmailAlreadyInUseError](err)
+
+			switch {
+			case errors.Is(err, authentication.ErrInvalidToken):
+				status = http.StatusBadRequest
+				data["Error"] = "This link is invalid or has expired, or the address was changed since it was sent."
+			case isInUse:
+				status = http.StatusConflict
+				data["Error"] = "This address is already verified on another account."
+			default:
+				slog.ErrorContext(r.Context(), "failed to verify email", "error", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+				return
+			}
+		}
+
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.WriteHeader(status)
+		h.renderTemplate(w, r, "verify-email-page.gohtml", data)
+	})
+}
+
+func (h *Handler) HandleEmailSettingsPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.renderEmailSettingsPage(w, r, http.StatusOK, nil)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) renderEmailSettingsPage(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	extraData map[string]any,
+) {
+	user, err := h.authSvc.GetCurrentUser(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get current user", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	data := map[string]any{
+		"User":            user,
+		"Email":           user.Email,
+		csrf.TemplateTag:  csrf.TemplateField(r),
+		"SiteTitle":       "Email",
+		"SettingsSection": "email",
+	}
+
+	maps.Copy(data, extraData)
+
+	w.WriteHeader(status)
+	h.renderTemplate(w, r, "settings-email-page.gohtml", data)
+}
+
+func (h *Handler) HandleSetEmail() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		email := r.FormValue("email")
+
+		err = h.authSvc.SetEmail(r.Context(), email)
+		if err != nil {
+			if validationErr, ok := errors.AsType[*authentication.ValidationError](err); ok {
+				h.renderEmailSettingsPage(w, r, http.StatusUnprocessableEntity, map[string]any{
+					"Email":  email,
+					"Errors": validationErr.Fields,
+				})
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to set email", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		h.renderEmailSettingsPage(w, r, http.StatusOK, map[string]any{"Sent": true})
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) HandleResendEmailVerification() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h.authSvc.ResendEmailVerification(r.Context())
+		if err != nil {
+			if errors.Is(err, authentication.ErrEmailNotSet) {
+				http.Error(w, "No email to verify", http.StatusConflict)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to resend email verification", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		h.renderEmailSettingsPage(w, r, http.StatusOK, map[string]any{"Sent": true})
+	})
+
+	return h.SessionOnly(hf)
+}
@@ -1,11 +1,23 @@
 package web
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 )
 
-const sessionIDKey = "sessionId"
+const (
+	sessionIDKey = "sessionId"
+	// loginChallengeIDKey holds a password-verified login that still needs its second factor.
+	loginChallengeIDKey = "loginChallengeId"
+	// passkeyLoginStateKey and passkeyRegistrationStateKey hold the state of a WebAuthn ceremony between its two
+	// requests. The session cookie is signed, so the challenge can't be swapped.
+	passkeyLoginStateKey        = "passkeyLoginState"
+	passkeyRegistrationStateKey = "passkeyRegistrationState"
+	// oidcLoginStateKey and oidcLinkStateKey hold a sign-in at an identity provider until it redirects back.
+	oidcLoginStateKey = "oidcLoginState"
+	oidcLinkStateKey  = "oidcLinkState"
+)
 
 type SessionValueNotFoundError struct {
 	Key string
@@ -65,3 +77,25 @@ func (h *Handler) deleteSessionValue(w http.ResponseWriter, r *http.Request, key
 
 	return nil
 }
+
+// takeSessionState returns the state of a ceremony in progress and forgets it, so each one is finished once. It
+// returns nil if none was started.
+func (h *Handler) takeSessionState(w http.ResponseWriter, r *http.Request, key string) ([]byte, error) {
+	value, err := h.getSessionValue(r, key)
+	if err != nil {
+		if _, ok := errors.AsType[*SessionValueNotFoundError](err); ok {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	err = h.deleteSessionValue(w, r, key)
+	if err != nil {
+		return nil, err
+	}
+
+	state, _ := value.(string)
+
+	return []byte(state), nil
+}
@@ -0,0 +1,208 @@
+package web
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/csrf"
+	"github.com/nasermirzaei89/scribble/authentication"
+	authcontext "github.com/nasermirzaei89/scribble/authentication/context"
+	"github.com/nasermirzaei89/scribble/authorization"
+)
+
+// apiTokenExpiryOptions are the lifetimes offered when creating a token, in days. Zero means it never expires.
+var apiTokenExpiryOptions = []int{7, 30, 90, 365, 0}
+
+func (h *Handler) HandleAPITokensPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.renderAPITokensPage(w, r, http.StatusOK, nil)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+// renderAPITokensPage renders the token list. extraData carries the outcome of a create request: either the new
+// secret, which is shown only this once, or a form error.
+func (h *Handler) renderAPITokensPage(w http.ResponseWriter, r *http.Request, status int, extraData map[string]any) {
+	tokens, err := h.authSvc.ListAPITokens(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list api tokens", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	data := map[string]any{
+		"Tokens":          tokens,
+		"Scopes":          authentication.Scopes(),
+		"ExpiryOptions":   apiTokenExpiryOptions,
+		"Now":             time.Now(),
+		csrf.TemplateTag:  csrf.TemplateField(r),
+		"SiteTitle":       "API Tokens",
+		"SettingsSection": "tokens",
+	}
+
+	maps.Copy(data, extraData)
+
+	w.WriteHeader(status)
+	h.renderTemplate(w, r, "settings-tokens-page.gohtml", data)
+}
+
+func (h *Handler) HandleCreateAPIToken() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		var expiresAt *time.Time
+
+		days, err := strconv.Atoi(r.FormValue("expires_in_days"))
+		if err != nil || days < 0 {
+			http.Error(w, "Invalid expiry", http.StatusBadRequest)
+
+			return
+		}
+
+		if days > 0 {
+			t := time.Now().AddDate(0, 0, days)
+			expiresAt = &t
+		}
+
+		token, secret, err := h.authSvc.CreateAPIToken(r.Context(), authentication.CreateAPITokenRequest{
+			Name:      r.FormValue("name"),
+			Scopes:    r.Form["scopes"],
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			_, nameRequired := errors.AsType[*authentication.APITokenNameRequiredError](err)
+			_, invalidScope := errors.AsType[*authentication.InvalidScopeError](err)
+
+			switch {
+			case nameRequired:
+				h.renderAPITokensPage(w, r, http.StatusBadRequest, map[string]any{"Error": "Give the token a name."})
+			case invalidScope:
+				h.renderAPITokensPage(w, r, http.StatusBadRequest, map[string]any{"Error": "Pick at least one scope."})
+			default:
+				slog.ErrorContext(r.Context(), "failed to create api token", "error", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		h.renderAPITokensPage(w, r, http.StatusCreated, map[string]any{
+			"NewToken":  token,
+			"NewSecret": secret,
+		})
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) HandleRevokeAPIToken() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenID := r.PathValue("tokenId")
+
+		err := h.authSvc.RevokeAPIToken(r.Context(), tokenID)
+		if err != nil {
+			if _, ok := errors.AsType[*authentication.APITokenNotFoundError](err); ok {
+				http.Error(w, "Token not found", http.StatusNotFound)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to revoke api token", "tokenId", tokenID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		http.Redirect(w, r, "/settings/tokens", http.StatusSeeOther)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) HandleSessionsPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessions, err := h.authSvc.ListSessions(r.Context(), authcontext.GetSubject(r.Context()))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to list sessions", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		currentSessionID, _ := authcontext.SessionIDFromContext(r.Context())
+
+		data := map[string]any{
+			"Sessions":         sessions,
+			"CurrentSessionID": currentSessionID,
+			"Revoked":          r.URL.Query().Get("revoked"),
+			csrf.TemplateTag:   csrf.TemplateField(r),
+			"SiteTitle":        "Sessions",
+			"SettingsSection":  "sessions",
+		}
+
+		h.renderTemplate(w, r, "settings-sessions-page.gohtml", data)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) HandleRevokeSession() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.PathValue("sessionId")
+
+		err := h.authSvc.RevokeSession(r.Context(), sessionID)
+		if err != nil {
+			_, notFound := errors.AsType[*authentication.SessionNotFoundError](err)
+			_, accessDenied := errors.AsType[*authorization.AccessDeniedError](err)
+
+			switch {
+			case notFound, accessDenied:
+				// Don't tell other users' session IDs apart from missing ones.
+				http.Error(w, "Session not found", http.StatusNotFound)
+			default:
+				slog.ErrorContext(r.Context(), "failed to revoke session", "error", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		http.Redirect(w, r, "/settings/sessions?revoked=1", http.StatusSeeOther)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) HandleRevokeOtherSessions() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		revoked, err := h.authSvc.RevokeAllOtherSessions(r.Context())
+		if err != nil {
+			if errors.Is(err, authentication.ErrCurrentSessionNotFound) {
+				http.Error(w, "Only a signed-in browser can sign out other sessions", http.StatusBadRequest)
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to revoke other sessions", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		http.Redirect(w, r, "/settings/sessions?revoked="+strconv.Itoa(revoked), http.StatusSeeOther)
+	})
+
+	return h.SessionOnly(hf)
+}
@@ -0,0 +1,360 @@
+package web
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/csrf"
+	"github.com/nasermirzaei89/scribble/authentication"
+)
+
+// redirectToSecondFactor parks a password-verified login in the cookie session until the second factor is given.
+func (h *Handler) redirectToSecondFactor(w http.ResponseWriter, r *http.Request, challengeID string) {
+	err := h.setSessionValue(w, r, loginChallengeIDKey, challengeID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to set login challenge ID", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	http.Redirect(w, r, "/login/two-factor", http.StatusSeeOther)
+}
+
+// loginChallengeID returns the login waiting for its second factor, or an empty string if there is none.
+func (h *Handler) loginChallengeID(r *http.Request) (string, error) {
+	value, err := h.getSessionValue(r, loginChallengeIDKey)
+	if err != nil {
+		if _, ok := errors.AsType[*SessionValueNotFoundError](err); ok {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	challengeID, _ := value.(string)
+
+	return challengeID, nil
+}
+
+func (h *Handler) HandleLoginTwoFactorPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		challengeID, err := h.loginChallengeID(r)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get login challenge ID", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		if challengeID == "" {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+
+			return
+		}
+
+		h.renderLoginTwoFactorPage(w, r, http.StatusOK, "")
+	})
+
+	return h.GuestOnly(hf)
+}
+
+func (h *Handler) renderLoginTwoFactorPage(w http.ResponseWriter, r *http.Request, status int, formError string) {
+	data := map[string]any{
+		"Error":          formError,
+		csrf.TemplateTag: csrf.TemplateField(r),
+		"SiteTitle":      "Two-factor authentication",
+	}
+
+	w.WriteHeader(status)
+	h.renderTemplate(w, r, "login-two-factor-page.gohtml", data)
+}
+
+func (h *Handler) HandleLoginTwoFactor() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		challengeID, err := h.loginChallengeID(r)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get login challenge ID", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		if challengeID == "" {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+
+			return
+		}
+
+		session, err := h.authSvc.VerifySecondFactor(r.Context(), challengeID, r.FormValue("code"), clientInfo(r))
+		if err != nil {
+			tooManyAttemptsErr, isTooManyAttempts := errors.AsType[*authentication.TooManyAttemptsError](err)
+
+			switch {
+			case errors.Is(err, authentication.ErrInvalidSecondFactorCode):
+				h.renderLoginTwoFactorPage(w, r, http.StatusUnauthorized, "That code didn't work. Try again.")
+			case isTooManyAttempts:
+				w.Header().Set("Retry-After", strconv.Itoa(tooManyAttemptsErr.RetryAfterSeconds()))
+				h.renderLoginTwoFactorPage(
+					w,
+					r,
+					http.StatusTooManyRequests,
+					"Too many failed login attempts. Try again later.",
+				)
+			case errors.Is(err, authentication.ErrInvalidLoginChallenge):
+				h.expireLoginChallenge(w, r)
+			default:
+				slog.ErrorContext(r.Context(), "failed to verify second factor", "error", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		err = h.deleteSessionValue(w, r, loginChallengeIDKey)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to delete login challenge ID", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		err = h.setSessionValue(w, r, sessionIDKey, session.ID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to set session ID", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+
+	return h.GuestOnly(hf)
+}
+
+// expireLoginChallenge forgets a login that timed out or had too many wrong codes, so the next attempt starts over
+// with the password.
+func (h *Handler) expireLoginChallenge(w http.ResponseWriter, r *http.Request) {
+	err := h.deleteSessionValue(w, r, loginChallengeIDKey)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to delete login challenge ID", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	h.renderLoginTwoFactorPage(w, r, http.StatusUnauthorized, "This sign-in has expired. Please sign in again.")
+}
+
+func (h *Handler) HandleTwoFactorPage() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.renderTwoFactorPage(w, r, http.StatusOK, nil)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+// renderTwoFactorPage renders the two-factor settings. extraData carries the outcome of a form: either freshly
+// generated recovery codes, which are shown only this once, or a form error.
+func (h *Handler) renderTwoFactorPage(w http.ResponseWriter, r *http.Request, status int, extraData map[string]any) {
+	twoFactor, err := h.authSvc.GetTwoFactorStatus(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get two-factor status", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	data := map[string]any{
+		"TwoFactor":       twoFactor,
+		csrf.TemplateTag:  csrf.TemplateField(r),
+		"SiteTitle":       "Two-factor authentication",
+		"SettingsSection": "two-factor",
+	}
+
+	if twoFactor.PendingEnrollment {
+		enrollment, err := h.authSvc.GetTOTPEnrollment(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to get totp enrollment", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		data["Enrollment"] = enrollment
+	}
+
+	maps.Copy(data, extraData)
+
+	w.WriteHeader(status)
+	h.renderTemplate(w, r, "settings-two-factor-page.gohtml", data)
+}
+
+func (h *Handler) HandleTwoFactorQRCode() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enrollment, err := h.authSvc.GetTOTPEnrollment(r.Context())
+		if err != nil {
+			_, notFound := errors.AsType[*authentication.TOTPCredentialNotFoundError](err)
+			_, alreadyEnabled := errors.AsType[*authentication.TOTPAlreadyEnabledError](err)
+
+			switch {
+			case notFound, alreadyEnabled:
+				http.Error(w, "No pending enrollment", http.StatusNotFound)
+			default:
+				slog.ErrorContext(r.Context(), "failed to get totp enrollment", "error", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		svg, err := renderQRCodeSVG(enrollment.URI)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to render qr code", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		// The code embeds the secret.
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", "image/svg+xml")
+
+		_, err = w.Write([]byte(svg))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to write qr code", "error", err)
+		}
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) HandleBeginTwoFactor() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := h.authSvc.BeginTOTPEnrollment(r.Context())
+		if err != nil {
+			if _, ok := errors.AsType[*authentication.TOTPAlreadyEnabledError](err); ok {
+				h.renderTwoFactorPage(w, r, http.StatusConflict, map[string]any{
+					"Error": "Two-factor authentication is already on.",
+				})
+
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "failed to begin totp enrollment", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
+		http.Redirect(w, r, "/settings/two-factor", http.StatusSeeOther)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) HandleConfirmTwoFactor() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		codes, err := h.authSvc.ConfirmTOTPEnrollment(r.Context(), r.FormValue("code"))
+		if err != nil {
+			h.handleTwoFactorFormError(w, r, err, "failed to confirm totp enrollment")
+
+			return
+		}
+
+		h.renderTwoFactorPage(w, r, http.StatusOK, map[string]any{"RecoveryCodes": codes})
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) HandleRegenerateRecoveryCodes() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		codes, err := h.authSvc.RegenerateRecoveryCodes(r.Context(), r.FormValue("code"))
+		if err != nil {
+			h.handleTwoFactorFormError(w, r, err, "failed to regenerate recovery codes")
+
+			return
+		}
+
+		h.renderTwoFactorPage(w, r, http.StatusOK, map[string]any{"RecoveryCodes": codes})
+	})
+
+	return h.SessionOnly(hf)
+}
+
+func (h *Handler) HandleDisableTwoFactor() http.Handler {
+	hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to parse form", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+
+			return
+		}
+
+		err = h.authSvc.DisableTOTP(r.Context(), r.FormValue("code"))
+		if err != nil {
+			h.handleTwoFactorFormError(w, r, err, "failed to disable totp")
+
+			return
+		}
+
+		http.Redirect(w, r, "/settings/two-factor", http.StatusSeeOther)
+	})
+
+	return h.SessionOnly(hf)
+}
+
+// handleTwoFactorFormError shows the errors a user can fix on the settings page and fails with a 500 otherwise.
+func (h *Handler) handleTwoFactorFormError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	_, alreadyEnabled := errors.AsType[*authentication.TOTPAlreadyEnabledError](err)
+	_, notEnabled := errors.AsType[*authentication.TOTPNotEnabledError](err)
+	_, notFound := errors.AsType[*authentication.TOTPCredentialNotFoundError](err)
+
+	switch {
+	case errors.Is(err, authentication.ErrInvalidSecondFactorCode):
+		h.renderTwoFactorPage(w, r, http.StatusUnprocessableEntity, map[string]any{
+			"Error": "That code didn't work. Try again.",
+		})
+	case alreadyEnabled:
+		h.renderTwoFactorPage(w, r, http.StatusConflict, map[string]any{
+			"Error": "Two-factor authentication is already on.",
+		})
+	case notEnabled, notFound:
+		h.renderTwoFactorPage(w, r, http.StatusConflict, map[string]any{
+			"Error": "Two-factor authentication is off.",
+		})
+	default:
+		slog.ErrorContext(r.Context(), msg, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}